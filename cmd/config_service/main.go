@@ -3,11 +3,14 @@ package main
 import (
 	"distributedConfig/config"
 	"distributedConfig/internal/app"
+	"flag"
 	"log"
 )
 
 func main() {
-	log.Println("Starting server")
+	reencryptSecrets := flag.Bool("reencrypt-secrets", false,
+		"re-encrypt secret values with the primary master key of SECRETS_KEY_FILE and exit")
+	flag.Parse()
 	configPath := "."
 	cfg, err := config.GetConfig(configPath)
 	if err != nil {
@@ -15,5 +18,15 @@ func main() {
 		return
 	}
 	log.Printf("Successfully parsed config")
-	app.Run(cfg)
+	if *reencryptSecrets {
+		if err := app.ReencryptSecrets(cfg); err != nil {
+			log.Fatalf("Failed to re-encrypt secrets: %v", err)
+		}
+		return
+	}
+	log.Println("Starting server")
+	if err := app.Run(cfg); err != nil {
+		log.Fatalf("Failed to run server: %v", err)
+	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"context"
+	"distributedConfig/internal/delivery/proto"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var errUsage = errors.New("invalid usage")
+
+type cli struct {
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	options options
+	flags   *flag.FlagSet
+	conn    *grpc.ClientConn
+	api     proto.ConfigServiceClient
+}
+
+func (c *cli) run(args []string) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(c.stdout)
+		return nil
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		usage(c.stderr)
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	c.flags = flag.NewFlagSet("dcctl "+args[0], flag.ContinueOnError)
+	c.flags.SetOutput(c.stderr)
+	c.flags.Usage = func() {
+		fmt.Fprintf(c.stderr, "Usage: dcctl %s %s\n\n%s.\n\nFlags:\n", args[0], cmd.usage, cmd.description)
+		c.flags.PrintDefaults()
+	}
+	c.options.register(c.flags)
+	err := cmd.run(c, args[1:])
+	if c.conn != nil {
+		_ = c.conn.Close()
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return nil
+	}
+	return err
+}
+
+// parse parses flags interleaved with positional arguments, checks that the number of positional
+// arguments is one of counts and connects to the service.
+func (c *cli) parse(args []string, counts ...int) ([]string, error) {
+	var positional []string
+	for {
+		if err := c.flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = c.flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	valid := false
+	for _, n := range counts {
+		valid = valid || len(positional) == n
+	}
+	if !valid {
+		c.flags.Usage()
+		return nil, errUsage
+	}
+	if err := c.options.resolve(c.flags); err != nil {
+		return nil, err
+	}
+	conn, err := c.options.dial()
+	if err != nil {
+		return nil, err
+	}
+	c.conn = conn
+	c.api = proto.NewConfigServiceClient(conn)
+	return positional, nil
+}
+
+func (c *cli) context() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), c.options.timeout)
+}
+
+func (c *cli) get(args []string) error {
+	version := c.flags.Int64("version", 0, "version to print instead of the relevant one")
+	positional, err := c.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	response, err := c.fetch(ctx, positional[0], *version)
+	if err != nil {
+		return err
+	}
+	return printConfig(c.stdout, c.options.output, newConfigView(response))
+}
+
+func (c *cli) history(args []string) error {
+	positional, err := c.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	stream, err := c.api.ListConfigs(ctx, &proto.ListRequest{Namespace: c.options.namespace, ServiceName: positional[0]})
+	if err != nil {
+		return err
+	}
+	var configs []configView
+	for {
+		response, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		configs = append(configs, newConfigView(response))
+	}
+	return printHistory(c.stdout, c.options.output, configs)
+}
+
+func (c *cli) create(args []string) error {
+	file := c.flags.String("f", "", "file with the keys of the config, - for stdin")
+	positional, err := c.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	request, err := c.readConfig(*file)
+	if err != nil {
+		return err
+	}
+	request.Namespace, request.ServiceName = c.options.namespace, positional[0]
+	ctx, cancel := c.context()
+	defer cancel()
+	response, err := c.api.CreateConfig(ctx, request)
+	if err != nil {
+		return err
+	}
+	return printConfig(c.stdout, c.options.output, newConfigView(response))
+}
+
+func (c *cli) update(args []string) error {
+	file := c.flags.String("f", "", "file with the keys of the config, - for stdin")
+	expectedVersion := c.flags.Int64("expected-version", 0, "fail if the latest version differs")
+	positional, err := c.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	request, err := c.readConfig(*file)
+	if err != nil {
+		return err
+	}
+	request.Namespace, request.ServiceName = c.options.namespace, positional[0]
+	if *expectedVersion != 0 {
+		request.ExpectedVersion = expectedVersion
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	response, err := c.api.UpdateConfig(ctx, request)
+	if err != nil {
+		return err
+	}
+	return printConfig(c.stdout, c.options.output, newConfigView(response))
+}
+
+func (c *cli) patch(args []string) error {
+	set := make(map[string]string)
+	var unset []string
+	c.flags.Func("set", "key=value to add or replace, can be repeated", func(value string) error {
+		key, value, ok := strings.Cut(value, "=")
+		if !ok {
+			return errors.New("expected key=value")
+		}
+		set[key] = value
+		return nil
+	})
+	c.flags.Func("unset", "key to remove, can be repeated", func(key string) error {
+		unset = append(unset, key)
+		return nil
+	})
+	expectedVersion := c.flags.Int64("expected-version", 0, "fail if the relevant version differs")
+	positional, err := c.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	request := &proto.PatchConfigRequest{Namespace: c.options.namespace, ServiceName: positional[0], Set: set, Unset: unset}
+	if *expectedVersion != 0 {
+		request.ExpectedVersion = expectedVersion
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	response, err := c.api.PatchConfig(ctx, request)
+	if err != nil {
+		return err
+	}
+	return printConfig(c.stdout, c.options.output, newConfigView(response))
+}
+
+func (c *cli) delete(args []string) error {
+	version := c.flags.Int64("version", 0, "version to delete instead of the whole config")
+	positional, err := c.parse(args, 1)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	var response *proto.DeleteResponse
+	if *version != 0 {
+		response, err = c.api.DeleteConfigVersion(ctx, &proto.ConfigNameAndVersion{
+			Namespace:   c.options.namespace,
+			ServiceName: positional[0],
+			Version:     *version,
+		})
+	} else {
+		response, err = c.api.DeleteConfig(ctx, &proto.ConfigName{Namespace: c.options.namespace, ServiceName: positional[0]})
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.stdout, response.GetMessage())
+	return nil
+}
+
+func (c *cli) setRelevant(args []string) error {
+	positional, err := c.parse(args, 2)
+	if err != nil {
+		return err
+	}
+	version, err := parseVersion(positional[1])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	response, err := c.api.SetRelevantConfig(ctx, &proto.ConfigNameAndVersion{
+		Namespace:   c.options.namespace,
+		ServiceName: positional[0],
+		Version:     version,
+	})
+	if err != nil {
+		return err
+	}
+	return printConfig(c.stdout, c.options.output, newConfigView(response))
+}
+
+func (c *cli) promote(args []string) error {
+	target := c.flags.String("to", "", "namespace to promote the version to")
+	setOverrides := make(map[string]string)
+	var unsetOverrides []string
+	c.flags.Func("set-override", "key=value to keep in the target namespace, can be repeated", func(value string) error {
+		key, value, ok := strings.Cut(value, "=")
+		if !ok {
+			return errors.New("expected key=value")
+		}
+		setOverrides[key] = value
+		return nil
+	})
+	c.flags.Func("unset-override", "key to stop overriding in the target namespace, can be repeated", func(key string) error {
+		unsetOverrides = append(unsetOverrides, key)
+		return nil
+	})
+	positional, err := c.parse(args, 2)
+	if err != nil {
+		return err
+	}
+	if *target == "" {
+		return errors.New("flag --to is required")
+	}
+	version, err := parseVersion(positional[1])
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	response, err := c.api.PromoteConfig(ctx, &proto.PromoteConfigRequest{
+		ServiceName:     positional[0],
+		SourceNamespace: c.options.namespace,
+		TargetNamespace: *target,
+		Version:         version,
+		SetOverrides:    setOverrides,
+		UnsetOverrides:  unsetOverrides,
+	})
+	if err != nil {
+		return err
+	}
+	return printConfig(c.stdout, c.options.output, newConfigView(response))
+}
+
+func (c *cli) diff(args []string) error {
+	file := c.flags.String("f", "", "file with proposed keys to compare with the relevant version")
+	positional, err := c.parse(args, 1, 3)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := c.context()
+	defer cancel()
+	var response *proto.DiffResponse
+	if len(positional) == 1 {
+		request, err := c.readConfig(*file)
+		if err != nil {
+			return err
+		}
+		request.Namespace, request.ServiceName = c.options.namespace, positional[0]
+		response, err = c.api.DiffProposedConfig(ctx, request)
+		if err != nil {
+			return err
+		}
+	} else {
+		from, err := parseVersion(positional[1])
+		if err != nil {
+			return err
+		}
+		to, err := parseVersion(positional[2])
+		if err != nil {
+			return err
+		}
+		response, err = c.api.DiffConfigVersions(ctx, &proto.DiffRequest{
+			Namespace:   c.options.namespace,
+			ServiceName: positional[0],
+			FromVersion: from,
+			ToVersion:   to,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return printDiff(c.stdout, c.options.output, response)
+}
+
+// fetch returns the given version of the config, or the relevant one if version is 0.
+func (c *cli) fetch(ctx context.Context, name string, version int64) (*proto.ConfigResponse, error) {
+	if version != 0 {
+		return c.api.GetConfigByVersion(ctx, &proto.ConfigNameAndVersion{
+			Namespace:   c.options.namespace,
+			ServiceName: name,
+			Version:     version,
+		})
+	}
+	return c.api.GetConfig(ctx, &proto.ConfigName{Namespace: c.options.namespace, ServiceName: name})
+}
+
+func (c *cli) readConfig(file string) (*proto.Config, error) {
+	if file == "" {
+		return nil, errors.New("flag -f is required")
+	}
+	return readConfig(file, c.stdin)
+}
+
+func parseVersion(value string) (int64, error) {
+	version, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, fmt.Errorf("invalid version %q", value)
+	}
+	return version, nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"distributedConfig/internal/delivery/proto"
+	"fmt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// readConfig reads the keys of a config from a YAML or JSON object, or from a .env file.
+// The path "-" reads a YAML or JSON object from stdin. Keys of .env files and YAML strings are sent
+// as strings, while other YAML values keep their types: numbers, booleans, lists and objects,
+// and strings tagged !secret or !duration.
+func readConfig(path string, stdin io.Reader) (*proto.Config, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if strings.EqualFold(filepath.Ext(path), ".env") {
+		values, err := parseEnv(data)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.Config{Data: values}, nil
+	}
+	// YAML is a superset of JSON, so one decoder handles both.
+	var nodes map[string]yaml.Node
+	if err := yaml.Unmarshal(data, &nodes); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	config := &proto.Config{Data: make(map[string]string), Values: make(map[string]*proto.TypedValue)}
+	for key, node := range nodes {
+		typed, err := typedValue(&node)
+		if err != nil {
+			return nil, fmt.Errorf("%s: value of key %s: %w", path, key, err)
+		}
+		if typed == nil {
+			config.Data[key] = node.Value
+		} else {
+			config.Values[key] = typed
+		}
+	}
+	return config, nil
+}
+
+// typedValue converts a YAML value to a typed value, or returns nil if the value is a string.
+func typedValue(node *yaml.Node) (*proto.TypedValue, error) {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var value []interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		list, err := structpb.NewList(value)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.TypedValue{Kind: &proto.TypedValue_ListValue{ListValue: list}}, nil
+	case yaml.MappingNode:
+		var value map[string]interface{}
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		object, err := structpb.NewStruct(value)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.TypedValue{Kind: &proto.TypedValue_ObjectValue{ObjectValue: object}}, nil
+	case yaml.ScalarNode:
+	default:
+		return nil, fmt.Errorf("unsupported YAML node %s", node.Tag)
+	}
+	switch node.Tag {
+	case "!secret":
+		return &proto.TypedValue{Kind: &proto.TypedValue_SecretValue{SecretValue: node.Value}}, nil
+	case "!duration":
+		value, err := time.ParseDuration(node.Value)
+		if err != nil {
+			return nil, err
+		}
+		return &proto.TypedValue{Kind: &proto.TypedValue_DurationValue{DurationValue: durationpb.New(value)}}, nil
+	case "!!int":
+		var value int64
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return &proto.TypedValue{Kind: &proto.TypedValue_IntValue{IntValue: value}}, nil
+	case "!!float":
+		var value float64
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return &proto.TypedValue{Kind: &proto.TypedValue_FloatValue{FloatValue: value}}, nil
+	case "!!bool":
+		var value bool
+		if err := node.Decode(&value); err != nil {
+			return nil, err
+		}
+		return &proto.TypedValue{Kind: &proto.TypedValue_BoolValue{BoolValue: value}}, nil
+	default:
+		// Strings, nulls and timestamps are sent as they are written.
+		return nil, nil
+	}
+}
+
+func parseEnv(data []byte) (map[string]string, error) {
+	values := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", line)
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			value = unquoted
+		case len(value) >= 2 && strings.HasPrefix(value, "'") && strings.HasSuffix(value, "'"):
+			value = value[1 : len(value)-1]
+		}
+		values[strings.TrimSpace(key)] = value
+	}
+	return values, scanner.Err()
+}
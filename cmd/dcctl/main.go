@@ -0,0 +1,52 @@
+// dcctl manages configs of the distributed config service from the command line.
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+)
+
+type command struct {
+	usage       string
+	description string
+	run         func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"get":          {"<name> [--version N]", "print the relevant or the given version of a config", (*cli).get},
+	"history":      {"<name>", "list all versions of a config", (*cli).history},
+	"create":       {"<name> -f <file>", "create a config from a YAML, JSON or .env file", (*cli).create},
+	"update":       {"<name> -f <file> [--expected-version N]", "create a new version of a config", (*cli).update},
+	"patch":        {"<name> [--set key=value] [--unset key]", "create a new version from the relevant one", (*cli).patch},
+	"delete":       {"<name> [--version N]", "delete a config or one of its versions", (*cli).delete},
+	"set-relevant": {"<name> <version>", "make the given version of a config relevant", (*cli).setRelevant},
+	"rollback":     {"<name> <version>", "alias of set-relevant", (*cli).setRelevant},
+	"promote":      {"<name> <version> --to <namespace>", "copy a version of a config to another namespace", (*cli).promote},
+	"diff":         {"<name> <version> <version> | <name> -f <file>", "show changed keys between two versions or against a file", (*cli).diff},
+}
+
+func main() {
+	c := &cli{stdin: os.Stdin, stdout: os.Stdout, stderr: os.Stderr}
+	if err := c.run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "dcctl:", err)
+		os.Exit(1)
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: dcctl <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-13s %-42s %s\n", name, commands[name].usage, commands[name].description)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Run 'dcctl <command> -h' to list the flags of a command.")
+}
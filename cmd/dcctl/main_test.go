@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	cfg "distributedConfig/config"
+	grpc_service "distributedConfig/internal/delivery/grpc"
+	"distributedConfig/internal/delivery/proto"
+	"distributedConfig/internal/repository/memory_repository"
+	"distributedConfig/internal/usecase"
+	"distributedConfig/pkg/logger"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// startServer starts the config service on a loopback port and points dcctl at it through
+// the environment. The interceptor records the Authorization header of the last call.
+func startServer(t *testing.T) *string {
+	t.Helper()
+	configRepository, auditRepository := memory_repository.NewAuditedConfigRepository()
+	configUseCase := usecase.NewConfigUseCase(*logger.New("error"), configRepository,
+		auditRepository, memory_repository.NewConfigNotifier(),
+		&cfg.Config{Server: cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true}})
+	var authorization string
+	server := grpc.NewServer(grpc.UnaryInterceptor(func(ctx context.Context, req interface{},
+		info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		authorization = strings.Join(md.Get("authorization"), ",")
+		return handler(ctx, req)
+	}))
+	proto.RegisterConfigServiceServer(server, grpc_service.NewConfigService(*configUseCase))
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("DCCTL_ADDRESS", listener.Addr().String())
+	t.Setenv("DCCTL_PROFILE", "")
+	t.Setenv("DCCTL_NAMESPACE", "")
+	t.Setenv("DCCTL_TOKEN", "")
+	t.Setenv("DCCTL_TLS", "")
+	return &authorization
+}
+
+func run(t *testing.T, stdin string, args ...string) (string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	c := &cli{stdin: strings.NewReader(stdin), stdout: &stdout, stderr: &stderr}
+	err := c.run(args)
+	return stdout.String(), err
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestCommands(t *testing.T) {
+	startServer(t)
+
+	_, err := run(t, "", "create", "test", "-f", writeFile(t, "test.env", "# comment\nHOST=localhost\nexport GREETING=\"hello world\"\n"))
+	require.NoError(t, err)
+	out, err := run(t, "", "get", "test", "-o", "env")
+	require.NoError(t, err)
+	require.Equal(t, "GREETING=\"hello world\"\nHOST=localhost\n", out)
+
+	_, err = run(t, "port: 8080\nhost: example.com\n", "update", "test", "-f", "-", "--expected-version", "1")
+	require.NoError(t, err)
+	_, err = run(t, "a: b", "update", "test", "-f", "-", "--expected-version", "1")
+	require.Equal(t, codes.Aborted, status.Code(err))
+
+	out, err = run(t, "", "get", "test", "--version", "1", "-o", "json")
+	require.NoError(t, err)
+	var view configView
+	require.NoError(t, json.Unmarshal([]byte(out), &view))
+	require.Equal(t, int64(1), view.Version)
+	require.Equal(t, "localhost", view.Data["HOST"])
+
+	out, err = run(t, "", "history", "test", "-o", "yaml")
+	require.NoError(t, err)
+	require.Contains(t, out, "version: 1")
+	require.Contains(t, out, "version: 2")
+
+	out, err = run(t, "", "diff", "test", "1", "2", "-o", "json")
+	require.NoError(t, err)
+	var changes []change
+	require.NoError(t, json.Unmarshal([]byte(out), &changes))
+	require.Equal(t, []change{
+		{Key: "GREETING", Change: "removed", Old: "hello world"},
+		{Key: "HOST", Change: "removed", Old: "localhost"},
+		{Key: "host", Change: "added", New: "example.com"},
+		{Key: "port", Change: "added", New: "8080"},
+	}, changes)
+
+	out, err = run(t, "host: example.com\nport: 9090\n", "diff", "test", "-f", "-")
+	require.NoError(t, err)
+	require.Regexp(t, `KEY\s+CHANGE\s+VERSION 2\s+PROPOSED`, out)
+	require.Regexp(t, `port\s+changed\s+8080\s+9090`, out)
+
+	out, err = run(t, "", "rollback", "test", "1")
+	require.NoError(t, err)
+	require.Contains(t, out, "version 1")
+	require.Regexp(t, `HOST\s+localhost`, out)
+
+	out, err = run(t, "", "patch", "test", "--set", "HOST=example.com", "--unset", "GREETING", "-o", "env")
+	require.NoError(t, err)
+	require.Equal(t, "HOST=example.com\n", out)
+
+	_, err = run(t, "", "delete", "test", "--version", "2")
+	require.NoError(t, err)
+	_, err = run(t, "", "get", "test", "--version", "2")
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = run(t, "", "get")
+	require.Equal(t, errUsage, err)
+	_, err = run(t, "", "unknown")
+	require.Error(t, err)
+}
+
+func TestNamespace(t *testing.T) {
+	startServer(t)
+
+	_, err := run(t, "key: staging", "create", "test", "-f", "-", "--namespace", "staging")
+	require.NoError(t, err)
+	_, err = run(t, "", "get", "test")
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	t.Setenv("DCCTL_NAMESPACE", "staging")
+	out, err := run(t, "", "get", "test", "-o", "env")
+	require.NoError(t, err)
+	require.Equal(t, "key=staging\n", out)
+	_, err = run(t, "", "get", "test", "--namespace", "default")
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestPromote(t *testing.T) {
+	startServer(t)
+
+	_, err := run(t, "host: staging\nport: 8080\n", "create", "test", "-f", "-", "--namespace", "staging")
+	require.NoError(t, err)
+	_, err = run(t, "", "promote", "test", "1", "--namespace", "staging")
+	require.Error(t, err)
+	out, err := run(t, "", "promote", "test", "1", "--namespace", "staging", "--to", "production",
+		"--set-override", "host=production")
+	require.NoError(t, err)
+	require.Contains(t, out, "promoted from version 1 of staging")
+
+	out, err = run(t, "", "get", "test", "--namespace", "production", "-o", "json")
+	require.NoError(t, err)
+	var view configView
+	require.NoError(t, json.Unmarshal([]byte(out), &view))
+	require.Equal(t, map[string]string{"host": "production", "port": "8080"}, view.Data)
+	require.Equal(t, &sourceView{Namespace: "staging", Version: 1}, view.PromotedFrom)
+}
+
+func TestProfile(t *testing.T) {
+	authorization := startServer(t)
+	address := os.Getenv("DCCTL_ADDRESS")
+	t.Setenv("DCCTL_ADDRESS", "")
+	home := os.Getenv("HOME")
+	require.NoError(t, os.WriteFile(filepath.Join(home, profileFile),
+		[]byte("default:\n  address: "+address+"\n  token: default-token\nother:\n  address: 127.0.0.1:1\n"), 0o600))
+
+	_, err := run(t, "", "get", "test")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, "Bearer default-token", *authorization)
+
+	t.Setenv("DCCTL_TOKEN", "env-token")
+	_, err = run(t, "", "get", "test")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, "Bearer env-token", *authorization)
+
+	_, err = run(t, "", "get", "test", "--token", "flag-token")
+	require.Equal(t, codes.NotFound, status.Code(err))
+	require.Equal(t, "Bearer flag-token", *authorization)
+
+	_, err = run(t, "", "get", "test", "--profile", "other")
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	_, err = run(t, "", "get", "test", "--profile", "missing")
+	require.ErrorContains(t, err, "profile \"missing\" not found")
+}
+
+func TestTypedValues(t *testing.T) {
+	startServer(t)
+	conn, err := grpc.Dial(os.Getenv("DCCTL_ADDRESS"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	defer conn.Close()
+	api := proto.NewConfigServiceClient(conn)
+
+	_, err = run(t, "port: 8080\nratio: 0.5\ndebug: true\ntimeout: !duration 1m30s\nhosts: [a, b]\n"+
+		"limits: {cpu: 2}\npassword: !secret hunter2\nzip: '01234'\n", "create", "test", "-f", "-")
+	require.NoError(t, err)
+	_, err = run(t, `{"port": 9090, "password": "hunter3", "zip": "01234"}`, "update", "test", "-f", "-")
+	require.NoError(t, err)
+	response, err := api.GetConfig(context.Background(), &proto.ConfigName{ServiceName: "test"})
+	require.NoError(t, err)
+	values := response.GetConfig().GetValues()
+	require.Equal(t, int64(9090), values["port"].GetIntValue())
+	require.NotEmpty(t, values["password"].GetSecretValue())
+	require.Equal(t, "01234", values["zip"].GetStringValue())
+
+	response, err = api.GetConfigByVersion(context.Background(), &proto.ConfigNameAndVersion{ServiceName: "test", Version: 1})
+	require.NoError(t, err)
+	values = response.GetConfig().GetValues()
+	require.Equal(t, 0.5, values["ratio"].GetFloatValue())
+	require.True(t, values["debug"].GetBoolValue())
+	require.Equal(t, "1m30s", values["timeout"].GetDurationValue().AsDuration().String())
+	require.Len(t, values["hosts"].GetListValue().GetValues(), 2)
+	require.Equal(t, 2.0, values["limits"].GetObjectValue().GetFields()["cpu"].GetNumberValue())
+	require.NotEmpty(t, values["password"].GetSecretValue())
+
+	_, err = run(t, "timeout: !duration soon\n", "update", "test", "-f", "-")
+	require.ErrorContains(t, err, "timeout")
+}
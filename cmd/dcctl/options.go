@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultAddress = "localhost:8084"
+	defaultProfile = "default"
+	profileFile    = ".dcctl"
+)
+
+// profile is a named set of connection settings stored in ~/.dcctl:
+//
+//	default:
+//	  address: localhost:8084
+//	prod:
+//	  address: config.example.com:443
+//	  namespace: production
+//	  token: secret
+//	  tls: true
+type profile struct {
+	Address   string `yaml:"address"`
+	Namespace string `yaml:"namespace"`
+	Token     string `yaml:"token"`
+	TLS       bool   `yaml:"tls"`
+}
+
+// options are the settings shared by all commands. Flags take precedence over DCCTL_* environment
+// variables, which take precedence over the selected profile.
+type options struct {
+	address   string
+	namespace string
+	token     string
+	profile   string
+	tls       bool
+	timeout   time.Duration
+	output    string
+}
+
+func (o *options) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.address, "address", "", "address of the config service (env DCCTL_ADDRESS)")
+	fs.StringVar(&o.namespace, "namespace", "", "namespace of the configs, the default one if empty (env DCCTL_NAMESPACE)")
+	fs.StringVar(&o.token, "token", "", "API token sent in the Authorization header (env DCCTL_TOKEN)")
+	fs.StringVar(&o.profile, "profile", "", "profile from ~/.dcctl (env DCCTL_PROFILE)")
+	fs.BoolVar(&o.tls, "tls", false, "connect over TLS (env DCCTL_TLS)")
+	fs.DurationVar(&o.timeout, "timeout", 10*time.Second, "timeout of a request")
+	fs.StringVar(&o.output, "o", formatTable, "output format: table, json, yaml or env")
+}
+
+// resolve fills the settings that were not set by flags from the environment and the profile.
+func (o *options) resolve(fs *flag.FlagSet) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+
+	if !set["profile"] {
+		o.profile = os.Getenv("DCCTL_PROFILE")
+	}
+	p, err := loadProfile(o.profile)
+	if err != nil {
+		return err
+	}
+	if !set["address"] {
+		o.address = firstNonEmpty(os.Getenv("DCCTL_ADDRESS"), p.Address, defaultAddress)
+	}
+	if !set["namespace"] {
+		o.namespace = firstNonEmpty(os.Getenv("DCCTL_NAMESPACE"), p.Namespace)
+	}
+	if !set["token"] {
+		o.token = firstNonEmpty(os.Getenv("DCCTL_TOKEN"), p.Token)
+	}
+	if !set["tls"] {
+		o.tls = p.TLS
+		if value := os.Getenv("DCCTL_TLS"); value != "" {
+			if o.tls, err = strconv.ParseBool(value); err != nil {
+				return fmt.Errorf("invalid DCCTL_TLS: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// loadProfile reads the named profile from ~/.dcctl. A missing file or a missing default profile
+// is not an error, but an explicitly requested profile must exist.
+func loadProfile(name string) (profile, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return profile{}, nil
+	}
+	data, err := os.ReadFile(filepath.Join(home, profileFile))
+	if errors.Is(err, os.ErrNotExist) {
+		if name != "" {
+			return profile{}, fmt.Errorf("profile %q not found: %w", name, err)
+		}
+		return profile{}, nil
+	}
+	if err != nil {
+		return profile{}, err
+	}
+	var profiles map[string]profile
+	if err := yaml.Unmarshal(data, &profiles); err != nil {
+		return profile{}, fmt.Errorf("invalid %s: %w", profileFile, err)
+	}
+	if name == "" {
+		return profiles[defaultProfile], nil
+	}
+	p, ok := profiles[name]
+	if !ok {
+		return profile{}, fmt.Errorf("profile %q not found in %s", name, profileFile)
+	}
+	return p, nil
+}
+
+func (o *options) dial() (*grpc.ClientConn, error) {
+	transport := insecure.NewCredentials()
+	if o.tls {
+		transport = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	}
+	dialOptions := []grpc.DialOption{grpc.WithTransportCredentials(transport)}
+	if o.token != "" {
+		dialOptions = append(dialOptions, grpc.WithPerRPCCredentials(tokenCredentials{token: o.token, secure: o.tls}))
+	}
+	return grpc.Dial(o.address, dialOptions...)
+}
+
+// tokenCredentials sends the API token with every call.
+type tokenCredentials struct {
+	token  string
+	secure bool
+}
+
+func (c tokenCredentials) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + c.token}, nil
+}
+
+func (c tokenCredentials) RequireTransportSecurity() bool {
+	return c.secure
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"distributedConfig/internal/delivery/proto"
+	"encoding/json"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatYAML  = "yaml"
+	formatEnv   = "env"
+)
+
+type configView struct {
+	ServiceName string            `json:"service_name" yaml:"service_name"`
+	Version     int64             `json:"version" yaml:"version"`
+	CreatedAt   time.Time         `json:"created_at" yaml:"created_at"`
+	Data        map[string]string `json:"data" yaml:"data"`
+	// PromotedFrom is set if the version was promoted from another namespace.
+	PromotedFrom *sourceView `json:"promoted_from,omitempty" yaml:"promoted_from,omitempty"`
+}
+
+type sourceView struct {
+	Namespace string `json:"namespace" yaml:"namespace"`
+	Version   int64  `json:"version" yaml:"version"`
+}
+
+func newConfigView(response *proto.ConfigResponse) configView {
+	view := configView{
+		ServiceName: response.GetConfig().GetServiceName(),
+		Version:     response.GetVersion(),
+		CreatedAt:   response.GetCreatedAt().AsTime(),
+		Data:        response.GetConfig().GetData(),
+	}
+	if source := response.GetPromotedFrom(); source != nil {
+		view.PromotedFrom = &sourceView{Namespace: source.GetNamespace(), Version: source.GetVersion()}
+	}
+	return view
+}
+
+type change struct {
+	Key    string `json:"key" yaml:"key"`
+	Change string `json:"change" yaml:"change"`
+	Old    string `json:"old,omitempty" yaml:"old,omitempty"`
+	New    string `json:"new,omitempty" yaml:"new,omitempty"`
+}
+
+// changes flattens a diff into a list of changed keys sorted by key.
+func changes(diff *proto.DiffResponse) []change {
+	changes := make([]change, 0, len(diff.GetAdded())+len(diff.GetRemoved())+len(diff.GetChanged()))
+	for key, value := range diff.GetAdded() {
+		changes = append(changes, change{Key: key, Change: "added", New: value})
+	}
+	for key, value := range diff.GetRemoved() {
+		changes = append(changes, change{Key: key, Change: "removed", Old: value})
+	}
+	for key, value := range diff.GetChanged() {
+		changes = append(changes, change{Key: key, Change: "changed", Old: value.GetOldValue(), New: value.GetNewValue()})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Key < changes[j].Key
+	})
+	return changes
+}
+
+func printConfig(w io.Writer, format string, config configView) error {
+	switch format {
+	case formatTable:
+		fmt.Fprintf(w, "# %s, version %d, created at %s", config.ServiceName, config.Version,
+			config.CreatedAt.Format(time.RFC3339))
+		if config.PromotedFrom != nil {
+			fmt.Fprintf(w, ", promoted from version %d of %s", config.PromotedFrom.Version, config.PromotedFrom.Namespace)
+		}
+		fmt.Fprintln(w)
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "KEY\tVALUE")
+		for _, key := range sortedKeys(config.Data) {
+			fmt.Fprintf(tw, "%s\t%s\n", key, config.Data[key])
+		}
+		return tw.Flush()
+	case formatEnv:
+		for _, key := range sortedKeys(config.Data) {
+			fmt.Fprintf(w, "%s=%s\n", key, envValue(config.Data[key]))
+		}
+		return nil
+	default:
+		return encode(w, format, config)
+	}
+}
+
+func printHistory(w io.Writer, format string, configs []configView) error {
+	switch format {
+	case formatTable:
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "VERSION\tCREATED AT\tKEYS")
+		for _, config := range configs {
+			fmt.Fprintf(tw, "%d\t%s\t%s\n", config.Version, config.CreatedAt.Format(time.RFC3339),
+				strings.Join(sortedKeys(config.Data), ","))
+		}
+		return tw.Flush()
+	case formatEnv:
+		return fmt.Errorf("format %s is not supported by history", format)
+	default:
+		return encode(w, format, configs)
+	}
+}
+
+func printDiff(w io.Writer, format string, diff *proto.DiffResponse) error {
+	switch format {
+	case formatTable:
+		to := "PROPOSED"
+		if diff.GetToVersion() != 0 {
+			to = fmt.Sprintf("VERSION %d", diff.GetToVersion())
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "KEY\tCHANGE\tVERSION %d\t%s\n", diff.GetFromVersion(), to)
+		for _, c := range changes(diff) {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Key, c.Change, c.Old, c.New)
+		}
+		return tw.Flush()
+	case formatEnv:
+		return fmt.Errorf("format %s is not supported by diff", format)
+	default:
+		return encode(w, format, changes(diff))
+	}
+}
+
+func encode(w io.Writer, format string, value interface{}) error {
+	switch format {
+	case formatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(value)
+	case formatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(value); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// envValue quotes values that a shell would not read back verbatim.
+func envValue(value string) string {
+	if value == "" || strings.ContainsAny(value, " \t\n\"'\\$#`") {
+		return strconv.Quote(value)
+	}
+	return value
+}
+
+func sortedKeys(data map[string]string) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -2,12 +2,22 @@ package config
 
 import (
 	"github.com/spf13/viper"
+	"time"
+)
+
+const (
+	DriverPostgres = "postgres"
+	DriverMemory   = "memory"
+	DriverFile     = "file"
 )
 
 type Config struct {
 	Server   ServerConfig
 	Database DatabaseConfig
 	Logger   LoggerConfig
+	Auth     AuthConfig
+	Secrets  SecretsConfig
+	Cache    CacheConfig
 }
 
 type ServerConfig struct {
@@ -15,6 +25,10 @@ type ServerConfig struct {
 	GatewayPort                string `mapstructure:"GATEWAY_PORT"`
 	DeleteConfigIfRecentlyUsed bool   `mapstructure:"DELETE_CONFIG_IF_RECENTLY_USED"`
 	RecentUseDurationDays      int    `mapstructure:"RECENT_USE_DURATION_DAYS"`
+	// MetricsPort is the port of /metrics. If it is empty, the metrics are served on the gateway port.
+	MetricsPort string `mapstructure:"METRICS_PORT"`
+	// ShutdownTimeout limits how long calls in progress may take to finish on shutdown, e.g. "25s".
+	ShutdownTimeout time.Duration `mapstructure:"SHUTDOWN_TIMEOUT"`
 }
 
 type DatabaseConfig struct {
@@ -24,12 +38,37 @@ type DatabaseConfig struct {
 	User     string `mapstructure:"DB_USER"`
 	Password string `mapstructure:"DB_PASSWORD"`
 	Dbname   string `mapstructure:"DB_NAME"`
+	DataDir  string `mapstructure:"DB_DATA_DIR"`
+	// PairBatchSize is the number of keys of a config inserted into Postgres by one statement, 500 if it is zero.
+	PairBatchSize int `mapstructure:"DB_PAIR_BATCH_SIZE"`
 }
 
 type LoggerConfig struct {
 	LogLevel string `mapstructure:"LOG_LEVEL"`
 }
 
+type AuthConfig struct {
+	// KeyFile is the file with API tokens, JWT keys and roles. Authentication is disabled if it is empty.
+	KeyFile string `mapstructure:"AUTH_KEY_FILE"`
+}
+
+type SecretsConfig struct {
+	// KeyFile is the file with the master keys that encrypt secret values. Secret values cannot be stored
+	// in Postgres if it is empty.
+	KeyFile string `mapstructure:"SECRETS_KEY_FILE"`
+}
+
+type CacheConfig struct {
+	// Size is the number of relevant configs kept in memory in front of Postgres. Configs are not cached if it is zero.
+	Size int `mapstructure:"CACHE_SIZE"`
+	// MaxBytes bounds the total length of the names, keys and values of cached configs, unless it is zero.
+	MaxBytes int `mapstructure:"CACHE_MAX_BYTES"`
+	// TTL bounds how long a config is served from memory, e.g. "1m". Configs do not expire if it is zero.
+	TTL time.Duration `mapstructure:"CACHE_TTL"`
+	// LastUsedInterval is how often the last use of configs served from memory is written to Postgres, "10s" if it is zero.
+	LastUsedInterval time.Duration `mapstructure:"CACHE_LAST_USED_INTERVAL"`
+}
+
 func GetConfig(path string) (*Config, error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigName("app")
@@ -43,6 +82,9 @@ func GetConfig(path string) (*Config, error) {
 	var serverConfig ServerConfig
 	var dbConfig DatabaseConfig
 	var loggerConfig LoggerConfig
+	var authConfig AuthConfig
+	var secretsConfig SecretsConfig
+	var cacheConfig CacheConfig
 	if err := viper.Unmarshal(&serverConfig); err != nil {
 		return nil, err
 	}
@@ -52,10 +94,22 @@ func GetConfig(path string) (*Config, error) {
 	if err := viper.Unmarshal(&loggerConfig); err != nil {
 		return nil, err
 	}
+	if err := viper.Unmarshal(&authConfig); err != nil {
+		return nil, err
+	}
+	if err := viper.Unmarshal(&secretsConfig); err != nil {
+		return nil, err
+	}
+	if err := viper.Unmarshal(&cacheConfig); err != nil {
+		return nil, err
+	}
 	cfg := &Config{
 		Server:   serverConfig,
 		Database: dbConfig,
 		Logger:   loggerConfig,
+		Auth:     authConfig,
+		Secrets:  secretsConfig,
+		Cache:    cacheConfig,
 	}
 
 	return cfg, nil
@@ -72,3 +126,15 @@ func (c *Config) GetDatabaseConfig() DatabaseConfig {
 func (c *Config) GetLoggerConfig() LoggerConfig {
 	return c.Logger
 }
+
+func (c *Config) GetAuthConfig() AuthConfig {
+	return c.Auth
+}
+
+func (c *Config) GetSecretsConfig() SecretsConfig {
+	return c.Secrets
+}
+
+func (c *Config) GetCacheConfig() CacheConfig {
+	return c.Cache
+}
@@ -1,28 +1,269 @@
 package app
 
 import (
+	"context"
 	"distributedConfig/config"
 	"distributedConfig/internal"
+	"distributedConfig/internal/auth"
 	"distributedConfig/internal/delivery/grpc"
+	"distributedConfig/internal/delivery/proto"
+	"distributedConfig/internal/health"
+	"distributedConfig/internal/lifecycle"
+	"distributedConfig/internal/metrics"
+	"distributedConfig/internal/repository"
+	"distributedConfig/internal/repository/cache_repository"
+	"distributedConfig/internal/repository/file_repository"
+	"distributedConfig/internal/repository/memory_repository"
 	"distributedConfig/internal/repository/pg_repository"
+	"distributedConfig/internal/secrets"
 	"distributedConfig/internal/usecase"
 	"distributedConfig/pkg/database"
 	"distributedConfig/pkg/logger"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 )
 
-func Run(cfg *config.Config) {
+const (
+	healthCheckInterval = 10 * time.Second
+	healthCheckTimeout  = 5 * time.Second
+)
+
+// ReencryptSecrets encrypts the data keys of the secret values stored in Postgres with the primary master key,
+// so that the other master keys can be removed from the key file. It is run once by an administrator after the
+// primary key is changed, rather than by every replica on startup.
+func ReencryptSecrets(cfg *config.Config) error {
 	l := logger.New(cfg.Logger.LogLevel)
+	if cfg.Database.Driver == config.DriverMemory || cfg.Database.Driver == config.DriverFile {
+		return fmt.Errorf("secret values are not encrypted by the %s driver", cfg.Database.Driver)
+	}
+	if cfg.Secrets.KeyFile == "" {
+		return errors.New("SECRETS_KEY_FILE is not set")
+	}
+	keyring, err := secrets.LoadKeyring(cfg.Secrets.KeyFile)
+	if err != nil {
+		return fmt.Errorf("failed to load secrets key file: %w", err)
+	}
 	db, err := database.NewDB(cfg)
 	if err != nil {
-		l.Fatal("Failed to connect to database: %v", err)
-		return
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 	defer db.Close()
-	l.Info("Database connected")
-	configRepository := pg_repository.NewConfigRepository(db)
-	configUseCase := usecase.NewConfigUseCase(*l, configRepository, cfg)
+	reencrypted, err := pg_repository.NewConfigRepository(db, keyring).ReencryptSecrets()
+	if err != nil {
+		return fmt.Errorf("failed to re-encrypt secrets with master key %s: %w", keyring.Primary(), err)
+	}
+	l.Info("Secrets re-encrypted with master key %s: %d", keyring.Primary(), reencrypted)
+	return nil
+}
+
+// Run serves ConfigService until SIGINT or SIGTERM, then stops gracefully: watch streams are ended, calls in
+// progress are given the shutdown timeout to finish, and the storage is closed last. Errors of startup are returned.
+func Run(cfg *config.Config) error {
+	l := logger.New(cfg.Logger.LogLevel)
+	m := metrics.New()
+	checker := health.New(healthCheckTimeout, proto.ConfigService_ServiceDesc.ServiceName)
+	var notifierCheck health.CheckFunc
+	var configRepository repository.ConfigRepository
+	var auditRepository repository.AuditRepository
+	var configNotifier repository.ConfigNotifier
+	var configCache *cache_repository.ConfigRepository
+	var keyring *secrets.Keyring
+	if cfg.Secrets.KeyFile != "" {
+		var err error
+		keyring, err = secrets.LoadKeyring(cfg.Secrets.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load secrets key file: %w", err)
+		}
+		l.Info("Secrets are encrypted with master key %s", keyring.Primary())
+	}
+	switch cfg.Database.Driver {
+	case config.DriverMemory:
+		l.Warn("Using in-memory storage, configs will be lost on shutdown")
+		configRepository, auditRepository = memory_repository.NewAuditedConfigRepository()
+		configNotifier = memory_repository.NewConfigNotifier()
+	case config.DriverFile:
+		fileRepository, err := file_repository.NewConfigRepository(cfg.Database.DataDir, *l)
+		if err != nil {
+			return fmt.Errorf("failed to open data directory %s: %w", cfg.Database.DataDir, err)
+		}
+		defer fileRepository.Close()
+		l.Info("Data directory %s opened", cfg.Database.DataDir)
+		l.Warn("Secret values are stored unencrypted in data directory %s", cfg.Database.DataDir)
+		configRepository = fileRepository
+		auditRepository = fileRepository
+		configNotifier = memory_repository.NewConfigNotifier()
+	default:
+		db, err := database.NewDB(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to connect to database: %w", err)
+		}
+		defer db.Close()
+		l.Info("Database connected")
+		m.RegisterDB(db)
+		checker.Add("database", db.PingContext)
+		checker.Add("migrations", func(ctx context.Context) error {
+			return database.CheckSchemaVersion(ctx, db)
+		})
+		pgRepository := pg_repository.NewConfigRepository(db, keyring)
+		pgRepository.SetPairBatchSize(cfg.Database.PairBatchSize)
+		if keyring == nil {
+			l.Warn("SECRETS_KEY_FILE is not set, secret values cannot be stored")
+		}
+		configRepository = pgRepository
+		auditRepository = pg_repository.NewAuditRepository(db)
+		pgNotifier := pg_repository.NewConfigNotifier(db, database.DataSourceName(cfg), *l)
+		configNotifier = pgNotifier
+		notifierCheck = pgNotifier.Check
+		if cfg.Cache.Size > 0 {
+			configCache = cache_repository.NewConfigRepository(pgRepository, cache_repository.Options{
+				MaxEntries:       cfg.Cache.Size,
+				MaxBytes:         cfg.Cache.MaxBytes,
+				TTL:              cfg.Cache.TTL,
+				LastUsedInterval: cfg.Cache.LastUsedInterval,
+			}, *l)
+			configRepository = configCache
+			configNotifier = configCache.Notifier(pgNotifier)
+			m.RegisterCache(configCache)
+			l.Info("Caching up to %d configs for %s", cfg.Cache.Size, cfg.Cache.TTL)
+		}
+	}
+	m.RegisterRepository(configRepository)
+	configUseCase := usecase.NewConfigUseCase(*l, configRepository, auditRepository, configNotifier, cfg)
+	configUseCase.CountReads(m)
+	m.RegisterWatchers(configUseCase.Watchers())
+	var authenticator *auth.Authenticator
+	if cfg.Auth.KeyFile != "" {
+		var err error
+		authenticator, err = auth.LoadAuthenticator(cfg.Auth.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load auth key file: %w", err)
+		}
+		l.Info("Authentication enabled with key file %s", cfg.Auth.KeyFile)
+	} else {
+		l.Warn("AUTH_KEY_FILE is not set, calls are not authenticated")
+	}
 	configService := grpc_service.NewConfigService(*configUseCase)
-	go internal.RunGatewayServer(configService, cfg, l)
-	internal.RunGrpcServer(configService, cfg, l)
 
+	// The storage is closed by the deferred calls above once the manager has stopped everything else.
+	manager := lifecycle.New(*l, cfg.Server.ShutdownTimeout)
+	if configCache != nil {
+		// The cache is stopped after the servers, so that the last use of the configs they read is recorded.
+		cacheCtx, stopCache := context.WithCancel(context.Background())
+		manager.Serve("config cache", func() error {
+			configCache.Run(cacheCtx)
+			return nil
+		}, func(context.Context) error {
+			stopCache()
+			return nil
+		})
+	}
+	listenCtx, stopListening := context.WithCancel(context.Background())
+	listening := make(chan struct{})
+	manager.OnStop("config changes listener", func(ctx context.Context) error {
+		stopListening()
+		select {
+		case <-listening:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+	checker.Add("config changes listener", func(ctx context.Context) error {
+		select {
+		case <-listening:
+			return errors.New("listener stopped")
+		default:
+		}
+		if notifierCheck != nil {
+			return notifierCheck(ctx)
+		}
+		return nil
+	})
+
+	grpcServer := internal.NewGrpcServer(configService, l, authenticator, m, checker.Server())
+	grpcListener, err := listen(cfg.Server.GPRCPort)
+	if err != nil {
+		return err
+	}
+	l.Info("Starting gRPC server on port %s", cfg.Server.GPRCPort)
+	manager.Serve("gRPC server", func() error {
+		return grpcServer.Serve(grpcListener)
+	}, func(ctx context.Context) error {
+		return internal.StopGrpcServer(ctx, grpcServer)
+	})
+
+	gatewayCtx, closeGateway := context.WithCancel(context.Background())
+	defer closeGateway()
+	gatewayServer, err := internal.NewGatewayServer(gatewayCtx, cfg, m, checker)
+	if err != nil {
+		return fmt.Errorf("failed to register gateway: %w", err)
+	}
+	if err := serveHTTP(manager, "gRPC gateway", gatewayServer, cfg.Server.GatewayPort); err != nil {
+		return err
+	}
+	l.Info("Starting gRPC gateway on port %s", cfg.Server.GatewayPort)
+	if cfg.Server.MetricsPort != "" {
+		if err := serveHTTP(manager, "metrics server", internal.NewMetricsServer(m), cfg.Server.MetricsPort); err != nil {
+			return err
+		}
+		l.Info("Starting metrics server on port %s", cfg.Server.MetricsPort)
+	}
+
+	// Watch streams never end on their own, so they are ended first for the servers to drain.
+	manager.OnStop("watchers", func(context.Context) error {
+		configUseCase.Watchers().Close()
+		return nil
+	})
+	healthCtx, stopHealthChecks := context.WithCancel(context.Background())
+	manager.Serve("health checks", func() error {
+		checker.Run(healthCtx, healthCheckInterval)
+		return nil
+	}, func(context.Context) error {
+		stopHealthChecks()
+		return nil
+	})
+	// The replica is reported as not ready before anything stops, so that no new calls are routed to it.
+	manager.OnStop("readiness", func(context.Context) error {
+		checker.Shutdown()
+		return nil
+	})
+
+	go func() {
+		defer close(listening)
+		if err := configNotifier.Listen(listenCtx, configUseCase.Watchers()); err != nil {
+			l.Error("Failed to listen for config changes: %v", err)
+		}
+	}()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return manager.Run(ctx)
+}
+
+func listen(port string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", ":"+port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on port %s: %w", port, err)
+	}
+	return listener, nil
+}
+
+// serveHTTP listens on the port and registers the server with the manager.
+func serveHTTP(manager *lifecycle.Manager, name string, server *http.Server, port string) error {
+	listener, err := listen(port)
+	if err != nil {
+		return err
+	}
+	manager.Serve(name, func() error {
+		if err := server.Serve(listener); err != http.ErrServerClosed {
+			return err
+		}
+		return nil
+	}, server.Shutdown)
+	return nil
 }
@@ -0,0 +1,67 @@
+// Package auth authenticates callers of the config service by static API tokens or HMAC-signed JWTs
+// and authorizes them with roles that grant permissions on namespace and config name patterns.
+package auth
+
+import (
+	"context"
+	"errors"
+)
+
+type Permission string
+
+const (
+	PermissionRead        Permission = "read"
+	PermissionWrite       Permission = "write"
+	PermissionDelete      Permission = "delete"
+	PermissionSetRelevant Permission = "set_relevant"
+	// PermissionAudit allows reading the audit log of a config. Listing the log of every config
+	// requires it on a pattern that matches the empty name, such as "*".
+	PermissionAudit Permission = "audit"
+	// PermissionSchema allows setting and deleting the schema of a config. Reading it requires PermissionRead.
+	PermissionSchema Permission = "schema"
+	// PermissionDecrypt allows reading the values of the secret keys of a config, which are masked otherwise.
+	// It is checked in addition to the permission of the method.
+	PermissionDecrypt Permission = "decrypt"
+)
+
+var (
+	ErrMissingCredentials = errors.New("missing credentials")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Principal is an authenticated caller.
+type Principal struct {
+	Subject string
+	Roles   []string
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the caller authenticated by the auth interceptors, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(*Principal)
+	return principal, ok
+}
+
+type authenticatorKey struct{}
+
+// WithAuthenticator stores the authenticator of the call, so that handlers can check permissions that
+// depend on what they respond with.
+func WithAuthenticator(ctx context.Context, authenticator *Authenticator) context.Context {
+	return context.WithValue(ctx, authenticatorKey{}, authenticator)
+}
+
+// Allowed reports whether the caller holds the permission on the config. Without an authenticator
+// in the context, authentication is disabled and every permission is held.
+func Allowed(ctx context.Context, permission Permission, namespace, configName string) bool {
+	authenticator, ok := ctx.Value(authenticatorKey{}).(*Authenticator)
+	if !ok {
+		return true
+	}
+	principal, ok := PrincipalFromContext(ctx)
+	return ok && authenticator.Authorize(principal, permission, namespace, configName)
+}
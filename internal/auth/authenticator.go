@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"distributedConfig/internal/entity"
+	"encoding/hex"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"gopkg.in/yaml.v3"
+	"os"
+	"path"
+	"strings"
+)
+
+// KeyFile is the local file with the credentials and roles, for example:
+//
+//	jwt:
+//	  keys:
+//	    2023-01: "hmac secret"
+//	  issuer: config-service
+//	tokens:
+//	  - subject: ci
+//	    token_sha256: 9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08
+//	    roles: [payments-writer]
+//	roles:
+//	  payments-writer:
+//	    - namespaces: [staging, production]
+//	      configs: ["payments-*"]
+//	      permissions: [read, write]
+type KeyFile struct {
+	JWT    JWTConfig         `yaml:"jwt"`
+	Tokens []StaticToken     `yaml:"tokens"`
+	Roles  map[string][]Rule `yaml:"roles"`
+}
+
+// JWTConfig lists the HMAC keys by key ID. A token with the kid header is verified with that key,
+// a token without it with any of the keys, which allows rotating keys.
+type JWTConfig struct {
+	Keys     map[string]string `yaml:"keys"`
+	Issuer   string            `yaml:"issuer"`
+	Audience string            `yaml:"audience"`
+}
+
+// StaticToken is an API token. Only the SHA-256 of the token is stored in the key file.
+type StaticToken struct {
+	Subject     string   `yaml:"subject"`
+	TokenSHA256 string   `yaml:"token_sha256"`
+	Roles       []string `yaml:"roles"`
+}
+
+// Rule grants permissions on configs whose names match one of the config patterns in namespaces that
+// match one of the namespace patterns, in the syntax of path.Match. A rule without namespaces applies
+// to the default namespace only.
+type Rule struct {
+	Namespaces  []string     `yaml:"namespaces"`
+	Configs     []string     `yaml:"configs"`
+	Permissions []Permission `yaml:"permissions"`
+}
+
+// Claims are the claims of the JWTs accepted by the service. The subject is the standard sub claim.
+type Claims struct {
+	Roles []string `json:"roles"`
+	jwt.RegisteredClaims
+}
+
+type Authenticator struct {
+	keyFile KeyFile
+	tokens  map[[sha256.Size]byte]StaticToken
+}
+
+// LoadAuthenticator reads the key file at path.
+func LoadAuthenticator(path string) (*Authenticator, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keyFile KeyFile
+	if err := yaml.Unmarshal(data, &keyFile); err != nil {
+		return nil, fmt.Errorf("invalid key file %s: %w", path, err)
+	}
+	return NewAuthenticator(keyFile)
+}
+
+func NewAuthenticator(keyFile KeyFile) (*Authenticator, error) {
+	a := &Authenticator{keyFile: keyFile, tokens: make(map[[sha256.Size]byte]StaticToken)}
+	for _, token := range keyFile.Tokens {
+		hash, err := hex.DecodeString(token.TokenSHA256)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("invalid token_sha256 of %s", token.Subject)
+		}
+		a.tokens[*(*[sha256.Size]byte)(hash)] = token
+	}
+	for role, rules := range keyFile.Roles {
+		for _, rule := range rules {
+			for _, pattern := range rule.Configs {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return nil, fmt.Errorf("invalid config pattern %q of role %s: %w", pattern, role, err)
+				}
+			}
+			for _, pattern := range rule.Namespaces {
+				if _, err := path.Match(pattern, ""); err != nil {
+					return nil, fmt.Errorf("invalid namespace pattern %q of role %s: %w", pattern, role, err)
+				}
+			}
+		}
+	}
+	return a, nil
+}
+
+// Authenticate validates the value of the Authorization header: "Bearer <token>", where the token
+// is either a static API token or a JWT.
+func (a *Authenticator) Authenticate(authorization string) (*Principal, error) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(authorization), " ")
+	if authorization == "" {
+		return nil, ErrMissingCredentials
+	}
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return nil, ErrInvalidCredentials
+	}
+	if principal, ok := a.staticToken(token); ok {
+		return principal, nil
+	}
+	return a.jwt(token)
+}
+
+// staticToken looks the token up by its hash, so the time of the lookup does not depend on the stored tokens.
+func (a *Authenticator) staticToken(token string) (*Principal, bool) {
+	static, ok := a.tokens[sha256.Sum256([]byte(token))]
+	if !ok {
+		return nil, false
+	}
+	return &Principal{Subject: static.Subject, Roles: static.Roles}, true
+}
+
+func (a *Authenticator) jwt(token string) (*Principal, error) {
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"HS256", "HS384", "HS512"}))
+	var lastErr error = ErrInvalidCredentials
+	for kid, key := range a.keyFile.JWT.Keys {
+		claims := &Claims{}
+		_, err := parser.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+			if headerKid, ok := t.Header["kid"].(string); ok && headerKid != kid {
+				return nil, ErrInvalidCredentials
+			}
+			return []byte(key), nil
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if err := a.validateClaims(claims); err != nil {
+			return nil, err
+		}
+		return &Principal{Subject: claims.Subject, Roles: claims.Roles}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrInvalidCredentials, lastErr)
+}
+
+func (a *Authenticator) validateClaims(claims *Claims) error {
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+	if issuer := a.keyFile.JWT.Issuer; issuer != "" && !claims.VerifyIssuer(issuer, true) {
+		return fmt.Errorf("%w: unexpected issuer", ErrInvalidCredentials)
+	}
+	if audience := a.keyFile.JWT.Audience; audience != "" && !claims.VerifyAudience(audience, true) {
+		return fmt.Errorf("%w: unexpected audience", ErrInvalidCredentials)
+	}
+	return nil
+}
+
+// Authorize reports whether any role of the principal grants the permission on the config of the namespace.
+func (a *Authenticator) Authorize(principal *Principal, permission Permission, namespace, configName string) bool {
+	for _, role := range principal.Roles {
+		for _, rule := range a.keyFile.Roles[role] {
+			if rule.allows(permission, namespace, configName) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (rule Rule) allows(permission Permission, namespace, configName string) bool {
+	granted := false
+	for _, p := range rule.Permissions {
+		granted = granted || p == permission
+	}
+	if !granted {
+		return false
+	}
+	namespaces := rule.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{entity.DefaultNamespace}
+	}
+	return matchesAny(namespaces, namespace) && matchesAny(rule.Configs, configName)
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
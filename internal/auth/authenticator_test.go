@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func testAuthenticator(t *testing.T) *Authenticator {
+	t.Helper()
+	authenticator, err := NewAuthenticator(KeyFile{
+		JWT: JWTConfig{
+			Keys:   map[string]string{"old": "old-secret", "new": "new-secret"},
+			Issuer: "config-service",
+		},
+		Tokens: []StaticToken{{Subject: "ci", TokenSHA256: hashToken("ci-token"), Roles: []string{"payments-writer"}}},
+		Roles: map[string][]Rule{
+			"payments-writer": {{Configs: []string{"payments-*"}, Permissions: []Permission{PermissionRead, PermissionWrite}}},
+			"admin": {{Namespaces: []string{"*"}, Configs: []string{"*"}, Permissions: []Permission{
+				PermissionRead, PermissionWrite, PermissionDelete, PermissionSetRelevant,
+			}}},
+			"staging-reader": {{Namespaces: []string{"staging-*"}, Configs: []string{"*"}, Permissions: []Permission{
+				PermissionRead,
+			}}},
+		},
+	})
+	require.NoError(t, err)
+	return authenticator
+}
+
+func signToken(t *testing.T, kid, key string, claims Claims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString([]byte(key))
+	require.NoError(t, err)
+	return signed
+}
+
+func TestAuthenticator_Authenticate(t *testing.T) {
+	authenticator := testAuthenticator(t)
+	valid := Claims{Roles: []string{"admin"}, RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "alice",
+		Issuer:    "config-service",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Hour))
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "other"
+
+	testCases := []struct {
+		name          string
+		authorization string
+		subject       string
+		err           error
+	}{
+		{"static token", "Bearer ci-token", "ci", nil},
+		{"jwt", "Bearer " + signToken(t, "new", "new-secret", valid), "alice", nil},
+		{"jwt without kid", "bearer " + signToken(t, "", "old-secret", valid), "alice", nil},
+		{"missing", "", "", ErrMissingCredentials},
+		{"unknown scheme", "Basic ci-token", "", ErrInvalidCredentials},
+		{"unknown token", "Bearer unknown", "", ErrInvalidCredentials},
+		{"jwt with wrong kid", "Bearer " + signToken(t, "old", "new-secret", valid), "", ErrInvalidCredentials},
+		{"jwt with unknown key", "Bearer " + signToken(t, "", "other-secret", valid), "", ErrInvalidCredentials},
+		{"expired jwt", "Bearer " + signToken(t, "new", "new-secret", expired), "", ErrInvalidCredentials},
+		{"jwt with wrong issuer", "Bearer " + signToken(t, "new", "new-secret", wrongIssuer), "", ErrInvalidCredentials},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			principal, err := authenticator.Authenticate(tc.authorization)
+			if tc.err != nil {
+				require.ErrorIs(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.subject, principal.Subject)
+		})
+	}
+}
+
+func TestAuthenticator_Authorize(t *testing.T) {
+	authenticator := testAuthenticator(t)
+	writer := &Principal{Subject: "ci", Roles: []string{"payments-writer"}}
+	admin := &Principal{Subject: "alice", Roles: []string{"admin"}}
+
+	stagingReader := &Principal{Subject: "carol", Roles: []string{"staging-reader"}}
+
+	require.True(t, authenticator.Authorize(writer, PermissionWrite, "default", "payments-api"))
+	require.True(t, authenticator.Authorize(writer, PermissionRead, "default", "payments-"))
+	require.False(t, authenticator.Authorize(writer, PermissionDelete, "default", "payments-api"))
+	require.False(t, authenticator.Authorize(writer, PermissionRead, "default", "billing"))
+	require.False(t, authenticator.Authorize(writer, PermissionRead, "staging", "payments-api"))
+	require.True(t, authenticator.Authorize(admin, PermissionDelete, "default", "billing"))
+	require.True(t, authenticator.Authorize(admin, PermissionDelete, "production", "billing"))
+	require.True(t, authenticator.Authorize(stagingReader, PermissionRead, "staging-eu", "billing"))
+	require.False(t, authenticator.Authorize(stagingReader, PermissionRead, "default", "billing"))
+	require.False(t, authenticator.Authorize(stagingReader, PermissionWrite, "staging-eu", "billing"))
+	require.False(t, authenticator.Authorize(&Principal{Subject: "bob", Roles: []string{"unknown"}}, PermissionRead,
+		"default", "billing"))
+}
+
+func TestLoadAuthenticator(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+tokens:
+  - subject: ci
+    token_sha256: `+hashToken("ci-token")+`
+    roles: [reader]
+roles:
+  reader:
+    - namespaces: [staging]
+      configs: ["*"]
+      permissions: [read]
+`), 0o600))
+	authenticator, err := LoadAuthenticator(path)
+	require.NoError(t, err)
+	principal, err := authenticator.Authenticate("Bearer ci-token")
+	require.NoError(t, err)
+	require.True(t, authenticator.Authorize(principal, PermissionRead, "staging", "test"))
+	require.False(t, authenticator.Authorize(principal, PermissionRead, "default", "test"))
+
+	_, err = NewAuthenticator(KeyFile{Tokens: []StaticToken{{Subject: "ci", TokenSHA256: "plain"}}})
+	require.Error(t, err)
+	_, err = NewAuthenticator(KeyFile{Roles: map[string][]Rule{"broken": {{Configs: []string{"["}}}}})
+	require.Error(t, err)
+	_, err = NewAuthenticator(KeyFile{Roles: map[string][]Rule{"broken": {{Namespaces: []string{"["}}}}})
+	require.Error(t, err)
+}
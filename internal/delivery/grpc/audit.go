@@ -0,0 +1,76 @@
+package grpc_service
+
+import (
+	"context"
+	configService "distributedConfig/internal/delivery/proto"
+	"distributedConfig/internal/entity"
+	"errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strconv"
+)
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+)
+
+var errInvalidPageToken = errors.New("invalid page token")
+
+// clampPageSize returns the requested page size, or the default one if it is not set, but no more than max.
+func clampPageSize(requested int32, def, max int) int {
+	pageSize := int(requested)
+	if pageSize <= 0 {
+		return def
+	} else if pageSize > max {
+		return max
+	}
+	return pageSize
+}
+
+func (s *ConfigService) ListAuditEvents(ctx context.Context, r *configService.ListAuditEventsRequest) (*configService.ListAuditEventsResponse, error) {
+	pageSize := clampPageSize(r.PageSize, defaultAuditPageSize, maxAuditPageSize)
+	filter := &entity.AuditFilter{
+		Namespace:  entity.NamespaceOrDefault(r.Namespace),
+		ConfigName: r.ServiceName,
+		Actor:      r.Actor,
+		// One more event is requested to know whether there is a next page.
+		Limit: pageSize + 1,
+	}
+	if r.Since != nil {
+		filter.Since = r.Since.AsTime()
+	}
+	if r.Until != nil {
+		filter.Until = r.Until.AsTime()
+	}
+	if r.PageToken != "" {
+		beforeID, err := strconv.ParseInt(r.PageToken, 10, 64)
+		if err != nil || beforeID < 1 {
+			return nil, invalidArgument("page_token", errInvalidPageToken, "Unable to list audit events")
+		}
+		filter.BeforeID = beforeID
+	}
+	events, err := s.configUseCase.ListAuditEvents(filter)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to list audit events")
+	}
+	response := &configService.ListAuditEventsResponse{}
+	if len(events) > pageSize {
+		events = events[:pageSize]
+		response.NextPageToken = strconv.FormatInt(events[pageSize-1].ID, 10)
+	}
+	for _, event := range events {
+		response.Events = append(response.Events, &configService.AuditEvent{
+			Id:          event.ID,
+			Actor:       event.Actor,
+			Method:      event.Method,
+			Namespace:   event.Namespace,
+			ServiceName: event.ConfigName,
+			FromVersion: event.FromVersion,
+			ToVersion:   event.ToVersion,
+			DiffSummary: event.DiffSummary,
+			ClientIp:    event.ClientIP,
+			CreatedAt:   timestamppb.New(event.CreatedAt),
+		})
+	}
+	return response, nil
+}
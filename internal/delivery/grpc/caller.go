@@ -0,0 +1,47 @@
+package grpc_service
+
+import (
+	"context"
+	"distributedConfig/internal/auth"
+	"distributedConfig/internal/usecase"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"strings"
+)
+
+// forwardedForKey is the metadata key of the client addresses appended by grpc-gateway.
+const forwardedForKey = "x-forwarded-for"
+
+// withCaller stores the caller of a request in the context for the audit log: the authenticated
+// principal and the client address.
+func withCaller(ctx context.Context) context.Context {
+	var caller usecase.Caller
+	if principal, ok := auth.PrincipalFromContext(ctx); ok {
+		caller.Actor = principal.Subject
+	}
+	caller.ClientIP = clientIP(ctx)
+	return usecase.WithCaller(ctx, caller)
+}
+
+// clientIP returns the address of the gRPC peer. Requests proxied by the gateway come from the loopback
+// interface, so for them the address the gateway appended to x-forwarded-for is taken. Addresses before
+// it are sent by the HTTP client and cannot be trusted.
+func clientIP(ctx context.Context) string {
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+	}
+	if parsed := net.ParseIP(ip); ip != "" && (parsed == nil || !parsed.IsLoopback()) {
+		return ip
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(forwardedForKey); len(values) > 0 {
+		addresses := strings.Split(values[len(values)-1], ",")
+		return strings.TrimSpace(addresses[len(addresses)-1])
+	}
+	return ip
+}
@@ -0,0 +1,39 @@
+package grpc_service
+
+import (
+	"context"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"net"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	remote := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}
+	loopback := &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 50000}
+	testCases := []struct {
+		name     string
+		addr     net.Addr
+		md       metadata.MD
+		expected string
+	}{
+		{"grpc client", remote, nil, "10.0.0.1"},
+		{"grpc client forging forwarded address", remote, metadata.Pairs(forwardedForKey, "10.0.0.2"), "10.0.0.1"},
+		{"gateway", loopback, metadata.Pairs(forwardedForKey, "10.0.0.2"), "10.0.0.2"},
+		{"gateway behind proxy", loopback, metadata.Pairs(forwardedForKey, "10.0.0.3, 10.0.0.2"), "10.0.0.2"},
+		{"in-process gateway", nil, metadata.Pairs(forwardedForKey, "10.0.0.2"), "10.0.0.2"},
+		{"local grpc client", loopback, nil, "127.0.0.1"},
+		{"unknown", nil, nil, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tc.md)
+			if tc.addr != nil {
+				ctx = peer.NewContext(ctx, &peer.Peer{Addr: tc.addr})
+			}
+			require.Equal(t, tc.expected, clientIP(ctx))
+		})
+	}
+}
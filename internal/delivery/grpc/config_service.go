@@ -5,8 +5,16 @@ import (
 	configService "distributedConfig/internal/delivery/proto"
 	"distributedConfig/internal/entity"
 	"distributedConfig/internal/usecase"
+	"distributedConfig/internal/watcher"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
+	"strconv"
+)
+
+const (
+	defaultVersionPageSize = 100
+	maxVersionPageSize     = 1000
 )
 
 type ConfigService struct {
@@ -18,89 +26,174 @@ func NewConfigService(configUseCase usecase.ConfigUseCase) *ConfigService {
 }
 
 func (s *ConfigService) CreateConfig(ctx context.Context, r *configService.Config) (*configService.ConfigResponse, error) {
+	data, types, err := fromConfigValues(r.Data, r.Values)
+	if err != nil {
+		return nil, invalidArgument("values", err, "Unable to create %s config", r.ServiceName)
+	}
 	config := &entity.Config{
-		Name:    r.ServiceName,
-		Data:    r.Data,
-		Version: 1,
+		Namespace: entity.NamespaceOrDefault(r.Namespace),
+		Name:      r.ServiceName,
+		Data:      data,
+		Types:     types,
+		Version:   1,
 	}
-	err := s.configUseCase.CreateConfig(config)
-	if err != nil && err == usecase.ErrConfigAlreadyExists {
-		return nil, status.Errorf(409, "Unable to create %s config: %s", r.ServiceName, err)
-	} else if err != nil {
-		return nil, status.Errorf(500, "Unable to create %s config: %s", r.ServiceName, err)
+	err = s.configUseCase.CreateConfig(withCaller(ctx), config)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to create %s config", r.ServiceName)
 	}
 	return &configService.ConfigResponse{
-		Config: &configService.Config{
-			ServiceName: config.Name,
-			Data:        config.Data,
-		},
-		Version:   1,
-		CreatedAt: timestamppb.New(config.CreatedAt),
+		Config:       toConfigMessage(ctx, config),
+		Version:      1,
+		CreatedAt:    timestamppb.New(config.CreatedAt),
+		PromotedFrom: toConfigSource(config.PromotedFrom),
 	}, nil
 }
 
 func (s *ConfigService) GetConfig(ctx context.Context, r *configService.ConfigName) (*configService.ConfigResponse, error) {
-	config, err := s.configUseCase.GetConfig(r.ServiceName)
-	if err != nil && err == usecase.ErrConfigNotFound {
-		return nil, status.Errorf(404, "Unable to get %s config: %s", r.ServiceName, err)
-	} else if err != nil {
-		return nil, status.Errorf(500, "Unable to get %s config: %s", r.ServiceName, err)
+	config, err := s.configUseCase.GetConfig(entity.NamespaceOrDefault(r.Namespace), r.ServiceName)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to get %s config", r.ServiceName)
 	}
 	return &configService.ConfigResponse{
-		Config: &configService.Config{
-			ServiceName: config.Name,
-			Data:        config.Data,
-		},
-		Version:   config.Version,
-		CreatedAt: timestamppb.New(config.CreatedAt),
+		Config:       toConfigMessage(ctx, config),
+		Version:      config.Version,
+		CreatedAt:    timestamppb.New(config.CreatedAt),
+		PromotedFrom: toConfigSource(config.PromotedFrom),
 	}, nil
 }
 
 func (s *ConfigService) GetConfigByVersion(ctx context.Context, r *configService.ConfigNameAndVersion) (*configService.ConfigResponse, error) {
-	config, err := s.configUseCase.GetConfigByVersion(r.ServiceName, r.Version)
-	if err != nil && err == usecase.ErrConfigNotFound {
-		return nil, status.Errorf(404, "Unable to get %s config with version %d : %s", r.ServiceName, r.Version, err)
-	} else if err != nil {
-		return nil, status.Errorf(500, "Unable to get %s config with version %d: %s", r.ServiceName, r.Version, err)
+	config, err := s.configUseCase.GetConfigByVersion(entity.NamespaceOrDefault(r.Namespace), r.ServiceName, r.Version)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to get %s config with version %d", r.ServiceName, r.Version)
 	}
 	return &configService.ConfigResponse{
-		Config: &configService.Config{
-			ServiceName: config.Name,
-			Data:        config.Data,
-		},
-		Version:   config.Version,
-		CreatedAt: timestamppb.New(config.CreatedAt),
+		Config:       toConfigMessage(ctx, config),
+		Version:      config.Version,
+		CreatedAt:    timestamppb.New(config.CreatedAt),
+		PromotedFrom: toConfigSource(config.PromotedFrom),
 	}, nil
 }
 
 func (s *ConfigService) UpdateConfig(ctx context.Context, r *configService.Config) (*configService.ConfigResponse, error) {
+	data, types, err := fromConfigValues(r.Data, r.Values)
+	if err != nil {
+		return nil, invalidArgument("values", err, "Unable to update %s config", r.ServiceName)
+	}
 	config := &entity.Config{
-		Name: r.ServiceName,
-		Data: r.Data,
+		Namespace: entity.NamespaceOrDefault(r.Namespace),
+		Name:      r.ServiceName,
+		Data:      data,
+		Types:     types,
+	}
+	expectedVersion, err := expectedVersion(ctx, r.ExpectedVersion)
+	if err != nil {
+		return nil, invalidArgument("expected_version", err, "Unable to update %s config", r.ServiceName)
+	}
+	err = s.configUseCase.UpdateConfig(withCaller(ctx), config, expectedVersion)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to update %s config", r.ServiceName)
 	}
-	err := s.configUseCase.UpdateConfig(config)
-	if err != nil && err == usecase.ErrConfigNotFound {
-		return nil, status.Errorf(404, "Unable to update %s config: %s", r.ServiceName, err)
-	} else if err != nil {
-		return nil, status.Errorf(500, "Unable to update %s config: %s", r.ServiceName, err)
+	return &configService.ConfigResponse{
+		Config:       toConfigMessage(ctx, config),
+		Version:      config.Version,
+		CreatedAt:    timestamppb.New(config.CreatedAt),
+		PromotedFrom: toConfigSource(config.PromotedFrom),
+	}, nil
+}
+
+func (s *ConfigService) PatchConfig(ctx context.Context, r *configService.PatchConfigRequest) (*configService.ConfigResponse, error) {
+	expectedVersion, err := expectedVersion(ctx, r.ExpectedVersion)
+	if err != nil {
+		return nil, invalidArgument("expected_version", err, "Unable to patch %s config", r.ServiceName)
+	}
+	set, types, err := fromConfigValues(r.Set, r.SetValues)
+	if err != nil {
+		return nil, invalidArgument("set_values", err, "Unable to patch %s config", r.ServiceName)
+	}
+	config, err := s.configUseCase.PatchConfig(withCaller(ctx), &entity.ConfigPatch{
+		Namespace: entity.NamespaceOrDefault(r.Namespace),
+		Name:      r.ServiceName,
+		Set:       set,
+		Types:     types,
+		Unset:     r.Unset,
+	}, expectedVersion)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to patch %s config", r.ServiceName)
 	}
 	return &configService.ConfigResponse{
-		Config: &configService.Config{
-			ServiceName: config.Name,
-			Data:        config.Data,
-		},
-		Version:   config.Version,
-		CreatedAt: timestamppb.New(config.CreatedAt),
+		Config:       toConfigMessage(ctx, config),
+		Version:      config.Version,
+		CreatedAt:    timestamppb.New(config.CreatedAt),
+		PromotedFrom: toConfigSource(config.PromotedFrom),
 	}, nil
 }
 
+func (s *ConfigService) PromoteConfig(ctx context.Context, r *configService.PromoteConfigRequest) (*configService.ConfigResponse, error) {
+	sourceNamespace, targetNamespace := entity.NamespaceOrDefault(r.SourceNamespace), entity.NamespaceOrDefault(r.TargetNamespace)
+	config, err := s.configUseCase.PromoteConfig(withCaller(ctx), &entity.ConfigPromotion{
+		SourceNamespace: sourceNamespace,
+		TargetNamespace: targetNamespace,
+		Name:            r.ServiceName,
+		Version:         r.Version,
+		SetOverrides:    r.SetOverrides,
+		UnsetOverrides:  r.UnsetOverrides,
+	})
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to promote version %d of %s config from %s to %s",
+			r.Version, r.ServiceName, sourceNamespace, targetNamespace)
+	}
+	return &configService.ConfigResponse{
+		Config:       toConfigMessage(ctx, config),
+		Version:      config.Version,
+		CreatedAt:    timestamppb.New(config.CreatedAt),
+		PromotedFrom: toConfigSource(config.PromotedFrom),
+	}, nil
+}
+
+func (s *ConfigService) GetConfigOverrides(ctx context.Context, r *configService.ConfigName) (*configService.ConfigOverridesResponse, error) {
+	namespace := entity.NamespaceOrDefault(r.Namespace)
+	overrides, err := s.configUseCase.GetConfigOverrides(namespace, r.ServiceName)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to get overrides of %s config", r.ServiceName)
+	}
+	if len(overrides) > 0 && !revealSecrets(ctx, namespace, r.ServiceName) {
+		if overrides, err = s.maskOverrides(namespace, r.ServiceName, overrides); err != nil {
+			return nil, toStatus(err, r.ServiceName, "Unable to get overrides of %s config", r.ServiceName)
+		}
+	}
+	return &configService.ConfigOverridesResponse{ServiceName: r.ServiceName, Namespace: namespace, Overrides: overrides}, nil
+}
+
+// maskOverrides masks the overrides of keys that are secret in the relevant version of the config, whose types
+// the overrides have unless the config changed since the latest promotion.
+func (s *ConfigService) maskOverrides(namespace, name string, overrides map[string]string) (map[string]string, error) {
+	config, err := s.configUseCase.PeekConfig(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	masked := make(map[string]string, len(overrides))
+	for key, value := range overrides {
+		if config.TypeOf(key) == entity.TypeSecret {
+			value = entity.SecretMask
+		}
+		masked[key] = value
+	}
+	return masked, nil
+}
+
+func toConfigSource(source *entity.ConfigSource) *configService.ConfigSource {
+	if source == nil {
+		return nil
+	}
+	return &configService.ConfigSource{Namespace: source.Namespace, Version: source.Version}
+}
+
 func (s *ConfigService) DeleteConfig(ctx context.Context, r *configService.ConfigName) (*configService.DeleteResponse, error) {
 	var err error
-	err = s.configUseCase.DeleteConfig(r.ServiceName)
-	if err != nil && err == usecase.ErrConfigWasRecentlyUsed {
-		return nil, status.Errorf(403, "Unable to delete %s config: %s", r.ServiceName, err)
-	} else if err != nil {
-		return nil, status.Errorf(500, "Unable to delete %s config: %s", r.ServiceName, err)
+	err = s.configUseCase.DeleteConfig(withCaller(ctx), entity.NamespaceOrDefault(r.Namespace), r.ServiceName)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to delete %s config", r.ServiceName)
 	} else {
 		return &configService.DeleteResponse{
 			Message: "Config was deleted",
@@ -110,11 +203,10 @@ func (s *ConfigService) DeleteConfig(ctx context.Context, r *configService.Confi
 
 func (s *ConfigService) DeleteConfigVersion(ctx context.Context, r *configService.ConfigNameAndVersion) (*configService.DeleteResponse, error) {
 	var err error
-	err = s.configUseCase.DeleteConfigVersion(r.ServiceName, r.Version)
-	if err != nil && err == usecase.ErrConfigWasRecentlyUsed {
-		return nil, status.Errorf(403, "Unable to delete %s config with version %d: %s", r.ServiceName, r.Version, err)
-	} else if err != nil {
-		return nil, status.Errorf(500, "Unable to delete %s config with version %d: %s", r.ServiceName, r.Version, err)
+	err = s.configUseCase.DeleteConfigVersion(withCaller(ctx), entity.NamespaceOrDefault(r.Namespace), r.ServiceName,
+		r.Version)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to delete %s config with version %d", r.ServiceName, r.Version)
 	} else {
 		return &configService.DeleteResponse{
 			Message: "Config was deleted",
@@ -123,20 +215,16 @@ func (s *ConfigService) DeleteConfigVersion(ctx context.Context, r *configServic
 }
 
 func (s *ConfigService) ListConfigs(r *configService.ListRequest, stream configService.ConfigService_ListConfigsServer) error {
-	configs, err := s.configUseCase.GetConfigs(r.ServiceName)
-	if err != nil && err == usecase.ErrConfigNotFound {
-		return status.Errorf(404, "Unable to get %s configs: %s", r.ServiceName, err)
-	} else if err != nil {
-		return status.Errorf(500, "Unable to get %s configs: %s", r.ServiceName, err)
+	configs, err := s.configUseCase.GetConfigs(entity.NamespaceOrDefault(r.Namespace), r.ServiceName)
+	if err != nil {
+		return toStatus(err, r.ServiceName, "Unable to get %s configs", r.ServiceName)
 	}
 	for _, config := range configs {
 		err := stream.Send(&configService.ConfigResponse{
-			Config: &configService.Config{
-				ServiceName: config.Name,
-				Data:        config.Data,
-			},
-			Version:   config.Version,
-			CreatedAt: timestamppb.New(config.CreatedAt),
+			Config:       toConfigMessage(stream.Context(), config),
+			Version:      config.Version,
+			CreatedAt:    timestamppb.New(config.CreatedAt),
+			PromotedFrom: toConfigSource(config.PromotedFrom),
 		})
 		if err != nil {
 			return err
@@ -145,19 +233,139 @@ func (s *ConfigService) ListConfigs(r *configService.ListRequest, stream configS
 	return nil
 }
 
+func (s *ConfigService) ListConfigVersions(ctx context.Context, r *configService.ListConfigVersionsRequest) (*configService.ListConfigVersionsResponse, error) {
+	pageSize := clampPageSize(r.PageSize, defaultVersionPageSize, maxVersionPageSize)
+	filter := &entity.VersionFilter{
+		Namespace:   entity.NamespaceOrDefault(r.Namespace),
+		Name:        r.ServiceName,
+		WithoutData: r.IncludeData != nil && !*r.IncludeData,
+		// One more version is requested to know whether there is a next page.
+		Limit: pageSize + 1,
+	}
+	if r.PageToken != "" {
+		beforeVersion, err := strconv.ParseInt(r.PageToken, 10, 64)
+		if err != nil || beforeVersion < 1 {
+			return nil, invalidArgument("page_token", errInvalidPageToken, "Unable to list %s config versions", r.ServiceName)
+		}
+		filter.BeforeVersion = beforeVersion
+	}
+	configs, err := s.configUseCase.ListConfigVersions(filter)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to list %s config versions", r.ServiceName)
+	}
+	response := &configService.ListConfigVersionsResponse{}
+	if len(configs) > pageSize {
+		configs = configs[:pageSize]
+		response.NextPageToken = strconv.FormatInt(configs[pageSize-1].Version, 10)
+	}
+	for _, config := range configs {
+		response.Versions = append(response.Versions, &configService.ConfigResponse{
+			Config:       toConfigMessage(ctx, config),
+			Version:      config.Version,
+			CreatedAt:    timestamppb.New(config.CreatedAt),
+			PromotedFrom: toConfigSource(config.PromotedFrom),
+		})
+	}
+	return response, nil
+}
+
 func (s *ConfigService) SetRelevantConfig(ctx context.Context, r *configService.ConfigNameAndVersion) (*configService.ConfigResponse, error) {
-	config, err := s.configUseCase.SetRelevantConfig(r.ServiceName, r.Version)
-	if err != nil && err == usecase.ErrConfigNotFound {
-		return nil, status.Errorf(404, "Unable to set relevant %s config: %s", r.ServiceName, err)
-	} else if err != nil {
-		return nil, status.Errorf(500, "Unable to set relevant %s config: %s", r.ServiceName, err)
+	config, err := s.configUseCase.SetRelevantConfig(withCaller(ctx), entity.NamespaceOrDefault(r.Namespace), r.ServiceName,
+		r.Version)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to set relevant %s config", r.ServiceName)
 	}
 	return &configService.ConfigResponse{
-		Config: &configService.Config{
-			ServiceName: config.Name,
-			Data:        config.Data,
-		},
-		Version:   config.Version,
-		CreatedAt: timestamppb.New(config.CreatedAt),
+		Config:       toConfigMessage(ctx, config),
+		Version:      config.Version,
+		CreatedAt:    timestamppb.New(config.CreatedAt),
+		PromotedFrom: toConfigSource(config.PromotedFrom),
 	}, nil
 }
+
+func (s *ConfigService) WatchConfig(r *configService.ConfigName, stream configService.ConfigService_WatchConfigServer) error {
+	subscription, err := s.configUseCase.WatchConfig(entity.NamespaceOrDefault(r.Namespace), r.ServiceName)
+	if err != nil {
+		return toStatus(err, r.ServiceName, "Unable to watch %s config", r.ServiceName)
+	}
+	defer subscription.Close()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case update := <-subscription.Updates():
+			if update.Err == usecase.ErrConfigNotFound || update.Err == watcher.ErrClosed {
+				return toStatus(update.Err, r.ServiceName, "Unable to watch %s config", r.ServiceName)
+			} else if update.Err != nil {
+				// The change could not be loaded. The client watches again to receive the current version.
+				return status.Errorf(codes.Unavailable, "Unable to load %s config, watch it again: %s", r.ServiceName,
+					update.Err)
+			}
+			err := stream.Send(&configService.ConfigResponse{
+				Config:       toConfigMessage(stream.Context(), update.Config),
+				Version:      update.Config.Version,
+				CreatedAt:    timestamppb.New(update.Config.CreatedAt),
+				PromotedFrom: toConfigSource(update.Config.PromotedFrom),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *ConfigService) DiffConfigVersions(ctx context.Context, r *configService.DiffRequest) (*configService.DiffResponse, error) {
+	diff, err := s.configUseCase.DiffConfigVersions(entity.NamespaceOrDefault(r.Namespace), r.ServiceName, r.FromVersion,
+		r.ToVersion)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to diff %s config versions %d and %d",
+			r.ServiceName, r.FromVersion, r.ToVersion)
+	}
+	return toDiffResponse(ctx, diff), nil
+}
+
+func (s *ConfigService) DiffProposedConfig(ctx context.Context, r *configService.Config) (*configService.DiffResponse, error) {
+	data, types, err := fromConfigValues(r.Data, r.Values)
+	if err != nil {
+		return nil, invalidArgument("values", err, "Unable to diff proposed %s config", r.ServiceName)
+	}
+	diff, err := s.configUseCase.DiffProposedConfig(&entity.Config{
+		Namespace: entity.NamespaceOrDefault(r.Namespace),
+		Name:      r.ServiceName,
+		Data:      data,
+		Types:     types,
+	})
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to diff proposed %s config", r.ServiceName)
+	}
+	return toDiffResponse(ctx, diff), nil
+}
+
+// toDiffResponse converts a diff to a response, masking the values of secret keys unless the caller
+// may read them.
+func toDiffResponse(ctx context.Context, diff *entity.ConfigDiff) *configService.DiffResponse {
+	if !revealSecrets(ctx, diff.Namespace, diff.Name) {
+		diff = diff.Masked()
+	}
+	changed := make(map[string]*configService.ValueChange, len(diff.Changed))
+	for key, change := range diff.Changed {
+		changed[key] = &configService.ValueChange{OldValue: change.Old, NewValue: change.New}
+	}
+	return &configService.DiffResponse{
+		ServiceName: diff.Name,
+		Namespace:   diff.Namespace,
+		FromVersion: diff.FromVersion,
+		ToVersion:   diff.ToVersion,
+		Added:       diff.Added,
+		Removed:     diff.Removed,
+		Changed:     changed,
+	}
+}
+
+func (s *ConfigService) ListNamespaces(ctx context.Context, r *configService.ListNamespacesRequest) (*configService.ListNamespacesResponse, error) {
+	namespaces, err := s.configUseCase.ListNamespaces()
+	if err != nil {
+		return nil, toStatus(err, "", "Unable to list namespaces")
+	}
+	return &configService.ListNamespacesResponse{Namespaces: namespaces}, nil
+}
@@ -0,0 +1,578 @@
+package grpc_service
+
+import (
+	"bytes"
+	"context"
+	cfg "distributedConfig/config"
+	"distributedConfig/internal/auth"
+	configService "distributedConfig/internal/delivery/proto"
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository/memory_repository"
+	"distributedConfig/internal/usecase"
+	"distributedConfig/pkg/logger"
+	"encoding/json"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestGateway(t *testing.T) *httptest.Server {
+	t.Helper()
+	configRepository, auditRepository := memory_repository.NewAuditedConfigRepository()
+	configUseCase := usecase.NewConfigUseCase(*logger.New("error"), configRepository,
+		auditRepository, memory_repository.NewConfigNotifier(),
+		&cfg.Config{Server: cfg.ServerConfig{RecentUseDurationDays: 5}})
+	mux := NewGatewayMux()
+	require.NoError(t, configService.RegisterConfigServiceHandlerServer(context.Background(), mux,
+		NewConfigService(*configUseCase)))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestConfigService_GatewayStatusCodes(t *testing.T) {
+	server := newTestGateway(t)
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"create", http.MethodPost, "/v1/config", `{"service_name": "test", "data": {"k1": "v1"}}`, http.StatusOK},
+		{"create existing", http.MethodPost, "/v1/config", `{"service_name": "test", "data": {"k1": "v1"}}`, http.StatusConflict},
+		{"create invalid", http.MethodPost, "/v1/config", `{"service_name": "invalid"}`, http.StatusBadRequest},
+		{"get unknown", http.MethodGet, "/v1/config/unknown", "", http.StatusNotFound},
+		{"get unknown version", http.MethodGet, "/v1/config/test/5", "", http.StatusNotFound},
+		{"update conflict", http.MethodPut, "/v1/config/test", `{"data": {"k1": "v2"}, "expected_version": 3}`, http.StatusConflict},
+		{"diff", http.MethodGet, "/v1/config/test/diff?from_version=1", "", http.StatusOK},
+		{"diff unknown version", http.MethodGet, "/v1/config/test/diff?to_version=5", "", http.StatusNotFound},
+		{"diff proposed", http.MethodPost, "/v1/config/test/diff", `{"data": {"k1": "v2"}}`, http.StatusOK},
+		{"diff proposed invalid", http.MethodPost, "/v1/config/test/diff", `{}`, http.StatusBadRequest},
+		{"delete recently used", http.MethodDelete, "/v1/config/test", "", http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, tc.status, response.StatusCode)
+		})
+	}
+}
+
+func TestConfigService_GatewayPatch(t *testing.T) {
+	server := newTestGateway(t)
+	testCases := []struct {
+		name        string
+		contentType string
+		ifMatch     string
+		body        string
+		status      int
+		data        map[string]string
+	}{
+		{"create", "", "", `{"k1": "v1", "k2": "v2"}`, http.StatusOK, nil},
+		{"set and unset", "", "", `{"set": {"k3": "v3"}, "unset": ["k1"]}`, http.StatusOK,
+			map[string]string{"k2": "v2", "k3": "v3"}},
+		{"merge patch", MergePatchContentType, `"2"`, `{"k2": "new", "k3": null}`, http.StatusOK,
+			map[string]string{"k2": "new"}},
+		{"merge patch conflict", MergePatchContentType, `"2"`, `{"k2": "v2"}`, http.StatusConflict, nil},
+		{"merge patch removing every key", MergePatchContentType, "", `{"k2": null}`, http.StatusBadRequest, nil},
+		{"merge patch with typed values", MergePatchContentType, `"3"`, `{"k4": 5, "k5": [1, "a"]}`, http.StatusOK,
+			map[string]string{"k2": "new", "k4": "5", "k5": `[1,"a"]`}},
+		{"invalid merge patch", MergePatchContentType, "", `["k2"]`, http.StatusBadRequest, nil},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			method, path, body := http.MethodPatch, "/v1/config/test", tc.body
+			if tc.name == "create" {
+				method, path, body = http.MethodPost, "/v1/config", `{"service_name": "test", "data": `+tc.body+`}`
+			}
+			request, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+			require.NoError(t, err)
+			if tc.contentType != "" {
+				request.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.ifMatch != "" {
+				request.Header.Set("If-Match", tc.ifMatch)
+			}
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, tc.status, response.StatusCode)
+			if tc.data != nil {
+				var config struct {
+					Config struct {
+						Data map[string]string `json:"data"`
+					} `json:"config"`
+				}
+				require.NoError(t, json.NewDecoder(response.Body).Decode(&config))
+				require.Equal(t, tc.data, config.Config.Data)
+			}
+		})
+	}
+}
+
+func TestConfigService_GatewayTypedValues(t *testing.T) {
+	server := newTestGateway(t)
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"create", http.MethodPost, "/v1/config", `{"service_name": "test", "data": {"host": "localhost"}, "values": {
+			"port": {"int_value": 8080}, "ratio": {"float_value": 0.5}, "debug": {"bool_value": true},
+			"timeout": {"duration_value": "1.5s"}, "hosts": {"list_value": ["a", "b"]},
+			"limits": {"object_value": {"rps": 10}}}}`, http.StatusOK},
+		{"key in data and values", http.MethodPut, "/v1/config/test",
+			`{"data": {"port": "8080"}, "values": {"port": {"int_value": 8080}}}`, http.StatusBadRequest},
+		{"empty value", http.MethodPut, "/v1/config/test", `{"values": {"port": {}}}`, http.StatusBadRequest},
+		{"patch set value", http.MethodPatch, "/v1/config/test", `{"set_values": {"port": {"int_value": 9090}}}`,
+			http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, tc.status, response.StatusCode)
+		})
+	}
+
+	response, err := http.Get(server.URL + "/v1/config/test")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var config struct {
+		Config struct {
+			Data   map[string]string                     `json:"data"`
+			Values map[string]map[string]json.RawMessage `json:"values"`
+		} `json:"config"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&config))
+	require.Equal(t, map[string]string{"host": "localhost", "port": "9090", "ratio": "0.5", "debug": "true",
+		"timeout": "1.5s", "hosts": `["a","b"]`, "limits": `{"rps":10}`}, config.Config.Data)
+	values := make(map[string]string, len(config.Config.Values))
+	for key, value := range config.Config.Values {
+		for kind, raw := range value {
+			var compact bytes.Buffer
+			require.NoError(t, json.Compact(&compact, raw))
+			values[key] = kind + "=" + compact.String()
+		}
+	}
+	require.Equal(t, map[string]string{"host": `stringValue="localhost"`, "port": `intValue="9090"`,
+		"ratio": "floatValue=0.5", "debug": "boolValue=true", "timeout": `durationValue="1.500s"`,
+		"hosts": `listValue=["a","b"]`, "limits": `objectValue={"rps":10}`}, values)
+}
+
+func TestConfigService_GatewaySchema(t *testing.T) {
+	server := newTestGateway(t)
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"get missing", http.MethodGet, "/v1/config/test/schema", "", http.StatusNotFound},
+		{"set", http.MethodPut, "/v1/config/test/schema",
+			`{"keys": {"port": {"required": true, "type": "int", "min": 1, "max": 65535}}}`, http.StatusOK},
+		{"set invalid", http.MethodPut, "/v1/config/test/schema", `{"keys": {"port": {"type": "port"}}}`,
+			http.StatusBadRequest},
+		{"create violating", http.MethodPost, "/v1/config", `{"service_name": "test", "values": {"port": {"int_value": 0}}}`,
+			http.StatusBadRequest},
+		{"create", http.MethodPost, "/v1/config", `{"service_name": "test", "values": {"port": {"int_value": 80}}}`,
+			http.StatusOK},
+		{"set allowing unknown keys", http.MethodPut, "/v1/config/test/schema",
+			`{"keys": {"port": {"type": "int"}}, "allow_unknown_keys": true}`, http.StatusOK},
+		{"get latest", http.MethodGet, "/v1/config/test/schema", "", http.StatusOK},
+		{"get version", http.MethodGet, "/v1/config/test/schema/1", "", http.StatusOK},
+		{"get config version", http.MethodGet, "/v1/config/test/1", "", http.StatusOK},
+		{"list", http.MethodGet, "/v1/config/test/schemas", "", http.StatusOK},
+		{"delete", http.MethodDelete, "/v1/config/test/schema", "", http.StatusOK},
+		{"delete missing", http.MethodDelete, "/v1/config/test/schema", "", http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, tc.status, response.StatusCode)
+			if tc.name != "create violating" {
+				return
+			}
+			var body struct {
+				Details []struct {
+					FieldViolations []struct {
+						Field string `json:"field"`
+					} `json:"fieldViolations"`
+				} `json:"details"`
+			}
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&body))
+			require.Equal(t, "data.port", body.Details[0].FieldViolations[0].Field)
+		})
+	}
+}
+
+func TestConfigService_GatewayAuditEvents(t *testing.T) {
+	server := newTestGateway(t)
+	for _, request := range []struct{ method, path, body string }{
+		{http.MethodPost, "/v1/config", `{"service_name": "test", "data": {"k1": "v1"}}`},
+		{http.MethodPut, "/v1/config/test", `{"data": {"k1": "v2"}}`},
+		{http.MethodPatch, "/v1/config/test", `{"set": {"k2": "v2"}}`},
+		{http.MethodPost, "/v1/config", `{"service_name": "other", "data": {"k1": "v1"}}`},
+	} {
+		request, err := http.NewRequest(request.method, server.URL+request.path, strings.NewReader(request.body))
+		require.NoError(t, err)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.NoError(t, response.Body.Close())
+	}
+
+	type page struct {
+		Events []struct {
+			Actor       string `json:"actor"`
+			Method      string `json:"method"`
+			DiffSummary string `json:"diffSummary"`
+			ClientIP    string `json:"clientIp"`
+		} `json:"events"`
+		NextPageToken string `json:"nextPageToken"`
+	}
+	list := func(query string) (int, page) {
+		response, err := http.Get(server.URL + "/v1/audit?" + query)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		var result page
+		if response.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&result))
+		}
+		return response.StatusCode, result
+	}
+
+	status, first := list("service_name=test&page_size=2")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, first.Events, 2)
+	require.Equal(t, "PatchConfig", first.Events[0].Method)
+	require.Equal(t, "added: k2", first.Events[0].DiffSummary)
+	require.Equal(t, "anonymous", first.Events[0].Actor)
+	require.Equal(t, "127.0.0.1", first.Events[0].ClientIP)
+	require.NotEmpty(t, first.NextPageToken)
+
+	status, second := list("service_name=test&page_size=2&page_token=" + first.NextPageToken)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, second.Events, 1)
+	require.Equal(t, "CreateConfig", second.Events[0].Method)
+	require.Empty(t, second.NextPageToken)
+
+	status, all := list("")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, all.Events, 4)
+	status, _ = list("page_token=invalid")
+	require.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestConfigService_GatewayConfigVersions(t *testing.T) {
+	server := newTestGateway(t)
+	for _, request := range []struct{ method, path, body string }{
+		{http.MethodPost, "/v1/config", `{"service_name": "test", "data": {"k1": "v1"}}`},
+		{http.MethodPut, "/v1/config/test", `{"data": {"k1": "v2"}}`},
+		{http.MethodPut, "/v1/config/test", `{"data": {"k1": "v3"}}`},
+	} {
+		request, err := http.NewRequest(request.method, server.URL+request.path, strings.NewReader(request.body))
+		require.NoError(t, err)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, response.StatusCode)
+		require.NoError(t, response.Body.Close())
+	}
+
+	type page struct {
+		Versions []struct {
+			Config struct {
+				Data map[string]string `json:"data"`
+			} `json:"config"`
+			Version   string `json:"version"`
+			CreatedAt string `json:"createdAt"`
+		} `json:"versions"`
+		NextPageToken string `json:"nextPageToken"`
+	}
+	list := func(path string) (int, page) {
+		response, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		var result page
+		if response.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&result))
+		}
+		return response.StatusCode, result
+	}
+
+	status, first := list("/v1/configs/test?page_size=2")
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, first.Versions, 2)
+	require.Equal(t, "3", first.Versions[0].Version)
+	require.Equal(t, map[string]string{"k1": "v3"}, first.Versions[0].Config.Data)
+	require.Equal(t, "2", first.NextPageToken)
+
+	status, second := list("/v1/configs/test?page_size=2&include_data=false&page_token=" + first.NextPageToken)
+	require.Equal(t, http.StatusOK, status)
+	require.Len(t, second.Versions, 1)
+	require.Equal(t, "1", second.Versions[0].Version)
+	require.NotEmpty(t, second.Versions[0].CreatedAt)
+	require.Empty(t, second.Versions[0].Config.Data)
+	require.Empty(t, second.NextPageToken)
+
+	status, _ = list("/v1/configs/unknown")
+	require.Equal(t, http.StatusNotFound, status)
+	status, _ = list("/v1/configs/test?page_token=invalid")
+	require.Equal(t, http.StatusBadRequest, status)
+}
+
+func TestConfigService_GatewayConfigNames(t *testing.T) {
+	server := newTestGateway(t)
+	for _, request := range []struct{ method, path, body string }{
+		{http.MethodPost, "/v1/config", `{"service_name": "payments-api", "data": {"k1": "v1"}}`},
+		{http.MethodPost, "/v1/config", `{"service_name": "payments-worker", "data": {"k1": "v1"}}`},
+		{http.MethodPost, "/v1/config", `{"service_name": "billing", "data": {"k1": "v1"}}`},
+		{http.MethodPost, "/v1/namespaces/staging/config", `{"service_name": "payments-api", "data": {"k1": "v1"}}`},
+		{http.MethodPut, "/v1/config/payments-api/labels", `{"labels": {"team": "payments", "tier": "backend"}}`},
+		{http.MethodPut, "/v1/config/payments-worker/labels", `{"labels": {"team": "payments", "tier": "batch"}}`},
+	} {
+		request, err := http.NewRequest(request.method, server.URL+request.path, strings.NewReader(request.body))
+		require.NoError(t, err)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, response.StatusCode, request.URL.Path)
+		require.NoError(t, response.Body.Close())
+	}
+
+	type page struct {
+		Configs []struct {
+			ServiceName string            `json:"serviceName"`
+			Namespace   string            `json:"namespace"`
+			Version     string            `json:"version"`
+			LastUsed    string            `json:"lastUsed"`
+			Labels      map[string]string `json:"labels"`
+		} `json:"configs"`
+		NextPageToken string `json:"nextPageToken"`
+	}
+	list := func(path string) (int, page) {
+		response, err := http.Get(server.URL + path)
+		require.NoError(t, err)
+		defer response.Body.Close()
+		var result page
+		if response.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(response.Body).Decode(&result))
+		}
+		return response.StatusCode, result
+	}
+	names := func(p page) []string {
+		var names []string
+		for _, config := range p.Configs {
+			names = append(names, config.ServiceName)
+		}
+		return names
+	}
+
+	status, first := list("/v1/configs?page_size=2")
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"billing", "payments-api"}, names(first))
+	require.Equal(t, entity.DefaultNamespace, first.Configs[1].Namespace)
+	require.Equal(t, "1", first.Configs[1].Version)
+	require.NotEmpty(t, first.Configs[1].LastUsed)
+	require.Equal(t, map[string]string{"team": "payments", "tier": "backend"}, first.Configs[1].Labels)
+	require.NotEmpty(t, first.NextPageToken)
+	status, second := list("/v1/configs?page_size=2&page_token=" + first.NextPageToken)
+	require.Equal(t, http.StatusOK, status)
+	require.Equal(t, []string{"payments-worker"}, names(second))
+	require.Empty(t, second.NextPageToken)
+
+	testCases := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{"prefix", "/v1/configs?prefix=payments", []string{"payments-api", "payments-worker"}},
+		{"contains", "/v1/configs?contains=work", []string{"payments-worker"}},
+		{"labels", "/v1/configs?labels[team]=payments&labels[tier]=batch", []string{"payments-worker"}},
+		{"descending", "/v1/configs?order_by=name%20desc", []string{"payments-worker", "payments-api", "billing"}},
+		{"created", "/v1/configs?order_by=created", []string{"payments-api", "payments-worker", "billing"}},
+		{"namespace", "/v1/namespaces/staging/configs", []string{"payments-api"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			status, result := list(tc.path)
+			require.Equal(t, http.StatusOK, status)
+			require.Equal(t, tc.want, names(result))
+		})
+	}
+
+	status, _ = list("/v1/configs?order_by=size")
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = list("/v1/configs?page_token=invalid")
+	require.Equal(t, http.StatusBadRequest, status)
+	status, _ = list("/v1/configs?order_by=created&page_token=" + first.NextPageToken)
+	require.Equal(t, http.StatusBadRequest, status)
+
+	request, err := http.NewRequest(http.MethodPut, server.URL+"/v1/config/unknown/labels", strings.NewReader(`{"labels": {"team": "x"}}`))
+	require.NoError(t, err)
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+	require.NoError(t, response.Body.Close())
+}
+
+func TestConfigService_GatewayNamespaces(t *testing.T) {
+	server := newTestGateway(t)
+	testCases := []struct {
+		name   string
+		method string
+		path   string
+		body   string
+		status int
+		key    string
+	}{
+		{"create default", http.MethodPost, "/v1/config", `{"service_name": "test", "data": {"k1": "default"}}`,
+			http.StatusOK, ""},
+		{"create staging", http.MethodPost, "/v1/namespaces/staging/config",
+			`{"service_name": "test", "data": {"k1": "staging"}}`, http.StatusOK, ""},
+		{"get default", http.MethodGet, "/v1/config/test", "", http.StatusOK, "default"},
+		{"get staging", http.MethodGet, "/v1/namespaces/staging/config/test", "", http.StatusOK, "staging"},
+		{"get default explicitly", http.MethodGet, "/v1/namespaces/default/config/test", "", http.StatusOK, "default"},
+		{"update staging", http.MethodPut, "/v1/namespaces/staging/config/test", `{"data": {"k1": "updated"}}`,
+			http.StatusOK, "updated"},
+		{"get unknown namespace", http.MethodGet, "/v1/namespaces/other/config/test", "", http.StatusNotFound, ""},
+		{"create invalid namespace", http.MethodPost, "/v1/namespaces/-bad/config",
+			`{"service_name": "test", "data": {"k1": "v1"}}`, http.StatusBadRequest, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, tc.status, response.StatusCode)
+			if tc.key != "" {
+				var config struct {
+					Config struct {
+						Data map[string]string `json:"data"`
+					} `json:"config"`
+				}
+				require.NoError(t, json.NewDecoder(response.Body).Decode(&config))
+				require.Equal(t, tc.key, config.Config.Data["k1"])
+			}
+		})
+	}
+
+	response, err := http.Get(server.URL + "/v1/namespaces")
+	require.NoError(t, err)
+	defer response.Body.Close()
+	var namespaces struct {
+		Namespaces []string `json:"namespaces"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&namespaces))
+	require.Equal(t, []string{"default", "staging"}, namespaces.Namespaces)
+}
+
+func TestConfigService_GatewayPromote(t *testing.T) {
+	server := newTestGateway(t)
+	do := func(method, path, body string) *http.Response {
+		request, err := http.NewRequest(method, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		response, err := http.DefaultClient.Do(request)
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			_ = response.Body.Close()
+		})
+		return response
+	}
+	response := do(http.MethodPost, "/v1/namespaces/staging/config", `{"service_name": "test", "data": {"k1": "v1", "k2": "v2"}}`)
+	require.Equal(t, http.StatusOK, response.StatusCode)
+
+	testCases := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"promote", `{"source_namespace": "staging", "version": 1, "set_overrides": {"k2": "production"}}`, http.StatusOK},
+		{"promote unknown version", `{"source_namespace": "staging", "version": 2}`, http.StatusNotFound},
+		{"promote to source namespace", `{"source_namespace": "production", "version": 1}`, http.StatusBadRequest},
+		{"promote without version", `{"source_namespace": "staging"}`, http.StatusBadRequest},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			response := do(http.MethodPost, "/v1/namespaces/production/config/test/promote", tc.body)
+			require.Equal(t, tc.status, response.StatusCode)
+		})
+	}
+
+	response = do(http.MethodGet, "/v1/namespaces/production/config/test/1", "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var config struct {
+		Config struct {
+			Data map[string]string `json:"data"`
+		} `json:"config"`
+		PromotedFrom struct {
+			Namespace string `json:"namespace"`
+			Version   int64  `json:"version,string"`
+		} `json:"promotedFrom"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&config))
+	require.Equal(t, map[string]string{"k1": "v1", "k2": "production"}, config.Config.Data)
+	require.Equal(t, "staging", config.PromotedFrom.Namespace)
+	require.Equal(t, int64(1), config.PromotedFrom.Version)
+
+	response = do(http.MethodGet, "/v1/namespaces/production/config/test/overrides", "")
+	require.Equal(t, http.StatusOK, response.StatusCode)
+	var overrides struct {
+		Overrides map[string]string `json:"overrides"`
+	}
+	require.NoError(t, json.NewDecoder(response.Body).Decode(&overrides))
+	require.Equal(t, map[string]string{"k2": "production"}, overrides.Overrides)
+}
+
+func TestConfigService_SecretOverrides(t *testing.T) {
+	configRepository, auditRepository := memory_repository.NewAuditedConfigRepository()
+	configUseCase := usecase.NewConfigUseCase(*logger.New("error"), configRepository,
+		auditRepository, memory_repository.NewConfigNotifier(),
+		&cfg.Config{Server: cfg.ServerConfig{RecentUseDurationDays: 5}})
+	service := NewConfigService(*configUseCase)
+	authenticator, err := auth.NewAuthenticator(auth.KeyFile{Roles: map[string][]auth.Rule{
+		"reader": {{Namespaces: []string{"*"}, Configs: []string{"*"}, Permissions: []auth.Permission{auth.PermissionRead}}},
+	}})
+	require.NoError(t, err)
+	reader := auth.WithAuthenticator(auth.WithPrincipal(context.Background(),
+		&auth.Principal{Subject: "reader", Roles: []string{"reader"}}), authenticator)
+
+	_, err = service.CreateConfig(context.Background(), &configService.Config{Namespace: "staging", ServiceName: "test",
+		Data: map[string]string{"user": "app"}, Values: map[string]*configService.TypedValue{
+			"password": {Kind: &configService.TypedValue_SecretValue{SecretValue: "p@ssw0rd"}}}})
+	require.NoError(t, err)
+	_, err = service.PromoteConfig(context.Background(), &configService.PromoteConfigRequest{ServiceName: "test",
+		SourceNamespace: "staging", TargetNamespace: "production", Version: 1,
+		SetOverrides: map[string]string{"user": "svc", "password": "production"}})
+	require.NoError(t, err)
+
+	overrides, err := service.GetConfigOverrides(reader, &configService.ConfigName{Namespace: "production", ServiceName: "test"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"user": "svc", "password": entity.SecretMask}, overrides.Overrides)
+	overrides, err = service.GetConfigOverrides(context.Background(),
+		&configService.ConfigName{Namespace: "production", ServiceName: "test"})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"user": "svc", "password": "production"}, overrides.Overrides)
+}
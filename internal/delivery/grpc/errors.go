@@ -0,0 +1,132 @@
+package grpc_service
+
+import (
+	"database/sql"
+	"distributedConfig/internal/usecase"
+	"distributedConfig/internal/watcher"
+	"errors"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/runtime/protoiface"
+	"sort"
+)
+
+const configResourceType = "config"
+
+// requestFields maps fields of entity.Config to the fields of the request that carry them.
+var requestFields = map[string]string{
+	"name":  "service_name",
+	"data":  "data",
+	"types": "values",
+}
+
+// toStatus converts an error returned by the use case layer to a gRPC status. The message is
+// prefixed with the formatted description of the failed action, and details describe the cause
+// so that the gateway responds with the matching HTTP status and a structured body.
+func toStatus(err error, serviceName string, format string, args ...interface{}) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	message := fmt.Sprintf(format, args...) + ": " + err.Error()
+	var validationErrors validation.Errors
+	switch {
+	case errors.Is(err, usecase.ErrConfigNotFound), errors.Is(err, sql.ErrNoRows):
+		return withDetails(status.New(codes.NotFound, message), resourceInfo(serviceName, usecase.ErrConfigNotFound))
+	case errors.Is(err, usecase.ErrSchemaNotFound):
+		return withDetails(status.New(codes.NotFound, message), resourceInfo(serviceName, err))
+	case errors.Is(err, usecase.ErrConfigAlreadyExists):
+		return withDetails(status.New(codes.AlreadyExists, message), resourceInfo(serviceName, err))
+	case errors.Is(err, usecase.ErrConfigVersionConflict):
+		return withDetails(status.New(codes.Aborted, message), resourceInfo(serviceName, err))
+	case errors.Is(err, usecase.ErrConfigWasRecentlyUsed):
+		return withDetails(status.New(codes.FailedPrecondition, message), &errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{
+				Type:        "RECENTLY_USED",
+				Subject:     configResourceType + "/" + serviceName,
+				Description: err.Error(),
+			}},
+		})
+	case errors.Is(err, usecase.ErrNoMasterKey):
+		return withDetails(status.New(codes.FailedPrecondition, message), &errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{
+				Type:        "NO_MASTER_KEY",
+				Subject:     configResourceType + "/" + serviceName,
+				Description: err.Error(),
+			}},
+		})
+	case errors.Is(err, usecase.ErrSchemaViolation):
+		return withDetails(status.New(codes.FailedPrecondition, message), &errdetails.PreconditionFailure{
+			Violations: []*errdetails.PreconditionFailure_Violation{{
+				Type:        "SCHEMA_VIOLATION",
+				Subject:     configResourceType + "/" + serviceName,
+				Description: err.Error(),
+			}},
+		})
+	case errors.Is(err, watcher.ErrClosed):
+		return status.Error(codes.Unavailable, message)
+	case errors.As(err, &validationErrors):
+		return withDetails(status.New(codes.InvalidArgument, message), badRequest(validationErrors))
+	default:
+		return status.Error(codes.Internal, message)
+	}
+}
+
+// invalidArgument reports a malformed request field.
+func invalidArgument(field string, err error, format string, args ...interface{}) error {
+	message := fmt.Sprintf(format, args...) + ": " + err.Error()
+	return withDetails(status.New(codes.InvalidArgument, message), &errdetails.BadRequest{
+		FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: field, Description: err.Error()}},
+	})
+}
+
+func withDetails(st *status.Status, details ...protoiface.MessageV1) error {
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+func resourceInfo(serviceName string, err error) *errdetails.ResourceInfo {
+	return &errdetails.ResourceInfo{
+		ResourceType: configResourceType,
+		ResourceName: serviceName,
+		Description:  err.Error(),
+	}
+}
+
+func badRequest(validationErrors validation.Errors) *errdetails.BadRequest {
+	details := &errdetails.BadRequest{}
+	addFieldViolations(details, "", validationErrors)
+	return details
+}
+
+// addFieldViolations adds a violation for every field. Violations of nested fields, such as the keys
+// of data rejected by a schema, are named "data.key".
+func addFieldViolations(details *errdetails.BadRequest, prefix string, validationErrors validation.Errors) {
+	fields := make([]string, 0, len(validationErrors))
+	for field := range validationErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	for _, field := range fields {
+		name := prefix + "." + field
+		if prefix == "" {
+			var ok bool
+			if name, ok = requestFields[field]; !ok {
+				name = field
+			}
+		}
+		if nested, ok := validationErrors[field].(validation.Errors); ok {
+			addFieldViolations(details, name, nested)
+			continue
+		}
+		details.FieldViolations = append(details.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       name,
+			Description: validationErrors[field].Error(),
+		})
+	}
+}
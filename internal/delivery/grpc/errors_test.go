@@ -0,0 +1,140 @@
+package grpc_service
+
+import (
+	"database/sql"
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/usecase"
+	"distributedConfig/internal/watcher"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestToStatus(t *testing.T) {
+	testCases := []struct {
+		name    string
+		err     error
+		code    codes.Code
+		details interface{}
+	}{
+		{
+			name:    "not found",
+			err:     usecase.ErrConfigNotFound,
+			code:    codes.NotFound,
+			details: &errdetails.ResourceInfo{},
+		},
+		{
+			name:    "no rows",
+			err:     sql.ErrNoRows,
+			code:    codes.NotFound,
+			details: &errdetails.ResourceInfo{},
+		},
+		{
+			name:    "wrapped not found",
+			err:     fmt.Errorf("lookup: %w", usecase.ErrConfigNotFound),
+			code:    codes.NotFound,
+			details: &errdetails.ResourceInfo{},
+		},
+		{
+			name:    "schema not found",
+			err:     usecase.ErrSchemaNotFound,
+			code:    codes.NotFound,
+			details: &errdetails.ResourceInfo{},
+		},
+		{
+			name:    "already exists",
+			err:     usecase.ErrConfigAlreadyExists,
+			code:    codes.AlreadyExists,
+			details: &errdetails.ResourceInfo{},
+		},
+		{
+			name:    "version conflict",
+			err:     usecase.ErrConfigVersionConflict,
+			code:    codes.Aborted,
+			details: &errdetails.ResourceInfo{},
+		},
+		{
+			name:    "recently used",
+			err:     usecase.ErrConfigWasRecentlyUsed,
+			code:    codes.FailedPrecondition,
+			details: &errdetails.PreconditionFailure{},
+		},
+		{
+			name:    "no master key",
+			err:     usecase.ErrNoMasterKey,
+			code:    codes.FailedPrecondition,
+			details: &errdetails.PreconditionFailure{},
+		},
+		{
+			name:    "schema violation",
+			err:     fmt.Errorf("%w: version 1 would become relevant", usecase.ErrSchemaViolation),
+			code:    codes.FailedPrecondition,
+			details: &errdetails.PreconditionFailure{},
+		},
+		{
+			name: "watchers closed",
+			err:  watcher.ErrClosed,
+			code: codes.Unavailable,
+		},
+		{
+			name:    "validation",
+			err:     (&entity.Config{}).Validate(),
+			code:    codes.InvalidArgument,
+			details: &errdetails.BadRequest{},
+		},
+		{
+			name: "internal",
+			err:  errors.New("connection refused"),
+			code: codes.Internal,
+		},
+		{
+			name: "status",
+			err:  status.Error(codes.Unavailable, "shutting down"),
+			code: codes.Unavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			st := status.Convert(toStatus(tc.err, "test", "Unable to get %s config", "test"))
+			require.Equal(t, tc.code, st.Code())
+			if tc.details == nil {
+				require.Empty(t, st.Details())
+				return
+			}
+			require.Len(t, st.Details(), 1)
+			require.IsType(t, tc.details, st.Details()[0])
+		})
+	}
+}
+
+func TestToStatus_FieldViolations(t *testing.T) {
+	err := (&entity.Config{}).Validate()
+	st := status.Convert(toStatus(err, "", "Unable to create %s config", ""))
+	require.Equal(t, "Unable to create  config: "+err.Error(), st.Message())
+	details := st.Details()[0].(*errdetails.BadRequest)
+	require.Len(t, details.FieldViolations, 3)
+	require.Equal(t, "data", details.FieldViolations[0].Field)
+	require.Equal(t, "service_name", details.FieldViolations[1].Field)
+	require.Equal(t, "namespace", details.FieldViolations[2].Field)
+}
+
+func TestToStatus_SchemaViolations(t *testing.T) {
+	schema := &entity.ConfigSchema{Keys: map[string]entity.KeyRule{
+		"port": {Required: true, Type: entity.TypeInt},
+		"mode": {Enum: []string{"fast", "safe"}},
+	}}
+	err := schema.Check(&entity.Config{Data: map[string]string{"mode": "slow", "host": "localhost"}})
+	st := status.Convert(toStatus(err, "test", "Unable to update %s config", "test"))
+	require.Equal(t, codes.InvalidArgument, st.Code())
+	details := st.Details()[0].(*errdetails.BadRequest)
+	require.Len(t, details.FieldViolations, 3)
+	require.Equal(t, "data.host", details.FieldViolations[0].Field)
+	require.Equal(t, "data.mode", details.FieldViolations[1].Field)
+	require.Equal(t, "data.port", details.FieldViolations[2].Field)
+	require.Equal(t, "is required by the schema", details.FieldViolations[2].Description)
+}
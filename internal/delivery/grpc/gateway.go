@@ -0,0 +1,106 @@
+package grpc_service
+
+import (
+	"bytes"
+	configService "distributedConfig/internal/delivery/proto"
+	"encoding/json"
+	"fmt"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+	"io"
+	"sort"
+)
+
+// MergePatchContentType is the media type of JSON Merge Patch (RFC 7396) bodies of PATCH requests.
+const MergePatchContentType = "application/merge-patch+json"
+
+// NewGatewayMux creates the gateway mux. Besides the default JSON body of PatchConfig, it accepts
+// a JSON Merge Patch of the config data: keys with null are unset, keys with strings are set to plain
+// strings and keys with other JSON values are set to typed values. Integral numbers become ints.
+func NewGatewayMux(opts ...runtime.ServeMuxOption) *runtime.ServeMux {
+	opts = append([]runtime.ServeMuxOption{
+		runtime.WithMarshalerOption(MergePatchContentType, &mergePatchMarshaler{Marshaler: &runtime.JSONPb{
+			MarshalOptions:   protojson.MarshalOptions{EmitUnpopulated: true},
+			UnmarshalOptions: protojson.UnmarshalOptions{DiscardUnknown: true},
+		}}),
+	}, opts...)
+	return runtime.NewServeMux(opts...)
+}
+
+// mergePatchMarshaler decodes merge patches into PatchConfigRequest and otherwise behaves like JSONPb,
+// which also encodes the responses.
+type mergePatchMarshaler struct {
+	runtime.Marshaler
+}
+
+func (m *mergePatchMarshaler) Unmarshal(data []byte, v interface{}) error {
+	return m.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (m *mergePatchMarshaler) NewDecoder(r io.Reader) runtime.Decoder {
+	return runtime.DecoderFunc(func(v interface{}) error {
+		request, ok := v.(*configService.PatchConfigRequest)
+		if !ok {
+			return m.Marshaler.NewDecoder(r).Decode(v)
+		}
+		var patch map[string]json.RawMessage
+		if err := json.NewDecoder(r).Decode(&patch); err != nil {
+			return fmt.Errorf("invalid merge patch, expected an object: %w", err)
+		}
+		request.Set = make(map[string]string)
+		request.SetValues = make(map[string]*configService.TypedValue)
+		request.Unset = nil
+		for key, raw := range patch {
+			var value interface{}
+			decoder := json.NewDecoder(bytes.NewReader(raw))
+			decoder.UseNumber()
+			if err := decoder.Decode(&value); err != nil {
+				return fmt.Errorf("invalid merge patch value of key %s: %w", key, err)
+			}
+			switch v := value.(type) {
+			case nil:
+				request.Unset = append(request.Unset, key)
+			case string:
+				request.Set[key] = v
+			default:
+				typed, err := mergePatchValue(v, raw)
+				if err != nil {
+					return fmt.Errorf("invalid merge patch value of key %s: %w", key, err)
+				}
+				request.SetValues[key] = typed
+			}
+		}
+		sort.Strings(request.Unset)
+		return nil
+	})
+}
+
+// mergePatchValue converts a JSON value of a merge patch other than a string or null to a typed value.
+func mergePatchValue(value interface{}, raw json.RawMessage) (*configService.TypedValue, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &configService.TypedValue{Kind: &configService.TypedValue_IntValue{IntValue: i}}, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return nil, err
+		}
+		return &configService.TypedValue{Kind: &configService.TypedValue_FloatValue{FloatValue: f}}, nil
+	case bool:
+		return &configService.TypedValue{Kind: &configService.TypedValue_BoolValue{BoolValue: v}}, nil
+	case []interface{}:
+		list := &structpb.ListValue{}
+		if err := protojson.Unmarshal(raw, list); err != nil {
+			return nil, err
+		}
+		return &configService.TypedValue{Kind: &configService.TypedValue_ListValue{ListValue: list}}, nil
+	default:
+		object := &structpb.Struct{}
+		if err := protojson.Unmarshal(raw, object); err != nil {
+			return nil, err
+		}
+		return &configService.TypedValue{Kind: &configService.TypedValue_ObjectValue{ObjectValue: object}}, nil
+	}
+}
@@ -0,0 +1,157 @@
+package grpc_service
+
+import (
+	"context"
+	"distributedConfig/internal/auth"
+	configService "distributedConfig/internal/delivery/proto"
+	"distributedConfig/internal/entity"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"strings"
+	"time"
+)
+
+const (
+	defaultNamePageSize = 100
+	maxNamePageSize     = 1000
+)
+
+var errInvalidOrder = errors.New("order must be name, created or last_used, optionally followed by desc")
+
+// nameToken is the position of the last config of a page in a listing, which continues after it.
+// It records the order of the listing, because a position is meaningless in another order.
+type nameToken struct {
+	OrderBy string `json:"o"`
+	Time    int64  `json:"t,omitempty"`
+	Name    string `json:"n"`
+}
+
+func (s *ConfigService) ListConfigNames(ctx context.Context, r *configService.ListConfigNamesRequest) (*configService.ListConfigNamesResponse, error) {
+	pageSize := clampPageSize(r.PageSize, defaultNamePageSize, maxNamePageSize)
+	filter := &entity.NameFilter{
+		Namespace: entity.NamespaceOrDefault(r.Namespace),
+		Prefix:    r.Prefix,
+		Contains:  r.Contains,
+		Labels:    r.Labels,
+		// One more config is requested to know whether there is a next page.
+		Limit: pageSize + 1,
+	}
+	orderBy, err := parseOrderBy(r.OrderBy, filter)
+	if err != nil {
+		return nil, invalidArgument("order_by", err, "Unable to list configs")
+	}
+	if r.PageToken != "" {
+		if filter.After, err = decodeNameToken(r.PageToken, orderBy); err != nil {
+			return nil, invalidArgument("page_token", err, "Unable to list configs")
+		}
+	}
+	summaries, err := s.readableConfigNames(ctx, filter)
+	if err != nil {
+		return nil, toStatus(err, "", "Unable to list configs")
+	}
+	response := &configService.ListConfigNamesResponse{Configs: make([]*configService.ConfigSummary, 0, len(summaries))}
+	if len(summaries) > pageSize {
+		summaries = summaries[:pageSize]
+		response.NextPageToken = encodeNameToken(summaries[pageSize-1].Cursor(filter.OrderBy), orderBy)
+	}
+	for _, summary := range summaries {
+		response.Configs = append(response.Configs, &configService.ConfigSummary{
+			ServiceName: summary.Name,
+			Namespace:   summary.Namespace,
+			Version:     summary.Version,
+			CreatedAt:   timestamppb.New(summary.CreatedAt),
+			LastUsed:    timestamppb.New(summary.LastUsed),
+			Labels:      summary.Labels,
+		})
+	}
+	return response, nil
+}
+
+func (s *ConfigService) SetConfigLabels(ctx context.Context, r *configService.ConfigLabels) (*configService.ConfigLabels, error) {
+	labels := &entity.ConfigLabels{
+		Namespace: entity.NamespaceOrDefault(r.Namespace),
+		Name:      r.ServiceName,
+		Labels:    r.Labels,
+	}
+	err := s.configUseCase.SetConfigLabels(withCaller(ctx), labels)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to set labels of %s config", r.ServiceName)
+	}
+	return &configService.ConfigLabels{ServiceName: labels.Name, Namespace: labels.Namespace, Labels: labels.Labels}, nil
+}
+
+// readableConfigNames lists the configs matching the filter that the caller may read, up to filter.Limit of them.
+// Configs the caller cannot read are skipped before the page is cut, so that pages stay full
+// and page tokens never point at a config the caller cannot see.
+func (s *ConfigService) readableConfigNames(ctx context.Context, filter *entity.NameFilter) ([]*entity.ConfigSummary, error) {
+	batch := *filter
+	readable := make([]*entity.ConfigSummary, 0, filter.Limit)
+	for {
+		summaries, err := s.configUseCase.ListConfigNames(&batch)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range summaries {
+			if len(readable) == filter.Limit {
+				return readable, nil
+			}
+			if auth.Allowed(ctx, auth.PermissionRead, summary.Namespace, summary.Name) {
+				readable = append(readable, summary)
+			}
+		}
+		if len(readable) == filter.Limit || len(summaries) < batch.Limit {
+			return readable, nil
+		}
+		batch.After = summaries[len(summaries)-1].Cursor(filter.OrderBy)
+	}
+}
+
+// parseOrderBy sets the order of the filter from order_by, like "last_used desc", and returns it normalized.
+func parseOrderBy(orderBy string, filter *entity.NameFilter) (string, error) {
+	fields := strings.Fields(strings.ToLower(orderBy))
+	if len(fields) == 0 {
+		fields = []string{string(entity.OrderByName)}
+	}
+	if len(fields) > 2 || (len(fields) == 2 && fields[1] != "asc" && fields[1] != "desc") {
+		return "", errInvalidOrder
+	}
+	filter.OrderBy = entity.ConfigOrder(fields[0])
+	if !filter.OrderBy.IsValid() {
+		return "", errInvalidOrder
+	}
+	filter.Descending = len(fields) == 2 && fields[1] == "desc"
+	if filter.Descending {
+		return fields[0] + " desc", nil
+	}
+	return fields[0], nil
+}
+
+func encodeNameToken(cursor *entity.NameCursor, orderBy string) string {
+	token := nameToken{OrderBy: orderBy, Name: cursor.Name}
+	if !cursor.Time.IsZero() {
+		token.Time = cursor.Time.UnixNano()
+	}
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeNameToken(pageToken, orderBy string) (*entity.NameCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return nil, errInvalidPageToken
+	}
+	var token nameToken
+	if err := json.Unmarshal(data, &token); err != nil || token.Name == "" {
+		return nil, errInvalidPageToken
+	}
+	if token.OrderBy != orderBy {
+		return nil, errors.New("page token was issued for another order")
+	}
+	cursor := &entity.NameCursor{Name: token.Name}
+	if token.Time != 0 {
+		cursor.Time = time.Unix(0, token.Time).UTC()
+	}
+	return cursor, nil
+}
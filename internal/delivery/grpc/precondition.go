@@ -0,0 +1,38 @@
+package grpc_service
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/metadata"
+	"strconv"
+	"strings"
+)
+
+// ifMatchKeys are the metadata keys of the If-Match precondition: as sent by gRPC clients
+// and as forwarded by grpc-gateway from the HTTP header.
+var ifMatchKeys = []string{"if-match", "grpcgateway-if-match"}
+
+// expectedVersion returns the version precondition of a request, or zero if the caller did not
+// set one. The expected_version field of the request takes priority over If-Match.
+func expectedVersion(ctx context.Context, field *int64) (int64, error) {
+	if field != nil {
+		return *field, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range ifMatchKeys {
+		if values := md.Get(key); len(values) > 0 {
+			return parseIfMatch(values[0])
+		}
+	}
+	return 0, nil
+}
+
+// parseIfMatch accepts a version in the form of an entity tag: 3, "3" or W/"3".
+func parseIfMatch(value string) (int64, error) {
+	tag := strings.TrimPrefix(strings.TrimSpace(value), "W/")
+	version, err := strconv.ParseInt(strings.Trim(tag, `"`), 10, 64)
+	if err != nil || version < 1 {
+		return 0, fmt.Errorf("invalid If-Match version %q", value)
+	}
+	return version, nil
+}
@@ -0,0 +1,67 @@
+package grpc_service
+
+import (
+	"context"
+	configService "distributedConfig/internal/delivery/proto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"testing"
+)
+
+func TestExpectedVersion(t *testing.T) {
+	version := int64(7)
+	testCases := []struct {
+		name     string
+		md       metadata.MD
+		config   *configService.Config
+		expected int64
+		isValid  bool
+	}{
+		{
+			name:     "no precondition",
+			config:   &configService.Config{},
+			expected: 0,
+			isValid:  true,
+		},
+		{
+			name:     "expected version field",
+			md:       metadata.Pairs("grpcgateway-if-match", "3"),
+			config:   &configService.Config{ExpectedVersion: &version},
+			expected: 7,
+			isValid:  true,
+		},
+		{
+			name:     "gateway if-match",
+			md:       metadata.Pairs("grpcgateway-if-match", `"3"`),
+			config:   &configService.Config{},
+			expected: 3,
+			isValid:  true,
+		},
+		{
+			name:     "weak if-match",
+			md:       metadata.Pairs("if-match", `W/"4"`),
+			config:   &configService.Config{},
+			expected: 4,
+			isValid:  true,
+		},
+		{
+			name:    "invalid if-match",
+			md:      metadata.Pairs("if-match", "*"),
+			config:  &configService.Config{},
+			isValid: false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tc.md)
+			expected, err := expectedVersion(ctx, tc.config.ExpectedVersion)
+			if !tc.isValid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, expected)
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package grpc_service
+
+import (
+	"context"
+	configService "distributedConfig/internal/delivery/proto"
+	"distributedConfig/internal/entity"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func (s *ConfigService) SetConfigSchema(ctx context.Context, r *configService.ConfigSchema) (*configService.ConfigSchemaResponse, error) {
+	schema := &entity.ConfigSchema{
+		Namespace:        entity.NamespaceOrDefault(r.Namespace),
+		Name:             r.ServiceName,
+		Keys:             make(map[string]entity.KeyRule, len(r.Keys)),
+		AllowUnknownKeys: r.AllowUnknownKeys,
+	}
+	for key, rule := range r.Keys {
+		schema.Keys[key] = entity.KeyRule{
+			Required: rule.GetRequired(),
+			Type:     entity.ValueType(rule.GetType()),
+			Pattern:  rule.GetPattern(),
+			Min:      rule.Min,
+			Max:      rule.Max,
+			Enum:     rule.GetEnum(),
+		}
+	}
+	err := s.configUseCase.SetConfigSchema(withCaller(ctx), schema)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to set schema of %s config", r.ServiceName)
+	}
+	return toSchemaResponse(schema), nil
+}
+
+func (s *ConfigService) GetConfigSchema(ctx context.Context, r *configService.ConfigNameAndVersion) (*configService.ConfigSchemaResponse, error) {
+	schema, err := s.configUseCase.GetConfigSchema(entity.NamespaceOrDefault(r.Namespace), r.ServiceName, r.Version)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to get schema of %s config", r.ServiceName)
+	}
+	return toSchemaResponse(schema), nil
+}
+
+func (s *ConfigService) ListConfigSchemas(ctx context.Context, r *configService.ConfigName) (*configService.ListConfigSchemasResponse, error) {
+	schemas, err := s.configUseCase.ListConfigSchemas(entity.NamespaceOrDefault(r.Namespace), r.ServiceName)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to list schemas of %s config", r.ServiceName)
+	}
+	response := &configService.ListConfigSchemasResponse{Schemas: make([]*configService.ConfigSchemaResponse, 0, len(schemas))}
+	for _, schema := range schemas {
+		response.Schemas = append(response.Schemas, toSchemaResponse(schema))
+	}
+	return response, nil
+}
+
+func (s *ConfigService) DeleteConfigSchema(ctx context.Context, r *configService.ConfigName) (*configService.DeleteResponse, error) {
+	err := s.configUseCase.DeleteConfigSchema(withCaller(ctx), entity.NamespaceOrDefault(r.Namespace), r.ServiceName)
+	if err != nil {
+		return nil, toStatus(err, r.ServiceName, "Unable to delete schema of %s config", r.ServiceName)
+	}
+	return &configService.DeleteResponse{Message: "Schema was deleted"}, nil
+}
+
+func toSchemaResponse(schema *entity.ConfigSchema) *configService.ConfigSchemaResponse {
+	keys := make(map[string]*configService.KeyRule, len(schema.Keys))
+	for key, rule := range schema.Keys {
+		keys[key] = &configService.KeyRule{
+			Required: rule.Required,
+			Type:     string(rule.Type),
+			Pattern:  rule.Pattern,
+			Min:      rule.Min,
+			Max:      rule.Max,
+			Enum:     rule.Enum,
+		}
+	}
+	return &configService.ConfigSchemaResponse{
+		Schema: &configService.ConfigSchema{
+			ServiceName:      schema.Name,
+			Namespace:        schema.Namespace,
+			Keys:             keys,
+			AllowUnknownKeys: schema.AllowUnknownKeys,
+		},
+		Version:   schema.Version,
+		CreatedAt: timestamppb.New(schema.CreatedAt),
+	}
+}
@@ -0,0 +1,134 @@
+package grpc_service
+
+import (
+	"context"
+	"distributedConfig/internal/auth"
+	configService "distributedConfig/internal/delivery/proto"
+	"distributedConfig/internal/entity"
+	"errors"
+	"fmt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"time"
+)
+
+// toConfigMessage converts a config to the Config message of responses, with every value both in data
+// and in values. Values of secret keys are masked unless the caller may read them.
+func toConfigMessage(ctx context.Context, config *entity.Config) *configService.Config {
+	if !revealSecrets(ctx, config.Namespace, config.Name) {
+		config = config.Masked()
+	}
+	values := make(map[string]*configService.TypedValue, len(config.Data))
+	for key, value := range config.Data {
+		typed, err := toTypedValue(config.TypeOf(key), value)
+		if err != nil {
+			// Stored values are validated, so the value can only be returned as it is.
+			typed = &configService.TypedValue{Kind: &configService.TypedValue_StringValue{StringValue: value}}
+		}
+		values[key] = typed
+	}
+	return &configService.Config{
+		ServiceName: config.Name,
+		Namespace:   config.Namespace,
+		Data:        config.Data,
+		Values:      values,
+	}
+}
+
+// fromConfigValues merges the string values and the typed values of a request into the data of a config
+// and the types of its values that are not strings.
+func fromConfigValues(data map[string]string, values map[string]*configService.TypedValue) (
+	map[string]string, map[string]entity.ValueType, error) {
+	if len(values) == 0 {
+		return data, nil, nil
+	}
+	merged := make(map[string]string, len(data)+len(values))
+	for key, value := range data {
+		merged[key] = value
+	}
+	var types map[string]entity.ValueType
+	for key, value := range values {
+		if _, ok := data[key]; ok {
+			return nil, nil, fmt.Errorf("key %s is both in data and in values", key)
+		}
+		valueType, formatted, err := fromTypedValue(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("value of key %s: %w", key, err)
+		}
+		merged[key] = formatted
+		if valueType != entity.TypeString {
+			if types == nil {
+				types = make(map[string]entity.ValueType)
+			}
+			types[key] = valueType
+		}
+	}
+	return merged, types, nil
+}
+
+// revealSecrets reports whether the caller may read the values of the secret keys of the config.
+func revealSecrets(ctx context.Context, namespace, name string) bool {
+	return auth.Allowed(ctx, auth.PermissionDecrypt, namespace, name)
+}
+
+func toTypedValue(valueType entity.ValueType, value string) (*configService.TypedValue, error) {
+	if valueType == entity.TypeSecret {
+		return &configService.TypedValue{Kind: &configService.TypedValue_SecretValue{SecretValue: value}}, nil
+	}
+	parsed, err := entity.ParseValue(valueType, value)
+	if err != nil {
+		return nil, err
+	}
+	switch v := parsed.(type) {
+	case string:
+		return &configService.TypedValue{Kind: &configService.TypedValue_StringValue{StringValue: v}}, nil
+	case int64:
+		return &configService.TypedValue{Kind: &configService.TypedValue_IntValue{IntValue: v}}, nil
+	case float64:
+		return &configService.TypedValue{Kind: &configService.TypedValue_FloatValue{FloatValue: v}}, nil
+	case bool:
+		return &configService.TypedValue{Kind: &configService.TypedValue_BoolValue{BoolValue: v}}, nil
+	case time.Duration:
+		return &configService.TypedValue{Kind: &configService.TypedValue_DurationValue{DurationValue: durationpb.New(v)}}, nil
+	case []interface{}:
+		list, err := structpb.NewList(v)
+		if err != nil {
+			return nil, err
+		}
+		return &configService.TypedValue{Kind: &configService.TypedValue_ListValue{ListValue: list}}, nil
+	case map[string]interface{}:
+		object, err := structpb.NewStruct(v)
+		if err != nil {
+			return nil, err
+		}
+		return &configService.TypedValue{Kind: &configService.TypedValue_ObjectValue{ObjectValue: object}}, nil
+	default:
+		return nil, fmt.Errorf("unsupported value of type %T", parsed)
+	}
+}
+
+func fromTypedValue(value *configService.TypedValue) (entity.ValueType, string, error) {
+	switch kind := value.GetKind().(type) {
+	case *configService.TypedValue_StringValue:
+		return entity.FormatValue(kind.StringValue)
+	case *configService.TypedValue_IntValue:
+		return entity.FormatValue(kind.IntValue)
+	case *configService.TypedValue_FloatValue:
+		return entity.FormatValue(kind.FloatValue)
+	case *configService.TypedValue_BoolValue:
+		return entity.FormatValue(kind.BoolValue)
+	case *configService.TypedValue_DurationValue:
+		if err := kind.DurationValue.CheckValid(); err != nil {
+			return "", "", err
+		}
+		return entity.FormatValue(kind.DurationValue.AsDuration())
+	case *configService.TypedValue_ListValue:
+		return entity.FormatValue(kind.ListValue.AsSlice())
+	case *configService.TypedValue_ObjectValue:
+		return entity.FormatValue(kind.ObjectValue.AsMap())
+	case *configService.TypedValue_SecretValue:
+		return entity.TypeSecret, kind.SecretValue, nil
+	default:
+		return "", "", errors.New("value is empty")
+	}
+}
@@ -26,10 +26,32 @@ type ConfigServiceClient interface {
 	GetConfig(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (*ConfigResponse, error)
 	GetConfigByVersion(ctx context.Context, in *ConfigNameAndVersion, opts ...grpc.CallOption) (*ConfigResponse, error)
 	UpdateConfig(ctx context.Context, in *Config, opts ...grpc.CallOption) (*ConfigResponse, error)
+	PatchConfig(ctx context.Context, in *PatchConfigRequest, opts ...grpc.CallOption) (*ConfigResponse, error)
+	PromoteConfig(ctx context.Context, in *PromoteConfigRequest, opts ...grpc.CallOption) (*ConfigResponse, error)
+	GetConfigOverrides(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (*ConfigOverridesResponse, error)
 	DeleteConfig(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (*DeleteResponse, error)
 	DeleteConfigVersion(ctx context.Context, in *ConfigNameAndVersion, opts ...grpc.CallOption) (*DeleteResponse, error)
+	// ListConfigs streams every version of the config, latest first. It is only available over gRPC,
+	// REST clients use ListConfigVersions.
 	ListConfigs(ctx context.Context, in *ListRequest, opts ...grpc.CallOption) (ConfigService_ListConfigsClient, error)
+	// ListConfigVersions lists the versions of the config a page at a time, latest first.
+	ListConfigVersions(ctx context.Context, in *ListConfigVersionsRequest, opts ...grpc.CallOption) (*ListConfigVersionsResponse, error)
+	// ListConfigNames lists the configs of a namespace a page at a time, by their relevant versions and without
+	// their data. Configs that the caller cannot read are left out, so a page can hold fewer of them than page_size.
+	ListConfigNames(ctx context.Context, in *ListConfigNamesRequest, opts ...grpc.CallOption) (*ListConfigNamesResponse, error)
+	// SetConfigLabels replaces the labels of the config. An empty map removes them.
+	SetConfigLabels(ctx context.Context, in *ConfigLabels, opts ...grpc.CallOption) (*ConfigLabels, error)
 	SetRelevantConfig(ctx context.Context, in *ConfigNameAndVersion, opts ...grpc.CallOption) (*ConfigResponse, error)
+	WatchConfig(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (ConfigService_WatchConfigClient, error)
+	DiffConfigVersions(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*DiffResponse, error)
+	DiffProposedConfig(ctx context.Context, in *Config, opts ...grpc.CallOption) (*DiffResponse, error)
+	ListNamespaces(ctx context.Context, in *ListNamespacesRequest, opts ...grpc.CallOption) (*ListNamespacesResponse, error)
+	ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error)
+	SetConfigSchema(ctx context.Context, in *ConfigSchema, opts ...grpc.CallOption) (*ConfigSchemaResponse, error)
+	// GetConfigSchema returns the latest version of the schema if the version is 0.
+	GetConfigSchema(ctx context.Context, in *ConfigNameAndVersion, opts ...grpc.CallOption) (*ConfigSchemaResponse, error)
+	ListConfigSchemas(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (*ListConfigSchemasResponse, error)
+	DeleteConfigSchema(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (*DeleteResponse, error)
 }
 
 type configServiceClient struct {
@@ -76,6 +98,33 @@ func (c *configServiceClient) UpdateConfig(ctx context.Context, in *Config, opts
 	return out, nil
 }
 
+func (c *configServiceClient) PatchConfig(ctx context.Context, in *PatchConfigRequest, opts ...grpc.CallOption) (*ConfigResponse, error) {
+	out := new(ConfigResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/PatchConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) PromoteConfig(ctx context.Context, in *PromoteConfigRequest, opts ...grpc.CallOption) (*ConfigResponse, error) {
+	out := new(ConfigResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/PromoteConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) GetConfigOverrides(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (*ConfigOverridesResponse, error) {
+	out := new(ConfigOverridesResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/GetConfigOverrides", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *configServiceClient) DeleteConfig(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (*DeleteResponse, error) {
 	out := new(DeleteResponse)
 	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/DeleteConfig", in, out, opts...)
@@ -126,6 +175,33 @@ func (x *configServiceListConfigsClient) Recv() (*ConfigResponse, error) {
 	return m, nil
 }
 
+func (c *configServiceClient) ListConfigVersions(ctx context.Context, in *ListConfigVersionsRequest, opts ...grpc.CallOption) (*ListConfigVersionsResponse, error) {
+	out := new(ListConfigVersionsResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/ListConfigVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) ListConfigNames(ctx context.Context, in *ListConfigNamesRequest, opts ...grpc.CallOption) (*ListConfigNamesResponse, error) {
+	out := new(ListConfigNamesResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/ListConfigNames", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) SetConfigLabels(ctx context.Context, in *ConfigLabels, opts ...grpc.CallOption) (*ConfigLabels, error) {
+	out := new(ConfigLabels)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/SetConfigLabels", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *configServiceClient) SetRelevantConfig(ctx context.Context, in *ConfigNameAndVersion, opts ...grpc.CallOption) (*ConfigResponse, error) {
 	out := new(ConfigResponse)
 	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/SetRelevantConfig", in, out, opts...)
@@ -135,6 +211,110 @@ func (c *configServiceClient) SetRelevantConfig(ctx context.Context, in *ConfigN
 	return out, nil
 }
 
+func (c *configServiceClient) WatchConfig(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (ConfigService_WatchConfigClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ConfigService_ServiceDesc.Streams[1], "/tutorial.ConfigService/WatchConfig", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &configServiceWatchConfigClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ConfigService_WatchConfigClient interface {
+	Recv() (*ConfigResponse, error)
+	grpc.ClientStream
+}
+
+type configServiceWatchConfigClient struct {
+	grpc.ClientStream
+}
+
+func (x *configServiceWatchConfigClient) Recv() (*ConfigResponse, error) {
+	m := new(ConfigResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *configServiceClient) DiffConfigVersions(ctx context.Context, in *DiffRequest, opts ...grpc.CallOption) (*DiffResponse, error) {
+	out := new(DiffResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/DiffConfigVersions", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) DiffProposedConfig(ctx context.Context, in *Config, opts ...grpc.CallOption) (*DiffResponse, error) {
+	out := new(DiffResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/DiffProposedConfig", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) ListNamespaces(ctx context.Context, in *ListNamespacesRequest, opts ...grpc.CallOption) (*ListNamespacesResponse, error) {
+	out := new(ListNamespacesResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/ListNamespaces", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) ListAuditEvents(ctx context.Context, in *ListAuditEventsRequest, opts ...grpc.CallOption) (*ListAuditEventsResponse, error) {
+	out := new(ListAuditEventsResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/ListAuditEvents", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) SetConfigSchema(ctx context.Context, in *ConfigSchema, opts ...grpc.CallOption) (*ConfigSchemaResponse, error) {
+	out := new(ConfigSchemaResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/SetConfigSchema", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) GetConfigSchema(ctx context.Context, in *ConfigNameAndVersion, opts ...grpc.CallOption) (*ConfigSchemaResponse, error) {
+	out := new(ConfigSchemaResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/GetConfigSchema", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) ListConfigSchemas(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (*ListConfigSchemasResponse, error) {
+	out := new(ListConfigSchemasResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/ListConfigSchemas", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *configServiceClient) DeleteConfigSchema(ctx context.Context, in *ConfigName, opts ...grpc.CallOption) (*DeleteResponse, error) {
+	out := new(DeleteResponse)
+	err := c.cc.Invoke(ctx, "/tutorial.ConfigService/DeleteConfigSchema", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ConfigServiceServer is the server API for ConfigService service.
 // All implementations should embed UnimplementedConfigServiceServer
 // for forward compatibility
@@ -143,10 +323,32 @@ type ConfigServiceServer interface {
 	GetConfig(context.Context, *ConfigName) (*ConfigResponse, error)
 	GetConfigByVersion(context.Context, *ConfigNameAndVersion) (*ConfigResponse, error)
 	UpdateConfig(context.Context, *Config) (*ConfigResponse, error)
+	PatchConfig(context.Context, *PatchConfigRequest) (*ConfigResponse, error)
+	PromoteConfig(context.Context, *PromoteConfigRequest) (*ConfigResponse, error)
+	GetConfigOverrides(context.Context, *ConfigName) (*ConfigOverridesResponse, error)
 	DeleteConfig(context.Context, *ConfigName) (*DeleteResponse, error)
 	DeleteConfigVersion(context.Context, *ConfigNameAndVersion) (*DeleteResponse, error)
+	// ListConfigs streams every version of the config, latest first. It is only available over gRPC,
+	// REST clients use ListConfigVersions.
 	ListConfigs(*ListRequest, ConfigService_ListConfigsServer) error
+	// ListConfigVersions lists the versions of the config a page at a time, latest first.
+	ListConfigVersions(context.Context, *ListConfigVersionsRequest) (*ListConfigVersionsResponse, error)
+	// ListConfigNames lists the configs of a namespace a page at a time, by their relevant versions and without
+	// their data. Configs that the caller cannot read are left out, so a page can hold fewer of them than page_size.
+	ListConfigNames(context.Context, *ListConfigNamesRequest) (*ListConfigNamesResponse, error)
+	// SetConfigLabels replaces the labels of the config. An empty map removes them.
+	SetConfigLabels(context.Context, *ConfigLabels) (*ConfigLabels, error)
 	SetRelevantConfig(context.Context, *ConfigNameAndVersion) (*ConfigResponse, error)
+	WatchConfig(*ConfigName, ConfigService_WatchConfigServer) error
+	DiffConfigVersions(context.Context, *DiffRequest) (*DiffResponse, error)
+	DiffProposedConfig(context.Context, *Config) (*DiffResponse, error)
+	ListNamespaces(context.Context, *ListNamespacesRequest) (*ListNamespacesResponse, error)
+	ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error)
+	SetConfigSchema(context.Context, *ConfigSchema) (*ConfigSchemaResponse, error)
+	// GetConfigSchema returns the latest version of the schema if the version is 0.
+	GetConfigSchema(context.Context, *ConfigNameAndVersion) (*ConfigSchemaResponse, error)
+	ListConfigSchemas(context.Context, *ConfigName) (*ListConfigSchemasResponse, error)
+	DeleteConfigSchema(context.Context, *ConfigName) (*DeleteResponse, error)
 }
 
 // UnimplementedConfigServiceServer should be embedded to have forward compatible implementations.
@@ -165,6 +367,15 @@ func (UnimplementedConfigServiceServer) GetConfigByVersion(context.Context, *Con
 func (UnimplementedConfigServiceServer) UpdateConfig(context.Context, *Config) (*ConfigResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method UpdateConfig not implemented")
 }
+func (UnimplementedConfigServiceServer) PatchConfig(context.Context, *PatchConfigRequest) (*ConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PatchConfig not implemented")
+}
+func (UnimplementedConfigServiceServer) PromoteConfig(context.Context, *PromoteConfigRequest) (*ConfigResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PromoteConfig not implemented")
+}
+func (UnimplementedConfigServiceServer) GetConfigOverrides(context.Context, *ConfigName) (*ConfigOverridesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConfigOverrides not implemented")
+}
 func (UnimplementedConfigServiceServer) DeleteConfig(context.Context, *ConfigName) (*DeleteResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DeleteConfig not implemented")
 }
@@ -174,9 +385,45 @@ func (UnimplementedConfigServiceServer) DeleteConfigVersion(context.Context, *Co
 func (UnimplementedConfigServiceServer) ListConfigs(*ListRequest, ConfigService_ListConfigsServer) error {
 	return status.Errorf(codes.Unimplemented, "method ListConfigs not implemented")
 }
+func (UnimplementedConfigServiceServer) ListConfigVersions(context.Context, *ListConfigVersionsRequest) (*ListConfigVersionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListConfigVersions not implemented")
+}
+func (UnimplementedConfigServiceServer) ListConfigNames(context.Context, *ListConfigNamesRequest) (*ListConfigNamesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListConfigNames not implemented")
+}
+func (UnimplementedConfigServiceServer) SetConfigLabels(context.Context, *ConfigLabels) (*ConfigLabels, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetConfigLabels not implemented")
+}
 func (UnimplementedConfigServiceServer) SetRelevantConfig(context.Context, *ConfigNameAndVersion) (*ConfigResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method SetRelevantConfig not implemented")
 }
+func (UnimplementedConfigServiceServer) WatchConfig(*ConfigName, ConfigService_WatchConfigServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchConfig not implemented")
+}
+func (UnimplementedConfigServiceServer) DiffConfigVersions(context.Context, *DiffRequest) (*DiffResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiffConfigVersions not implemented")
+}
+func (UnimplementedConfigServiceServer) DiffProposedConfig(context.Context, *Config) (*DiffResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiffProposedConfig not implemented")
+}
+func (UnimplementedConfigServiceServer) ListNamespaces(context.Context, *ListNamespacesRequest) (*ListNamespacesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNamespaces not implemented")
+}
+func (UnimplementedConfigServiceServer) ListAuditEvents(context.Context, *ListAuditEventsRequest) (*ListAuditEventsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAuditEvents not implemented")
+}
+func (UnimplementedConfigServiceServer) SetConfigSchema(context.Context, *ConfigSchema) (*ConfigSchemaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetConfigSchema not implemented")
+}
+func (UnimplementedConfigServiceServer) GetConfigSchema(context.Context, *ConfigNameAndVersion) (*ConfigSchemaResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConfigSchema not implemented")
+}
+func (UnimplementedConfigServiceServer) ListConfigSchemas(context.Context, *ConfigName) (*ListConfigSchemasResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListConfigSchemas not implemented")
+}
+func (UnimplementedConfigServiceServer) DeleteConfigSchema(context.Context, *ConfigName) (*DeleteResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteConfigSchema not implemented")
+}
 
 // UnsafeConfigServiceServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to ConfigServiceServer will
@@ -261,6 +508,60 @@ func _ConfigService_UpdateConfig_Handler(srv interface{}, ctx context.Context, d
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ConfigService_PatchConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PatchConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).PatchConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/PatchConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).PatchConfig(ctx, req.(*PatchConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_PromoteConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PromoteConfigRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).PromoteConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/PromoteConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).PromoteConfig(ctx, req.(*PromoteConfigRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_GetConfigOverrides_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).GetConfigOverrides(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/GetConfigOverrides",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).GetConfigOverrides(ctx, req.(*ConfigName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ConfigService_DeleteConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ConfigName)
 	if err := dec(in); err != nil {
@@ -318,6 +619,60 @@ func (x *configServiceListConfigsServer) Send(m *ConfigResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _ConfigService_ListConfigVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListConfigVersionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).ListConfigVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/ListConfigVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).ListConfigVersions(ctx, req.(*ListConfigVersionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_ListConfigNames_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListConfigNamesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).ListConfigNames(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/ListConfigNames",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).ListConfigNames(ctx, req.(*ListConfigNamesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_SetConfigLabels_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigLabels)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).SetConfigLabels(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/SetConfigLabels",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).SetConfigLabels(ctx, req.(*ConfigLabels))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ConfigService_SetRelevantConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ConfigNameAndVersion)
 	if err := dec(in); err != nil {
@@ -336,6 +691,171 @@ func _ConfigService_SetRelevantConfig_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ConfigService_WatchConfig_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ConfigName)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ConfigServiceServer).WatchConfig(m, &configServiceWatchConfigServer{stream})
+}
+
+type ConfigService_WatchConfigServer interface {
+	Send(*ConfigResponse) error
+	grpc.ServerStream
+}
+
+type configServiceWatchConfigServer struct {
+	grpc.ServerStream
+}
+
+func (x *configServiceWatchConfigServer) Send(m *ConfigResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _ConfigService_DiffConfigVersions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiffRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).DiffConfigVersions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/DiffConfigVersions",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).DiffConfigVersions(ctx, req.(*DiffRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_DiffProposedConfig_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Config)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).DiffProposedConfig(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/DiffProposedConfig",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).DiffProposedConfig(ctx, req.(*Config))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_ListNamespaces_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNamespacesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).ListNamespaces(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/ListNamespaces",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).ListNamespaces(ctx, req.(*ListNamespacesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_ListAuditEvents_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListAuditEventsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).ListAuditEvents(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/ListAuditEvents",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).ListAuditEvents(ctx, req.(*ListAuditEventsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_SetConfigSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigSchema)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).SetConfigSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/SetConfigSchema",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).SetConfigSchema(ctx, req.(*ConfigSchema))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_GetConfigSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigNameAndVersion)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).GetConfigSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/GetConfigSchema",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).GetConfigSchema(ctx, req.(*ConfigNameAndVersion))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_ListConfigSchemas_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).ListConfigSchemas(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/ListConfigSchemas",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).ListConfigSchemas(ctx, req.(*ConfigName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ConfigService_DeleteConfigSchema_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigName)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ConfigServiceServer).DeleteConfigSchema(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/tutorial.ConfigService/DeleteConfigSchema",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ConfigServiceServer).DeleteConfigSchema(ctx, req.(*ConfigName))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ConfigService_ServiceDesc is the grpc.ServiceDesc for ConfigService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -359,6 +879,18 @@ var ConfigService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "UpdateConfig",
 			Handler:    _ConfigService_UpdateConfig_Handler,
 		},
+		{
+			MethodName: "PatchConfig",
+			Handler:    _ConfigService_PatchConfig_Handler,
+		},
+		{
+			MethodName: "PromoteConfig",
+			Handler:    _ConfigService_PromoteConfig_Handler,
+		},
+		{
+			MethodName: "GetConfigOverrides",
+			Handler:    _ConfigService_GetConfigOverrides_Handler,
+		},
 		{
 			MethodName: "DeleteConfig",
 			Handler:    _ConfigService_DeleteConfig_Handler,
@@ -367,10 +899,54 @@ var ConfigService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DeleteConfigVersion",
 			Handler:    _ConfigService_DeleteConfigVersion_Handler,
 		},
+		{
+			MethodName: "ListConfigVersions",
+			Handler:    _ConfigService_ListConfigVersions_Handler,
+		},
+		{
+			MethodName: "ListConfigNames",
+			Handler:    _ConfigService_ListConfigNames_Handler,
+		},
+		{
+			MethodName: "SetConfigLabels",
+			Handler:    _ConfigService_SetConfigLabels_Handler,
+		},
 		{
 			MethodName: "SetRelevantConfig",
 			Handler:    _ConfigService_SetRelevantConfig_Handler,
 		},
+		{
+			MethodName: "DiffConfigVersions",
+			Handler:    _ConfigService_DiffConfigVersions_Handler,
+		},
+		{
+			MethodName: "DiffProposedConfig",
+			Handler:    _ConfigService_DiffProposedConfig_Handler,
+		},
+		{
+			MethodName: "ListNamespaces",
+			Handler:    _ConfigService_ListNamespaces_Handler,
+		},
+		{
+			MethodName: "ListAuditEvents",
+			Handler:    _ConfigService_ListAuditEvents_Handler,
+		},
+		{
+			MethodName: "SetConfigSchema",
+			Handler:    _ConfigService_SetConfigSchema_Handler,
+		},
+		{
+			MethodName: "GetConfigSchema",
+			Handler:    _ConfigService_GetConfigSchema_Handler,
+		},
+		{
+			MethodName: "ListConfigSchemas",
+			Handler:    _ConfigService_ListConfigSchemas_Handler,
+		},
+		{
+			MethodName: "DeleteConfigSchema",
+			Handler:    _ConfigService_DeleteConfigSchema_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -378,6 +954,11 @@ var ConfigService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ConfigService_ListConfigs_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "WatchConfig",
+			Handler:       _ConfigService_WatchConfig_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "config_service.proto",
 }
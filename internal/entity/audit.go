@@ -0,0 +1,84 @@
+package entity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AuditEvent records a change of a config. A version of 0 means that there was no version
+// before the change (creation) or after it (deletion).
+type AuditEvent struct {
+	ID          int64     `json:"id"`
+	Actor       string    `json:"actor"`
+	Method      string    `json:"method"`
+	Namespace   string    `json:"namespace"`
+	ConfigName  string    `json:"config_name"`
+	FromVersion int64     `json:"from_version"`
+	ToVersion   int64     `json:"to_version"`
+	DiffSummary string    `json:"diff_summary"`
+	ClientIP    string    `json:"client_ip"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// SetChange describes in the event a change of the config from one version to another. A nil version stands
+// for the absence of the config before creation or after deletion.
+func (e *AuditEvent) SetChange(namespace, name string, from, to *Config) {
+	if from == nil {
+		from = &Config{Namespace: namespace, Name: name}
+	}
+	if to == nil {
+		to = &Config{Namespace: namespace, Name: name}
+	}
+	e.Namespace, e.ConfigName = namespace, name
+	e.FromVersion, e.ToVersion = from.Version, to.Version
+	e.DiffSummary = DiffConfigs(from, to).Summary()
+}
+
+// SetLabelsChange describes in the event a change of the labels of the config. Labels are not versioned,
+// so the event has no versions.
+func (e *AuditEvent) SetLabelsChange(labels *ConfigLabels) {
+	pairs := make([]string, 0, len(labels.Labels))
+	for key, value := range labels.Labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	e.Namespace, e.ConfigName = labels.Namespace, labels.Name
+	e.DiffSummary = "labels removed"
+	if len(pairs) > 0 {
+		e.DiffSummary = "labels: " + strings.Join(pairs, ", ")
+	}
+}
+
+// SetSchemaChange describes in the event a change of the schema of the config. The versions of the event
+// are versions of the schema, and a version of 0 stands for the absence of a schema.
+func (e *AuditEvent) SetSchemaChange(namespace, name string, from, to int64) {
+	e.Namespace, e.ConfigName = namespace, name
+	e.FromVersion, e.ToVersion = from, to
+	e.DiffSummary = fmt.Sprintf("schema version %d", to)
+	if to == 0 {
+		e.DiffSummary = "schema deleted"
+	}
+}
+
+// AuditFilter selects audit events. Empty fields match every event. Events are listed from the latest,
+// and BeforeID continues a listing after the event with that ID.
+type AuditFilter struct {
+	Namespace  string
+	ConfigName string
+	Actor      string
+	Since      time.Time
+	Until      time.Time
+	BeforeID   int64
+	Limit      int
+}
+
+func (f *AuditFilter) Matches(event *AuditEvent) bool {
+	return (f.Namespace == "" || event.Namespace == f.Namespace) &&
+		(f.ConfigName == "" || event.ConfigName == f.ConfigName) &&
+		(f.Actor == "" || event.Actor == f.Actor) &&
+		(f.Since.IsZero() || !event.CreatedAt.Before(f.Since)) &&
+		(f.Until.IsZero() || event.CreatedAt.Before(f.Until)) &&
+		(f.BeforeID == 0 || event.ID < f.BeforeID)
+}
@@ -0,0 +1,37 @@
+package entity
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAuditFilter_Matches(t *testing.T) {
+	now := time.Now()
+	event := &AuditEvent{ID: 5, Actor: "alice", Namespace: DefaultNamespace, ConfigName: "test", CreatedAt: now}
+	testCases := []struct {
+		name    string
+		filter  AuditFilter
+		matches bool
+	}{
+		{"empty", AuditFilter{}, true},
+		{"namespace", AuditFilter{Namespace: DefaultNamespace}, true},
+		{"other namespace", AuditFilter{Namespace: "prod"}, false},
+		{"config name", AuditFilter{ConfigName: "test"}, true},
+		{"other config name", AuditFilter{ConfigName: "other"}, false},
+		{"actor", AuditFilter{Actor: "alice"}, true},
+		{"other actor", AuditFilter{Actor: "bob"}, false},
+		{"since", AuditFilter{Since: now}, true},
+		{"since later", AuditFilter{Since: now.Add(time.Second)}, false},
+		{"until", AuditFilter{Until: now}, false},
+		{"until later", AuditFilter{Until: now.Add(time.Second)}, true},
+		{"before", AuditFilter{BeforeID: 6}, true},
+		{"before earlier", AuditFilter{BeforeID: 5}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.matches, tc.filter.Matches(event))
+		})
+	}
+}
@@ -1,22 +1,163 @@
 package entity
 
 import (
+	"errors"
+	"fmt"
 	validation "github.com/go-ozzo/ozzo-validation"
 	"time"
 )
 
 type Config struct {
 	ID        int               `json:"id"`
+	Namespace string            `json:"namespace"`
 	Name      string            `json:"name"`
 	Data      map[string]string `json:"data"`
-	CreatedAt time.Time         `json:"created_at"`
-	Version   int64             `json:"version"`
+	// Types holds the types of the values of Data that are not strings.
+	Types     map[string]ValueType `json:"types,omitempty"`
+	CreatedAt time.Time            `json:"created_at"`
+	Version   int64                `json:"version"`
+	// PromotedFrom is the version the config was promoted from, or nil if it was not promoted.
+	PromotedFrom *ConfigSource `json:"promoted_from,omitempty"`
+}
+
+// ConfigCount is the number of configs of a namespace and of their versions.
+type ConfigCount struct {
+	Namespace string `json:"namespace"`
+	Configs   int64  `json:"configs"`
+	Versions  int64  `json:"versions"`
+}
+
+// LastUse is a use of a version of a config at Time.
+type LastUse struct {
+	Namespace string
+	Name      string
+	Version   int64
+	Time      time.Time
+}
+
+// VersionFilter selects versions of a config, which are listed from the latest. BeforeVersion continues a listing
+// after that version, and WithoutData lists only the metadata of the versions.
+type VersionFilter struct {
+	Namespace     string
+	Name          string
+	BeforeVersion int64
+	Limit         int
+	WithoutData   bool
+}
+
+// ConfigSource identifies a version of a config with the same name in another namespace.
+type ConfigSource struct {
+	Namespace string `json:"namespace"`
+	Version   int64  `json:"version"`
 }
 
 func (config *Config) Validate() error {
 	return validation.ValidateStruct(
 		config,
+		validation.Field(&config.Namespace, validation.Required, validation.Length(1, 255), namespaceRule),
 		validation.Field(&config.Name, validation.Required, validation.Length(1, 255)),
 		validation.Field(&config.Data, validation.Required),
+		validation.Field(&config.Types, validation.By(func(interface{}) error {
+			return validateTypes(config.Data, config.Types)
+		})),
 	)
 }
+
+// validateTypes checks that every typed key has a value of its type. A secret cannot be SecretMask, so that a config
+// read by a caller that may not read its secrets cannot be written back with the secrets replaced by the mask.
+func validateTypes(data map[string]string, types map[string]ValueType) error {
+	for _, key := range sortedKeys(types) {
+		value, ok := data[key]
+		if !ok {
+			return errors.New("key " + key + " has a type but no value")
+		}
+		if types[key] == TypeSecret && value == SecretMask {
+			return errors.New("value of key " + key + " is the mask of a secret, not a secret")
+		}
+		if _, err := ParseValue(types[key], value); err != nil {
+			return fmt.Errorf("value of key %s is not a valid %s: %w", key, types[key], err)
+		}
+	}
+	return nil
+}
+
+// SecretKeys returns the sorted keys of the secret values of the config.
+func (config *Config) SecretKeys() []string {
+	var keys []string
+	for _, key := range sortedKeys(config.Types) {
+		if config.Types[key] == TypeSecret {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// KeepSecrets types the keys of the config without a type that are secret in the version it replaces as secrets,
+// so that a config read without the decrypt permission and written back without types does not store its masked
+// secrets as strings. The config must be validated afterwards, which rejects the secrets that are still masked.
+func (config *Config) KeepSecrets(secretKeys []string) {
+	for _, key := range secretKeys {
+		if _, ok := config.Data[key]; !ok {
+			continue
+		}
+		if _, typed := config.Types[key]; typed {
+			continue
+		}
+		if config.Types == nil {
+			config.Types = make(map[string]ValueType)
+		}
+		config.Types[key] = TypeSecret
+	}
+}
+
+// TypeOf returns the type of the value of the key.
+func (config *Config) TypeOf(key string) ValueType {
+	if valueType, ok := config.Types[key]; ok {
+		return valueType
+	}
+	return TypeString
+}
+
+// Clone returns a deep copy of the config.
+func (config *Config) Clone() *Config {
+	c := *config
+	c.Data = make(map[string]string, len(config.Data))
+	for key, value := range config.Data {
+		c.Data[key] = value
+	}
+	if config.Types != nil {
+		c.Types = make(map[string]ValueType, len(config.Types))
+		for key, valueType := range config.Types {
+			c.Types[key] = valueType
+		}
+	}
+	if config.PromotedFrom != nil {
+		source := *config.PromotedFrom
+		c.PromotedFrom = &source
+	}
+	return &c
+}
+
+// Masked returns a copy of the config with the values of secret keys replaced by SecretMask, or the config
+// itself if it has no secrets.
+func (config *Config) Masked() *Config {
+	var masked *Config
+	for key, valueType := range config.Types {
+		if valueType != TypeSecret {
+			continue
+		}
+		if masked == nil {
+			copied := *config
+			copied.Data = make(map[string]string, len(config.Data))
+			for key, value := range config.Data {
+				copied.Data[key] = value
+			}
+			masked = &copied
+		}
+		masked.Data[key] = SecretMask
+	}
+	if masked == nil {
+		return config
+	}
+	return masked
+}
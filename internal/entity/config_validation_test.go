@@ -25,6 +25,26 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			isValid: false,
 		},
+		{
+			name: "empty namespace",
+			c: func() *Config {
+				c := TestConfig(t)
+				c.Namespace = ""
+
+				return c
+			},
+			isValid: false,
+		},
+		{
+			name: "namespace with slash",
+			c: func() *Config {
+				c := TestConfig(t)
+				c.Namespace = "prod/eu"
+
+				return c
+			},
+			isValid: false,
+		},
 		{
 			name: "empty data",
 			c: func() *Config {
@@ -35,6 +55,49 @@ func TestConfig_Validate(t *testing.T) {
 			},
 			isValid: false,
 		},
+		{
+			name: "typed values",
+			c: func() *Config {
+				c := TestConfig(t)
+				c.Data["port"] = "8080"
+				c.Data["timeout"] = "1m30s"
+				c.Types = map[string]ValueType{"port": TypeInt, "timeout": TypeDuration}
+
+				return c
+			},
+			isValid: true,
+		},
+		{
+			name: "value of wrong type",
+			c: func() *Config {
+				c := TestConfig(t)
+				c.Types = map[string]ValueType{"key1": TypeInt}
+
+				return c
+			},
+			isValid: false,
+		},
+		{
+			name: "masked secret",
+			c: func() *Config {
+				c := TestConfig(t)
+				c.Data["password"] = SecretMask
+				c.Types = map[string]ValueType{"password": TypeSecret}
+
+				return c
+			},
+			isValid: false,
+		},
+		{
+			name: "type without value",
+			c: func() *Config {
+				c := TestConfig(t)
+				c.Types = map[string]ValueType{"port": TypeString}
+
+				return c
+			},
+			isValid: false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -0,0 +1,126 @@
+package entity
+
+import (
+	"sort"
+	"strings"
+)
+
+type ValueChange struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// ConfigDiff describes the changes of keys from one version of a config to another.
+type ConfigDiff struct {
+	Namespace   string                 `json:"namespace"`
+	Name        string                 `json:"name"`
+	FromVersion int64                  `json:"from_version"`
+	ToVersion   int64                  `json:"to_version"`
+	Added       map[string]string      `json:"added"`
+	Removed     map[string]string      `json:"removed"`
+	Changed     map[string]ValueChange `json:"changed"`
+	// Secrets lists the keys that are secret in either version.
+	Secrets map[string]bool `json:"secrets,omitempty"`
+}
+
+// DiffConfigs compares the string forms of the values. A value whose type changed is changed
+// even if its string form is the same.
+func DiffConfigs(from, to *Config) *ConfigDiff {
+	diff := &ConfigDiff{
+		Namespace:   from.Namespace,
+		Name:        from.Name,
+		FromVersion: from.Version,
+		ToVersion:   to.Version,
+		Added:       make(map[string]string),
+		Removed:     make(map[string]string),
+		Changed:     make(map[string]ValueChange),
+	}
+	for key, old := range from.Data {
+		value, ok := to.Data[key]
+		if !ok {
+			diff.Removed[key] = old
+		} else if value != old || from.TypeOf(key) != to.TypeOf(key) {
+			diff.Changed[key] = ValueChange{Old: old, New: value}
+		}
+	}
+	for key, value := range to.Data {
+		if _, ok := from.Data[key]; !ok {
+			diff.Added[key] = value
+		}
+	}
+	for _, config := range []*Config{from, to} {
+		for key, valueType := range config.Types {
+			if valueType == TypeSecret {
+				if diff.Secrets == nil {
+					diff.Secrets = make(map[string]bool)
+				}
+				diff.Secrets[key] = true
+			}
+		}
+	}
+	return diff
+}
+
+// Masked returns a copy of the diff with the values of secret keys replaced by SecretMask. A changed secret
+// stays changed.
+func (diff *ConfigDiff) Masked() *ConfigDiff {
+	if len(diff.Secrets) == 0 {
+		return diff
+	}
+	masked := *diff
+	masked.Added = maskValues(diff.Added, diff.Secrets)
+	masked.Removed = maskValues(diff.Removed, diff.Secrets)
+	masked.Changed = make(map[string]ValueChange, len(diff.Changed))
+	for key, change := range diff.Changed {
+		if diff.Secrets[key] {
+			change = ValueChange{Old: SecretMask, New: SecretMask}
+		}
+		masked.Changed[key] = change
+	}
+	return &masked
+}
+
+func maskValues(values map[string]string, secrets map[string]bool) map[string]string {
+	masked := make(map[string]string, len(values))
+	for key, value := range values {
+		if secrets[key] {
+			value = SecretMask
+		}
+		masked[key] = value
+	}
+	return masked
+}
+
+func (diff *ConfigDiff) IsEmpty() bool {
+	return len(diff.Added) == 0 && len(diff.Removed) == 0 && len(diff.Changed) == 0
+}
+
+// Summary lists the changed keys without their values, for example "added: k1; changed: k2, k3".
+func (diff *ConfigDiff) Summary() string {
+	if diff.IsEmpty() {
+		return "no changes"
+	}
+	var parts []string
+	for _, group := range []struct {
+		name string
+		keys []string
+	}{
+		{"added", sortedKeys(diff.Added)},
+		{"removed", sortedKeys(diff.Removed)},
+		{"changed", sortedKeys(diff.Changed)},
+	} {
+		if len(group.keys) > 0 {
+			parts = append(parts, group.name+": "+strings.Join(group.keys, ", "))
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDiffConfigs(t *testing.T) {
+	testCases := []struct {
+		name    string
+		from    map[string]string
+		to      map[string]string
+		added   map[string]string
+		removed map[string]string
+		changed map[string]ValueChange
+	}{
+		{
+			name:    "equal",
+			from:    map[string]string{"k1": "v1"},
+			to:      map[string]string{"k1": "v1"},
+			added:   map[string]string{},
+			removed: map[string]string{},
+			changed: map[string]ValueChange{},
+		},
+		{
+			name:    "added, removed and changed",
+			from:    map[string]string{"k1": "v1", "k2": "v2", "k3": "v3"},
+			to:      map[string]string{"k1": "v1", "k2": "new", "k4": "v4"},
+			added:   map[string]string{"k4": "v4"},
+			removed: map[string]string{"k3": "v3"},
+			changed: map[string]ValueChange{"k2": {Old: "v2", New: "new"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diff := DiffConfigs(&Config{Name: "test", Version: 1, Data: tc.from}, &Config{Name: "test", Version: 2, Data: tc.to})
+			require.Equal(t, int64(1), diff.FromVersion)
+			require.Equal(t, int64(2), diff.ToVersion)
+			require.Equal(t, tc.added, diff.Added)
+			require.Equal(t, tc.removed, diff.Removed)
+			require.Equal(t, tc.changed, diff.Changed)
+			require.Equal(t, tc.name == "equal", diff.IsEmpty())
+		})
+	}
+}
+
+func TestConfigDiff_Summary(t *testing.T) {
+	diff := DiffConfigs(
+		&Config{Data: map[string]string{"k1": "v1", "k2": "v2", "k3": "v3"}},
+		&Config{Data: map[string]string{"k2": "new", "k3": "v3", "k5": "v5", "k4": "v4"}})
+	require.Equal(t, "added: k4, k5; removed: k1; changed: k2", diff.Summary())
+	require.Equal(t, "no changes", DiffConfigs(&Config{}, &Config{}).Summary())
+}
+
+func TestDiffConfigs_TypeChanged(t *testing.T) {
+	diff := DiffConfigs(
+		&Config{Data: map[string]string{"port": "8080"}},
+		&Config{Data: map[string]string{"port": "8080"}, Types: map[string]ValueType{"port": TypeInt}})
+	require.Equal(t, map[string]ValueChange{"port": {Old: "8080", New: "8080"}}, diff.Changed)
+}
+
+func TestConfigDiff_Masked(t *testing.T) {
+	diff := DiffConfigs(
+		&Config{Data: map[string]string{"user": "app", "password": "old", "token": "t1"},
+			Types: map[string]ValueType{"password": TypeSecret, "token": TypeSecret}},
+		&Config{Data: map[string]string{"user": "svc", "password": "new", "key": "k1"},
+			Types: map[string]ValueType{"password": TypeSecret, "key": TypeSecret}})
+	masked := diff.Masked()
+	require.Equal(t, map[string]string{"key": SecretMask}, masked.Added)
+	require.Equal(t, map[string]string{"token": SecretMask}, masked.Removed)
+	require.Equal(t, map[string]ValueChange{
+		"user":     {Old: "app", New: "svc"},
+		"password": {Old: SecretMask, New: SecretMask},
+	}, masked.Changed)
+	require.Equal(t, ValueChange{Old: "old", New: "new"}, diff.Changed["password"])
+	require.Equal(t, "added: key; removed: token; changed: password, user", masked.Summary())
+}
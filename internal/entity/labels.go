@@ -0,0 +1,57 @@
+package entity
+
+import (
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"regexp"
+)
+
+// maxLabels bounds the number of labels of a config, which are meant to classify it, not to hold data.
+const maxLabels = 64
+
+// labelKeyPattern allows letters, digits, dots, dashes, underscores and slashes, e.g. "team" or "app.io/tier".
+var labelKeyPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+// ConfigLabels are the labels of the config with the same namespace and name. Unlike its data, labels are not
+// versioned: they describe the config as a whole and are used to find it.
+type ConfigLabels struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Labels    map[string]string `json:"labels"`
+}
+
+func (labels *ConfigLabels) Validate() error {
+	return validation.ValidateStruct(
+		labels,
+		validation.Field(&labels.Namespace, validation.Required, validation.Length(1, 255), namespaceRule),
+		validation.Field(&labels.Name, validation.Required, validation.Length(1, 255)),
+		validation.Field(&labels.Labels, validation.By(func(interface{}) error {
+			return validateLabels(labels.Labels)
+		})),
+	)
+}
+
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return errors.New("there must be no more than 64 labels")
+	}
+	errs := validation.Errors{}
+	for _, key := range sortedKeys(labels) {
+		if len(key) > 63 || !labelKeyPattern.MatchString(key) {
+			errs[key] = errors.New("key must be up to 63 letters, digits, dots, dashes, underscores and slashes")
+		} else if len(labels[key]) > 255 {
+			errs[key] = errors.New("value must be no longer than 255 characters")
+		}
+	}
+	return errs.Filter()
+}
+
+// HasLabels reports whether the labels include every one of selector.
+func HasLabels(labels, selector map[string]string) bool {
+	for key, value := range selector {
+		if actual, ok := labels[key]; !ok || actual != value {
+			return false
+		}
+	}
+	return true
+}
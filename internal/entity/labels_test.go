@@ -0,0 +1,27 @@
+package entity
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfigLabels_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		labels  map[string]string
+		wantErr bool
+	}{
+		{"none", nil, false},
+		{"valid", map[string]string{"team": "payments", "app.io/tier": "backend", "empty": ""}, false},
+		{"space in key", map[string]string{"my team": "payments"}, true},
+		{"leading dash", map[string]string{"-team": "payments"}, true},
+		{"long key", map[string]string{string(make([]byte, 64)): "x"}, true},
+		{"long value", map[string]string{"team": string(make([]byte, 256))}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := (&ConfigLabels{Namespace: DefaultNamespace, Name: "test", Labels: tc.labels}).Validate()
+			require.Equal(t, tc.wantErr, err != nil, err)
+		})
+	}
+}
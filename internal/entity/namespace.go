@@ -0,0 +1,37 @@
+package entity
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+	"regexp"
+	"strings"
+)
+
+// DefaultNamespace holds the configs of clients that do not specify a namespace.
+const DefaultNamespace = "default"
+
+// namespaceRule allows letters, digits, dots, dashes and underscores. A namespace cannot contain
+// a slash, so a qualified name can be split unambiguously.
+var namespaceRule = validation.Match(regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)).
+	Error("must contain only letters, digits, dots, dashes and underscores")
+
+// NamespaceOrDefault returns the namespace, or DefaultNamespace if it is empty.
+func NamespaceOrDefault(namespace string) string {
+	if namespace == "" {
+		return DefaultNamespace
+	}
+	return namespace
+}
+
+// QualifiedName identifies a config across namespaces as "namespace/name".
+func QualifiedName(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// SplitQualifiedName is the inverse of QualifiedName. A name without a namespace belongs to DefaultNamespace.
+func SplitQualifiedName(qualifiedName string) (namespace, name string) {
+	namespace, name, ok := strings.Cut(qualifiedName, "/")
+	if !ok {
+		return DefaultNamespace, qualifiedName
+	}
+	return namespace, name
+}
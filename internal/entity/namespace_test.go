@@ -0,0 +1,31 @@
+package entity
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestQualifiedName(t *testing.T) {
+	testCases := []struct {
+		qualifiedName string
+		namespace     string
+		name          string
+	}{
+		{"default/test", DefaultNamespace, "test"},
+		{"prod/payments/api", "prod", "payments/api"},
+		{"test", DefaultNamespace, "test"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.qualifiedName, func(t *testing.T) {
+			namespace, name := SplitQualifiedName(tc.qualifiedName)
+			require.Equal(t, tc.namespace, namespace)
+			require.Equal(t, tc.name, name)
+			if tc.qualifiedName != "test" {
+				require.Equal(t, tc.qualifiedName, QualifiedName(namespace, name))
+			}
+		})
+	}
+	require.Equal(t, DefaultNamespace, NamespaceOrDefault(""))
+	require.Equal(t, "prod", NamespaceOrDefault("prod"))
+}
@@ -0,0 +1,90 @@
+package entity
+
+import (
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// ConfigPatch describes a new version of a config derived from its relevant version:
+// keys of Set are added or replaced and keys of Unset are removed. Types holds the types
+// of the values of Set that are not strings.
+type ConfigPatch struct {
+	Namespace string               `json:"namespace"`
+	Name      string               `json:"name"`
+	Set       map[string]string    `json:"set"`
+	Types     map[string]ValueType `json:"types"`
+	Unset     []string             `json:"unset"`
+}
+
+func (patch *ConfigPatch) Validate() error {
+	rules := []*validation.FieldRules{
+		validation.Field(&patch.Namespace, validation.Required, validation.Length(1, 255), namespaceRule),
+		validation.Field(&patch.Name, validation.Required, validation.Length(1, 255)),
+		validation.Field(&patch.Unset, validation.By(patch.validateUnset)),
+		validation.Field(&patch.Types, validation.By(func(interface{}) error {
+			return validateTypes(patch.Set, patch.Types)
+		})),
+	}
+	if len(patch.Unset) == 0 {
+		rules = append(rules, validation.Field(&patch.Set, validation.Required))
+	}
+	return validation.ValidateStruct(patch, rules...)
+}
+
+func (patch *ConfigPatch) validateUnset(value interface{}) error {
+	for _, key := range patch.Unset {
+		if _, ok := patch.Set[key]; ok {
+			return errors.New("key " + key + " is both set and unset")
+		}
+	}
+	return nil
+}
+
+// Apply returns the data of the base config with the patch applied as a new config without a version.
+// Keys of Set without a type keep the secret type of the base. The base config is not modified.
+func (patch *ConfigPatch) Apply(base *Config) *Config {
+	data := make(map[string]string, len(base.Data)+len(patch.Set))
+	for key, value := range base.Data {
+		data[key] = value
+	}
+	types := make(map[string]ValueType, len(base.Types)+len(patch.Types))
+	for key, valueType := range base.Types {
+		types[key] = valueType
+	}
+	for key, value := range patch.Set {
+		data[key] = value
+		// A secret set without a type stays secret, like in Config.KeepSecrets.
+		valueType := patch.Types[key]
+		if valueType == "" && base.TypeOf(key) == TypeSecret {
+			valueType = TypeSecret
+		}
+		setType(types, key, valueType)
+	}
+	for _, key := range patch.Unset {
+		delete(data, key)
+		delete(types, key)
+	}
+	return &Config{
+		Namespace: base.Namespace,
+		Name:      base.Name,
+		Data:      data,
+		Types:     typesOrNil(types),
+	}
+}
+
+// setType sets the type of the key. Strings and values without a type are not listed in types.
+func setType(types map[string]ValueType, key string, valueType ValueType) {
+	if valueType == "" || valueType == TypeString {
+		delete(types, key)
+	} else {
+		types[key] = valueType
+	}
+}
+
+// typesOrNil returns nil instead of empty types, like configs without typed values have.
+func typesOrNil(types map[string]ValueType) map[string]ValueType {
+	if len(types) == 0 {
+		return nil
+	}
+	return types
+}
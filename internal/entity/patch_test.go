@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfigPatch_Validate(t *testing.T) {
+	testCases := []struct {
+		name    string
+		patch   *ConfigPatch
+		isValid bool
+	}{
+		{"set", &ConfigPatch{Namespace: DefaultNamespace, Name: "test", Set: map[string]string{"k1": "v1"}}, true},
+		{"unset", &ConfigPatch{Namespace: DefaultNamespace, Name: "test", Unset: []string{"k1"}}, true},
+		{"empty name", &ConfigPatch{Namespace: DefaultNamespace, Set: map[string]string{"k1": "v1"}}, false},
+		{"empty namespace", &ConfigPatch{Name: "test", Set: map[string]string{"k1": "v1"}}, false},
+		{"empty patch", &ConfigPatch{Namespace: DefaultNamespace, Name: "test"}, false},
+		{"set and unset", &ConfigPatch{Namespace: DefaultNamespace, Name: "test", Set: map[string]string{"k1": "v1"}, Unset: []string{"k1"}}, false},
+		{"masked secret", &ConfigPatch{Namespace: DefaultNamespace, Name: "test", Set: map[string]string{"k1": SecretMask}, Types: map[string]ValueType{"k1": TypeSecret}}, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.patch.Validate()
+			if tc.isValid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestConfigPatch_Apply(t *testing.T) {
+	base := TestConfig(t)
+	patch := &ConfigPatch{Namespace: DefaultNamespace, Name: "test", Set: map[string]string{"key1": "new", "key4": "value4"}, Unset: []string{"key2", "unknown"}}
+
+	config := patch.Apply(base)
+	require.Equal(t, DefaultNamespace, config.Namespace)
+	require.Equal(t, "test", config.Name)
+	require.Equal(t, map[string]string{"key1": "new", "key3": "value3", "key4": "value4"}, config.Data)
+	require.Equal(t, TestConfig(t).Data, base.Data)
+}
+
+func TestConfigPatch_ApplyTypes(t *testing.T) {
+	base := TestConfig(t)
+	base.Data["port"] = "8080"
+	base.Data["debug"] = "true"
+	base.Types = map[string]ValueType{"port": TypeInt, "debug": TypeBool}
+	patch := &ConfigPatch{
+		Namespace: DefaultNamespace,
+		Name:      "test",
+		Set:       map[string]string{"port": "http", "ratio": "0.5"},
+		Types:     map[string]ValueType{"ratio": TypeFloat},
+		Unset:     []string{"debug"},
+	}
+	require.NoError(t, patch.Validate())
+
+	config := patch.Apply(base)
+	require.Equal(t, map[string]ValueType{"ratio": TypeFloat}, config.Types)
+	require.Equal(t, TypeString, config.TypeOf("port"))
+	require.NoError(t, config.Validate())
+
+	patch.Types["port"] = TypeInt
+	require.Error(t, patch.Validate())
+}
+
+func TestConfigPatch_ApplyKeepsSecrets(t *testing.T) {
+	base := TestConfig(t)
+	base.Data["password"] = "p@ssw0rd"
+	base.Types = map[string]ValueType{"password": TypeSecret}
+
+	config := (&ConfigPatch{Namespace: DefaultNamespace, Name: "test", Set: map[string]string{"password": "new"}}).Apply(base)
+	require.Equal(t, TypeSecret, config.TypeOf("password"))
+	config = (&ConfigPatch{Namespace: DefaultNamespace, Name: "test", Set: map[string]string{"password": SecretMask}}).Apply(base)
+	require.Error(t, config.Validate())
+}
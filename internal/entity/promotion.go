@@ -0,0 +1,80 @@
+package entity
+
+import (
+	"errors"
+	validation "github.com/go-ozzo/ozzo-validation"
+)
+
+// ConfigPromotion describes a new version of a config in the target namespace copied from a version
+// of the config in the source namespace. The target keeps its overrides between promotions:
+// keys of SetOverrides are added to them or replaced, keys of UnsetOverrides are removed, and
+// the resulting overrides replace the values of the source.
+type ConfigPromotion struct {
+	SourceNamespace string            `json:"source_namespace"`
+	TargetNamespace string            `json:"target_namespace"`
+	Name            string            `json:"name"`
+	Version         int64             `json:"version"`
+	SetOverrides    map[string]string `json:"set_overrides"`
+	UnsetOverrides  []string          `json:"unset_overrides"`
+}
+
+func (promotion *ConfigPromotion) Validate() error {
+	return validation.ValidateStruct(
+		promotion,
+		validation.Field(&promotion.SourceNamespace, validation.Required, validation.Length(1, 255), namespaceRule),
+		validation.Field(&promotion.TargetNamespace, validation.Required, validation.Length(1, 255), namespaceRule,
+			validation.NotIn(promotion.SourceNamespace).Error("must differ from the source namespace")),
+		validation.Field(&promotion.Name, validation.Required, validation.Length(1, 255)),
+		validation.Field(&promotion.Version, validation.Required, validation.Min(int64(1))),
+		validation.Field(&promotion.UnsetOverrides, validation.By(promotion.validateUnsetOverrides)),
+	)
+}
+
+func (promotion *ConfigPromotion) validateUnsetOverrides(value interface{}) error {
+	for _, key := range promotion.UnsetOverrides {
+		if _, ok := promotion.SetOverrides[key]; ok {
+			return errors.New("key " + key + " is both set and unset")
+		}
+	}
+	return nil
+}
+
+// ApplyOverrides returns the overrides of the target with the changes of the promotion applied.
+// The stored overrides are not modified.
+func (promotion *ConfigPromotion) ApplyOverrides(stored map[string]string) map[string]string {
+	overrides := make(map[string]string, len(stored)+len(promotion.SetOverrides))
+	for key, value := range stored {
+		overrides[key] = value
+	}
+	for key, value := range promotion.SetOverrides {
+		overrides[key] = value
+	}
+	for _, key := range promotion.UnsetOverrides {
+		delete(overrides, key)
+	}
+	return overrides
+}
+
+// Apply returns the data of the source config with the overrides applied as a new config of the target
+// namespace without a version. Overrides keep the types of the values of the source, so the result must be
+// validated. The source config is not modified.
+func (promotion *ConfigPromotion) Apply(source *Config, overrides map[string]string) *Config {
+	data := make(map[string]string, len(source.Data)+len(overrides))
+	for key, value := range source.Data {
+		data[key] = value
+	}
+	for key, value := range overrides {
+		data[key] = value
+	}
+	types := make(map[string]ValueType, len(source.Types))
+	for key, valueType := range source.Types {
+		types[key] = valueType
+	}
+	return &Config{
+		Namespace:    promotion.TargetNamespace,
+		Name:         source.Name,
+		Data:         data,
+		Types:        typesOrNil(types),
+		PromotedFrom: &ConfigSource{Namespace: source.Namespace, Version: source.Version},
+	}
+}
@@ -0,0 +1,71 @@
+package entity
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfigPromotion_Validate(t *testing.T) {
+	promotion := func(modify func(p *ConfigPromotion)) *ConfigPromotion {
+		p := &ConfigPromotion{SourceNamespace: "staging", TargetNamespace: "production", Name: "test", Version: 1}
+		modify(p)
+		return p
+	}
+	testCases := []struct {
+		name      string
+		promotion *ConfigPromotion
+		isValid   bool
+	}{
+		{"valid", promotion(func(p *ConfigPromotion) {}), true},
+		{"overrides", promotion(func(p *ConfigPromotion) {
+			p.SetOverrides = map[string]string{"k1": "v1"}
+			p.UnsetOverrides = []string{"k2"}
+		}), true},
+		{"empty source namespace", promotion(func(p *ConfigPromotion) { p.SourceNamespace = "" }), false},
+		{"empty target namespace", promotion(func(p *ConfigPromotion) { p.TargetNamespace = "" }), false},
+		{"same namespace", promotion(func(p *ConfigPromotion) { p.TargetNamespace = "staging" }), false},
+		{"empty name", promotion(func(p *ConfigPromotion) { p.Name = "" }), false},
+		{"no version", promotion(func(p *ConfigPromotion) { p.Version = 0 }), false},
+		{"negative version", promotion(func(p *ConfigPromotion) { p.Version = -1 }), false},
+		{"set and unset", promotion(func(p *ConfigPromotion) {
+			p.SetOverrides = map[string]string{"k1": "v1"}
+			p.UnsetOverrides = []string{"k1"}
+		}), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.promotion.Validate()
+			if tc.isValid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestConfigPromotion_Apply(t *testing.T) {
+	source := TestConfig(t)
+	source.Namespace = "staging"
+	source.Version = 3
+	promotion := &ConfigPromotion{
+		SourceNamespace: "staging",
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         3,
+		SetOverrides:    map[string]string{"key1": "production"},
+		UnsetOverrides:  []string{"key2"},
+	}
+
+	overrides := promotion.ApplyOverrides(map[string]string{"key2": "stored", "key4": "stored"})
+	require.Equal(t, map[string]string{"key1": "production", "key4": "stored"}, overrides)
+
+	config := promotion.Apply(source, overrides)
+	require.Equal(t, "production", config.Namespace)
+	require.Equal(t, "test", config.Name)
+	require.Equal(t, map[string]string{"key1": "production", "key2": "value2", "key3": "value3", "key4": "stored"},
+		config.Data)
+	require.Equal(t, &ConfigSource{Namespace: "staging", Version: 3}, config.PromotedFrom)
+	require.Equal(t, TestConfig(t).Data, source.Data)
+}
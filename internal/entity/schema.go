@@ -0,0 +1,171 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// ConfigSchema is a version of the rules that the data of the config with the same namespace and name
+// must follow. The latest version is enforced on every version of the config that is stored or set relevant.
+type ConfigSchema struct {
+	Namespace string             `json:"namespace"`
+	Name      string             `json:"name"`
+	Version   int64              `json:"version"`
+	CreatedAt time.Time          `json:"created_at"`
+	Keys      map[string]KeyRule `json:"keys"`
+	// AllowUnknownKeys allows keys that have no rule, which are rejected otherwise.
+	AllowUnknownKeys bool `json:"allow_unknown_keys"`
+}
+
+// KeyRule constrains the value of a key. Fields with zero values do not constrain it.
+type KeyRule struct {
+	Required bool `json:"required,omitempty"`
+	// Type is the type that the value must have.
+	Type ValueType `json:"type,omitempty"`
+	// Pattern is a regular expression that the string form of the value must match.
+	Pattern string `json:"pattern,omitempty"`
+	// Min and Max bound int and float values and durations, which are measured in seconds.
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// Enum lists the allowed string forms of the value.
+	Enum []string `json:"enum,omitempty"`
+}
+
+func (schema *ConfigSchema) Validate() error {
+	return validation.ValidateStruct(
+		schema,
+		validation.Field(&schema.Namespace, validation.Required, validation.Length(1, 255), namespaceRule),
+		validation.Field(&schema.Name, validation.Required, validation.Length(1, 255)),
+		validation.Field(&schema.Keys, validation.By(func(interface{}) error {
+			errs := validation.Errors{}
+			for key, rule := range schema.Keys {
+				if err := rule.validate(); err != nil {
+					errs[key] = err
+				}
+			}
+			return errs.Filter()
+		})),
+	)
+}
+
+func (rule *KeyRule) validate() error {
+	if rule.Type != "" && !rule.Type.isKnown() {
+		return fmt.Errorf("unknown type %q", rule.Type)
+	}
+	if _, err := regexp.Compile(rule.Pattern); err != nil {
+		return fmt.Errorf("invalid pattern: %w", err)
+	}
+	if rule.Min != nil || rule.Max != nil {
+		if rule.Type != TypeInt && rule.Type != TypeFloat && rule.Type != TypeDuration {
+			return errors.New("min and max require the int, float or duration type")
+		}
+		if rule.Min != nil && rule.Max != nil && *rule.Min > *rule.Max {
+			return errors.New("min must not be greater than max")
+		}
+	}
+	if rule.Type != "" {
+		for _, value := range rule.Enum {
+			if _, err := ParseValue(rule.Type, value); err != nil {
+				return fmt.Errorf("enum value %q is not a valid %s", value, rule.Type)
+			}
+		}
+	}
+	return nil
+}
+
+// Check checks the data of the config against the schema. Violations are returned as validation.Errors
+// with the errors of the keys under "data". A nil schema allows any data.
+func (schema *ConfigSchema) Check(config *Config) error {
+	if schema == nil {
+		return nil
+	}
+	errs := validation.Errors{}
+	for key, rule := range schema.Keys {
+		if _, ok := config.Data[key]; !ok && rule.Required {
+			errs[key] = errors.New("is required by the schema")
+		}
+	}
+	for key, value := range config.Data {
+		rule, ok := schema.Keys[key]
+		if !ok {
+			if !schema.AllowUnknownKeys {
+				errs[key] = errors.New("is not allowed by the schema")
+			}
+			continue
+		}
+		if err := rule.check(config.TypeOf(key), value); err != nil {
+			errs[key] = err
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return validation.Errors{"data": errs}
+}
+
+func (rule *KeyRule) check(valueType ValueType, value string) error {
+	if rule.Type != "" && valueType != rule.Type {
+		return fmt.Errorf("must be a %s value", rule.Type)
+	}
+	if rule.Pattern != "" {
+		pattern, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return err
+		}
+		if !pattern.MatchString(value) {
+			return fmt.Errorf("must match %s", rule.Pattern)
+		}
+	}
+	if rule.Min != nil || rule.Max != nil {
+		number, err := numericValue(valueType, value)
+		if err != nil {
+			return err
+		}
+		if rule.Min != nil && number < *rule.Min {
+			return fmt.Errorf("must be no less than %s", strconv.FormatFloat(*rule.Min, 'g', -1, 64))
+		}
+		if rule.Max != nil && number > *rule.Max {
+			return fmt.Errorf("must be no greater than %s", strconv.FormatFloat(*rule.Max, 'g', -1, 64))
+		}
+	}
+	if len(rule.Enum) > 0 {
+		for _, allowed := range rule.Enum {
+			if value == allowed {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %q", rule.Enum)
+	}
+	return nil
+}
+
+// numericValue returns the value of an int or a float, or the seconds of a duration.
+func numericValue(valueType ValueType, value string) (float64, error) {
+	parsed, err := ParseValue(valueType, value)
+	if err != nil {
+		return 0, err
+	}
+	switch v := parsed.(type) {
+	case int64:
+		return float64(v), nil
+	case float64:
+		return v, nil
+	case time.Duration:
+		return v.Seconds(), nil
+	default:
+		return 0, errors.New("must be a number or a duration")
+	}
+}
+
+func (valueType ValueType) isKnown() bool {
+	switch valueType {
+	case TypeString, TypeInt, TypeFloat, TypeBool, TypeDuration, TypeList, TypeObject, TypeSecret:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,88 @@
+package entity
+
+import (
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfigSchema_Validate(t *testing.T) {
+	one, ten := 1.0, 10.0
+	schema := func(rule KeyRule) *ConfigSchema {
+		return &ConfigSchema{Namespace: DefaultNamespace, Name: "test", Keys: map[string]KeyRule{"key1": rule}}
+	}
+	testCases := []struct {
+		name    string
+		schema  *ConfigSchema
+		isValid bool
+	}{
+		{"valid", schema(KeyRule{Required: true, Type: TypeInt, Min: &one, Max: &ten, Enum: []string{"1", "5"}}), true},
+		{"no keys", &ConfigSchema{Namespace: DefaultNamespace, Name: "test"}, true},
+		{"empty name", &ConfigSchema{Namespace: DefaultNamespace}, false},
+		{"unknown type", schema(KeyRule{Type: "port"}), false},
+		{"invalid pattern", schema(KeyRule{Pattern: "("}), false},
+		{"range of strings", schema(KeyRule{Min: &one}), false},
+		{"min greater than max", schema(KeyRule{Type: TypeFloat, Min: &ten, Max: &one}), false},
+		{"enum of another type", schema(KeyRule{Type: TypeBool, Enum: []string{"yes"}}), false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.schema.Validate()
+			if tc.isValid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestConfigSchema_Check(t *testing.T) {
+	one, ten := 1.0, 10.0
+	schema := &ConfigSchema{Keys: map[string]KeyRule{
+		"host":    {Required: true, Pattern: `^[a-z.]+$`},
+		"port":    {Type: TypeInt, Min: &one, Max: &ten},
+		"timeout": {Type: TypeDuration, Max: &ten},
+		"mode":    {Enum: []string{"fast", "safe"}},
+	}}
+	testCases := []struct {
+		name       string
+		data       map[string]string
+		types      map[string]ValueType
+		violations []string
+	}{
+		{"valid", map[string]string{"host": "localhost", "port": "5", "timeout": "9s", "mode": "safe"},
+			map[string]ValueType{"port": TypeInt, "timeout": TypeDuration}, nil},
+		{"missing required key", map[string]string{"mode": "fast"}, nil, []string{"host"}},
+		{"unknown key", map[string]string{"host": "localhost", "user": "admin"}, nil, []string{"user"}},
+		{"pattern", map[string]string{"host": "local host"}, nil, []string{"host"}},
+		{"type", map[string]string{"host": "localhost", "port": "5"}, nil, []string{"port"}},
+		{"range", map[string]string{"host": "localhost", "port": "11", "timeout": "1m"},
+			map[string]ValueType{"port": TypeInt, "timeout": TypeDuration}, []string{"port", "timeout"}},
+		{"enum", map[string]string{"host": "localhost", "mode": "slow"}, nil, []string{"mode"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := schema.Check(&Config{Data: tc.data, Types: tc.types})
+			if tc.violations == nil {
+				require.NoError(t, err)
+				return
+			}
+			var errs validation.Errors
+			require.ErrorAs(t, err, &errs)
+			data, ok := errs["data"].(validation.Errors)
+			require.True(t, ok)
+			keys := make([]string, 0, len(data))
+			for key := range data {
+				keys = append(keys, key)
+			}
+			require.ElementsMatch(t, tc.violations, keys)
+		})
+	}
+
+	schema.AllowUnknownKeys = true
+	require.NoError(t, schema.Check(&Config{Data: map[string]string{"host": "localhost", "user": "admin"}}))
+	require.NoError(t, (*ConfigSchema)(nil).Check(&Config{Data: map[string]string{"user": "admin"}}))
+}
@@ -0,0 +1,96 @@
+package entity
+
+import (
+	"strings"
+	"time"
+)
+
+// ConfigOrder is the order in which configs are listed.
+type ConfigOrder string
+
+const (
+	OrderByName     ConfigOrder = "name"
+	OrderByCreated  ConfigOrder = "created"
+	OrderByLastUsed ConfigOrder = "last_used"
+)
+
+func (order ConfigOrder) IsValid() bool {
+	return order == OrderByName || order == OrderByCreated || order == OrderByLastUsed
+}
+
+// ConfigSummary describes a config without its data. CreatedAt and LastUsed are those of the relevant version,
+// whose last use is what decides whether the config was recently used.
+type ConfigSummary struct {
+	Namespace string            `json:"namespace"`
+	Name      string            `json:"name"`
+	Version   int64             `json:"version"`
+	CreatedAt time.Time         `json:"created_at"`
+	LastUsed  time.Time         `json:"last_used"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// NameCursor is the position of a config in a listing: its name and, unless configs are ordered by name,
+// the time they are ordered by.
+type NameCursor struct {
+	Time time.Time
+	Name string
+}
+
+// Cursor returns the position of the config in a listing in the order.
+func (summary *ConfigSummary) Cursor(order ConfigOrder) *NameCursor {
+	switch order {
+	case OrderByCreated:
+		return &NameCursor{Time: summary.CreatedAt, Name: summary.Name}
+	case OrderByLastUsed:
+		return &NameCursor{Time: summary.LastUsed, Name: summary.Name}
+	default:
+		return &NameCursor{Name: summary.Name}
+	}
+}
+
+// Compare returns -1, 0 or 1 if the cursor is before, at or after the other one in ascending order.
+// Ties of time are broken by name, so that no two configs of a namespace have the same position.
+func (cursor *NameCursor) Compare(other *NameCursor) int {
+	if cursor.Time.Before(other.Time) {
+		return -1
+	} else if cursor.Time.After(other.Time) {
+		return 1
+	}
+	return strings.Compare(cursor.Name, other.Name)
+}
+
+// NameFilter selects configs of a namespace. Empty fields match every config: Prefix and Contains match
+// the name, and a config matches Labels if it has every one of them. Configs are listed in OrderBy, by name
+// if it is empty, and After continues a listing after that position.
+type NameFilter struct {
+	Namespace  string
+	Prefix     string
+	Contains   string
+	Labels     map[string]string
+	OrderBy    ConfigOrder
+	Descending bool
+	After      *NameCursor
+	Limit      int
+}
+
+func (f *NameFilter) Matches(summary *ConfigSummary) bool {
+	if f.After != nil {
+		cmp := summary.Cursor(f.OrderBy).Compare(f.After)
+		if (!f.Descending && cmp <= 0) || (f.Descending && cmp >= 0) {
+			return false
+		}
+	}
+	return summary.Namespace == f.Namespace &&
+		strings.HasPrefix(summary.Name, f.Prefix) &&
+		strings.Contains(summary.Name, f.Contains) &&
+		HasLabels(summary.Labels, f.Labels)
+}
+
+// Less reports whether a is listed before b.
+func (f *NameFilter) Less(a, b *ConfigSummary) bool {
+	cmp := a.Cursor(f.OrderBy).Compare(b.Cursor(f.OrderBy))
+	if f.Descending {
+		return cmp > 0
+	}
+	return cmp < 0
+}
@@ -0,0 +1,54 @@
+package entity
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestNameFilter_Matches(t *testing.T) {
+	now := time.Now()
+	summary := &ConfigSummary{Namespace: DefaultNamespace, Name: "payments-api", CreatedAt: now, LastUsed: now,
+		Labels: map[string]string{"team": "payments", "tier": "backend"}}
+	testCases := []struct {
+		name    string
+		filter  NameFilter
+		matches bool
+	}{
+		{"empty", NameFilter{}, true},
+		{"other namespace", NameFilter{Namespace: "prod"}, false},
+		{"prefix", NameFilter{Prefix: "pay"}, true},
+		{"other prefix", NameFilter{Prefix: "api"}, false},
+		{"contains", NameFilter{Contains: "ments-a"}, true},
+		{"contains other case", NameFilter{Contains: "API"}, false},
+		{"labels", NameFilter{Labels: map[string]string{"team": "payments", "tier": "backend"}}, true},
+		{"other label value", NameFilter{Labels: map[string]string{"team": "billing"}}, false},
+		{"missing label", NameFilter{Labels: map[string]string{"env": "prod"}}, false},
+		{"after name", NameFilter{After: &NameCursor{Name: "billing"}}, true},
+		{"after itself", NameFilter{After: &NameCursor{Name: "payments-api"}}, false},
+		{"before name descending", NameFilter{After: &NameCursor{Name: "reports"}, Descending: true}, true},
+		{"after earlier time", NameFilter{OrderBy: OrderByCreated, After: &NameCursor{Time: now.Add(-time.Second), Name: "z"}}, true},
+		{"after same time and name", NameFilter{OrderBy: OrderByLastUsed, After: &NameCursor{Time: now, Name: "payments-api"}}, false},
+		{"after same time and earlier name", NameFilter{OrderBy: OrderByLastUsed, After: &NameCursor{Time: now, Name: "a"}}, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter := tc.filter
+			if filter.Namespace == "" {
+				filter.Namespace = DefaultNamespace
+			}
+			require.Equal(t, tc.matches, filter.Matches(summary))
+		})
+	}
+}
+
+func TestNameFilter_Less(t *testing.T) {
+	now := time.Now()
+	older := &ConfigSummary{Name: "b", CreatedAt: now.Add(-time.Hour), LastUsed: now}
+	newer := &ConfigSummary{Name: "a", CreatedAt: now, LastUsed: now}
+	require.True(t, (&NameFilter{}).Less(newer, older))
+	require.True(t, (&NameFilter{Descending: true}).Less(older, newer))
+	require.True(t, (&NameFilter{OrderBy: OrderByCreated}).Less(older, newer))
+	require.True(t, (&NameFilter{OrderBy: OrderByCreated, Descending: true}).Less(newer, older))
+	require.True(t, (&NameFilter{OrderBy: OrderByLastUsed}).Less(newer, older), "ties are ordered by name")
+}
@@ -6,7 +6,8 @@ func TestConfig(t *testing.T) *Config {
 	t.Helper()
 
 	return &Config{
-		Name: "test",
-		Data: map[string]string{"key1": "value1", "key2": "value2", "key3": "value3"},
+		Namespace: DefaultNamespace,
+		Name:      "test",
+		Data:      map[string]string{"key1": "value1", "key2": "value2", "key3": "value3"},
 	}
 }
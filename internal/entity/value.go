@@ -0,0 +1,85 @@
+package entity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ValueType is the type of a config value. Values of every type are stored in Config.Data in their canonical
+// string form, which clients that only know strings read unchanged: integers and floats in decimal, booleans
+// as "true" or "false", durations as time.Duration.String and lists and objects as compact JSON. Secrets are
+// strings that are encrypted at rest and masked for callers that may not read them.
+type ValueType string
+
+const (
+	TypeString   ValueType = "string"
+	TypeInt      ValueType = "int"
+	TypeFloat    ValueType = "float"
+	TypeBool     ValueType = "bool"
+	TypeDuration ValueType = "duration"
+	TypeList     ValueType = "list"
+	TypeObject   ValueType = "object"
+	TypeSecret   ValueType = "secret"
+)
+
+// SecretMask replaces the values of secrets for callers that may not read them.
+const SecretMask = "******"
+
+// ParseValue parses the string form of a value of the type into a string, int64, float64, bool,
+// time.Duration, []interface{} or map[string]interface{}. Secrets are parsed into strings and lists
+// and objects are decoded like by encoding/json.
+func ParseValue(valueType ValueType, value string) (interface{}, error) {
+	switch valueType {
+	case TypeString, TypeSecret:
+		return value, nil
+	case TypeInt:
+		return strconv.ParseInt(value, 10, 64)
+	case TypeFloat:
+		return strconv.ParseFloat(value, 64)
+	case TypeBool:
+		return strconv.ParseBool(value)
+	case TypeDuration:
+		return time.ParseDuration(value)
+	case TypeList:
+		var list []interface{}
+		if err := json.Unmarshal([]byte(value), &list); err != nil || list == nil {
+			return nil, errors.New("must be a JSON array")
+		}
+		return list, nil
+	case TypeObject:
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &object); err != nil || object == nil {
+			return nil, errors.New("must be a JSON object")
+		}
+		return object, nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", valueType)
+	}
+}
+
+// FormatValue returns the type and the canonical string form of a value of one of the types returned by ParseValue.
+func FormatValue(value interface{}) (ValueType, string, error) {
+	switch v := value.(type) {
+	case string:
+		return TypeString, v, nil
+	case int64:
+		return TypeInt, strconv.FormatInt(v, 10), nil
+	case float64:
+		return TypeFloat, strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return TypeBool, strconv.FormatBool(v), nil
+	case time.Duration:
+		return TypeDuration, v.String(), nil
+	case []interface{}:
+		data, err := json.Marshal(v)
+		return TypeList, string(data), err
+	case map[string]interface{}:
+		data, err := json.Marshal(v)
+		return TypeObject, string(data), err
+	default:
+		return "", "", fmt.Errorf("unsupported value of type %T", value)
+	}
+}
@@ -0,0 +1,66 @@
+package entity
+
+import (
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseValue(t *testing.T) {
+	testCases := []struct {
+		valueType ValueType
+		value     string
+		parsed    interface{}
+		isValid   bool
+	}{
+		{TypeString, "text", "text", true},
+		{TypeInt, "-42", int64(-42), true},
+		{TypeInt, "4.2", nil, false},
+		{TypeFloat, "0.25", 0.25, true},
+		{TypeFloat, "NaN?", nil, false},
+		{TypeBool, "true", true, true},
+		{TypeBool, "yes", nil, false},
+		{TypeDuration, "1m30s", 90 * time.Second, true},
+		{TypeDuration, "90", nil, false},
+		{TypeList, `["a",1]`, []interface{}{"a", 1.0}, true},
+		{TypeList, `{"a":1}`, nil, false},
+		{TypeList, `null`, nil, false},
+		{TypeObject, `{"a":{"b":[true]}}`, map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{true}}}, true},
+		{TypeObject, `[1]`, nil, false},
+		{"unknown", "text", nil, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.valueType)+" "+tc.value, func(t *testing.T) {
+			parsed, err := ParseValue(tc.valueType, tc.value)
+			if !tc.isValid {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.parsed, parsed)
+
+			valueType, value, err := FormatValue(parsed)
+			require.NoError(t, err)
+			require.Equal(t, tc.valueType, valueType)
+			require.Equal(t, tc.value, value)
+		})
+	}
+}
+
+func TestFormatValue_Unsupported(t *testing.T) {
+	_, _, err := FormatValue(42)
+	require.Error(t, err)
+}
+
+func TestConfig_Masked(t *testing.T) {
+	config := &Config{Namespace: DefaultNamespace, Name: "test", Data: map[string]string{"user": "app", "password": "p@ssw0rd"},
+		Types: map[string]ValueType{"password": TypeSecret}}
+	masked := config.Masked()
+	require.Equal(t, map[string]string{"user": "app", "password": SecretMask}, masked.Data)
+	require.Equal(t, "p@ssw0rd", config.Data["password"])
+	require.Error(t, masked.Validate(), "a masked config cannot be written back")
+
+	plain := &Config{Name: "test", Data: map[string]string{"user": "app"}}
+	require.Same(t, plain, plain.Masked())
+}
@@ -5,31 +5,30 @@ import (
 	"distributedConfig/config"
 	grpc_service "distributedConfig/internal/delivery/grpc"
 	"distributedConfig/internal/delivery/proto"
-	"distributedConfig/pkg/logger"
-	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
-	"net"
+	"distributedConfig/internal/health"
+	"distributedConfig/internal/metrics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 	"net/http"
 )
 
-func RunGatewayServer(configService *grpc_service.ConfigService, cfg *config.Config, l *logger.Logger) {
-	grpcMux := runtime.NewServeMux()
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-	err := proto.RegisterConfigServiceHandlerServer(ctx, grpcMux, configService)
+// NewGatewayServer creates the server of the REST API, which proxies requests to the gRPC server, so they pass
+// through the same interceptors as gRPC calls. The Authorization header is forwarded as authorization metadata.
+// The metrics are served on /metrics unless they have a port of their own, and the probes on /healthz and /readyz.
+// The connection to the gRPC server is closed when ctx is done.
+func NewGatewayServer(ctx context.Context, cfg *config.Config, m *metrics.Metrics, checker *health.Checker) (*http.Server, error) {
+	grpcMux := grpc_service.NewGatewayMux()
+	err := proto.RegisterConfigServiceHandlerFromEndpoint(ctx, grpcMux, "localhost:"+cfg.Server.GPRCPort,
+		[]grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())})
 	if err != nil {
-		l.Fatal("Failed to register gateway: %v", err)
-		return
+		return nil, err
 	}
-	l.Info("Starting gRPC gateway on port %s", cfg.Server.GatewayPort)
 	mux := http.NewServeMux()
 	mux.Handle("/", grpcMux)
-	listener, err := net.Listen("tcp", ":"+cfg.Server.GatewayPort)
-	if err != nil {
-		l.Fatal("Failed to listen: %v", err)
-		return
-	}
-	if err := http.Serve(listener, mux); err != nil {
-		l.Fatal("Failed to serve: %v", err)
-		return
+	mux.Handle("/healthz", checker.LivenessHandler())
+	mux.Handle("/readyz", checker.ReadinessHandler())
+	if cfg.Server.MetricsPort == "" {
+		mux.Handle("/metrics", m.Handler())
 	}
+	return &http.Server{Handler: mux}, nil
 }
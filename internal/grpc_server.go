@@ -1,27 +1,48 @@
 package internal
 
 import (
-	"distributedConfig/config"
+	"context"
+	"distributedConfig/internal/auth"
 	"distributedConfig/internal/delivery/grpc"
 	"distributedConfig/internal/delivery/proto"
 	"distributedConfig/internal/interceptors"
+	"distributedConfig/internal/metrics"
 	"distributedConfig/pkg/logger"
 	"google.golang.org/grpc"
-	"net"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
-func RunGrpcServer(configService *grpc_service.ConfigService, cfg *config.Config, l *logger.Logger) {
+// NewGrpcServer creates the server of ConfigService and of the health service. If authenticator is nil, calls are
+// not authenticated; health checks never are.
+func NewGrpcServer(configService *grpc_service.ConfigService, l *logger.Logger, authenticator *auth.Authenticator,
+	m *metrics.Metrics, healthServer grpc_health_v1.HealthServer) *grpc.Server {
 	interceptor := interceptors.NewInterceptor(*l)
-	server := grpc.NewServer(grpc.UnaryInterceptor(interceptor.Logger))
-	proto.RegisterConfigServiceServer(server, configService)
-	l.Info("Starting gRPC server on port %s", cfg.Server.GPRCPort)
-	listener, err := net.Listen("tcp", ":"+cfg.Server.GPRCPort)
-	if err != nil {
-		l.Fatal("Failed to listen: %v", err)
-		return
+	metricsInterceptor := interceptors.NewMetricsInterceptor(m.Registry())
+	unaryInterceptors := []grpc.UnaryServerInterceptor{metricsInterceptor.Unary, interceptor.Logger}
+	streamInterceptors := []grpc.StreamServerInterceptor{metricsInterceptor.Stream}
+	if authenticator != nil {
+		authInterceptor := interceptors.NewAuthInterceptor(*l, authenticator)
+		unaryInterceptors = append(unaryInterceptors, authInterceptor.Unary)
+		streamInterceptors = append(streamInterceptors, authInterceptor.Stream)
 	}
-	if err := server.Serve(listener); err != nil {
-		l.Fatal("Failed to serve: %v", err)
-		return
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(unaryInterceptors...), grpc.ChainStreamInterceptor(streamInterceptors...))
+	proto.RegisterConfigServiceServer(server, configService)
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	return server
+}
+
+// StopGrpcServer waits for the calls in progress to finish and cancels the remaining ones once ctx is done.
+func StopGrpcServer(ctx context.Context, server *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return ctx.Err()
 	}
 }
@@ -0,0 +1,162 @@
+// Package health reports whether the service and its dependencies are up, over the standard grpc.health.v1.Health
+// service and HTTP probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+var errShuttingDown = errors.New("service is shutting down")
+
+// CheckFunc returns an error if a dependency is not usable.
+type CheckFunc func(ctx context.Context) error
+
+// Result is the status of a dependency.
+type Result struct {
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Report is the status of the service, which is ok only if every dependency is.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker checks the dependencies of the service. The readiness probe checks them on every request, and the status
+// of the gRPC health service is updated by Run. Liveness does not depend on them, so that a replica is not restarted
+// while, for example, the database is down.
+type Checker struct {
+	timeout  time.Duration
+	services []string
+	server   *health.Server
+
+	mu           sync.Mutex
+	checks       []check
+	shuttingDown bool
+}
+
+// New creates a checker that fails checks that take longer than timeout. The status of the gRPC health service
+// is reported for the whole server and for each of services.
+func New(timeout time.Duration, services ...string) *Checker {
+	return &Checker{timeout: timeout, services: append([]string{""}, services...), server: health.NewServer()}
+}
+
+// Add registers a dependency.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// Check checks every dependency concurrently.
+func (c *Checker) Check(ctx context.Context) *Report {
+	c.mu.Lock()
+	checks := append([]check{}, c.checks...)
+	shuttingDown := c.shuttingDown
+	c.mu.Unlock()
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	if shuttingDown {
+		report.Status = StatusUnavailable
+		report.Checks["shutdown"] = Result{Status: StatusUnavailable, Error: errShuttingDown.Error()}
+		return report
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, ch := range checks {
+		wg.Add(1)
+		go func(ch check) {
+			defer wg.Done()
+			result := Result{Status: StatusOK}
+			if err := ch.fn(ctx); err != nil {
+				result = Result{Status: StatusUnavailable, Error: err.Error()}
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[ch.name] = result
+			if result.Status != StatusOK {
+				report.Status = StatusUnavailable
+			}
+		}(ch)
+	}
+	wg.Wait()
+	return report
+}
+
+// Server returns the gRPC health service.
+func (c *Checker) Server() grpc_health_v1.HealthServer {
+	return c.server
+}
+
+// Run checks the dependencies every interval and updates the status of the gRPC health service until ctx is done.
+func (c *Checker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		status := grpc_health_v1.HealthCheckResponse_SERVING
+		if c.Check(ctx).Status != StatusOK {
+			status = grpc_health_v1.HealthCheckResponse_NOT_SERVING
+		}
+		for _, service := range c.services {
+			c.server.SetServingStatus(service, status)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown reports the service as not ready for good, so that no new calls are routed to it while it stops.
+func (c *Checker) Shutdown() {
+	c.mu.Lock()
+	c.shuttingDown = true
+	c.mu.Unlock()
+	c.server.Shutdown()
+}
+
+// LivenessHandler responds with 200 while the process serves HTTP.
+func (c *Checker) LivenessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, &Report{Status: StatusOK, Checks: map[string]Result{}})
+	})
+}
+
+// ReadinessHandler responds with the report of the dependencies, with 200 if they are all ok and 503 otherwise.
+func (c *Checker) ReadinessHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.Check(r.Context())
+		code := http.StatusOK
+		if report.Status != StatusOK {
+			code = http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, report)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, report *Report) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(report)
+}
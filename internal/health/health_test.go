@@ -0,0 +1,96 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func readiness(t *testing.T, c *Checker) (int, *Report) {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	c.ReadinessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var report Report
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &report))
+	return recorder.Code, &report
+}
+
+func TestChecker_Readiness(t *testing.T) {
+	c := New(time.Second)
+	c.Add("database", func(context.Context) error { return nil })
+	code, report := readiness(t, c)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, &Report{Status: StatusOK, Checks: map[string]Result{"database": {Status: StatusOK}}}, report)
+
+	c.Add("migrations", func(context.Context) error { return errors.New("schema version 7 is older than 8") })
+	code, report = readiness(t, c)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, &Report{Status: StatusUnavailable, Checks: map[string]Result{
+		"database":   {Status: StatusOK},
+		"migrations": {Status: StatusUnavailable, Error: "schema version 7 is older than 8"},
+	}}, report)
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	c := New(10 * time.Millisecond)
+	c.Add("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	report := c.Check(context.Background())
+	require.Equal(t, StatusUnavailable, report.Status)
+	require.Equal(t, context.DeadlineExceeded.Error(), report.Checks["database"].Error)
+}
+
+func TestChecker_Liveness(t *testing.T) {
+	c := New(time.Second)
+	c.Add("database", func(context.Context) error { return errors.New("connection refused") })
+	recorder := httptest.NewRecorder()
+	c.LivenessHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestChecker_GrpcStatus(t *testing.T) {
+	c := New(time.Second, "tutorial.ConfigService")
+	healthy := make(chan error, 1)
+	healthy <- errors.New("connection refused")
+	c.Add("database", func(context.Context) error {
+		select {
+		case err := <-healthy:
+			return err
+		default:
+			return nil
+		}
+	})
+	status := func(service string) grpc_health_v1.HealthCheckResponse_ServingStatus {
+		response, err := c.Server().Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		return response.Status
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx, 10*time.Millisecond)
+	}()
+	require.Eventually(t, func() bool {
+		return status("tutorial.ConfigService") == grpc_health_v1.HealthCheckResponse_SERVING
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, status(""))
+
+	c.Shutdown()
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, status(""))
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_NOT_SERVING, status("tutorial.ConfigService"))
+	code, report := readiness(t, c)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, StatusUnavailable, report.Checks["shutdown"].Status)
+	cancel()
+	<-done
+}
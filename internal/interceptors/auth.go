@@ -0,0 +1,180 @@
+package interceptors
+
+import (
+	"context"
+	"distributedConfig/internal/auth"
+	"distributedConfig/internal/entity"
+	"distributedConfig/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const configServicePrefix = "/tutorial.ConfigService/"
+
+// methodPermissions maps the RPCs of ConfigService to the permission they require on the requested config.
+// RPCs missing from the map are denied.
+var methodPermissions = map[string]auth.Permission{
+	configServicePrefix + "CreateConfig":        auth.PermissionWrite,
+	configServicePrefix + "GetConfig":           auth.PermissionRead,
+	configServicePrefix + "GetConfigByVersion":  auth.PermissionRead,
+	configServicePrefix + "UpdateConfig":        auth.PermissionWrite,
+	configServicePrefix + "PatchConfig":         auth.PermissionWrite,
+	configServicePrefix + "PromoteConfig":       auth.PermissionWrite,
+	configServicePrefix + "GetConfigOverrides":  auth.PermissionRead,
+	configServicePrefix + "DeleteConfig":        auth.PermissionDelete,
+	configServicePrefix + "DeleteConfigVersion": auth.PermissionDelete,
+	configServicePrefix + "ListConfigs":         auth.PermissionRead,
+	configServicePrefix + "ListConfigVersions":  auth.PermissionRead,
+	configServicePrefix + "SetConfigLabels":     auth.PermissionWrite,
+	configServicePrefix + "SetRelevantConfig":   auth.PermissionSetRelevant,
+	configServicePrefix + "WatchConfig":         auth.PermissionRead,
+	configServicePrefix + "DiffConfigVersions":  auth.PermissionRead,
+	configServicePrefix + "DiffProposedConfig":  auth.PermissionRead,
+	configServicePrefix + "ListAuditEvents":     auth.PermissionAudit,
+	configServicePrefix + "SetConfigSchema":     auth.PermissionSchema,
+	configServicePrefix + "GetConfigSchema":     auth.PermissionRead,
+	configServicePrefix + "ListConfigSchemas":   auth.PermissionRead,
+	configServicePrefix + "DeleteConfigSchema":  auth.PermissionSchema,
+}
+
+// authenticatedMethods are the RPCs of ConfigService that any authenticated caller may call. ListConfigNames
+// lists only the configs that the caller may read.
+var authenticatedMethods = map[string]bool{
+	configServicePrefix + "ListNamespaces":  true,
+	configServicePrefix + "ListConfigNames": true,
+}
+
+// publicMethods are called without authentication, e.g. by the health probes of the orchestrator.
+var publicMethods = map[string]bool{
+	"/grpc.health.v1.Health/Check": true,
+	"/grpc.health.v1.Health/Watch": true,
+}
+
+// configRequest is implemented by every request of ConfigService that refers to a config.
+type configRequest interface {
+	GetNamespace() string
+	GetServiceName() string
+}
+
+// promotionRequest refers to a config in two namespaces. The permission of the method is required
+// on the target config and PermissionRead on the source one.
+type promotionRequest interface {
+	GetSourceNamespace() string
+	GetTargetNamespace() string
+	GetServiceName() string
+}
+
+// AuthInterceptor authenticates every call by its authorization metadata, which grpc-gateway
+// fills from the Authorization header, and authorizes it on the requested config.
+type AuthInterceptor struct {
+	l             logger.Logger
+	authenticator *auth.Authenticator
+}
+
+func NewAuthInterceptor(l logger.Logger, authenticator *auth.Authenticator) *AuthInterceptor {
+	return &AuthInterceptor{l: l, authenticator: authenticator}
+}
+
+func (i *AuthInterceptor) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if publicMethods[info.FullMethod] {
+		return handler(ctx, req)
+	}
+	principal, err := i.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := i.authorize(principal, info.FullMethod, req); err != nil {
+		return nil, err
+	}
+	return handler(i.withPrincipal(ctx, principal), req)
+}
+
+func (i *AuthInterceptor) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if publicMethods[info.FullMethod] {
+		return handler(srv, ss)
+	}
+	principal, err := i.authenticate(ss.Context())
+	if err != nil {
+		return err
+	}
+	return handler(srv, &authorizedStream{
+		ServerStream: ss,
+		ctx:          i.withPrincipal(ss.Context(), principal),
+		authorize: func(req interface{}) error {
+			return i.authorize(principal, info.FullMethod, req)
+		},
+	})
+}
+
+// withPrincipal stores the caller and the authenticator, which handlers use to check permissions on
+// what they respond with.
+func (i *AuthInterceptor) withPrincipal(ctx context.Context, principal *auth.Principal) context.Context {
+	return auth.WithAuthenticator(auth.WithPrincipal(ctx, principal), i.authenticator)
+}
+
+func (i *AuthInterceptor) authenticate(ctx context.Context) (*auth.Principal, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var authorization string
+	if values := md.Get("authorization"); len(values) > 0 {
+		authorization = values[0]
+	}
+	principal, err := i.authenticator.Authenticate(authorization)
+	if err != nil {
+		i.l.Warn("Unable to authenticate caller: %s", err)
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return principal, nil
+}
+
+func (i *AuthInterceptor) authorize(principal *auth.Principal, method string, req interface{}) error {
+	if authenticatedMethods[method] {
+		return nil
+	}
+	permission, ok := methodPermissions[method]
+	if ok {
+		switch request := req.(type) {
+		case promotionRequest:
+			err := i.authorizeConfig(principal, method, auth.PermissionRead, request.GetSourceNamespace(), request.GetServiceName())
+			if err != nil {
+				return err
+			}
+			return i.authorizeConfig(principal, method, permission, request.GetTargetNamespace(), request.GetServiceName())
+		case configRequest:
+			return i.authorizeConfig(principal, method, permission, request.GetNamespace(), request.GetServiceName())
+		}
+	}
+	i.l.Warn("Denied %s to %s: method is not covered by roles", method, principal.Subject)
+	return status.Errorf(codes.PermissionDenied, "%s is not allowed", method)
+}
+
+func (i *AuthInterceptor) authorizeConfig(principal *auth.Principal, method string, permission auth.Permission,
+	namespace, name string) error {
+	namespace = entity.NamespaceOrDefault(namespace)
+	if !i.authenticator.Authorize(principal, permission, namespace, name) {
+		qualifiedName := entity.QualifiedName(namespace, name)
+		i.l.Warn("Denied %s of %s config to %s", method, qualifiedName, principal.Subject)
+		return status.Errorf(codes.PermissionDenied, "%s permission on %s config is required", permission, qualifiedName)
+	}
+	return nil
+}
+
+// authorizedStream authorizes the requests of a stream as they are received, because the requested
+// config is known only from the request.
+type authorizedStream struct {
+	grpc.ServerStream
+	ctx       context.Context
+	authorize func(req interface{}) error
+}
+
+func (s *authorizedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *authorizedStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.authorize(m)
+}
@@ -0,0 +1,354 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/sha256"
+	cfg "distributedConfig/config"
+	"distributedConfig/internal/auth"
+	grpc_service "distributedConfig/internal/delivery/grpc"
+	"distributedConfig/internal/delivery/proto"
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository/memory_repository"
+	"distributedConfig/internal/usecase"
+	"distributedConfig/pkg/logger"
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// newTestServer serves ConfigService with authentication, where the token "writer" may read and write
+// payments-* configs and the token "admin" may do anything.
+func newTestServer(t *testing.T) []grpc.DialOption {
+	t.Helper()
+	return newLoggingTestServer(t, *logger.New("error"))
+}
+
+// newLoggingTestServer is newTestServer that logs with l.
+func newLoggingTestServer(t *testing.T, l logger.Logger) []grpc.DialOption {
+	t.Helper()
+	authenticator, err := auth.NewAuthenticator(auth.KeyFile{
+		Tokens: []auth.StaticToken{
+			{Subject: "writer", TokenSHA256: hashToken("writer"), Roles: []string{"payments-writer"}},
+			{Subject: "admin", TokenSHA256: hashToken("admin"), Roles: []string{"admin"}},
+		},
+		Roles: map[string][]auth.Rule{
+			"payments-writer": {{Configs: []string{"payments-*"}, Permissions: []auth.Permission{auth.PermissionRead, auth.PermissionWrite}}},
+			"admin": {{Namespaces: []string{"*"}, Configs: []string{"*"}, Permissions: []auth.Permission{
+				auth.PermissionRead, auth.PermissionWrite, auth.PermissionDelete, auth.PermissionSetRelevant, auth.PermissionAudit,
+				auth.PermissionSchema, auth.PermissionDecrypt,
+			}}},
+		},
+	})
+	require.NoError(t, err)
+	configRepository, auditRepository := memory_repository.NewAuditedConfigRepository()
+	configUseCase := usecase.NewConfigUseCase(l, configRepository,
+		auditRepository, memory_repository.NewConfigNotifier(),
+		&cfg.Config{Server: cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true}})
+	authInterceptor := NewAuthInterceptor(l, authenticator)
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(NewInterceptor(l).Logger, authInterceptor.Unary),
+		grpc.ChainStreamInterceptor(authInterceptor.Stream))
+	proto.RegisterConfigServiceServer(server, grpc_service.NewConfigService(*configUseCase))
+	grpc_health_v1.RegisterHealthServer(server, health.NewServer())
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	return []grpc.DialOption{
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuthInterceptor_Unary(t *testing.T) {
+	conn, err := grpc.Dial("bufnet", newTestServer(t)...)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := proto.NewConfigServiceClient(conn)
+	payments := &proto.Config{ServiceName: "payments-api", Data: map[string]string{"k1": "v1"}}
+
+	_, err = client.CreateConfig(context.Background(), payments)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	_, err = client.CreateConfig(withToken("unknown"), payments)
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	_, err = client.CreateConfig(withToken("writer"), payments)
+	require.NoError(t, err)
+	_, err = client.GetConfig(withToken("writer"), &proto.ConfigName{ServiceName: "payments-api"})
+	require.NoError(t, err)
+	_, err = client.CreateConfig(withToken("writer"), &proto.Config{ServiceName: "billing", Data: map[string]string{"k1": "v1"}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.SetRelevantConfig(withToken("writer"), &proto.ConfigNameAndVersion{ServiceName: "payments-api", Version: 1})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.DeleteConfig(withToken("writer"), &proto.ConfigName{ServiceName: "payments-api"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.DeleteConfig(withToken("admin"), &proto.ConfigName{ServiceName: "payments-api"})
+	require.NoError(t, err)
+
+	_, err = client.ListAuditEvents(withToken("writer"), &proto.ListAuditEventsRequest{ServiceName: "payments-api"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	audit, err := client.ListAuditEvents(withToken("admin"), &proto.ListAuditEventsRequest{ServiceName: "payments-api"})
+	require.NoError(t, err)
+	require.Len(t, audit.Events, 2)
+	require.Equal(t, "admin", audit.Events[0].Actor)
+	require.Equal(t, "writer", audit.Events[1].Actor)
+}
+
+func TestAuthInterceptor_Namespaces(t *testing.T) {
+	conn, err := grpc.Dial("bufnet", newTestServer(t)...)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := proto.NewConfigServiceClient(conn)
+	staging := &proto.Config{Namespace: "staging", ServiceName: "payments-api", Data: map[string]string{"k1": "v1"}}
+
+	_, err = client.CreateConfig(withToken("writer"), staging)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.CreateConfig(withToken("admin"), staging)
+	require.NoError(t, err)
+	_, err = client.GetConfig(withToken("writer"), &proto.ConfigName{Namespace: "staging", ServiceName: "payments-api"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.GetConfig(withToken("admin"), &proto.ConfigName{Namespace: "staging", ServiceName: "payments-api"})
+	require.NoError(t, err)
+
+	_, err = client.ListNamespaces(context.Background(), &proto.ListNamespacesRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	namespaces, err := client.ListNamespaces(withToken("writer"), &proto.ListNamespacesRequest{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"default", "staging"}, namespaces.Namespaces)
+}
+
+func TestAuthInterceptor_ListConfigNames(t *testing.T) {
+	conn, err := grpc.Dial("bufnet", newTestServer(t)...)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := proto.NewConfigServiceClient(conn)
+	for _, name := range []string{"billing", "payments-api", "payments-worker", "reports"} {
+		_, err := client.CreateConfig(withToken("admin"), &proto.Config{ServiceName: name, Data: map[string]string{"k1": "v1"}})
+		require.NoError(t, err)
+	}
+	_, err = client.SetConfigLabels(withToken("writer"), &proto.ConfigLabels{ServiceName: "billing", Labels: map[string]string{"team": "x"}})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.SetConfigLabels(withToken("writer"), &proto.ConfigLabels{ServiceName: "payments-api", Labels: map[string]string{"team": "x"}})
+	require.NoError(t, err)
+
+	_, err = client.ListConfigNames(context.Background(), &proto.ListConfigNamesRequest{})
+	require.Equal(t, codes.Unauthenticated, status.Code(err))
+	page, err := client.ListConfigNames(withToken("writer"), &proto.ListConfigNamesRequest{PageSize: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Configs, 2)
+	require.Equal(t, "payments-api", page.Configs[0].ServiceName)
+	require.Equal(t, "payments-worker", page.Configs[1].ServiceName)
+	require.Empty(t, page.NextPageToken)
+	first, err := client.ListConfigNames(withToken("writer"), &proto.ListConfigNamesRequest{PageSize: 1})
+	require.NoError(t, err)
+	require.Len(t, first.Configs, 1)
+	require.Equal(t, "payments-api", first.Configs[0].ServiceName)
+	token, err := base64.RawURLEncoding.DecodeString(first.NextPageToken)
+	require.NoError(t, err)
+	require.Contains(t, string(token), "payments-api")
+	second, err := client.ListConfigNames(withToken("writer"), &proto.ListConfigNamesRequest{PageSize: 1, PageToken: first.NextPageToken})
+	require.NoError(t, err)
+	require.Len(t, second.Configs, 1)
+	require.Equal(t, "payments-worker", second.Configs[0].ServiceName)
+	require.Empty(t, second.NextPageToken)
+	all, err := client.ListConfigNames(withToken("admin"), &proto.ListConfigNamesRequest{})
+	require.NoError(t, err)
+	require.Len(t, all.Configs, 4)
+}
+
+func TestAuthInterceptor_PromoteConfig(t *testing.T) {
+	conn, err := grpc.Dial("bufnet", newTestServer(t)...)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := proto.NewConfigServiceClient(conn)
+	for _, namespace := range []string{"staging", "default"} {
+		config := &proto.Config{Namespace: namespace, ServiceName: "payments-api", Data: map[string]string{"k1": namespace}}
+		_, err = client.CreateConfig(withToken("admin"), config)
+		require.NoError(t, err)
+	}
+
+	// The writer may not read the source config.
+	_, err = client.PromoteConfig(withToken("writer"), &proto.PromoteConfigRequest{
+		ServiceName: "payments-api", SourceNamespace: "staging", TargetNamespace: "default", Version: 1,
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	// The writer may not write the target config.
+	_, err = client.PromoteConfig(withToken("writer"), &proto.PromoteConfigRequest{
+		ServiceName: "payments-api", SourceNamespace: "default", TargetNamespace: "staging", Version: 1,
+	})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	promoted, err := client.PromoteConfig(withToken("admin"), &proto.PromoteConfigRequest{
+		ServiceName: "payments-api", SourceNamespace: "staging", TargetNamespace: "default", Version: 1,
+	})
+	require.NoError(t, err)
+	require.Equal(t, &proto.ConfigSource{Namespace: "staging", Version: 1}, promoted.PromotedFrom)
+	_, err = client.GetConfigOverrides(withToken("writer"), &proto.ConfigName{ServiceName: "payments-api"})
+	require.NoError(t, err)
+}
+
+func TestAuthInterceptor_ConfigSchema(t *testing.T) {
+	conn, err := grpc.Dial("bufnet", newTestServer(t)...)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := proto.NewConfigServiceClient(conn)
+	schema := &proto.ConfigSchema{ServiceName: "payments-api", Keys: map[string]*proto.KeyRule{"k1": {Required: true}}}
+
+	_, err = client.SetConfigSchema(withToken("writer"), schema)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+	_, err = client.SetConfigSchema(withToken("admin"), schema)
+	require.NoError(t, err)
+	got, err := client.GetConfigSchema(withToken("writer"), &proto.ConfigNameAndVersion{ServiceName: "payments-api"})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), got.Version)
+	_, err = client.DeleteConfigSchema(withToken("writer"), &proto.ConfigName{ServiceName: "payments-api"})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestAuthInterceptor_Secrets(t *testing.T) {
+	conn, err := grpc.Dial("bufnet", newTestServer(t)...)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := proto.NewConfigServiceClient(conn)
+	secret := func(value string) *proto.TypedValue {
+		return &proto.TypedValue{Kind: &proto.TypedValue_SecretValue{SecretValue: value}}
+	}
+	created, err := client.CreateConfig(withToken("writer"), &proto.Config{ServiceName: "payments-db",
+		Data: map[string]string{"user": "app"}, Values: map[string]*proto.TypedValue{"password": secret("p@ssw0rd")}})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"user": "app", "password": entity.SecretMask}, created.Config.Data)
+	require.Equal(t, entity.SecretMask, created.Config.Values["password"].GetSecretValue())
+
+	got, err := client.GetConfig(withToken("admin"), &proto.ConfigName{ServiceName: "payments-db"})
+	require.NoError(t, err)
+	require.Equal(t, "p@ssw0rd", got.Config.Data["password"])
+	require.Equal(t, "p@ssw0rd", got.Config.Values["password"].GetSecretValue())
+
+	_, err = client.PatchConfig(withToken("writer"), &proto.PatchConfigRequest{ServiceName: "payments-db",
+		SetValues: map[string]*proto.TypedValue{"password": secret("n3w")}})
+	require.NoError(t, err)
+	stream, err := client.ListConfigs(withToken("writer"), &proto.ListRequest{ServiceName: "payments-db"})
+	require.NoError(t, err)
+	for version := 2; version > 0; version-- {
+		response, err := stream.Recv()
+		require.NoError(t, err)
+		require.Equal(t, entity.SecretMask, response.Config.Data["password"])
+	}
+	diff, err := client.DiffConfigVersions(withToken("writer"), &proto.DiffRequest{ServiceName: "payments-db",
+		FromVersion: 1, ToVersion: 2})
+	require.NoError(t, err)
+	require.Equal(t, entity.SecretMask, diff.Changed["password"].NewValue)
+	diff, err = client.DiffConfigVersions(withToken("admin"), &proto.DiffRequest{ServiceName: "payments-db",
+		FromVersion: 1, ToVersion: 2})
+	require.NoError(t, err)
+	require.Equal(t, "n3w", diff.Changed["password"].NewValue)
+}
+
+func TestAuthInterceptor_Stream(t *testing.T) {
+	conn, err := grpc.Dial("bufnet", newTestServer(t)...)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := proto.NewConfigServiceClient(conn)
+	for _, name := range []string{"payments-api", "billing"} {
+		_, err = client.CreateConfig(withToken("admin"), &proto.Config{ServiceName: name, Data: map[string]string{"k1": "v1"}})
+		require.NoError(t, err)
+	}
+
+	testCases := []struct {
+		name    string
+		token   string
+		config  string
+		code    codes.Code
+		version int64
+	}{
+		{"unauthenticated", "", "payments-api", codes.Unauthenticated, 0},
+		{"denied", "writer", "billing", codes.PermissionDenied, 0},
+		{"allowed", "writer", "payments-api", codes.OK, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tc.token != "" {
+				ctx = withToken(tc.token)
+			}
+			stream, err := client.ListConfigs(ctx, &proto.ListRequest{ServiceName: tc.config})
+			require.NoError(t, err)
+			response, err := stream.Recv()
+			require.Equal(t, tc.code, status.Code(err))
+			if tc.code == codes.OK {
+				require.Equal(t, tc.version, response.Version)
+			}
+		})
+	}
+}
+
+func TestAuthInterceptor_Gateway(t *testing.T) {
+	mux := grpc_service.NewGatewayMux()
+	require.NoError(t, proto.RegisterConfigServiceHandlerFromEndpoint(context.Background(), mux, "bufnet", newTestServer(t)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		body          string
+		authorization string
+		status        int
+	}{
+		{"unauthenticated", http.MethodPost, "/v1/config", `{"service_name": "payments-api", "data": {"k1": "v1"}}`, "", http.StatusUnauthorized},
+		{"create", http.MethodPost, "/v1/config", `{"service_name": "payments-api", "data": {"k1": "v1"}}`, "Bearer writer", http.StatusOK},
+		{"get", http.MethodGet, "/v1/config/payments-api", "", "Bearer writer", http.StatusOK},
+		{"denied", http.MethodDelete, "/v1/config/payments-api", "", "Bearer writer", http.StatusForbidden},
+		{"delete", http.MethodDelete, "/v1/config/payments-api", "", "Bearer admin", http.StatusOK},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			request, err := http.NewRequest(tc.method, server.URL+tc.path, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			if tc.authorization != "" {
+				request.Header.Set("Authorization", tc.authorization)
+			}
+			response, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			defer response.Body.Close()
+			require.Equal(t, tc.status, response.StatusCode)
+		})
+	}
+}
+
+func TestAuthInterceptor_Health(t *testing.T) {
+	conn, err := grpc.Dial("bufnet", newTestServer(t)...)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	response, err := grpc_health_v1.NewHealthClient(conn).Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	require.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, response.Status)
+}
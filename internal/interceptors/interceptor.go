@@ -8,6 +8,10 @@ import (
 	"time"
 )
 
+// redactedMetadata are the keys of the credentials that are not logged. The gateway forwards the Authorization
+// and Cookie headers of REST calls both as they are and with the grpcgateway- prefix.
+var redactedMetadata = []string{"authorization", "grpcgateway-authorization", "cookie", "grpcgateway-cookie"}
+
 type Interceptor struct {
 	l logger.Logger
 }
@@ -19,6 +23,10 @@ func NewInterceptor(l logger.Logger) *Interceptor {
 func (i *Interceptor) Logger(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 	start := time.Now()
 	md, _ := metadata.FromIncomingContext(ctx)
+	md = md.Copy()
+	for _, key := range redactedMetadata {
+		md.Delete(key)
+	}
 	reply, err := handler(ctx, req)
 	i.l.Info("Method: %s, Time: %v, Metadata: %v, Err: %v", info.FullMethod, time.Since(start), md, err)
 	return reply, err
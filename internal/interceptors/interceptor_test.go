@@ -0,0 +1,58 @@
+package interceptors
+
+import (
+	"bytes"
+	"context"
+	grpc_service "distributedConfig/internal/delivery/grpc"
+	"distributedConfig/internal/delivery/proto"
+	"distributedConfig/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// syncBuffer collects the output of a logger used by the goroutines of a server.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestInterceptor_LoggerRedactsCredentials(t *testing.T) {
+	output := &syncBuffer{}
+	l := *logger.NewWriter("info", output)
+	// The level is global, so the level of the other tests is restored.
+	defer logger.New("error")
+	mux := grpc_service.NewGatewayMux()
+	require.NoError(t, proto.RegisterConfigServiceHandlerFromEndpoint(context.Background(), mux, "bufnet",
+		newLoggingTestServer(t, l)))
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	request, err := http.NewRequest(http.MethodGet, server.URL+"/v1/config/payments-api", nil)
+	require.NoError(t, err)
+	request.Header.Set("Authorization", "Bearer writer")
+	request.Header.Set("Cookie", "session=secret-session")
+	response, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	require.Equal(t, http.StatusNotFound, response.StatusCode)
+
+	logged := output.String()
+	require.Contains(t, logged, "ConfigService/GetConfig")
+	require.NotContains(t, logged, "Bearer writer")
+	require.NotContains(t, logged, "secret-session")
+}
@@ -0,0 +1,101 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"strings"
+	"time"
+)
+
+const (
+	unaryType        = "unary"
+	serverStreamType = "server_stream"
+	clientStreamType = "client_stream"
+	bidiStreamType   = "bidi_stream"
+)
+
+// MetricsInterceptor counts the calls of every RPC by their status code and measures how long they are handled.
+// It precedes the other interceptors, so rejected calls are counted too.
+type MetricsInterceptor struct {
+	handled  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	sent     *prometheus.CounterVec
+}
+
+func NewMetricsInterceptor(registerer prometheus.Registerer) *MetricsInterceptor {
+	i := &MetricsInterceptor{
+		handled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "RPCs completed on the server, regardless of success or failure.",
+		}, []string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Time the server took to handle RPCs, which for streams lasts until they end.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"grpc_type", "grpc_service", "grpc_method"}),
+		sent: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_msg_sent_total",
+			Help: "Messages sent by the server on streams.",
+		}, []string{"grpc_type", "grpc_service", "grpc_method"}),
+	}
+	registerer.MustRegister(i.handled, i.duration, i.sent)
+	return i
+}
+
+func (i *MetricsInterceptor) Unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	i.observe(unaryType, info.FullMethod, start, err)
+	return resp, err
+}
+
+func (i *MetricsInterceptor) Stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	grpcType := streamType(info)
+	service, method := splitMethod(info.FullMethod)
+	err := handler(srv, &countingStream{ServerStream: ss, sent: i.sent.WithLabelValues(grpcType, service, method)})
+	i.observe(grpcType, info.FullMethod, start, err)
+	return err
+}
+
+func (i *MetricsInterceptor) observe(grpcType, fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+	i.handled.WithLabelValues(grpcType, service, method, status.Code(err).String()).Inc()
+	i.duration.WithLabelValues(grpcType, service, method).Observe(time.Since(start).Seconds())
+}
+
+// splitMethod splits "/package.Service/Method" into the service and the method.
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return bidiStreamType
+	case info.IsClientStream:
+		return clientStreamType
+	default:
+		return serverStreamType
+	}
+}
+
+// countingStream counts the messages sent on the stream.
+type countingStream struct {
+	grpc.ServerStream
+	sent prometheus.Counter
+}
+
+func (s *countingStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		s.sent.Inc()
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package interceptors
+
+import (
+	"context"
+	"distributedConfig/internal/delivery/proto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"testing"
+)
+
+func TestMetricsInterceptor_Unary(t *testing.T) {
+	i := NewMetricsInterceptor(prometheus.NewRegistry())
+	info := &grpc.UnaryServerInfo{FullMethod: configServicePrefix + "GetConfig"}
+	_, err := i.Unary(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return &proto.Config{}, nil
+	})
+	require.NoError(t, err)
+	_, err = i.Unary(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "not found")
+	})
+	require.Equal(t, codes.NotFound, status.Code(err))
+
+	require.Equal(t, 1.0, testutil.ToFloat64(i.handled.WithLabelValues("unary", "tutorial.ConfigService", "GetConfig", "OK")))
+	require.Equal(t, 1.0, testutil.ToFloat64(i.handled.WithLabelValues("unary", "tutorial.ConfigService", "GetConfig", "NotFound")))
+	require.Equal(t, 1, testutil.CollectAndCount(i.duration))
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+}
+
+func (s *testServerStream) SendMsg(interface{}) error {
+	return nil
+}
+
+func TestMetricsInterceptor_Stream(t *testing.T) {
+	i := NewMetricsInterceptor(prometheus.NewRegistry())
+	info := &grpc.StreamServerInfo{FullMethod: configServicePrefix + "WatchConfig", IsServerStream: true}
+	err := i.Stream(nil, &testServerStream{}, info, func(_ interface{}, stream grpc.ServerStream) error {
+		for n := 0; n < 3; n++ {
+			if err := stream.SendMsg(&proto.Config{}); err != nil {
+				return err
+			}
+		}
+		return status.Error(codes.Canceled, "canceled")
+	})
+	require.Equal(t, codes.Canceled, status.Code(err))
+
+	require.Equal(t, 3.0, testutil.ToFloat64(i.sent.WithLabelValues("server_stream", "tutorial.ConfigService", "WatchConfig")))
+	require.Equal(t, 1.0, testutil.ToFloat64(i.handled.WithLabelValues("server_stream", "tutorial.ConfigService", "WatchConfig", "Canceled")))
+}
@@ -0,0 +1,100 @@
+// Package lifecycle runs the servers of the service until it is asked to stop or one of them fails, and then
+// stops them and releases their resources in order within a shutdown timeout.
+package lifecycle
+
+import (
+	"context"
+	"distributedConfig/pkg/logger"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// StopFunc stops a server or releases a resource. It must return once ctx is done, even if it did not finish.
+type StopFunc func(ctx context.Context) error
+
+type server struct {
+	name  string
+	serve func() error
+}
+
+type hook struct {
+	name string
+	stop StopFunc
+}
+
+// Manager stops everything that was registered with it in the reverse order of registration, like deferred calls:
+// servers registered after the resources they use are stopped before those resources are released.
+type Manager struct {
+	l       logger.Logger
+	timeout time.Duration
+	servers []server
+	hooks   []hook
+}
+
+func New(l logger.Logger, timeout time.Duration) *Manager {
+	return &Manager{l: l, timeout: timeout}
+}
+
+// Serve registers a server, which is started by Run. serve must block until the server fails or is stopped
+// by stop, in which case it returns nil.
+func (m *Manager) Serve(name string, serve func() error, stop StopFunc) {
+	m.servers = append(m.servers, server{name: name, serve: serve})
+	m.OnStop(name, stop)
+}
+
+// OnStop registers a function that is called on shutdown, e.g. to close the database.
+func (m *Manager) OnStop(name string, stop StopFunc) {
+	m.hooks = append(m.hooks, hook{name: name, stop: stop})
+}
+
+// Run starts the servers and blocks until ctx is done or a server fails. Then it calls the stop functions,
+// which share the shutdown timeout, and waits for the servers to return. It returns the error of the failed
+// server, or else the first error of a stop function.
+func (m *Manager) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	failures := make(chan error, len(m.servers))
+	for _, s := range m.servers {
+		wg.Add(1)
+		go func(s server) {
+			defer wg.Done()
+			if err := s.serve(); err != nil {
+				failures <- fmt.Errorf("%s failed: %w", s.name, err)
+			}
+		}(s)
+	}
+
+	var err error
+	select {
+	case <-ctx.Done():
+		m.l.Info("Shutting down")
+	case err = <-failures:
+		m.l.Error("Shutting down: %s", err)
+	}
+
+	stopCtx, cancel := context.WithTimeout(context.Background(), m.timeout)
+	defer cancel()
+	for i := len(m.hooks) - 1; i >= 0; i-- {
+		h := m.hooks[i]
+		if stopErr := h.stop(stopCtx); stopErr != nil {
+			m.l.Error("Unable to stop %s: %s", h.name, stopErr)
+			if err == nil {
+				err = fmt.Errorf("unable to stop %s: %w", h.name, stopErr)
+			}
+			continue
+		}
+		m.l.Info("Stopped %s", h.name)
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-stopCtx.Done():
+		m.l.Error("Servers did not stop within %s", m.timeout)
+	}
+	return err
+}
@@ -0,0 +1,99 @@
+package lifecycle
+
+import (
+	"context"
+	"distributedConfig/pkg/logger"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer serves until it is stopped or fails.
+type testServer struct {
+	stopped chan struct{}
+	failed  chan error
+	once    sync.Once
+}
+
+func newTestServer() *testServer {
+	return &testServer{stopped: make(chan struct{}), failed: make(chan error, 1)}
+}
+
+func (s *testServer) serve() error {
+	select {
+	case <-s.stopped:
+		return nil
+	case err := <-s.failed:
+		return err
+	}
+}
+
+func (s *testServer) stop(context.Context) error {
+	s.once.Do(func() {
+		close(s.stopped)
+	})
+	return nil
+}
+
+func TestManager_StopsInReverseOrder(t *testing.T) {
+	m := New(*logger.New("error"), time.Second)
+	var stops []string
+	record := func(name string, stop StopFunc) StopFunc {
+		return func(ctx context.Context) error {
+			stops = append(stops, name)
+			return stop(ctx)
+		}
+	}
+	noop := func(context.Context) error { return nil }
+	grpcServer, gateway := newTestServer(), newTestServer()
+	m.OnStop("database", record("database", noop))
+	m.Serve("gRPC server", grpcServer.serve, record("gRPC server", grpcServer.stop))
+	m.Serve("gateway", gateway.serve, record("gateway", gateway.stop))
+	m.OnStop("watchers", record("watchers", noop))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, m.Run(ctx))
+	require.Equal(t, []string{"watchers", "gateway", "gRPC server", "database"}, stops)
+}
+
+func TestManager_ServerFailure(t *testing.T) {
+	m := New(*logger.New("error"), time.Second)
+	failing, other := newTestServer(), newTestServer()
+	m.Serve("failing", failing.serve, failing.stop)
+	m.Serve("other", other.serve, other.stop)
+	closed := false
+	m.OnStop("database", func(context.Context) error {
+		closed = true
+		return nil
+	})
+
+	errFailed := errors.New("address already in use")
+	failing.failed <- errFailed
+	err := m.Run(context.Background())
+	require.ErrorIs(t, err, errFailed)
+	require.True(t, closed)
+}
+
+func TestManager_StopTimeout(t *testing.T) {
+	m := New(*logger.New("error"), 10*time.Millisecond)
+	stuck := newTestServer()
+	m.Serve("stuck", stuck.serve, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	closed := false
+	m.OnStop("database", func(context.Context) error {
+		closed = true
+		return nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := m.Run(ctx)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.True(t, closed)
+	_ = stuck.stop(context.Background())
+}
@@ -0,0 +1,163 @@
+// Package metrics collects the metrics of the config service and exposes them in the Prometheus format.
+package metrics
+
+import (
+	"database/sql"
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository"
+	"distributedConfig/internal/repository/cache_repository"
+	"distributedConfig/internal/watcher"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const namespace = "config_service"
+
+// Metrics is the registry of the metrics of the service, which includes the metrics of the Go runtime
+// and of the process.
+type Metrics struct {
+	registry *prometheus.Registry
+	reads    *prometheus.CounterVec
+}
+
+func New() *Metrics {
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	m := &Metrics{
+		registry: registry,
+		reads: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "config_reads_total",
+			Help:      "Versions of configs read by the users of the configs.",
+		}, []string{"namespace", "name"}),
+	}
+	registry.MustRegister(m.reads)
+	return m
+}
+
+// Registry returns the registry that other components register their metrics with.
+func (m *Metrics) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler serves the metrics to Prometheus.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterDB exposes the statistics of the connection pool of the database.
+func (m *Metrics) RegisterDB(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, "configs"))
+}
+
+// RegisterRepository exposes the number of configs and versions of every namespace, which are counted
+// by the repository on every scrape.
+func (m *Metrics) RegisterRepository(repo repository.ConfigRepository) {
+	m.registry.MustRegister(&countsCollector{repo: repo})
+}
+
+// RegisterWatchers exposes the number of watchers of every watched config.
+func (m *Metrics) RegisterWatchers(hub *watcher.Hub) {
+	m.registry.MustRegister(&watchersCollector{hub: hub})
+}
+
+// RegisterCache exposes the hits, misses, evictions and size of the cache of configs.
+func (m *Metrics) RegisterCache(cache *cache_repository.ConfigRepository) {
+	m.registry.MustRegister(&cacheCollector{cache: cache})
+}
+
+// CountReads counts the versions of the config read by its users. It implements usecase.ReadCounter.
+func (m *Metrics) CountReads(namespace, name string, versions int) {
+	m.reads.WithLabelValues(namespace, name).Add(float64(versions))
+}
+
+// ForgetReads removes the count of the deleted config, so that deleted configs do not pile up in the metrics.
+// It implements usecase.ReadCounter.
+func (m *Metrics) ForgetReads(namespace, name string) {
+	m.reads.DeleteLabelValues(namespace, name)
+}
+
+var (
+	configsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "configs"),
+		"Configs stored in the namespace.", []string{"namespace"}, nil)
+	versionsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "config_versions"),
+		"Versions of configs stored in the namespace.", []string{"namespace"}, nil)
+	watchersDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "", "watchers"),
+		"Watchers of the config connected to this replica.", []string{"namespace", "name"}, nil)
+	cacheRequestsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "requests_total"),
+		"Reads of relevant configs by whether they were served from memory.", []string{"result"}, nil)
+	cacheRemovalsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "removals_total"),
+		"Configs removed from memory by the reason of removal.", []string{"reason"}, nil)
+	cacheEntriesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "entries"),
+		"Configs kept in memory.", nil, nil)
+	cacheBytesDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "bytes"),
+		"Total length of the names, keys and values of the configs kept in memory.", nil, nil)
+	cachePendingLastUsedDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "pending_last_used"),
+		"Versions served from memory whose last use is not recorded yet.", nil, nil)
+	cacheLastUsedErrorsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "cache", "last_used_errors_total"),
+		"Failures to record the last use of versions served from memory.", nil, nil)
+)
+
+type countsCollector struct {
+	repo repository.ConfigRepository
+}
+
+func (c *countsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- configsDesc
+	ch <- versionsDesc
+}
+
+func (c *countsCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.repo.GetConfigCounts()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(configsDesc, err)
+		return
+	}
+	for _, count := range counts {
+		ch <- prometheus.MustNewConstMetric(configsDesc, prometheus.GaugeValue, float64(count.Configs), count.Namespace)
+		ch <- prometheus.MustNewConstMetric(versionsDesc, prometheus.GaugeValue, float64(count.Versions), count.Namespace)
+	}
+}
+
+type watchersCollector struct {
+	hub *watcher.Hub
+}
+
+func (c *watchersCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- watchersDesc
+}
+
+func (c *watchersCollector) Collect(ch chan<- prometheus.Metric) {
+	for qualifiedName, count := range c.hub.SubscriberCounts() {
+		namespace, name := entity.SplitQualifiedName(qualifiedName)
+		ch <- prometheus.MustNewConstMetric(watchersDesc, prometheus.GaugeValue, float64(count), namespace, name)
+	}
+}
+
+type cacheCollector struct {
+	cache *cache_repository.ConfigRepository
+}
+
+func (c *cacheCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cacheRequestsDesc
+	ch <- cacheRemovalsDesc
+	ch <- cacheEntriesDesc
+	ch <- cacheBytesDesc
+	ch <- cachePendingLastUsedDesc
+	ch <- cacheLastUsedErrorsDesc
+}
+
+func (c *cacheCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := c.cache.Stats()
+	ch <- prometheus.MustNewConstMetric(cacheRequestsDesc, prometheus.CounterValue, float64(stats.Hits), "hit")
+	ch <- prometheus.MustNewConstMetric(cacheRequestsDesc, prometheus.CounterValue, float64(stats.Misses), "miss")
+	ch <- prometheus.MustNewConstMetric(cacheRemovalsDesc, prometheus.CounterValue, float64(stats.Evictions), "evicted")
+	ch <- prometheus.MustNewConstMetric(cacheRemovalsDesc, prometheus.CounterValue, float64(stats.Expirations), "expired")
+	ch <- prometheus.MustNewConstMetric(cacheRemovalsDesc, prometheus.CounterValue, float64(stats.Invalidations), "invalidated")
+	ch <- prometheus.MustNewConstMetric(cacheEntriesDesc, prometheus.GaugeValue, float64(stats.Entries))
+	ch <- prometheus.MustNewConstMetric(cacheBytesDesc, prometheus.GaugeValue, float64(stats.Bytes))
+	ch <- prometheus.MustNewConstMetric(cachePendingLastUsedDesc, prometheus.GaugeValue, float64(stats.PendingLastUsed))
+	ch <- prometheus.MustNewConstMetric(cacheLastUsedErrorsDesc, prometheus.CounterValue, float64(stats.LastUsedErrors))
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"context"
+	cfg "distributedConfig/config"
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository/cache_repository"
+	"distributedConfig/internal/repository/memory_repository"
+	"distributedConfig/internal/usecase"
+	"distributedConfig/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	m := New()
+	repo, audit := memory_repository.NewAuditedConfigRepository()
+	m.RegisterRepository(repo)
+	configUseCase := usecase.NewConfigUseCase(*logger.New("error"), repo, audit, memory_repository.NewConfigNotifier(),
+		&cfg.Config{})
+	configUseCase.CountReads(m)
+	m.RegisterWatchers(configUseCase.Watchers())
+
+	for _, config := range []*entity.Config{
+		{Namespace: entity.DefaultNamespace, Name: "payments", Version: 1, Data: map[string]string{"k1": "v1"}},
+		{Namespace: entity.DefaultNamespace, Name: "payments", Version: 2, Data: map[string]string{"k1": "v2"}},
+		{Namespace: "staging", Name: "payments", Version: 1, Data: map[string]string{"k1": "v1"}},
+	} {
+		require.NoError(t, repo.CreateConfig(config, nil))
+	}
+	_, err := configUseCase.GetConfig(entity.DefaultNamespace, "payments")
+	require.NoError(t, err)
+	_, err = configUseCase.GetConfigs(entity.DefaultNamespace, "payments")
+	require.NoError(t, err)
+	_, err = configUseCase.GetConfig(entity.DefaultNamespace, "billing")
+	require.Error(t, err)
+	_, err = configUseCase.ListConfigVersions(&entity.VersionFilter{Namespace: entity.DefaultNamespace, Name: "payments",
+		WithoutData: true})
+	require.NoError(t, err)
+	_, err = configUseCase.DiffConfigVersions(entity.DefaultNamespace, "payments", 1, 2)
+	require.NoError(t, err)
+	// Watching loads the config for the watcher, which is not a read by a user of the config.
+	subscription, err := configUseCase.WatchConfig("staging", "payments")
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	body := scrape(t, m)
+	require.Contains(t, body, `config_service_configs{namespace="default"} 1`)
+	require.Contains(t, body, `config_service_config_versions{namespace="default"} 2`)
+	require.Contains(t, body, `config_service_configs{namespace="staging"} 1`)
+	require.Contains(t, body, `config_service_config_reads_total{name="payments",namespace="default"} 3`)
+	require.NotContains(t, body, `config_service_config_reads_total{name="payments",namespace="staging"}`)
+	require.NotContains(t, body, `name="billing"`)
+	require.Contains(t, body, `config_service_watchers{name="payments",namespace="staging"} 1`)
+	require.Contains(t, body, "go_goroutines")
+
+	require.NoError(t, configUseCase.DeleteConfig(context.Background(), entity.DefaultNamespace, "payments"))
+	require.NotContains(t, scrape(t, m), `config_service_config_reads_total{name="payments",namespace="default"}`)
+}
+
+func TestMetrics_Cache(t *testing.T) {
+	m := New()
+	cache := cache_repository.NewConfigRepository(memory_repository.NewConfigRepository(),
+		cache_repository.Options{MaxEntries: 10}, *logger.New("error"))
+	m.RegisterCache(cache)
+	require.NoError(t, cache.CreateConfig(&entity.Config{Namespace: entity.DefaultNamespace, Name: "payments",
+		Version: 1, Data: map[string]string{"k1": "v1"}}, nil))
+	for i := 0; i < 3; i++ {
+		_, err := cache.GetConfig(entity.DefaultNamespace, "payments")
+		require.NoError(t, err)
+	}
+
+	body := scrape(t, m)
+	require.Contains(t, body, `config_service_cache_requests_total{result="hit"} 2`)
+	require.Contains(t, body, `config_service_cache_requests_total{result="miss"} 1`)
+	require.Contains(t, body, `config_service_cache_removals_total{reason="evicted"} 0`)
+	require.Contains(t, body, "config_service_cache_entries 1")
+	require.Contains(t, body, "config_service_cache_bytes 19")
+	require.Contains(t, body, "config_service_cache_pending_last_used 1")
+}
@@ -0,0 +1,13 @@
+package internal
+
+import (
+	"distributedConfig/internal/metrics"
+	"net/http"
+)
+
+// NewMetricsServer creates the server of /metrics on the metrics port, which keeps them apart from the public REST API.
+func NewMetricsServer(m *metrics.Metrics) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	return &http.Server{Handler: mux}
+}
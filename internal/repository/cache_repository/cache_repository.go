@@ -0,0 +1,263 @@
+// Package cache_repository serves relevant versions of configs from memory in front of another repository.
+package cache_repository
+
+import (
+	"context"
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository"
+	"distributedConfig/internal/watcher"
+	"distributedConfig/pkg/logger"
+	"sync"
+	"time"
+)
+
+// DefaultLastUsedInterval is how often the last use of configs served from memory is recorded, unless it is set otherwise.
+const DefaultLastUsedInterval = 10 * time.Second
+
+// Options limit the configs kept in memory. MaxEntries must be positive, while zero MaxBytes and TTL are unlimited.
+type Options struct {
+	// MaxEntries is the number of configs kept in memory.
+	MaxEntries int
+	// MaxBytes bounds the total length of the names, keys and values of the configs kept in memory.
+	MaxBytes int
+	// TTL bounds how long a config is served from memory, and so how stale it is if a change is not notified.
+	TTL time.Duration
+	// LastUsedInterval is how often the last use of configs served from memory is recorded in the repository.
+	LastUsedInterval time.Duration
+}
+
+// Stats are the counters of the cache since it was created and its current size.
+type Stats struct {
+	Hits          uint64
+	Misses        uint64
+	Evictions     uint64
+	Expirations   uint64
+	Invalidations uint64
+	// LastUsedErrors counts the failures to record the last use of configs, which are retried.
+	LastUsedErrors uint64
+	Entries        int
+	Bytes          int
+	// PendingLastUsed is the number of versions whose last use is not recorded yet.
+	PendingLastUsed int
+}
+
+// versionKey identifies a version of a config whose last use is pending.
+type versionKey struct {
+	namespace string
+	name      string
+	version   int64
+}
+
+// ConfigRepository serves the relevant versions of configs from memory and reads everything else from
+// the repository it wraps. Cached configs are invalidated by every change made through it and, with Notifier,
+// by the changes notified by other replicas. Configs served from memory do not update their last use on every
+// read: their uses are recorded by Run in a single call every LastUsedInterval.
+type ConfigRepository struct {
+	repository.ConfigRepository
+	l        logger.Logger
+	interval time.Duration
+
+	mu      sync.Mutex
+	entries *lru
+	// generation changes on every invalidation, so that a config read before it is not cached after it.
+	generation uint64
+	uses       map[versionKey]time.Time
+	stats      Stats
+}
+
+func NewConfigRepository(repo repository.ConfigRepository, options Options, l logger.Logger) *ConfigRepository {
+	interval := options.LastUsedInterval
+	if interval <= 0 {
+		interval = DefaultLastUsedInterval
+	}
+	return &ConfigRepository{
+		ConfigRepository: repo,
+		l:                l,
+		interval:         interval,
+		entries:          newLRU(options.MaxEntries, options.MaxBytes, options.TTL),
+		uses:             make(map[versionKey]time.Time),
+	}
+}
+
+func (r *ConfigRepository) GetConfig(namespace, name string) (*entity.Config, error) {
+	qualifiedName := entity.QualifiedName(namespace, name)
+	now := time.Now()
+	r.mu.Lock()
+	if config, ok := r.entries.get(qualifiedName, now); ok {
+		r.stats.Hits++
+		r.uses[versionKey{namespace: namespace, name: name, version: config.Version}] = now
+		r.mu.Unlock()
+		return config.Clone(), nil
+	}
+	r.stats.Misses++
+	generation := r.generation
+	r.mu.Unlock()
+
+	config, err := r.ConfigRepository.GetConfig(namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generation == generation {
+		r.entries.add(qualifiedName, config.Clone(), now)
+	}
+	return config, nil
+}
+
+func (r *ConfigRepository) CreateConfig(config *entity.Config, audit *entity.AuditEvent) error {
+	defer r.invalidate(entity.QualifiedName(config.Namespace, config.Name))
+	return r.ConfigRepository.CreateConfig(config, audit)
+}
+
+func (r *ConfigRepository) DeleteConfig(namespace, name string, audit *entity.AuditEvent) error {
+	defer r.invalidate(entity.QualifiedName(namespace, name))
+	return r.ConfigRepository.DeleteConfig(namespace, name, audit)
+}
+
+func (r *ConfigRepository) DeleteConfigVersion(namespace, name string, version int64, audit *entity.AuditEvent) error {
+	defer r.invalidate(entity.QualifiedName(namespace, name))
+	return r.ConfigRepository.DeleteConfigVersion(namespace, name, version, audit)
+}
+
+func (r *ConfigRepository) UpdateConfig(config *entity.Config, expectedVersion int64, audit *entity.AuditEvent) error {
+	defer r.invalidate(entity.QualifiedName(config.Namespace, config.Name))
+	return r.ConfigRepository.UpdateConfig(config, expectedVersion, audit)
+}
+
+func (r *ConfigRepository) PatchConfig(patch *entity.ConfigPatch, expectedVersion int64, audit *entity.AuditEvent) (*entity.Config, error) {
+	defer r.invalidate(entity.QualifiedName(patch.Namespace, patch.Name))
+	return r.ConfigRepository.PatchConfig(patch, expectedVersion, audit)
+}
+
+func (r *ConfigRepository) PromoteConfig(promotion *entity.ConfigPromotion, audit *entity.AuditEvent) (*entity.Config, error) {
+	defer r.invalidate(entity.QualifiedName(promotion.TargetNamespace, promotion.Name))
+	return r.ConfigRepository.PromoteConfig(promotion, audit)
+}
+
+func (r *ConfigRepository) SetRelevantConfig(namespace, name string, version int64, audit *entity.AuditEvent) (*entity.Config, error) {
+	defer r.invalidate(entity.QualifiedName(namespace, name))
+	return r.ConfigRepository.SetRelevantConfig(namespace, name, version, audit)
+}
+
+// GetRelevantLastUsed records the pending uses first, so that a config served from memory is seen as used.
+func (r *ConfigRepository) GetRelevantLastUsed(namespace, name string) (time.Time, error) {
+	if err := r.recordUses(); err != nil {
+		return time.Time{}, err
+	}
+	return r.ConfigRepository.GetRelevantLastUsed(namespace, name)
+}
+
+// GetLastUsedByVersion records the pending uses first, so that a config served from memory is seen as used.
+func (r *ConfigRepository) GetLastUsedByVersion(namespace, name string, version int64) (time.Time, error) {
+	if err := r.recordUses(); err != nil {
+		return time.Time{}, err
+	}
+	return r.ConfigRepository.GetLastUsedByVersion(namespace, name, version)
+}
+
+// Run records the uses of configs served from memory every LastUsedInterval until ctx is done,
+// and once more before it returns.
+func (r *ConfigRepository) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			if err := r.recordUses(); err != nil {
+				r.l.Error("Unable to record last use of configs: %s", err)
+			}
+			return
+		case <-ticker.C:
+			if err := r.recordUses(); err != nil {
+				r.l.Error("Unable to record last use of configs: %s", err)
+			}
+		}
+	}
+}
+
+// Notifier wraps the notifier of the service, so that the changes it delivers invalidate the cache before
+// watchers reload the changed configs.
+func (r *ConfigRepository) Notifier(notifier repository.ConfigNotifier) repository.ConfigNotifier {
+	return &invalidatingNotifier{ConfigNotifier: notifier, cache: r}
+}
+
+func (r *ConfigRepository) Stats() Stats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats := r.stats
+	stats.Evictions = uint64(r.entries.evicted)
+	stats.Expirations = uint64(r.entries.expired)
+	stats.Entries = r.entries.len()
+	stats.Bytes = r.entries.bytes
+	stats.PendingLastUsed = len(r.uses)
+	return stats
+}
+
+func (r *ConfigRepository) invalidate(qualifiedName string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	if r.entries.remove(qualifiedName) {
+		r.stats.Invalidations++
+	}
+}
+
+// invalidateAll is used when changes could have been missed, e.g. while the notifier was reconnecting.
+func (r *ConfigRepository) invalidateAll() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	r.stats.Invalidations += uint64(r.entries.clear())
+}
+
+// recordUses records the pending uses in a single call. If it fails, they are kept to be recorded later.
+func (r *ConfigRepository) recordUses() error {
+	r.mu.Lock()
+	pending := r.uses
+	r.uses = make(map[versionKey]time.Time)
+	r.mu.Unlock()
+	if len(pending) == 0 {
+		return nil
+	}
+	uses := make([]*entity.LastUse, 0, len(pending))
+	for key, used := range pending {
+		uses = append(uses, &entity.LastUse{Namespace: key.namespace, Name: key.name, Version: key.version, Time: used})
+	}
+	err := r.ConfigRepository.SetLastUsed(uses)
+	if err != nil {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.stats.LastUsedErrors++
+		for key, used := range pending {
+			if newer, ok := r.uses[key]; !ok || used.After(newer) {
+				r.uses[key] = used
+			}
+		}
+	}
+	return err
+}
+
+type invalidatingNotifier struct {
+	repository.ConfigNotifier
+	cache *ConfigRepository
+}
+
+func (n *invalidatingNotifier) Listen(ctx context.Context, publisher watcher.Publisher) error {
+	return n.ConfigNotifier.Listen(ctx, &invalidatingPublisher{Publisher: publisher, cache: n.cache})
+}
+
+type invalidatingPublisher struct {
+	watcher.Publisher
+	cache *ConfigRepository
+}
+
+func (p *invalidatingPublisher) Publish(name string) {
+	p.cache.invalidate(name)
+	p.Publisher.Publish(name)
+}
+
+func (p *invalidatingPublisher) PublishAll() {
+	p.cache.invalidateAll()
+	p.Publisher.PublishAll()
+}
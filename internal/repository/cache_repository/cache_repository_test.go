@@ -0,0 +1,309 @@
+package cache_repository
+
+import (
+	"context"
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository"
+	"distributedConfig/internal/repository/memory_repository"
+	"distributedConfig/internal/repository/repositorytest"
+	"distributedConfig/internal/watcher"
+	"distributedConfig/pkg/logger"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+// countingRepository counts the reads of relevant configs and can hold them until released.
+type countingRepository struct {
+	repository.ConfigRepository
+	mu       sync.Mutex
+	reads    int
+	hold     chan struct{}
+	uses     [][]*entity.LastUse
+	usesErr  error
+	released chan struct{}
+}
+
+func newCountingRepository() *countingRepository {
+	return &countingRepository{ConfigRepository: memory_repository.NewConfigRepository()}
+}
+
+func (r *countingRepository) GetConfig(namespace, name string) (*entity.Config, error) {
+	r.mu.Lock()
+	r.reads++
+	hold := r.hold
+	r.mu.Unlock()
+	config, err := r.ConfigRepository.GetConfig(namespace, name)
+	if hold != nil {
+		r.released <- struct{}{}
+		<-hold
+	}
+	return config, err
+}
+
+func (r *countingRepository) SetLastUsed(uses []*entity.LastUse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.usesErr != nil {
+		return r.usesErr
+	}
+	r.uses = append(r.uses, uses)
+	return r.ConfigRepository.SetLastUsed(uses)
+}
+
+func (r *countingRepository) readCount() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.reads
+}
+
+// publisherNotifier hands the publisher it listens with to the test, which publishes changes with it.
+type publisherNotifier struct {
+	publisher watcher.Publisher
+}
+
+func (n *publisherNotifier) NotifyConfigChanged(name string) error {
+	n.publisher.Publish(name)
+	return nil
+}
+
+func (n *publisherNotifier) Listen(ctx context.Context, publisher watcher.Publisher) error {
+	n.publisher = publisher
+	return nil
+}
+
+// recordingPublisher records what it is told.
+type recordingPublisher struct {
+	published []string
+}
+
+func (p *recordingPublisher) Publish(name string) {
+	p.published = append(p.published, name)
+}
+
+func (p *recordingPublisher) PublishAll() {
+	p.published = append(p.published, "*")
+}
+
+func newCache(repo repository.ConfigRepository, options Options) *ConfigRepository {
+	if options.MaxEntries == 0 {
+		options.MaxEntries = 100
+	}
+	return NewConfigRepository(repo, options, *logger.New("error"))
+}
+
+func createConfig(t *testing.T, repo repository.ConfigRepository, name, value string) {
+	t.Helper()
+	require.NoError(t, repo.CreateConfig(&entity.Config{Namespace: entity.DefaultNamespace, Name: name, Version: 1,
+		Data: map[string]string{"key": value}}, nil))
+}
+
+func requireValue(t *testing.T, repo repository.ConfigRepository, name, value string) {
+	t.Helper()
+	config, err := repo.GetConfig(entity.DefaultNamespace, name)
+	require.NoError(t, err)
+	require.Equal(t, value, config.Data["key"])
+}
+
+func TestConfigRepository(t *testing.T) {
+	repositorytest.RunConfigRepositoryTests(t, func(t *testing.T) repository.ConfigRepository {
+		return newCache(memory_repository.NewConfigRepository(), Options{TTL: time.Minute})
+	})
+}
+
+func TestConfigRepository_GetConfig(t *testing.T) {
+	inner := newCountingRepository()
+	cache := newCache(inner, Options{})
+	createConfig(t, cache, "test", "v1")
+
+	config, err := cache.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	config.Data["key"] = "changed by the caller"
+	requireValue(t, cache, "test", "v1")
+	requireValue(t, cache, "test", "v1")
+	require.Equal(t, 1, inner.readCount())
+
+	_, err = cache.GetConfig(entity.DefaultNamespace, "unknown")
+	require.Error(t, err)
+	_, err = cache.GetConfig(entity.DefaultNamespace, "unknown")
+	require.Error(t, err)
+	require.Equal(t, 3, inner.readCount(), "missing configs are not cached")
+
+	stats := cache.Stats()
+	require.Equal(t, uint64(2), stats.Hits)
+	require.Equal(t, uint64(3), stats.Misses)
+	require.Equal(t, 1, stats.Entries)
+	require.Equal(t, len(entity.DefaultNamespace+"test"+"key"+"v1"), stats.Bytes)
+}
+
+func TestConfigRepository_InvalidatedByWrites(t *testing.T) {
+	cache := newCache(newCountingRepository(), Options{})
+	createConfig(t, cache, "test", "v1")
+	requireValue(t, cache, "test", "v1")
+
+	require.NoError(t, cache.UpdateConfig(&entity.Config{Namespace: entity.DefaultNamespace, Name: "test",
+		Data: map[string]string{"key": "v2"}}, 0, nil))
+	requireValue(t, cache, "test", "v2")
+
+	_, err := cache.PatchConfig(&entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test",
+		Set: map[string]string{"key": "v3"}}, 0, nil)
+	require.NoError(t, err)
+	requireValue(t, cache, "test", "v3")
+
+	_, err = cache.SetRelevantConfig(entity.DefaultNamespace, "test", 1, nil)
+	require.NoError(t, err)
+	requireValue(t, cache, "test", "v1")
+
+	require.NoError(t, cache.DeleteConfigVersion(entity.DefaultNamespace, "test", 1, nil))
+	requireValue(t, cache, "test", "v3")
+
+	_, err = cache.PromoteConfig(&entity.ConfigPromotion{SourceNamespace: entity.DefaultNamespace,
+		TargetNamespace: "staging", Name: "test", Version: 3}, nil)
+	require.NoError(t, err)
+	config, err := cache.GetConfig("staging", "test")
+	require.NoError(t, err)
+	require.Equal(t, "v3", config.Data["key"])
+	_, err = cache.PromoteConfig(&entity.ConfigPromotion{SourceNamespace: entity.DefaultNamespace,
+		TargetNamespace: "staging", Name: "test", Version: 2}, nil)
+	require.NoError(t, err)
+	config, err = cache.GetConfig("staging", "test")
+	require.NoError(t, err)
+	require.Equal(t, "v2", config.Data["key"])
+
+	require.NoError(t, cache.DeleteConfig(entity.DefaultNamespace, "test", nil))
+	_, err = cache.GetConfig(entity.DefaultNamespace, "test")
+	require.Error(t, err)
+	require.Equal(t, uint64(6), cache.Stats().Invalidations)
+}
+
+func TestConfigRepository_InvalidatedByNotifications(t *testing.T) {
+	inner := newCountingRepository()
+	cache := newCache(inner, Options{})
+	notifier := &publisherNotifier{}
+	publisher := &recordingPublisher{}
+	require.NoError(t, cache.Notifier(notifier).Listen(context.Background(), publisher))
+	createConfig(t, cache, "test", "v1")
+	createConfig(t, cache, "other", "v1")
+	requireValue(t, cache, "test", "v1")
+	requireValue(t, cache, "other", "v1")
+
+	// Changes made by another replica are only seen once they are notified.
+	require.NoError(t, inner.UpdateConfig(&entity.Config{Namespace: entity.DefaultNamespace, Name: "test",
+		Data: map[string]string{"key": "v2"}}, 0, nil))
+	require.NoError(t, inner.UpdateConfig(&entity.Config{Namespace: entity.DefaultNamespace, Name: "other",
+		Data: map[string]string{"key": "v2"}}, 0, nil))
+	requireValue(t, cache, "test", "v1")
+	require.NoError(t, notifier.NotifyConfigChanged(entity.QualifiedName(entity.DefaultNamespace, "test")))
+	requireValue(t, cache, "test", "v2")
+	requireValue(t, cache, "other", "v1")
+
+	notifier.publisher.PublishAll()
+	requireValue(t, cache, "other", "v2")
+	require.Equal(t, []string{entity.QualifiedName(entity.DefaultNamespace, "test"), "*"}, publisher.published)
+}
+
+func TestConfigRepository_NotCachedAfterInvalidation(t *testing.T) {
+	inner := newCountingRepository()
+	cache := newCache(inner, Options{})
+	createConfig(t, cache, "test", "v1")
+	inner.hold = make(chan struct{})
+	inner.released = make(chan struct{})
+
+	read := make(chan *entity.Config)
+	go func() {
+		config, _ := cache.GetConfig(entity.DefaultNamespace, "test")
+		read <- config
+	}()
+	<-inner.released
+	// The config changes while the version read before the change is on its way to the cache.
+	require.NoError(t, inner.UpdateConfig(&entity.Config{Namespace: entity.DefaultNamespace, Name: "test",
+		Data: map[string]string{"key": "v2"}}, 0, nil))
+	cache.invalidate(entity.QualifiedName(entity.DefaultNamespace, "test"))
+	close(inner.hold)
+	require.Equal(t, "v1", (<-read).Data["key"])
+
+	inner.hold = nil
+	requireValue(t, cache, "test", "v2")
+}
+
+func TestConfigRepository_TTL(t *testing.T) {
+	inner := newCountingRepository()
+	cache := newCache(inner, Options{TTL: 10 * time.Millisecond})
+	createConfig(t, cache, "test", "v1")
+	requireValue(t, cache, "test", "v1")
+	requireValue(t, cache, "test", "v1")
+	time.Sleep(20 * time.Millisecond)
+	requireValue(t, cache, "test", "v1")
+	require.Equal(t, 2, inner.readCount())
+	require.Equal(t, uint64(1), cache.Stats().Expirations)
+}
+
+func TestConfigRepository_MaxEntries(t *testing.T) {
+	inner := newCountingRepository()
+	cache := newCache(inner, Options{MaxEntries: 1})
+	createConfig(t, cache, "a", "v1")
+	createConfig(t, cache, "b", "v1")
+	requireValue(t, cache, "a", "v1")
+	requireValue(t, cache, "b", "v1")
+	requireValue(t, cache, "a", "v1")
+	require.Equal(t, 3, inner.readCount())
+	require.Equal(t, uint64(2), cache.Stats().Evictions)
+}
+
+func TestConfigRepository_LastUsed(t *testing.T) {
+	inner := newCountingRepository()
+	cache := newCache(inner, Options{})
+	createConfig(t, cache, "test", "v1")
+	requireValue(t, cache, "test", "v1")
+	before, err := inner.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	requireValue(t, cache, "test", "v1")
+	requireValue(t, cache, "test", "v1")
+	unchanged, err := inner.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, before, unchanged, "reads from memory are not written through")
+	require.Equal(t, 1, cache.Stats().PendingLastUsed)
+
+	inner.usesErr = errors.New("connection reset")
+	_, err = cache.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.Error(t, err)
+	require.Equal(t, uint64(1), cache.Stats().LastUsedErrors)
+	require.Equal(t, 1, cache.Stats().PendingLastUsed, "uses that could not be recorded are kept")
+
+	inner.usesErr = nil
+	after, err := cache.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.True(t, after.After(before))
+	require.Equal(t, 0, cache.Stats().PendingLastUsed)
+	require.Len(t, inner.uses, 1)
+	require.Len(t, inner.uses[0], 1)
+}
+
+func TestConfigRepository_Run(t *testing.T) {
+	inner := newCountingRepository()
+	cache := newCache(inner, Options{LastUsedInterval: 10 * time.Millisecond})
+	createConfig(t, cache, "a", "v1")
+	createConfig(t, cache, "b", "v1")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		cache.Run(ctx)
+	}()
+
+	requireValue(t, cache, "a", "v1")
+	requireValue(t, cache, "a", "v1")
+	require.Eventually(t, func() bool {
+		return cache.Stats().PendingLastUsed == 0
+	}, time.Second, 5*time.Millisecond)
+
+	requireValue(t, cache, "b", "v1")
+	requireValue(t, cache, "b", "v1")
+	cancel()
+	<-done
+	require.Equal(t, 0, cache.Stats().PendingLastUsed, "pending uses are recorded when the cache stops")
+}
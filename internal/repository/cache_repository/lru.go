@@ -0,0 +1,111 @@
+package cache_repository
+
+import (
+	"container/list"
+	"distributedConfig/internal/entity"
+	"time"
+)
+
+// lru keeps configs by their entity.QualifiedName and evicts the least recently used ones beyond its limits.
+// It is not safe for concurrent use.
+type lru struct {
+	maxEntries int
+	maxBytes   int
+	ttl        time.Duration
+
+	order   *list.List
+	entries map[string]*list.Element
+	bytes   int
+
+	evicted int
+	expired int
+}
+
+type lruEntry struct {
+	key     string
+	config  *entity.Config
+	size    int
+	expires time.Time
+}
+
+func newLRU(maxEntries, maxBytes int, ttl time.Duration) *lru {
+	return &lru{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		ttl:        ttl,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+// get returns the config unless it is missing or expired at now, in which case it is removed.
+func (c *lru) get(key string, now time.Time) (*entity.Config, bool) {
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	entry := element.Value.(*lruEntry)
+	if c.ttl > 0 && !now.Before(entry.expires) {
+		c.removeElement(element)
+		c.expired++
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+	return entry.config, true
+}
+
+// add keeps the config until it expires ttl after now. A config larger than maxBytes is not kept at all.
+func (c *lru) add(key string, config *entity.Config, now time.Time) {
+	if element, ok := c.entries[key]; ok {
+		c.removeElement(element)
+	}
+	entry := &lruEntry{key: key, config: config, size: configSize(config), expires: now.Add(c.ttl)}
+	if c.maxBytes > 0 && entry.size > c.maxBytes {
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	c.bytes += entry.size
+	for c.order.Len() > c.maxEntries || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.removeElement(c.order.Back())
+		c.evicted++
+	}
+}
+
+func (c *lru) remove(key string) bool {
+	element, ok := c.entries[key]
+	if ok {
+		c.removeElement(element)
+	}
+	return ok
+}
+
+// clear removes every config and returns their number.
+func (c *lru) clear() int {
+	removed := c.order.Len()
+	c.order.Init()
+	c.entries = make(map[string]*list.Element)
+	c.bytes = 0
+	return removed
+}
+
+func (c *lru) len() int {
+	return c.order.Len()
+}
+
+func (c *lru) removeElement(element *list.Element) {
+	entry := c.order.Remove(element).(*lruEntry)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+}
+
+// configSize approximates the memory taken by the config with the length of its strings.
+func configSize(config *entity.Config) int {
+	size := len(config.Namespace) + len(config.Name)
+	for key, value := range config.Data {
+		size += len(key) + len(value)
+	}
+	for key, valueType := range config.Types {
+		size += len(key) + len(valueType)
+	}
+	return size
+}
@@ -0,0 +1,78 @@
+package cache_repository
+
+import (
+	"distributedConfig/internal/entity"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func lruConfig(name, value string) *entity.Config {
+	return &entity.Config{Namespace: entity.DefaultNamespace, Name: name, Version: 1, Data: map[string]string{"key": value}}
+}
+
+func TestLRU_MaxEntries(t *testing.T) {
+	c := newLRU(2, 0, 0)
+	now := time.Now()
+	c.add("a", lruConfig("a", "1"), now)
+	c.add("b", lruConfig("b", "1"), now)
+	_, ok := c.get("a", now)
+	require.True(t, ok)
+	c.add("c", lruConfig("c", "1"), now)
+
+	_, ok = c.get("b", now)
+	require.False(t, ok, "the least recently used config is evicted")
+	_, ok = c.get("a", now)
+	require.True(t, ok)
+	_, ok = c.get("c", now)
+	require.True(t, ok)
+	require.Equal(t, 2, c.len())
+	require.Equal(t, 1, c.evicted)
+}
+
+func TestLRU_MaxBytes(t *testing.T) {
+	// Every config takes 7 bytes for the namespace, 1 for the name, 3 for the key and the length of its value.
+	c := newLRU(10, 30, 0)
+	now := time.Now()
+	c.add("a", lruConfig("a", "1"), now)
+	c.add("b", lruConfig("b", "1"), now)
+	require.Equal(t, 24, c.bytes)
+	c.add("c", lruConfig("c", "1"), now)
+	require.Equal(t, 2, c.len())
+	require.Equal(t, 24, c.bytes)
+	_, ok := c.get("a", now)
+	require.False(t, ok)
+
+	c.add("d", lruConfig("d", "a value longer than the limit"), now)
+	_, ok = c.get("d", now)
+	require.False(t, ok, "a config larger than the limit is not kept")
+	_, ok = c.get("c", now)
+	require.True(t, ok)
+
+	c.add("c", lruConfig("c", "12345"), now)
+	require.Equal(t, 28, c.bytes, "a replaced config is not counted twice")
+}
+
+func TestLRU_TTL(t *testing.T) {
+	c := newLRU(10, 0, time.Minute)
+	now := time.Now()
+	c.add("a", lruConfig("a", "1"), now)
+	_, ok := c.get("a", now.Add(59*time.Second))
+	require.True(t, ok)
+	_, ok = c.get("a", now.Add(time.Minute))
+	require.False(t, ok)
+	require.Equal(t, 0, c.len())
+	require.Equal(t, 1, c.expired)
+}
+
+func TestLRU_RemoveAndClear(t *testing.T) {
+	c := newLRU(10, 0, 0)
+	now := time.Now()
+	c.add("a", lruConfig("a", "1"), now)
+	c.add("b", lruConfig("b", "1"), now)
+	require.True(t, c.remove("a"))
+	require.False(t, c.remove("a"))
+	require.Equal(t, 1, c.clear())
+	require.Equal(t, 0, c.len())
+	require.Equal(t, 0, c.bytes)
+}
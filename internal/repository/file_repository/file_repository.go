@@ -0,0 +1,457 @@
+package file_repository
+
+import (
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository/memory_repository"
+	"distributedConfig/pkg/logger"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const (
+	snapshotFile = "snapshot"
+	logFile      = "log"
+)
+
+var (
+	ErrRepositoryClosed = errors.New("repository is closed")
+	ErrCorruptLog       = errors.New("log has records after a corrupt one")
+)
+
+// record holds every version, the overrides, the labels and the schemas of a single config after a change.
+// Records are idempotent: replaying a record replaces the config, and a record without versions deletes it.
+// A record with an audit event only appends the event to the audit log, while the event of a change is
+// persisted in the record of the change. Records written before namespaces were introduced have no namespace
+// and belong to the default one.
+type record struct {
+	Namespace string                      `json:"namespace,omitempty"`
+	Name      string                      `json:"name"`
+	Versions  []memory_repository.Version `json:"versions"`
+	Overrides map[string]string           `json:"overrides,omitempty"`
+	Labels    map[string]string           `json:"labels,omitempty"`
+	Schemas   []entity.ConfigSchema       `json:"schemas,omitempty"`
+	Audit     *entity.AuditEvent          `json:"audit,omitempty"`
+	Event     *entity.AuditEvent          `json:"event,omitempty"`
+}
+
+// ConfigRepository keeps configs and the audit log in memory and persists them to a data directory:
+// each change is appended to a log and fsynced before it is acknowledged, and on startup
+// the log is compacted into a snapshot. Times of last use that were not followed by a change
+// are persisted only by compaction, so they can be lost on a crash.
+type ConfigRepository struct {
+	mu      sync.RWMutex
+	l       logger.Logger
+	dir     string
+	configs *memory_repository.ConfigRepository
+	audit   *memory_repository.AuditRepository
+	log     *os.File
+	size    int64
+}
+
+// NewConfigRepository restores configs from dir, creating it if necessary, and compacts the log.
+func NewConfigRepository(dir string, l logger.Logger) (*ConfigRepository, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	r := &ConfigRepository{
+		l:       l,
+		dir:     dir,
+		configs: memory_repository.NewConfigRepository(),
+		audit:   memory_repository.NewAuditRepository(),
+	}
+	if err := r.restore(); err != nil {
+		if r.log != nil {
+			_ = r.log.Close()
+		}
+		return nil, err
+	}
+	if err := r.compact(); err != nil {
+		_ = r.log.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *ConfigRepository) restore() error {
+	snapshot, err := os.Open(filepath.Join(r.dir, snapshotFile))
+	if err == nil {
+		_, err = readRecords(snapshot, r.apply)
+		_ = snapshot.Close()
+		if err != nil {
+			return err
+		}
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	r.log, err = os.OpenFile(filepath.Join(r.dir, logFile), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return err
+	}
+	r.size, err = readRecords(r.log, r.apply)
+	if err != errCorruptRecord {
+		return err
+	}
+	// Only the tail of the log can be torn: a record is acknowledged after it is synced. A corrupt record
+	// followed by others is not discarded, as compaction would lose every acknowledged record after it.
+	torn, err := tornTail(r.log, r.size)
+	if err != nil {
+		return err
+	}
+	if !torn {
+		return fmt.Errorf("%w: %s is corrupt at offset %d, move it aside to start without the records after it",
+			ErrCorruptLog, r.log.Name(), r.size)
+	}
+	r.l.Warn("Discarding corrupt tail of %s after %d bytes", r.log.Name(), r.size)
+	return nil
+}
+
+func (r *ConfigRepository) apply(rec *record) {
+	if rec.Audit != nil {
+		r.audit.Import(rec.Audit)
+		return
+	}
+	namespace := entity.NamespaceOrDefault(rec.Namespace)
+	r.configs.Import(namespace, rec.Name, rec.Versions)
+	r.configs.ImportOverrides(namespace, rec.Name, rec.Overrides)
+	r.configs.ImportLabels(namespace, rec.Name, rec.Labels)
+	r.configs.ImportSchemas(namespace, rec.Name, rec.Schemas)
+	if rec.Event != nil {
+		r.audit.Import(rec.Event)
+	}
+}
+
+// compact writes every config and audit event to a new snapshot and empties the log.
+func (r *ConfigRepository) compact() error {
+	err := writeFileAtomically(filepath.Join(r.dir, snapshotFile), func(w io.Writer) error {
+		write := func(rec *record) error {
+			frame, err := encodeRecord(rec)
+			if err != nil {
+				return err
+			}
+			_, err = w.Write(frame)
+			return err
+		}
+		for _, qualifiedName := range r.configs.Names() {
+			namespace, name := entity.SplitQualifiedName(qualifiedName)
+			if err := write(r.configRecord(namespace, name)); err != nil {
+				return err
+			}
+		}
+		for _, event := range r.audit.Export() {
+			event := event
+			if err := write(&record{Namespace: event.Namespace, Name: event.ConfigName, Audit: &event}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := r.log.Truncate(0); err != nil {
+		return err
+	}
+	r.size = 0
+	return r.log.Sync()
+}
+
+// Close compacts the log, persisting times of last use, and closes the repository.
+func (r *ConfigRepository) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.log == nil {
+		return nil
+	}
+	err := r.compact()
+	if closeErr := r.log.Close(); err == nil {
+		err = closeErr
+	}
+	r.log = nil
+	return err
+}
+
+// mutate applies fn to the in-memory state and persists the resulting state of the config together with
+// the audit event of the change, which fn completes. If the change cannot be persisted, the in-memory state
+// is rolled back.
+func (r *ConfigRepository) mutate(namespace, name string, audit *entity.AuditEvent, fn func() error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.log == nil {
+		return ErrRepositoryClosed
+	}
+	previous := r.configRecord(namespace, name)
+	if err := fn(); err != nil {
+		return err
+	}
+	rec := r.configRecord(namespace, name)
+	if audit != nil {
+		rec.Event = r.nextAuditEvent(audit)
+	}
+	if err := r.append(rec); err != nil {
+		r.apply(previous)
+		return err
+	}
+	if audit != nil {
+		r.audit.Import(rec.Event)
+		audit.ID, audit.CreatedAt = rec.Event.ID, rec.Event.CreatedAt
+	}
+	return nil
+}
+
+// nextAuditEvent returns a copy of the event with the next ID. It must be called with r.mu held.
+func (r *ConfigRepository) nextAuditEvent(event *entity.AuditEvent) *entity.AuditEvent {
+	stored := *event
+	stored.ID = r.audit.LastID() + 1
+	stored.CreatedAt = time.Now()
+	return &stored
+}
+
+func (r *ConfigRepository) configRecord(namespace, name string) *record {
+	return &record{
+		Namespace: namespace,
+		Name:      name,
+		Versions:  r.configs.Export(namespace, name),
+		Overrides: r.configs.ExportOverrides(namespace, name),
+		Labels:    r.configs.ExportLabels(namespace, name),
+		Schemas:   r.configs.ExportSchemas(namespace, name),
+	}
+}
+
+func (r *ConfigRepository) append(rec *record) error {
+	frame, err := encodeRecord(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := r.log.WriteAt(frame, r.size); err != nil {
+		_ = r.log.Truncate(r.size)
+		return err
+	}
+	if err := r.log.Sync(); err != nil {
+		_ = r.log.Truncate(r.size)
+		return err
+	}
+	r.size += int64(len(frame))
+	return nil
+}
+
+func (r *ConfigRepository) CreateConfig(config *entity.Config, audit *entity.AuditEvent) error {
+	return r.mutate(config.Namespace, config.Name, audit, func() error {
+		return r.configs.CreateConfig(config, audit)
+	})
+}
+
+func (r *ConfigRepository) GetConfig(namespace, name string) (*entity.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetConfig(namespace, name)
+}
+
+func (r *ConfigRepository) PeekConfig(namespace, name string, version int64) (*entity.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.PeekConfig(namespace, name, version)
+}
+
+func (r *ConfigRepository) GetConfigs(namespace, name string) ([]*entity.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetConfigs(namespace, name)
+}
+
+func (r *ConfigRepository) ListConfigVersions(filter *entity.VersionFilter) ([]*entity.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.ListConfigVersions(filter)
+}
+
+func (r *ConfigRepository) ListConfigNames(filter *entity.NameFilter) ([]*entity.ConfigSummary, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.ListConfigNames(filter)
+}
+
+func (r *ConfigRepository) GetConfigByVersion(namespace, name string, version int64) (*entity.Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetConfigByVersion(namespace, name, version)
+}
+
+func (r *ConfigRepository) DeleteConfig(namespace, name string, audit *entity.AuditEvent) error {
+	return r.mutate(namespace, name, audit, func() error {
+		return r.configs.DeleteConfig(namespace, name, audit)
+	})
+}
+
+func (r *ConfigRepository) DeleteConfigVersion(namespace, name string, version int64, audit *entity.AuditEvent) error {
+	return r.mutate(namespace, name, audit, func() error {
+		return r.configs.DeleteConfigVersion(namespace, name, version, audit)
+	})
+}
+
+func (r *ConfigRepository) UpdateConfig(config *entity.Config, expectedVersion int64, audit *entity.AuditEvent) error {
+	return r.mutate(config.Namespace, config.Name, audit, func() error {
+		return r.configs.UpdateConfig(config, expectedVersion, audit)
+	})
+}
+
+func (r *ConfigRepository) PatchConfig(patch *entity.ConfigPatch, expectedVersion int64, audit *entity.AuditEvent) (*entity.Config, error) {
+	var config *entity.Config
+	err := r.mutate(patch.Namespace, patch.Name, audit, func() error {
+		var err error
+		config, err = r.configs.PatchConfig(patch, expectedVersion, audit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (r *ConfigRepository) PromoteConfig(promotion *entity.ConfigPromotion, audit *entity.AuditEvent) (*entity.Config, error) {
+	var config *entity.Config
+	err := r.mutate(promotion.TargetNamespace, promotion.Name, audit, func() error {
+		var err error
+		config, err = r.configs.PromoteConfig(promotion, audit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (r *ConfigRepository) GetConfigOverrides(namespace, name string) (map[string]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetConfigOverrides(namespace, name)
+}
+
+func (r *ConfigRepository) SetConfigLabels(labels *entity.ConfigLabels, audit *entity.AuditEvent) error {
+	return r.mutate(labels.Namespace, labels.Name, audit, func() error {
+		return r.configs.SetConfigLabels(labels, audit)
+	})
+}
+
+func (r *ConfigRepository) SetRelevantConfig(namespace, name string, version int64, audit *entity.AuditEvent) (*entity.Config, error) {
+	var config *entity.Config
+	err := r.mutate(namespace, name, audit, func() error {
+		var err error
+		config, err = r.configs.SetRelevantConfig(namespace, name, version, audit)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (r *ConfigRepository) GetRelevantLastUsed(namespace, name string) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetRelevantLastUsed(namespace, name)
+}
+
+func (r *ConfigRepository) GetLastUsedByVersion(namespace, name string, version int64) (time.Time, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetLastUsedByVersion(namespace, name, version)
+}
+
+func (r *ConfigRepository) SetLastUsed(uses []*entity.LastUse) error {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.SetLastUsed(uses)
+}
+
+func (r *ConfigRepository) IsConfigExists(namespace, name string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.IsConfigExists(namespace, name)
+}
+
+func (r *ConfigRepository) IsConfigVersionExists(namespace, name string, version int64) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.IsConfigVersionExists(namespace, name, version)
+}
+
+func (r *ConfigRepository) IsConfigRelevant(namespace, name string, version int64) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.IsConfigRelevant(namespace, name, version)
+}
+
+func (r *ConfigRepository) GetLastVersion(namespace, name string) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetLastVersion(namespace, name)
+}
+
+func (r *ConfigRepository) GetNamespaces() ([]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetNamespaces()
+}
+
+func (r *ConfigRepository) GetConfigCounts() ([]*entity.ConfigCount, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetConfigCounts()
+}
+
+func (r *ConfigRepository) CreateConfigSchema(schema *entity.ConfigSchema, audit *entity.AuditEvent) error {
+	return r.mutate(schema.Namespace, schema.Name, audit, func() error {
+		return r.configs.CreateConfigSchema(schema, audit)
+	})
+}
+
+func (r *ConfigRepository) GetConfigSchema(namespace, name string) (*entity.ConfigSchema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetConfigSchema(namespace, name)
+}
+
+func (r *ConfigRepository) GetConfigSchemaByVersion(namespace, name string, version int64) (*entity.ConfigSchema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetConfigSchemaByVersion(namespace, name, version)
+}
+
+func (r *ConfigRepository) GetConfigSchemas(namespace, name string) ([]*entity.ConfigSchema, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.configs.GetConfigSchemas(namespace, name)
+}
+
+func (r *ConfigRepository) DeleteConfigSchema(namespace, name string, audit *entity.AuditEvent) error {
+	return r.mutate(namespace, name, audit, func() error {
+		return r.configs.DeleteConfigSchema(namespace, name, audit)
+	})
+}
+
+func (r *ConfigRepository) CreateAuditEvent(event *entity.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.log == nil {
+		return ErrRepositoryClosed
+	}
+	stored := r.nextAuditEvent(event)
+	if err := r.append(&record{Namespace: stored.Namespace, Name: stored.ConfigName, Audit: stored}); err != nil {
+		return err
+	}
+	r.audit.Import(stored)
+	event.ID, event.CreatedAt = stored.ID, stored.CreatedAt
+	return nil
+}
+
+func (r *ConfigRepository) ListAuditEvents(filter *entity.AuditFilter) ([]*entity.AuditEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.audit.ListAuditEvents(filter)
+}
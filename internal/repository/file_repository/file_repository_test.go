@@ -0,0 +1,309 @@
+package file_repository
+
+import (
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository"
+	"distributedConfig/internal/repository/memory_repository"
+	"distributedConfig/internal/repository/repositorytest"
+	"distributedConfig/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openTestRepository(t *testing.T, dir string) *ConfigRepository {
+	t.Helper()
+	repo, err := NewConfigRepository(dir, *logger.New("error"))
+	require.NoError(t, err)
+	return repo
+}
+
+func TestConfigRepository(t *testing.T) {
+	repositorytest.RunConfigRepositoryTests(t, func(t *testing.T) repository.ConfigRepository {
+		repo := openTestRepository(t, t.TempDir())
+		t.Cleanup(func() {
+			require.NoError(t, repo.Close())
+		})
+		return repo
+	})
+}
+
+func TestAuditRepository(t *testing.T) {
+	repositorytest.RunAuditRepositoryTests(t, func(t *testing.T) repository.AuditRepository {
+		repo := openTestRepository(t, t.TempDir())
+		t.Cleanup(func() {
+			require.NoError(t, repo.Close())
+		})
+		return repo
+	})
+}
+
+func TestAuditRepository_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	repo := openTestRepository(t, dir)
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, repo.CreateConfig(config, nil))
+	for _, method := range []string{"CreateConfig", "UpdateConfig"} {
+		require.NoError(t, repo.CreateAuditEvent(&entity.AuditEvent{Actor: "alice", Method: method, ConfigName: "test"}))
+	}
+	require.NoError(t, repo.log.Close())
+
+	// The first reopening restores the events from the log, the second one from the snapshot.
+	for i := 0; i < 2; i++ {
+		repo = openTestRepository(t, dir)
+		events, err := repo.ListAuditEvents(&entity.AuditFilter{})
+		require.NoError(t, err)
+		require.Len(t, events, 2)
+		require.Equal(t, "UpdateConfig", events[0].Method)
+		require.Equal(t, int64(2), events[0].ID)
+		exists, err := repo.IsConfigExists(entity.DefaultNamespace, "test")
+		require.NoError(t, err)
+		require.True(t, exists)
+		require.NoError(t, repo.Close())
+	}
+
+	repo = openTestRepository(t, dir)
+	defer repo.Close()
+	event := &entity.AuditEvent{Actor: "alice", Method: "DeleteConfig", ConfigName: "test"}
+	require.NoError(t, repo.CreateAuditEvent(event))
+	require.Equal(t, int64(3), event.ID)
+}
+
+func TestAuditRepository_ReopenChanges(t *testing.T) {
+	dir := t.TempDir()
+	repo := openTestRepository(t, dir)
+	config := entity.TestConfig(t)
+	config.Version = 1
+	audit := &entity.AuditEvent{Actor: "alice", Method: "CreateConfig"}
+	require.NoError(t, repo.CreateConfig(config, audit))
+	require.Equal(t, int64(1), audit.ID)
+	audit = &entity.AuditEvent{Actor: "alice", Method: "DeleteConfig"}
+	require.NoError(t, repo.DeleteConfig(entity.DefaultNamespace, "test", audit))
+	require.NoError(t, repo.log.Close())
+
+	// The events of changes are restored from the records of the changes.
+	repo = openTestRepository(t, dir)
+	defer repo.Close()
+	events, err := repo.ListAuditEvents(&entity.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "DeleteConfig", events[0].Method)
+	require.Equal(t, int64(2), events[0].ID)
+	require.Equal(t, int64(1), events[0].FromVersion)
+	require.Equal(t, "test", events[1].ConfigName)
+	require.Equal(t, int64(1), events[1].ToVersion)
+}
+
+func TestConfigRepository_Reopen(t *testing.T) {
+	dir := t.TempDir()
+	repo := openTestRepository(t, dir)
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, repo.CreateConfig(config, nil))
+	require.NoError(t, repo.UpdateConfig(entity.TestConfig(t), 0, nil))
+	require.NoError(t, repo.UpdateConfig(&entity.Config{Namespace: entity.DefaultNamespace, Name: "test", Data: map[string]string{"key": "value"}}, 0, nil))
+	_, err := repo.SetRelevantConfig(entity.DefaultNamespace, "test", 2, nil)
+	require.NoError(t, err)
+	require.NoError(t, repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 1, nil))
+	// The log is not compacted, so the state is restored from it alone.
+	require.NoError(t, repo.log.Close())
+
+	repo = openTestRepository(t, dir)
+	defer repo.Close()
+	configs, err := repo.GetConfigs(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	relevant, err := repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), relevant.Version)
+	require.Equal(t, entity.TestConfig(t).Data, relevant.Data)
+
+	updated := entity.TestConfig(t)
+	require.NoError(t, repo.UpdateConfig(updated, 3, nil))
+	require.Equal(t, int64(4), updated.Version)
+	require.Greater(t, updated.ID, configs[0].ID)
+}
+
+func TestConfigRepository_ReopenPromoted(t *testing.T) {
+	dir := t.TempDir()
+	repo := openTestRepository(t, dir)
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, repo.CreateConfig(config, nil))
+	_, err := repo.PromoteConfig(&entity.ConfigPromotion{
+		SourceNamespace: entity.DefaultNamespace,
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         1,
+		SetOverrides:    map[string]string{"key1": "production"},
+	}, nil)
+	require.NoError(t, err)
+	require.NoError(t, repo.log.Close())
+
+	// The first reopening restores the promotion from the log, the second one from the snapshot.
+	for i := 0; i < 2; i++ {
+		repo = openTestRepository(t, dir)
+		promoted, err := repo.GetConfig("production", "test")
+		require.NoError(t, err)
+		require.Equal(t, &entity.ConfigSource{Namespace: entity.DefaultNamespace, Version: 1}, promoted.PromotedFrom)
+		overrides, err := repo.GetConfigOverrides("production", "test")
+		require.NoError(t, err)
+		require.Equal(t, map[string]string{"key1": "production"}, overrides)
+		require.NoError(t, repo.Close())
+	}
+}
+
+func TestConfigRepository_ReopenSchema(t *testing.T) {
+	dir := t.TempDir()
+	repo := openTestRepository(t, dir)
+	require.NoError(t, repo.CreateConfigSchema(&entity.ConfigSchema{
+		Namespace: "staging",
+		Name:      "test",
+		Keys:      map[string]entity.KeyRule{"port": {Required: true, Type: entity.TypeInt}},
+	}, nil))
+	require.NoError(t, repo.log.Close())
+
+	// A schema of a config without versions survives both the log and the snapshot.
+	for i := 0; i < 2; i++ {
+		repo = openTestRepository(t, dir)
+		schema, err := repo.GetConfigSchema("staging", "test")
+		require.NoError(t, err)
+		require.Equal(t, int64(1), schema.Version)
+		require.Equal(t, entity.KeyRule{Required: true, Type: entity.TypeInt}, schema.Keys["port"])
+		require.NoError(t, repo.Close())
+	}
+}
+
+func TestConfigRepository_CompactOnStartup(t *testing.T) {
+	dir := t.TempDir()
+	repo := openTestRepository(t, dir)
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, repo.CreateConfig(config, nil))
+	require.NoError(t, repo.log.Close())
+	info, err := os.Stat(filepath.Join(dir, logFile))
+	require.NoError(t, err)
+	require.NotZero(t, info.Size())
+
+	repo = openTestRepository(t, dir)
+	defer repo.Close()
+	info, err = os.Stat(filepath.Join(dir, logFile))
+	require.NoError(t, err)
+	require.Zero(t, info.Size())
+	exists, err := repo.IsConfigExists(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.True(t, exists)
+}
+
+func TestConfigRepository_TornLogTail(t *testing.T) {
+	testCases := []struct {
+		name    string
+		corrupt func(t *testing.T, path string)
+	}{
+		{
+			name: "truncated record",
+			corrupt: func(t *testing.T, path string) {
+				info, err := os.Stat(path)
+				require.NoError(t, err)
+				require.NoError(t, os.Truncate(path, info.Size()-3))
+			},
+		},
+		{
+			name: "checksum mismatch",
+			corrupt: func(t *testing.T, path string) {
+				data, err := os.ReadFile(path)
+				require.NoError(t, err)
+				data[len(data)-2] ^= 0xff
+				require.NoError(t, os.WriteFile(path, data, 0o600))
+			},
+		},
+		{
+			name: "zeros after truncated record",
+			corrupt: func(t *testing.T, path string) {
+				info, err := os.Stat(path)
+				require.NoError(t, err)
+				require.NoError(t, os.Truncate(path, info.Size()-3))
+				require.NoError(t, os.Truncate(path, info.Size()+100))
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			repo := openTestRepository(t, dir)
+			config := entity.TestConfig(t)
+			config.Version = 1
+			require.NoError(t, repo.CreateConfig(config, nil))
+			require.NoError(t, repo.UpdateConfig(entity.TestConfig(t), 0, nil))
+			require.NoError(t, repo.log.Close())
+			tc.corrupt(t, filepath.Join(dir, logFile))
+
+			repo = openTestRepository(t, dir)
+			defer repo.Close()
+			relevant, err := repo.GetConfig(entity.DefaultNamespace, "test")
+			require.NoError(t, err)
+			require.Equal(t, int64(1), relevant.Version)
+			require.NoError(t, repo.UpdateConfig(entity.TestConfig(t), 1, nil))
+		})
+	}
+}
+
+func TestConfigRepository_CorruptLogRecord(t *testing.T) {
+	dir := t.TempDir()
+	repo := openTestRepository(t, dir)
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, repo.CreateConfig(config, nil))
+	require.NoError(t, repo.UpdateConfig(entity.TestConfig(t), 0, nil))
+	require.NoError(t, repo.log.Close())
+
+	path := filepath.Join(dir, logFile)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[headerSize+1] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	_, err = NewConfigRepository(dir, *logger.New("error"))
+	require.ErrorIs(t, err, ErrCorruptLog)
+	corrupt, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, data, corrupt, "the log is not compacted")
+}
+
+func TestConfigRepository_CorruptSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	repo := openTestRepository(t, dir)
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, repo.CreateConfig(config, nil))
+	require.NoError(t, repo.Close())
+
+	path := filepath.Join(dir, snapshotFile)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	data[len(data)-2] ^= 0xff
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+
+	_, err = NewConfigRepository(dir, *logger.New("error"))
+	require.Equal(t, errCorruptRecord, err)
+}
+
+func TestConfigRepository_RecordWithoutNamespace(t *testing.T) {
+	dir := t.TempDir()
+	config := entity.TestConfig(t)
+	config.Namespace = ""
+	config.Version = 1
+	frame, err := encodeRecord(&record{Name: "test", Versions: []memory_repository.Version{{Config: *config, Relevant: true}}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, logFile), frame, 0o600))
+
+	repo := openTestRepository(t, dir)
+	defer repo.Close()
+	relevant, err := repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, entity.DefaultNamespace, relevant.Namespace)
+	require.Equal(t, int64(1), relevant.Version)
+}
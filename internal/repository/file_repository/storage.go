@@ -0,0 +1,148 @@
+package file_repository
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+const (
+	headerSize    = 8
+	maxRecordSize = 64 << 20
+)
+
+var (
+	crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errCorruptRecord = errors.New("corrupt record")
+)
+
+// encodeRecord frames a record as: payload length (4 bytes), CRC-32C of the payload (4 bytes), JSON payload.
+func encodeRecord(rec *record) ([]byte, error) {
+	payload, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	frame := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint32(frame[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(frame[4:8], crc32.Checksum(payload, crcTable))
+	copy(frame[headerSize:], payload)
+	return frame, nil
+}
+
+// readRecords calls fn for every record of r and returns the number of bytes taken by complete, valid records.
+// A torn or corrupt record stops reading and is reported with errCorruptRecord.
+func readRecords(r io.Reader, fn func(rec *record)) (int64, error) {
+	reader := bufio.NewReader(r)
+	header := make([]byte, headerSize)
+	var offset int64
+	for {
+		_, err := io.ReadFull(reader, header)
+		if err == io.EOF {
+			return offset, nil
+		} else if err == io.ErrUnexpectedEOF {
+			return offset, errCorruptRecord
+		} else if err != nil {
+			return offset, err
+		}
+		size := binary.BigEndian.Uint32(header[0:4])
+		if size > maxRecordSize {
+			return offset, errCorruptRecord
+		}
+		payload := make([]byte, size)
+		_, err = io.ReadFull(reader, payload)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return offset, errCorruptRecord
+		} else if err != nil {
+			return offset, err
+		}
+		if crc32.Checksum(payload, crcTable) != binary.BigEndian.Uint32(header[4:8]) {
+			return offset, errCorruptRecord
+		}
+		var rec record
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return offset, errCorruptRecord
+		}
+		fn(&rec)
+		offset += int64(headerSize + size)
+	}
+}
+
+// tornTail reports whether the corrupt record of f at offset is what an interrupted append leaves behind:
+// the last record of the file, possibly followed by the zeros of a file extended without its data.
+// Anything else following it means that records after a corrupt one would be lost.
+func tornTail(f *os.File, offset int64) (bool, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return false, err
+	}
+	if info.Size()-offset < headerSize {
+		return true, nil
+	}
+	header := make([]byte, headerSize)
+	if _, err := f.ReadAt(header, offset); err != nil {
+		return false, err
+	}
+	size := binary.BigEndian.Uint32(header[0:4])
+	if size > maxRecordSize {
+		return false, nil
+	}
+	end := offset + headerSize + int64(size)
+	if end >= info.Size() {
+		return true, nil
+	}
+	reader := bufio.NewReader(io.NewSectionReader(f, end, info.Size()-end))
+	for {
+		b, err := reader.ReadByte()
+		if err == io.EOF {
+			return true, nil
+		} else if err != nil {
+			return false, err
+		} else if b != 0 {
+			return false, nil
+		}
+	}
+}
+
+// writeFileAtomically replaces the file so that after a crash it holds either the old or the new content.
+func writeFileAtomically(path string, write func(w io.Writer) error) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(f)
+	if err := write(writer); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,80 @@
+package memory_repository
+
+import (
+	"distributedConfig/internal/entity"
+	"sync"
+	"time"
+)
+
+// AuditRepository keeps the audit log in memory, ordered by ID.
+type AuditRepository struct {
+	mu     sync.Mutex
+	events []*entity.AuditEvent
+	lastID int64
+}
+
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+func (r *AuditRepository) CreateAuditEvent(event *entity.AuditEvent) error {
+	r.create(event)
+	return nil
+}
+
+// ListAuditEvents returns the events matching the filter, latest first.
+func (r *AuditRepository) ListAuditEvents(filter *entity.AuditFilter) ([]*entity.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var events []*entity.AuditEvent
+	for i := len(r.events) - 1; i >= 0 && (filter.Limit == 0 || len(events) < filter.Limit); i-- {
+		if filter.Matches(r.events[i]) {
+			event := *r.events[i]
+			events = append(events, &event)
+		}
+	}
+	return events, nil
+}
+
+// LastID returns the ID of the latest event, or zero if there are no events.
+func (r *AuditRepository) LastID() int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.lastID
+}
+
+// Export returns copies of all events ordered by ID.
+func (r *AuditRepository) Export() []entity.AuditEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]entity.AuditEvent, 0, len(r.events))
+	for _, event := range r.events {
+		events = append(events, *event)
+	}
+	return events
+}
+
+// Import stores a copy of an event that already has an ID. Events must be imported in the order of their IDs.
+func (r *AuditRepository) Import(event *entity.AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.insert(event)
+}
+
+// create assigns the event the next ID and stores a copy of it.
+func (r *AuditRepository) create(event *entity.AuditEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	event.ID = r.lastID + 1
+	event.CreatedAt = time.Now()
+	r.insert(event)
+}
+
+// insert stores a copy of the event. It must be called with r.mu held.
+func (r *AuditRepository) insert(event *entity.AuditEvent) {
+	stored := *event
+	r.events = append(r.events, &stored)
+	if event.ID > r.lastID {
+		r.lastID = event.ID
+	}
+}
@@ -0,0 +1,37 @@
+package memory_repository
+
+import (
+	"context"
+	"distributedConfig/internal/watcher"
+	"sync"
+)
+
+// ConfigNotifier delivers config changes to the publishers of the current process only.
+type ConfigNotifier struct {
+	mu         sync.Mutex
+	publishers map[watcher.Publisher]struct{}
+}
+
+func NewConfigNotifier() *ConfigNotifier {
+	return &ConfigNotifier{publishers: make(map[watcher.Publisher]struct{})}
+}
+
+func (n *ConfigNotifier) NotifyConfigChanged(name string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for publisher := range n.publishers {
+		publisher.Publish(name)
+	}
+	return nil
+}
+
+func (n *ConfigNotifier) Listen(ctx context.Context, publisher watcher.Publisher) error {
+	n.mu.Lock()
+	n.publishers[publisher] = struct{}{}
+	n.mu.Unlock()
+	<-ctx.Done()
+	n.mu.Lock()
+	delete(n.publishers, publisher)
+	n.mu.Unlock()
+	return nil
+}
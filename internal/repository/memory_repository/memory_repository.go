@@ -0,0 +1,764 @@
+package memory_repository
+
+import (
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/usecase"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Version is a stored version of a config together with its bookkeeping.
+type Version struct {
+	Config   entity.Config `json:"config"`
+	Relevant bool          `json:"relevant"`
+	LastUsed time.Time     `json:"last_used"`
+}
+
+// ConfigRepository keeps configs, their overrides, labels and schemas in memory, by their entity.QualifiedName. It is safe
+// for concurrent use and behaves like pg_repository.ConfigRepository, but its data does not outlive the process.
+type ConfigRepository struct {
+	mu        sync.Mutex
+	configs   map[string][]*Version
+	overrides map[string]map[string]string
+	labels    map[string]map[string]string
+	schemas   map[string][]*entity.ConfigSchema
+	lastID    int
+	audit     *AuditRepository
+}
+
+func NewConfigRepository() *ConfigRepository {
+	return &ConfigRepository{
+		configs:   make(map[string][]*Version),
+		overrides: make(map[string]map[string]string),
+		labels:    make(map[string]map[string]string),
+		schemas:   make(map[string][]*entity.ConfigSchema),
+	}
+}
+
+// NewAuditedConfigRepository returns a config repository whose changes store their audit events in the returned
+// audit repository, under the same lock as the change. Changes of a repository returned by NewConfigRepository
+// only complete their audit events.
+func NewAuditedConfigRepository() (*ConfigRepository, *AuditRepository) {
+	r := NewConfigRepository()
+	r.audit = NewAuditRepository()
+	return r, r.audit
+}
+
+func (r *ConfigRepository) CreateConfig(config *entity.Config, audit *entity.AuditEvent) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.find(config.Namespace, config.Name, config.Version) != nil {
+		return usecase.ErrConfigAlreadyExists
+	}
+	if err := r.schema(config.Namespace, config.Name).Check(config); err != nil {
+		return err
+	}
+	from := r.relevantConfig(config.Namespace, config.Name)
+	r.insert(config)
+	r.recordChange(audit, config.Namespace, config.Name, from, config)
+	return nil
+}
+
+func (r *ConfigRepository) GetConfig(namespace, name string) (*entity.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.relevant(namespace, name)
+	if v == nil {
+		return nil, usecase.ErrConfigNotFound
+	}
+	v.LastUsed = time.Now()
+	return v.Config.Clone(), nil
+}
+
+func (r *ConfigRepository) PeekConfig(namespace, name string, version int64) (*entity.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.relevant(namespace, name)
+	if version != 0 {
+		v = r.find(namespace, name, version)
+	}
+	if v == nil {
+		return nil, usecase.ErrConfigNotFound
+	}
+	return v.Config.Clone(), nil
+}
+
+func (r *ConfigRepository) GetConfigs(namespace, name string) ([]*entity.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions := r.configs[entity.QualifiedName(namespace, name)]
+	configs := make([]*entity.Config, 0, len(versions))
+	now := time.Now()
+	for i := len(versions) - 1; i >= 0; i-- {
+		versions[i].LastUsed = now
+		configs = append(configs, versions[i].Config.Clone())
+	}
+	return configs, nil
+}
+
+func (r *ConfigRepository) ListConfigVersions(filter *entity.VersionFilter) ([]*entity.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions := r.configs[entity.QualifiedName(filter.Namespace, filter.Name)]
+	var configs []*entity.Config
+	now := time.Now()
+	for i := len(versions) - 1; i >= 0 && (filter.Limit <= 0 || len(configs) < filter.Limit); i-- {
+		if filter.BeforeVersion != 0 && versions[i].Config.Version >= filter.BeforeVersion {
+			continue
+		}
+		config := versions[i].Config.Clone()
+		if filter.WithoutData {
+			config.Data, config.Types = nil, nil
+		} else {
+			versions[i].LastUsed = now
+		}
+		configs = append(configs, config)
+	}
+	return configs, nil
+}
+
+func (r *ConfigRepository) ListConfigNames(filter *entity.NameFilter) ([]*entity.ConfigSummary, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	summaries := make([]*entity.ConfigSummary, 0)
+	for key := range r.configs {
+		namespace, name := entity.SplitQualifiedName(key)
+		v := r.relevant(namespace, name)
+		if namespace != filter.Namespace || v == nil {
+			continue
+		}
+		summary := &entity.ConfigSummary{
+			Namespace: namespace,
+			Name:      name,
+			Version:   v.Config.Version,
+			CreatedAt: v.Config.CreatedAt,
+			LastUsed:  v.LastUsed,
+		}
+		if labels, ok := r.labels[key]; ok {
+			summary.Labels = copyData(labels)
+		}
+		if filter.Matches(summary) {
+			summaries = append(summaries, summary)
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return filter.Less(summaries[i], summaries[j])
+	})
+	if filter.Limit > 0 && len(summaries) > filter.Limit {
+		summaries = summaries[:filter.Limit]
+	}
+	return summaries, nil
+}
+
+func (r *ConfigRepository) GetConfigByVersion(namespace, name string, version int64) (*entity.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.find(namespace, name, version)
+	if v == nil {
+		return nil, usecase.ErrConfigNotFound
+	}
+	v.LastUsed = time.Now()
+	return v.Config.Clone(), nil
+}
+
+func (r *ConfigRepository) DeleteConfig(namespace, name string, audit *entity.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recordChange(audit, namespace, name, r.relevantConfig(namespace, name), nil)
+	delete(r.configs, entity.QualifiedName(namespace, name))
+	delete(r.overrides, entity.QualifiedName(namespace, name))
+	delete(r.labels, entity.QualifiedName(namespace, name))
+	return nil
+}
+
+// DeleteConfigVersion deletes a single version. If it was relevant, the latest remaining version becomes relevant.
+func (r *ConfigRepository) DeleteConfigVersion(namespace, name string, version int64, audit *entity.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := entity.QualifiedName(namespace, name)
+	versions := r.configs[key]
+	for i, v := range versions {
+		if v.Config.Version != version {
+			continue
+		}
+		// Like versions made relevant by SetRelevantConfig, the version that becomes relevant must satisfy the schema.
+		if v.Relevant && len(versions) > 1 {
+			next := versions[len(versions)-1]
+			if next == v {
+				next = versions[len(versions)-2]
+			}
+			if err := r.schema(namespace, name).Check(&next.Config); err != nil {
+				return fmt.Errorf("%w: version %d would become relevant: %s", usecase.ErrSchemaViolation,
+					next.Config.Version, err)
+			}
+		}
+		r.recordChange(audit, namespace, name, &v.Config, nil)
+		versions = append(versions[:i], versions[i+1:]...)
+		if len(versions) == 0 {
+			delete(r.configs, key)
+			return nil
+		}
+		r.configs[key] = versions
+		if v.Relevant {
+			latest := versions[len(versions)-1]
+			latest.Relevant = true
+			latest.LastUsed = time.Now()
+		}
+		return nil
+	}
+	return usecase.ErrConfigNotFound
+}
+
+// UpdateConfig creates the next version of the config. If expectedVersion is not zero,
+// it fails with usecase.ErrConfigVersionConflict unless expectedVersion is the latest version.
+func (r *ConfigRepository) UpdateConfig(config *entity.Config, expectedVersion int64, audit *entity.AuditEvent) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	last := r.lastVersion(config.Namespace, config.Name)
+	if expectedVersion != 0 && last != expectedVersion {
+		return usecase.ErrConfigVersionConflict
+	}
+	if latest := r.find(config.Namespace, config.Name, last); latest != nil {
+		config.KeepSecrets(latest.Config.SecretKeys())
+		if err := config.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := r.schema(config.Namespace, config.Name).Check(config); err != nil {
+		return err
+	}
+	from := r.relevantConfig(config.Namespace, config.Name)
+	config.Version = last + 1
+	r.insert(config)
+	r.recordChange(audit, config.Namespace, config.Name, from, config)
+	return nil
+}
+
+// PatchConfig applies the patch to the relevant version and stores the result as the next version.
+// If expectedVersion is not zero, it fails with usecase.ErrConfigVersionConflict unless expectedVersion
+// is the relevant version.
+func (r *ConfigRepository) PatchConfig(patch *entity.ConfigPatch, expectedVersion int64, audit *entity.AuditEvent) (*entity.Config, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.relevant(patch.Namespace, patch.Name)
+	if v == nil {
+		return nil, usecase.ErrConfigNotFound
+	}
+	if expectedVersion != 0 && v.Config.Version != expectedVersion {
+		return nil, usecase.ErrConfigVersionConflict
+	}
+	config := patch.Apply(&v.Config)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := r.schema(patch.Namespace, patch.Name).Check(config); err != nil {
+		return nil, err
+	}
+	config.Version = r.lastVersion(patch.Namespace, patch.Name) + 1
+	r.insert(config)
+	r.recordChange(audit, patch.Namespace, patch.Name, &v.Config, config)
+	return config, nil
+}
+
+func (r *ConfigRepository) PromoteConfig(promotion *entity.ConfigPromotion, audit *entity.AuditEvent) (*entity.Config, error) {
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	source := r.find(promotion.SourceNamespace, promotion.Name, promotion.Version)
+	if source == nil {
+		return nil, usecase.ErrConfigNotFound
+	}
+	key := entity.QualifiedName(promotion.TargetNamespace, promotion.Name)
+	overrides := promotion.ApplyOverrides(r.overrides[key])
+	config := promotion.Apply(&source.Config, overrides)
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if err := r.schema(promotion.TargetNamespace, promotion.Name).Check(config); err != nil {
+		return nil, err
+	}
+	from := r.relevantConfig(promotion.TargetNamespace, promotion.Name)
+	config.Version = r.lastVersion(promotion.TargetNamespace, promotion.Name) + 1
+	r.setOverrides(key, overrides)
+	r.insert(config)
+	r.recordChange(audit, promotion.TargetNamespace, promotion.Name, from, config)
+	return config, nil
+}
+
+func (r *ConfigRepository) GetConfigOverrides(namespace, name string) (map[string]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return copyData(r.overrides[entity.QualifiedName(namespace, name)]), nil
+}
+
+func (r *ConfigRepository) SetConfigLabels(labels *entity.ConfigLabels, audit *entity.AuditEvent) error {
+	if err := labels.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := entity.QualifiedName(labels.Namespace, labels.Name)
+	if len(r.configs[key]) == 0 {
+		return usecase.ErrConfigNotFound
+	}
+	r.setLabels(key, copyData(labels.Labels))
+	if audit != nil {
+		audit.SetLabelsChange(labels)
+		r.recordAudit(audit)
+	}
+	return nil
+}
+
+func (r *ConfigRepository) SetRelevantConfig(namespace, name string, version int64, audit *entity.AuditEvent) (*entity.Config, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.find(namespace, name, version)
+	if v == nil {
+		return nil, usecase.ErrConfigNotFound
+	}
+	if err := r.schema(namespace, name).Check(&v.Config); err != nil {
+		return nil, err
+	}
+	from := r.relevantConfig(namespace, name)
+	r.setRelevant(v)
+	r.recordChange(audit, namespace, name, from, &v.Config)
+	return v.Config.Clone(), nil
+}
+
+func (r *ConfigRepository) GetRelevantLastUsed(namespace, name string) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.relevant(namespace, name)
+	if v == nil {
+		return time.Time{}, usecase.ErrConfigNotFound
+	}
+	return v.LastUsed, nil
+}
+
+func (r *ConfigRepository) GetLastUsedByVersion(namespace, name string, version int64) (time.Time, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.find(namespace, name, version)
+	if v == nil {
+		return time.Time{}, usecase.ErrConfigNotFound
+	}
+	return v.LastUsed, nil
+}
+
+func (r *ConfigRepository) SetLastUsed(uses []*entity.LastUse) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, use := range uses {
+		if v := r.find(use.Namespace, use.Name, use.Version); v != nil && use.Time.After(v.LastUsed) {
+			v.LastUsed = use.Time
+		}
+	}
+	return nil
+}
+
+func (r *ConfigRepository) IsConfigExists(namespace, name string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.configs[entity.QualifiedName(namespace, name)]) > 0, nil
+}
+
+func (r *ConfigRepository) IsConfigVersionExists(namespace, name string, version int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.find(namespace, name, version) != nil, nil
+}
+
+func (r *ConfigRepository) IsConfigRelevant(namespace, name string, version int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	v := r.find(namespace, name, version)
+	if v == nil {
+		return false, usecase.ErrConfigNotFound
+	}
+	return v.Relevant, nil
+}
+
+func (r *ConfigRepository) GetLastVersion(namespace, name string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.configs[entity.QualifiedName(namespace, name)]) == 0 {
+		return 0, usecase.ErrConfigNotFound
+	}
+	return r.lastVersion(namespace, name), nil
+}
+
+func (r *ConfigRepository) GetNamespaces() ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool)
+	namespaces := make([]string, 0)
+	for qualifiedName := range r.configs {
+		namespace, _ := entity.SplitQualifiedName(qualifiedName)
+		if !seen[namespace] {
+			seen[namespace] = true
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+func (r *ConfigRepository) GetConfigCounts() ([]*entity.ConfigCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	counts := make(map[string]*entity.ConfigCount)
+	for qualifiedName, versions := range r.configs {
+		if len(versions) == 0 {
+			continue
+		}
+		namespace, _ := entity.SplitQualifiedName(qualifiedName)
+		count, ok := counts[namespace]
+		if !ok {
+			count = &entity.ConfigCount{Namespace: namespace}
+			counts[namespace] = count
+		}
+		count.Configs++
+		count.Versions += int64(len(versions))
+	}
+	result := make([]*entity.ConfigCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, count)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result, nil
+}
+
+func (r *ConfigRepository) CreateConfigSchema(schema *entity.ConfigSchema, audit *entity.AuditEvent) error {
+	if err := schema.Validate(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := entity.QualifiedName(schema.Namespace, schema.Name)
+	schema.Version = 1
+	if versions := r.schemas[key]; len(versions) > 0 {
+		schema.Version = versions[len(versions)-1].Version + 1
+	}
+	schema.CreatedAt = time.Now()
+	r.schemas[key] = append(r.schemas[key], copySchema(schema))
+	if audit != nil {
+		audit.SetSchemaChange(schema.Namespace, schema.Name, schema.Version-1, schema.Version)
+		r.recordAudit(audit)
+	}
+	return nil
+}
+
+func (r *ConfigRepository) GetConfigSchema(namespace, name string) (*entity.ConfigSchema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	schema := r.schema(namespace, name)
+	if schema == nil {
+		return nil, usecase.ErrSchemaNotFound
+	}
+	return copySchema(schema), nil
+}
+
+func (r *ConfigRepository) GetConfigSchemaByVersion(namespace, name string, version int64) (*entity.ConfigSchema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, schema := range r.schemas[entity.QualifiedName(namespace, name)] {
+		if schema.Version == version {
+			return copySchema(schema), nil
+		}
+	}
+	return nil, usecase.ErrSchemaNotFound
+}
+
+func (r *ConfigRepository) GetConfigSchemas(namespace, name string) ([]*entity.ConfigSchema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions := r.schemas[entity.QualifiedName(namespace, name)]
+	schemas := make([]*entity.ConfigSchema, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		schemas = append(schemas, copySchema(versions[i]))
+	}
+	return schemas, nil
+}
+
+func (r *ConfigRepository) DeleteConfigSchema(namespace, name string, audit *entity.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if audit != nil {
+		var from int64
+		if schema := r.schema(namespace, name); schema != nil {
+			from = schema.Version
+		}
+		audit.SetSchemaChange(namespace, name, from, 0)
+		r.recordAudit(audit)
+	}
+	delete(r.schemas, entity.QualifiedName(namespace, name))
+	return nil
+}
+
+// Names returns the qualified names of all stored configs and of configs that only have overrides, labels
+// or schemas.
+func (r *ConfigRepository) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool, len(r.configs))
+	names := make([]string, 0, len(r.configs))
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for name := range r.configs {
+		add(name)
+	}
+	for name := range r.overrides {
+		add(name)
+	}
+	for name := range r.labels {
+		add(name)
+	}
+	for name := range r.schemas {
+		add(name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Export returns copies of all versions of the config ordered by version.
+func (r *ConfigRepository) Export(namespace, name string) []Version {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := entity.QualifiedName(namespace, name)
+	versions := make([]Version, 0, len(r.configs[key]))
+	for _, v := range r.configs[key] {
+		versions = append(versions, Version{Config: *v.Config.Clone(), Relevant: v.Relevant, LastUsed: v.LastUsed})
+	}
+	return versions
+}
+
+// Import replaces all versions of the config. An empty list deletes the config.
+func (r *ConfigRepository) Import(namespace, name string, versions []Version) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := entity.QualifiedName(namespace, name)
+	if len(versions) == 0 {
+		delete(r.configs, key)
+		return
+	}
+	imported := make([]*Version, 0, len(versions))
+	for _, v := range versions {
+		config := v.Config.Clone()
+		config.Namespace = namespace
+		imported = append(imported, &Version{Config: *config, Relevant: v.Relevant, LastUsed: v.LastUsed})
+		if v.Config.ID > r.lastID {
+			r.lastID = v.Config.ID
+		}
+	}
+	sort.Slice(imported, func(i, j int) bool {
+		return imported[i].Config.Version < imported[j].Config.Version
+	})
+	r.configs[key] = imported
+}
+
+// ExportOverrides returns a copy of the overrides of the config.
+func (r *ConfigRepository) ExportOverrides(namespace, name string) map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return copyData(r.overrides[entity.QualifiedName(namespace, name)])
+}
+
+// ImportOverrides replaces the overrides of the config. Empty overrides are deleted.
+func (r *ConfigRepository) ImportOverrides(namespace, name string, overrides map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setOverrides(entity.QualifiedName(namespace, name), copyData(overrides))
+}
+
+// ExportLabels returns a copy of the labels of the config.
+func (r *ConfigRepository) ExportLabels(namespace, name string) map[string]string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return copyData(r.labels[entity.QualifiedName(namespace, name)])
+}
+
+// ImportLabels replaces the labels of the config. Empty labels are deleted.
+func (r *ConfigRepository) ImportLabels(namespace, name string, labels map[string]string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.setLabels(entity.QualifiedName(namespace, name), copyData(labels))
+}
+
+// ExportSchemas returns copies of all versions of the schema of the config ordered by version.
+func (r *ConfigRepository) ExportSchemas(namespace, name string) []entity.ConfigSchema {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	versions := r.schemas[entity.QualifiedName(namespace, name)]
+	schemas := make([]entity.ConfigSchema, 0, len(versions))
+	for _, schema := range versions {
+		schemas = append(schemas, *copySchema(schema))
+	}
+	return schemas
+}
+
+// ImportSchemas replaces all versions of the schema of the config. An empty list deletes the schema.
+func (r *ConfigRepository) ImportSchemas(namespace, name string, schemas []entity.ConfigSchema) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := entity.QualifiedName(namespace, name)
+	if len(schemas) == 0 {
+		delete(r.schemas, key)
+		return
+	}
+	imported := make([]*entity.ConfigSchema, 0, len(schemas))
+	for i := range schemas {
+		schema := copySchema(&schemas[i])
+		schema.Namespace = namespace
+		imported = append(imported, schema)
+	}
+	sort.Slice(imported, func(i, j int) bool {
+		return imported[i].Version < imported[j].Version
+	})
+	r.schemas[key] = imported
+}
+
+// schema returns the latest version of the schema of the config, or nil. It must be called with r.mu held.
+func (r *ConfigRepository) schema(namespace, name string) *entity.ConfigSchema {
+	versions := r.schemas[entity.QualifiedName(namespace, name)]
+	if len(versions) == 0 {
+		return nil
+	}
+	return versions[len(versions)-1]
+}
+
+// setOverrides stores the overrides by the qualified name. It must be called with r.mu held.
+func (r *ConfigRepository) setOverrides(key string, overrides map[string]string) {
+	if len(overrides) == 0 {
+		delete(r.overrides, key)
+		return
+	}
+	r.overrides[key] = overrides
+}
+
+// setLabels stores the labels by the qualified name. It must be called with r.mu held.
+func (r *ConfigRepository) setLabels(key string, labels map[string]string) {
+	if len(labels) == 0 {
+		delete(r.labels, key)
+		return
+	}
+	r.labels[key] = labels
+}
+
+// insert stores a copy of the config as its relevant version. It must be called with r.mu held.
+func (r *ConfigRepository) insert(config *entity.Config) {
+	r.lastID++
+	config.ID = r.lastID
+	config.CreatedAt = time.Now()
+	v := &Version{Config: *config.Clone()}
+	key := entity.QualifiedName(config.Namespace, config.Name)
+	versions := append(r.configs[key], v)
+	sort.Slice(versions, func(i, j int) bool {
+		return versions[i].Config.Version < versions[j].Config.Version
+	})
+	r.configs[key] = versions
+	r.setRelevant(v)
+}
+
+func (r *ConfigRepository) setRelevant(v *Version) {
+	for _, other := range r.configs[entity.QualifiedName(v.Config.Namespace, v.Config.Name)] {
+		other.Relevant = false
+	}
+	v.Relevant = true
+	v.LastUsed = time.Now()
+}
+
+func (r *ConfigRepository) find(namespace, name string, version int64) *Version {
+	for _, v := range r.configs[entity.QualifiedName(namespace, name)] {
+		if v.Config.Version == version {
+			return v
+		}
+	}
+	return nil
+}
+
+func (r *ConfigRepository) relevant(namespace, name string) *Version {
+	for _, v := range r.configs[entity.QualifiedName(namespace, name)] {
+		if v.Relevant {
+			return v
+		}
+	}
+	return nil
+}
+
+func (r *ConfigRepository) lastVersion(namespace, name string) int64 {
+	versions := r.configs[entity.QualifiedName(namespace, name)]
+	if len(versions) == 0 {
+		return 0
+	}
+	return versions[len(versions)-1].Config.Version
+}
+
+func copyData(data map[string]string) map[string]string {
+	c := make(map[string]string, len(data))
+	for key, value := range data {
+		c[key] = value
+	}
+	return c
+}
+
+func copySchema(schema *entity.ConfigSchema) *entity.ConfigSchema {
+	c := *schema
+	c.Keys = make(map[string]entity.KeyRule, len(schema.Keys))
+	for key, rule := range schema.Keys {
+		if rule.Min != nil {
+			min := *rule.Min
+			rule.Min = &min
+		}
+		if rule.Max != nil {
+			max := *rule.Max
+			rule.Max = &max
+		}
+		rule.Enum = append([]string(nil), rule.Enum...)
+		c.Keys[key] = rule
+	}
+	return &c
+}
+
+// relevantConfig returns the relevant version of the config, or nil if there is none.
+func (r *ConfigRepository) relevantConfig(namespace, name string) *entity.Config {
+	if v := r.relevant(namespace, name); v != nil {
+		return &v.Config
+	}
+	return nil
+}
+
+// recordChange completes the audit event, if there is one, with a change of the config from one version
+// to another and records it. It must be called with r.mu held.
+func (r *ConfigRepository) recordChange(audit *entity.AuditEvent, namespace, name string, from, to *entity.Config) {
+	if audit == nil {
+		return
+	}
+	audit.SetChange(namespace, name, from, to)
+	r.recordAudit(audit)
+}
+
+// recordAudit stores the completed audit event, if there is an audit repository. It must be called with r.mu held.
+func (r *ConfigRepository) recordAudit(audit *entity.AuditEvent) {
+	if r.audit != nil {
+		r.audit.create(audit)
+	}
+}
@@ -0,0 +1,19 @@
+package memory_repository
+
+import (
+	"distributedConfig/internal/repository"
+	"distributedConfig/internal/repository/repositorytest"
+	"testing"
+)
+
+func TestConfigRepository(t *testing.T) {
+	repositorytest.RunConfigRepositoryTests(t, func(t *testing.T) repository.ConfigRepository {
+		return NewConfigRepository()
+	})
+}
+
+func TestAuditRepository(t *testing.T) {
+	repositorytest.RunAuditRepositoryTests(t, func(t *testing.T) repository.AuditRepository {
+		return NewAuditRepository()
+	})
+}
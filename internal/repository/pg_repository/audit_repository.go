@@ -0,0 +1,85 @@
+package pg_repository
+
+import (
+	"database/sql"
+	"distributedConfig/internal/entity"
+	"strconv"
+	"strings"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+}
+
+func NewAuditRepository(db *sql.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+func (r *AuditRepository) CreateAuditEvent(event *entity.AuditEvent) error {
+	return insertAuditEvent(r.db, event)
+}
+
+// insertAuditEvent records the event, in the transaction of the change it describes if q is one.
+func insertAuditEvent(q querier, event *entity.AuditEvent) error {
+	return q.QueryRow("INSERT INTO audit_events "+
+		"(actor, method, namespace, config_name, from_version, to_version, diff_summary, client_ip) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at",
+		event.Actor, event.Method, event.Namespace, event.ConfigName, event.FromVersion, event.ToVersion, event.DiffSummary,
+		event.ClientIP).
+		Scan(&event.ID, &event.CreatedAt)
+}
+
+// ListAuditEvents returns the events matching the filter, latest first.
+func (r *AuditRepository) ListAuditEvents(filter *entity.AuditFilter) ([]*entity.AuditEvent, error) {
+	var conditions []string
+	var args []interface{}
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, condition+" $"+strconv.Itoa(len(args)))
+	}
+	if filter.Namespace != "" {
+		where("namespace =", filter.Namespace)
+	}
+	if filter.ConfigName != "" {
+		where("config_name =", filter.ConfigName)
+	}
+	if filter.Actor != "" {
+		where("actor =", filter.Actor)
+	}
+	if !filter.Since.IsZero() {
+		where("created_at >=", filter.Since)
+	}
+	if !filter.Until.IsZero() {
+		where("created_at <", filter.Until)
+	}
+	if filter.BeforeID != 0 {
+		where("id <", filter.BeforeID)
+	}
+	query := "SELECT id, actor, method, namespace, config_name, from_version, to_version, diff_summary, client_ip, created_at " +
+		"FROM audit_events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY id DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var events []*entity.AuditEvent
+	for rows.Next() {
+		var event entity.AuditEvent
+		err := rows.Scan(&event.ID, &event.Actor, &event.Method, &event.Namespace, &event.ConfigName, &event.FromVersion,
+			&event.ToVersion, &event.DiffSummary, &event.ClientIP, &event.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, &event)
+	}
+	return events, rows.Err()
+}
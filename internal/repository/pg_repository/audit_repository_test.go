@@ -0,0 +1,79 @@
+package pg_repository
+
+import (
+	"database/sql/driver"
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository"
+	"distributedConfig/internal/repository/repositorytest"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestAuditRepository_CreateAuditEvent(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery("INSERT INTO audit_events "+
+		"(actor, method, namespace, config_name, from_version, to_version, diff_summary, client_ip) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at").
+		WithArgs("alice", "UpdateConfig", entity.DefaultNamespace, "test", 1, 2, "changed: key1", "10.0.0.1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+	repo := NewAuditRepository(db)
+	event := &entity.AuditEvent{Actor: "alice", Method: "UpdateConfig", Namespace: entity.DefaultNamespace, ConfigName: "test",
+		FromVersion: 1, ToVersion: 2, DiffSummary: "changed: key1", ClientIP: "10.0.0.1"}
+	require.NoError(t, repo.CreateAuditEvent(event))
+	require.Equal(t, int64(7), event.ID)
+	require.False(t, event.CreatedAt.IsZero())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuditRepository_ListAuditEvents(t *testing.T) {
+	const columns = "SELECT id, actor, method, namespace, config_name, from_version, to_version, diff_summary, client_ip, " +
+		"created_at FROM audit_events"
+	since := time.Now().Add(-time.Hour)
+	testCases := []struct {
+		name   string
+		filter entity.AuditFilter
+		query  string
+		args   []driver.Value
+	}{
+		{"all", entity.AuditFilter{}, columns + " ORDER BY id DESC", nil},
+		{
+			name: "filtered page",
+			filter: entity.AuditFilter{Namespace: entity.DefaultNamespace, ConfigName: "test", Actor: "alice", Since: since,
+				BeforeID: 10, Limit: 2},
+			query: columns + " WHERE namespace = $1 AND config_name = $2 AND actor = $3 AND created_at >= $4 AND id < $5" +
+				" ORDER BY id DESC LIMIT $6",
+			args: []driver.Value{entity.DefaultNamespace, "test", "alice", since, 10, 2},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			require.NoError(t, err)
+			defer db.Close()
+			mock.ExpectQuery(tc.query).
+				WithArgs(tc.args...).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "actor", "method", "namespace", "config_name", "from_version",
+					"to_version", "diff_summary", "client_ip", "created_at"}).
+					AddRow(9, "alice", "UpdateConfig", entity.DefaultNamespace, "test", 1, 2, "changed: key1", "10.0.0.1", time.Now()).
+					AddRow(8, "alice", "CreateConfig", entity.DefaultNamespace, "test", 0, 1, "added: key1", "10.0.0.1", time.Now()))
+			repo := NewAuditRepository(db)
+			events, err := repo.ListAuditEvents(&tc.filter)
+			require.NoError(t, err)
+			require.Len(t, events, 2)
+			require.Equal(t, int64(9), events[0].ID)
+			require.Equal(t, "changed: key1", events[0].DiffSummary)
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestAuditRepository_Conformance(t *testing.T) {
+	repositorytest.RunAuditRepositoryTests(t, func(t *testing.T) repository.AuditRepository {
+		return NewAuditRepository(testDB(t))
+	})
+}
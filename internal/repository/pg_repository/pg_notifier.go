@@ -0,0 +1,95 @@
+package pg_repository
+
+import (
+	"context"
+	"database/sql"
+	"distributedConfig/internal/watcher"
+	"distributedConfig/pkg/logger"
+	"errors"
+	"fmt"
+	"github.com/lib/pq"
+	"sync"
+	"time"
+)
+
+const configChangesChannel = "config_changes"
+
+// ConfigNotifier propagates config changes between replicas with Postgres LISTEN/NOTIFY.
+type ConfigNotifier struct {
+	db  *sql.DB
+	dsn string
+	l   logger.Logger
+
+	mu        sync.Mutex
+	connected bool
+	lastErr   error
+}
+
+func NewConfigNotifier(db *sql.DB, dsn string, l logger.Logger) *ConfigNotifier {
+	return &ConfigNotifier{db: db, dsn: dsn, l: l}
+}
+
+func (n *ConfigNotifier) NotifyConfigChanged(name string) error {
+	_, err := n.db.Exec("SELECT pg_notify($1, $2)", configChangesChannel, name)
+	return err
+}
+
+// Listen delivers notifications of every replica to the publisher until ctx is done.
+// It holds a single dedicated connection regardless of the number of watchers.
+func (n *ConfigNotifier) Listen(ctx context.Context, publisher watcher.Publisher) error {
+	listener := pq.NewListener(n.dsn, 10*time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			n.l.Error("Config changes listener: %s", err)
+		}
+		n.onEvent(event, err)
+	})
+	defer func() {
+		_ = listener.Close()
+		n.onEvent(pq.ListenerEventDisconnected, errors.New("listener is closed"))
+	}()
+	if err := listener.Listen(configChangesChannel); err != nil {
+		return err
+	}
+	n.l.Info("Listening for config changes")
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			if notification == nil {
+				// The connection was re-established, notifications could have been lost.
+				go publisher.PublishAll()
+				continue
+			}
+			// Configs are reloaded off this goroutine, so that a slow one delays neither other notifications
+			// nor the pings that keep the connection alive.
+			go publisher.Publish(notification.Extra)
+		case <-time.After(90 * time.Second):
+			go func() {
+				if err := listener.Ping(); err != nil {
+					n.l.Error("Config changes listener ping: %s", err)
+				}
+			}()
+		}
+	}
+}
+
+// Check returns an error unless Listen holds a connection, so that missed changes are noticed by readiness probes.
+func (n *ConfigNotifier) Check(context.Context) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.connected {
+		return nil
+	}
+	if n.lastErr != nil {
+		return fmt.Errorf("not connected: %w", n.lastErr)
+	}
+	return errors.New("not connected")
+}
+
+func (n *ConfigNotifier) onEvent(event pq.ListenerEventType, err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.connected = event == pq.ListenerEventConnected || event == pq.ListenerEventReconnected
+	n.lastErr = err
+}
@@ -0,0 +1,35 @@
+package pg_repository
+
+import (
+	"context"
+	"distributedConfig/pkg/logger"
+	"errors"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestConfigNotifier_NotifyConfigChanged(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectExec("SELECT pg_notify($1, $2)").
+		WithArgs("config_changes", "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	notifier := NewConfigNotifier(db, "", *logger.New("error"))
+	err = notifier.NotifyConfigChanged("test")
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigNotifier_Check(t *testing.T) {
+	notifier := NewConfigNotifier(nil, "", *logger.New("error"))
+	require.Error(t, notifier.Check(context.Background()))
+	notifier.onEvent(pq.ListenerEventConnected, nil)
+	require.NoError(t, notifier.Check(context.Background()))
+	notifier.onEvent(pq.ListenerEventDisconnected, errors.New("connection reset"))
+	require.ErrorContains(t, notifier.Check(context.Background()), "connection reset")
+	notifier.onEvent(pq.ListenerEventReconnected, nil)
+	require.NoError(t, notifier.Check(context.Background()))
+}
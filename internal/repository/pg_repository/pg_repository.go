@@ -3,237 +3,1326 @@ package pg_repository
 import (
 	"database/sql"
 	"distributedConfig/internal/entity"
+	"distributedConfig/internal/secrets"
 	"distributedConfig/internal/usecase"
+	"encoding/json"
+	"fmt"
+	"github.com/lib/pq"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
+const uniqueViolation = "23505"
+
+const (
+	// DefaultPairBatchSize is the number of pairs inserted by one statement, unless it is set otherwise.
+	DefaultPairBatchSize = 500
+	// pairColumns is the number of parameters of every inserted pair.
+	pairColumns = 4
+	// maxPairBatchSize keeps the parameters of a statement within the 65535 that Postgres allows.
+	maxPairBatchSize = 65535 / pairColumns
+)
+
+// nameColumn compares names bytewise, like the indexes that configs are listed by.
+const nameColumn = `name COLLATE "C"`
+
+// orderColumns are the columns that configs are listed by, before their names.
+var orderColumns = map[entity.ConfigOrder]string{
+	entity.OrderByCreated:  "created_at",
+	entity.OrderByLastUsed: "last_used",
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, so that names are matched literally.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// querier is satisfied by both *sql.DB and *sql.Tx.
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// scanner is satisfied by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// ConfigRepository stores configs in Postgres. Values of secret keys are stored encrypted with the keyring,
+// without which they can be neither stored nor read.
 type ConfigRepository struct {
-	db *sql.DB
+	db            *sql.DB
+	keyring       *secrets.Keyring
+	pairBatchSize int
+}
+
+func NewConfigRepository(db *sql.DB, keyring *secrets.Keyring) *ConfigRepository {
+	return &ConfigRepository{db: db, keyring: keyring, pairBatchSize: DefaultPairBatchSize}
 }
 
-func NewConfigRepository(db *sql.DB) *ConfigRepository {
-	return &ConfigRepository{db: db}
+// SetPairBatchSize sets the number of pairs inserted by one statement. A size below one restores the default,
+// and a size above what Postgres allows is clamped to it.
+func (r *ConfigRepository) SetPairBatchSize(size int) {
+	switch {
+	case size < 1:
+		size = DefaultPairBatchSize
+	case size > maxPairBatchSize:
+		size = maxPairBatchSize
+	}
+	r.pairBatchSize = size
 }
 
-func (r *ConfigRepository) CreateConfig(config *entity.Config) error {
+func (r *ConfigRepository) CreateConfig(config *entity.Config, audit *entity.AuditEvent) error {
 	if err := config.Validate(); err != nil {
 		return err
 	}
-	err := r.db.QueryRow("INSERT INTO configs (name, version) VALUES ($1, $2) RETURNING id, version, created_at",
-		config.Name, config.Version).Scan(&config.ID, &config.Version, &config.CreatedAt)
+	return r.withTx(func(tx *sql.Tx) error {
+		if err := lockConfig(tx, config.Namespace, config.Name); err != nil {
+			return err
+		}
+		from, err := r.relevantBefore(tx, config.Namespace, config.Name, audit)
+		if err != nil {
+			return err
+		}
+		if err := checkSchema(tx, config); err != nil {
+			return err
+		}
+		if err := insertConfig(tx, config); err != nil {
+			return err
+		}
+		if err := r.insertData(tx, config); err != nil {
+			return err
+		}
+		if err := setRelevant(tx, config.Namespace, config.Name, config.Version); err != nil {
+			return err
+		}
+		return recordChange(tx, audit, config.Namespace, config.Name, from, config)
+	})
+}
+
+func (r *ConfigRepository) GetConfig(namespace, name string) (*entity.Config, error) {
+	config, err := r.PeekConfig(namespace, name, 0)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	err = r.insertData(config.ID, config.Data)
-	if err != nil {
-		return err
+	if err := r.updateLastUsed(config.ID); err != nil {
+		return nil, err
 	}
-	_, err = r.SetRelevantConfig(config.Name, config.Version)
-	return err
+	return config, nil
+}
+
+func (r *ConfigRepository) PeekConfig(namespace, name string, version int64) (*entity.Config, error) {
+	return r.selectConfig(r.db, namespace, name, version)
 }
 
-func (r *ConfigRepository) GetConfig(name string) (*entity.Config, error) {
-	var config entity.Config
-	err := r.db.QueryRow("SELECT id, name, version, created_at FROM configs WHERE name = $1 AND relevant = TRUE",
-		name).Scan(&config.ID, &config.Name, &config.Version, &config.CreatedAt)
+// selectConfig returns the version of the config, or its relevant version if version is 0.
+func (r *ConfigRepository) selectConfig(q querier, namespace, name string, version int64) (*entity.Config, error) {
+	query := "SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version " +
+		"FROM configs WHERE namespace = $1 AND name = $2 AND relevant = TRUE"
+	args := []interface{}{namespace, name}
+	if version != 0 {
+		query = "SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version " +
+			"FROM configs WHERE namespace = $1 AND name = $2 AND version = $3"
+		args = append(args, version)
+	}
+	config := entity.Config{Namespace: namespace}
+	err := scanConfig(q.QueryRow(query, args...), &config)
+	if err == sql.ErrNoRows {
+		return nil, usecase.ErrConfigNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	config.Data, config.Types, err = r.selectData(q, config.ID)
 	if err != nil {
 		return nil, err
 	}
+	return &config, nil
+}
+
+// relevantBefore returns the relevant version of the config locked by the transaction, which an audited change
+// replaces. It is nil if the config has no versions or the change is not audited.
+func (r *ConfigRepository) relevantBefore(tx *sql.Tx, namespace, name string, audit *entity.AuditEvent) (*entity.Config, error) {
+	if audit == nil {
+		return nil, nil
+	}
+	config, err := r.selectConfig(tx, namespace, name, 0)
+	if err == usecase.ErrConfigNotFound {
+		return nil, nil
+	}
+	return config, err
+}
+
+// recordChange completes the audit event, if there is one, with a change of the config from one version
+// to another and records it in the transaction of the change.
+func recordChange(tx *sql.Tx, audit *entity.AuditEvent, namespace, name string, from, to *entity.Config) error {
+	if audit == nil {
+		return nil
+	}
+	audit.SetChange(namespace, name, from, to)
+	return insertAuditEvent(tx, audit)
+}
 
-	config.Data, err = r.GetDataByConfigID(config.ID)
+func (r *ConfigRepository) GetConfigs(namespace, name string) ([]*entity.Config, error) {
+	configs, err := selectVersions(r.db, namespace, "SELECT id, name, version, created_at, promoted_from_namespace, "+
+		"promoted_from_version FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC", namespace, name)
 	if err != nil {
 		return nil, err
 	}
-	err = r.updateLastUsed(config.ID)
-	if err != nil && err != sql.ErrNoRows {
+	if err := r.loadVersions(configs); err != nil {
 		return nil, err
-	} else if err == sql.ErrNoRows {
-		return nil, usecase.ErrConfigNotFound
 	}
-	return &config, nil
+	return configs, nil
 }
 
-func (r *ConfigRepository) GetConfigs(name string) ([]*entity.Config, error) {
-	rows, err := r.db.Query("SELECT id, name, version, created_at FROM configs WHERE name = $1 ORDER BY version DESC", name)
-	if err != nil && err != sql.ErrNoRows {
+func (r *ConfigRepository) ListConfigVersions(filter *entity.VersionFilter) ([]*entity.Config, error) {
+	args := []interface{}{filter.Namespace, filter.Name}
+	query := "SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version " +
+		"FROM configs WHERE namespace = $1 AND name = $2"
+	if filter.BeforeVersion != 0 {
+		args = append(args, filter.BeforeVersion)
+		query += " AND version < $" + strconv.Itoa(len(args))
+	}
+	query += " ORDER BY version DESC"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+	configs, err := selectVersions(r.db, filter.Namespace, query, args...)
+	if err != nil {
 		return nil, err
-	} else if err == sql.ErrNoRows {
-		return nil, usecase.ErrConfigNotFound
 	}
-	defer func(rows *sql.Rows) {
-		err = rows.Close()
-	}(rows)
+	if filter.WithoutData {
+		return configs, nil
+	}
+	if err := r.loadVersions(configs); err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
 
+// selectVersions returns the versions selected by the query, which selects the columns scanned by scanConfig.
+// The rows are closed before it returns, so that the connection is released before the data is loaded.
+func selectVersions(q querier, namespace, query string, args ...interface{}) ([]*entity.Config, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 	var configs []*entity.Config
 	for rows.Next() {
-		var config entity.Config
-		err = rows.Scan(&config.ID, &config.Name, &config.Version, &config.CreatedAt)
-		if err != nil {
+		config := entity.Config{Namespace: namespace}
+		if err := scanConfig(rows, &config); err != nil {
 			return nil, err
 		}
-		config.Data, err = r.GetDataByConfigID(config.ID)
-		if err != nil {
-			return nil, err
+		configs = append(configs, &config)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return configs, rows.Close()
+}
+
+// loadVersions reads the data of the versions with a single query and updates their last use with another,
+// however many versions there are.
+func (r *ConfigRepository) loadVersions(configs []*entity.Config) error {
+	if len(configs) == 0 {
+		return nil
+	}
+	ids := make([]int, 0, len(configs))
+	byID := make(map[int]*entity.Config, len(configs))
+	for _, config := range configs {
+		ids = append(ids, config.ID)
+		byID[config.ID] = config
+		config.Data = make(map[string]string)
+	}
+	rows, err := r.db.Query("SELECT config_id, key, value, type FROM pairs WHERE config_id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int
+		var key, value string
+		var valueType entity.ValueType
+		if err := rows.Scan(&id, &key, &value, &valueType); err != nil {
+			return err
 		}
-		err := r.updateLastUsed(config.ID)
-		if err != nil {
-			return nil, err
+		config, ok := byID[id]
+		if !ok {
+			continue
+		}
+		if config.Data[key], err = r.openPair(id, key, value, valueType); err != nil {
+			return err
+		}
+		if valueType != entity.TypeString {
+			if config.Types == nil {
+				config.Types = make(map[string]entity.ValueType)
+			}
+			config.Types[key] = valueType
 		}
-		configs = append(configs, &config)
 	}
-	return configs, nil
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	_, err = r.db.Exec("UPDATE configs SET last_used = $1 WHERE id = ANY($2)", time.Now(), pq.Array(ids))
+	return err
 }
 
-func (r *ConfigRepository) GetConfigByVersion(name string, version int64) (*entity.Config, error) {
-	var config entity.Config
-	err := r.db.QueryRow("SELECT id, name, version, created_at FROM configs WHERE name = $1 AND version = $2",
-		name, version).Scan(&config.ID, &config.Name, &config.Version, &config.CreatedAt)
+// ListConfigNames lists the relevant versions of the configs, which every config has exactly one of, so that
+// the listing is served by the partial indexes of relevant versions rather than by a scan of every version.
+func (r *ConfigRepository) ListConfigNames(filter *entity.NameFilter) ([]*entity.ConfigSummary, error) {
+	args := []interface{}{filter.Namespace}
+	query := "SELECT name, version, created_at, last_used FROM configs c WHERE namespace = $1 AND relevant = TRUE"
+	if filter.Prefix != "" {
+		args = append(args, likeEscaper.Replace(filter.Prefix)+"%")
+		query += " AND " + nameColumn + " LIKE $" + strconv.Itoa(len(args))
+	}
+	if filter.Contains != "" {
+		args = append(args, "%"+likeEscaper.Replace(filter.Contains)+"%")
+		query += " AND name LIKE $" + strconv.Itoa(len(args))
+	}
+	keys := make([]string, 0, len(filter.Labels))
+	for key := range filter.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		args = append(args, key, filter.Labels[key])
+		query += " AND EXISTS (SELECT 1 FROM config_labels l WHERE l.namespace = c.namespace AND l.name = c.name " +
+			"AND l.key = $" + strconv.Itoa(len(args)-1) + " AND l.value = $" + strconv.Itoa(len(args)) + ")"
+	}
+	column := orderColumns[filter.OrderBy]
+	direction, comparison := "", " > "
+	if filter.Descending {
+		direction, comparison = " DESC", " < "
+	}
+	if filter.After != nil && column == "" {
+		args = append(args, filter.After.Name)
+		query += " AND " + nameColumn + comparison + "$" + strconv.Itoa(len(args))
+	} else if filter.After != nil {
+		args = append(args, filter.After.Time, filter.After.Name)
+		query += " AND (" + column + ", " + nameColumn + ")" + comparison +
+			"($" + strconv.Itoa(len(args)-1) + ", $" + strconv.Itoa(len(args)) + ")"
+	}
+	query += " ORDER BY "
+	if column != "" {
+		query += column + direction + ", "
+	}
+	query += nameColumn + direction
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += " LIMIT $" + strconv.Itoa(len(args))
+	}
+
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
-	config.Data, err = r.GetDataByConfigID(config.ID)
-	if err != nil {
+	defer rows.Close()
+	summaries := make([]*entity.ConfigSummary, 0)
+	names := make([]string, 0)
+	for rows.Next() {
+		summary := &entity.ConfigSummary{Namespace: filter.Namespace}
+		if err := rows.Scan(&summary.Name, &summary.Version, &summary.CreatedAt, &summary.LastUsed); err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+		names = append(names, summary.Name)
+	}
+	if err := rows.Err(); err != nil {
 		return nil, err
 	}
-	err = r.updateLastUsed(config.ID)
-	if err != nil && err != sql.ErrNoRows {
+	if len(summaries) == 0 {
+		return summaries, nil
+	}
+	labels, err := selectLabels(r.db, filter.Namespace, names)
+	if err != nil {
 		return nil, err
-	} else if err == sql.ErrNoRows {
-		return nil, usecase.ErrConfigNotFound
 	}
-	return &config, nil
+	for _, summary := range summaries {
+		summary.Labels = labels[summary.Name]
+	}
+	return summaries, nil
 }
 
-func (r *ConfigRepository) DeleteConfig(name string) error {
-	_, err := r.db.Exec("DELETE FROM pairs WHERE config_id IN (SELECT id FROM configs WHERE name = $1)", name)
+func (r *ConfigRepository) GetConfigByVersion(namespace, name string, version int64) (*entity.Config, error) {
+	config, err := r.PeekConfig(namespace, name, version)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = r.db.Exec("DELETE FROM configs WHERE name = $1", name)
-	return err
+	if err := r.updateLastUsed(config.ID); err != nil {
+		return nil, err
+	}
+	return config, nil
 }
 
-func (r *ConfigRepository) DeleteConfigVersion(name string, version int64) error {
-	_, err := r.db.Exec("DELETE FROM pairs WHERE config_id IN (SELECT id FROM configs WHERE name = $1 AND version = $2)",
-		name, version)
-	if err != nil {
+func (r *ConfigRepository) DeleteConfig(namespace, name string, audit *entity.AuditEvent) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		if err := lockConfig(tx, namespace, name); err != nil {
+			return err
+		}
+		from, err := r.relevantBefore(tx, namespace, name, audit)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM pairs WHERE config_id IN (SELECT id FROM configs WHERE namespace = $1 AND name = $2)",
+			namespace, name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM configs WHERE namespace = $1 AND name = $2", namespace, name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM config_overrides WHERE namespace = $1 AND name = $2", namespace, name)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM config_labels WHERE namespace = $1 AND name = $2", namespace, name)
+		if err != nil {
+			return err
+		}
+		return recordChange(tx, audit, namespace, name, from, nil)
+	})
+}
+
+// DeleteConfigVersion deletes a single version. If it was relevant, the latest remaining version becomes relevant.
+func (r *ConfigRepository) DeleteConfigVersion(namespace, name string, version int64, audit *entity.AuditEvent) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		if err := lockConfig(tx, namespace, name); err != nil {
+			return err
+		}
+		var relevant bool
+		err := tx.QueryRow("SELECT relevant FROM configs WHERE namespace = $1 AND name = $2 AND version = $3",
+			namespace, name, version).Scan(&relevant)
+		if err == sql.ErrNoRows {
+			return usecase.ErrConfigNotFound
+		} else if err != nil {
+			return err
+		}
+		var next *entity.Config
+		if relevant {
+			if next, err = r.nextRelevant(tx, namespace, name, version); err != nil {
+				return err
+			}
+		}
+		if audit != nil {
+			deleted, err := r.selectConfig(tx, namespace, name, version)
+			if err != nil {
+				return err
+			}
+			if err := recordChange(tx, audit, namespace, name, deleted, nil); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec("DELETE FROM pairs WHERE config_id IN "+
+			"(SELECT id FROM configs WHERE namespace = $1 AND name = $2 AND version = $3)", namespace, name, version)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec("DELETE FROM configs WHERE namespace = $1 AND name = $2 AND version = $3", namespace, name, version)
+		if err != nil || next == nil {
+			return err
+		}
+		_, err = tx.Exec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE id = $2", time.Now(), next.ID)
 		return err
+	})
+}
+
+// nextRelevant returns the latest version of the config other than the relevant version, which becomes relevant when
+// the relevant version is deleted, or nil if there is none. Like versions made relevant by SetRelevantConfig,
+// it must satisfy the schema, otherwise usecase.ErrSchemaViolation is returned.
+func (r *ConfigRepository) nextRelevant(tx *sql.Tx, namespace, name string, relevant int64) (*entity.Config, error) {
+	var version int64
+	err := tx.QueryRow("SELECT version FROM configs WHERE namespace = $1 AND name = $2 AND version <> $3 "+
+		"ORDER BY version DESC LIMIT 1", namespace, name, relevant).Scan(&version)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
 	}
-	_, err = r.db.Exec("DELETE FROM configs WHERE name = $1 AND version = $2", name, version)
-	return err
+	next, err := r.selectConfig(tx, namespace, name, version)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := latestSchema(tx, namespace, name)
+	if err == sql.ErrNoRows {
+		return next, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if err := schema.Check(next); err != nil {
+		return nil, fmt.Errorf("%w: version %d would become relevant: %s", usecase.ErrSchemaViolation, version, err)
+	}
+	return next, nil
 }
 
-func (r *ConfigRepository) UpdateConfig(config *entity.Config) error {
+// UpdateConfig creates the next version of the config. If expectedVersion is not zero,
+// it fails with usecase.ErrConfigVersionConflict unless expectedVersion is the latest version.
+func (r *ConfigRepository) UpdateConfig(config *entity.Config, expectedVersion int64, audit *entity.AuditEvent) error {
 	if err := config.Validate(); err != nil {
 		return err
 	}
-	version, err := r.GetLastVersion(config.Name)
-	if err != nil && err != sql.ErrNoRows {
-		return err
-	} else if err == sql.ErrNoRows {
-		config.Version = 1
-	} else {
+	return r.withTx(func(tx *sql.Tx) error {
+		if err := lockConfig(tx, config.Namespace, config.Name); err != nil {
+			return err
+		}
+		version, err := lastVersion(tx, config.Namespace, config.Name)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		if expectedVersion != 0 && version != expectedVersion {
+			return usecase.ErrConfigVersionConflict
+		}
+		if version != 0 {
+			secrets, err := secretKeys(tx, config.Namespace, config.Name, version)
+			if err != nil {
+				return err
+			}
+			config.KeepSecrets(secrets)
+			if err := config.Validate(); err != nil {
+				return err
+			}
+		}
+		if err := checkSchema(tx, config); err != nil {
+			return err
+		}
+		from, err := r.relevantBefore(tx, config.Namespace, config.Name, audit)
+		if err != nil {
+			return err
+		}
 		config.Version = version + 1
+		if err := insertConfig(tx, config); err != nil {
+			return err
+		}
+		if err := r.insertData(tx, config); err != nil {
+			return err
+		}
+		if err := setRelevant(tx, config.Namespace, config.Name, config.Version); err != nil {
+			return err
+		}
+		return recordChange(tx, audit, config.Namespace, config.Name, from, config)
+	})
+}
+
+// PatchConfig applies the patch to the relevant version and stores the result as the next version.
+// If expectedVersion is not zero, it fails with usecase.ErrConfigVersionConflict unless expectedVersion
+// is the relevant version.
+func (r *ConfigRepository) PatchConfig(patch *entity.ConfigPatch, expectedVersion int64, audit *entity.AuditEvent) (*entity.Config, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
 	}
-	err = r.db.QueryRow("INSERT INTO configs (name, version) VALUES ($1, $2) RETURNING id, version, created_at",
-		config.Name, config.Version).Scan(&config.ID, &config.Version, &config.CreatedAt)
-	if err != nil {
-		return err
-	}
-	err = r.insertData(config.ID, config.Data)
+	var config *entity.Config
+	err := r.withTx(func(tx *sql.Tx) error {
+		if err := lockConfig(tx, patch.Namespace, patch.Name); err != nil {
+			return err
+		}
+		base := entity.Config{Namespace: patch.Namespace}
+		err := tx.QueryRow("SELECT id, name, version FROM configs WHERE namespace = $1 AND name = $2 AND relevant = TRUE",
+			patch.Namespace, patch.Name).Scan(&base.ID, &base.Name, &base.Version)
+		if err == sql.ErrNoRows {
+			return usecase.ErrConfigNotFound
+		} else if err != nil {
+			return err
+		}
+		if expectedVersion != 0 && base.Version != expectedVersion {
+			return usecase.ErrConfigVersionConflict
+		}
+		if base.Data, base.Types, err = r.selectData(tx, base.ID); err != nil {
+			return err
+		}
+		config = patch.Apply(&base)
+		if err := config.Validate(); err != nil {
+			return err
+		}
+		if err := checkSchema(tx, config); err != nil {
+			return err
+		}
+		version, err := lastVersion(tx, patch.Namespace, patch.Name)
+		if err != nil {
+			return err
+		}
+		config.Version = version + 1
+		if err := insertConfig(tx, config); err != nil {
+			return err
+		}
+		if err := r.insertData(tx, config); err != nil {
+			return err
+		}
+		if err := setRelevant(tx, config.Namespace, config.Name, config.Version); err != nil {
+			return err
+		}
+		return recordChange(tx, audit, patch.Namespace, patch.Name, &base, config)
+	})
 	if err != nil {
-		return err
+		return nil, err
 	}
-	_, err = r.SetRelevantConfig(config.Name, config.Version)
-	return err
+	return config, nil
 }
 
-func (r *ConfigRepository) SetRelevantConfig(name string, version int64) (*entity.Config, error) {
-	_, err := r.db.Exec("UPDATE configs SET relevant = FALSE WHERE name = $1", name)
+func (r *ConfigRepository) PromoteConfig(promotion *entity.ConfigPromotion, audit *entity.AuditEvent) (*entity.Config, error) {
+	if err := promotion.Validate(); err != nil {
+		return nil, err
+	}
+	var config *entity.Config
+	err := r.withTx(func(tx *sql.Tx) error {
+		if err := lockConfig(tx, promotion.TargetNamespace, promotion.Name); err != nil {
+			return err
+		}
+		source := entity.Config{Namespace: promotion.SourceNamespace}
+		err := tx.QueryRow("SELECT id, name, version FROM configs WHERE namespace = $1 AND name = $2 AND version = $3",
+			promotion.SourceNamespace, promotion.Name, promotion.Version).Scan(&source.ID, &source.Name, &source.Version)
+		if err == sql.ErrNoRows {
+			return usecase.ErrConfigNotFound
+		} else if err != nil {
+			return err
+		}
+		if source.Data, source.Types, err = r.selectData(tx, source.ID); err != nil {
+			return err
+		}
+		stored, err := r.selectOverrides(tx, promotion.TargetNamespace, promotion.Name)
+		if err != nil {
+			return err
+		}
+		overrides := promotion.ApplyOverrides(stored)
+		if err := r.replaceOverrides(tx, &source, promotion.TargetNamespace, overrides); err != nil {
+			return err
+		}
+		config = promotion.Apply(&source, overrides)
+		if err := config.Validate(); err != nil {
+			return err
+		}
+		if err := checkSchema(tx, config); err != nil {
+			return err
+		}
+		version, err := lastVersion(tx, promotion.TargetNamespace, promotion.Name)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+		from, err := r.relevantBefore(tx, promotion.TargetNamespace, promotion.Name, audit)
+		if err != nil {
+			return err
+		}
+		config.Version = version + 1
+		if err := insertConfig(tx, config); err != nil {
+			return err
+		}
+		if err := r.insertData(tx, config); err != nil {
+			return err
+		}
+		if err := setRelevant(tx, config.Namespace, config.Name, config.Version); err != nil {
+			return err
+		}
+		return recordChange(tx, audit, promotion.TargetNamespace, promotion.Name, from, config)
+	})
 	if err != nil {
 		return nil, err
 	}
-	_, err = r.db.Exec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE name = $2 AND version = $3",
-		time.Now(), name, version)
+	return config, nil
+}
+
+func (r *ConfigRepository) GetConfigOverrides(namespace, name string) (map[string]string, error) {
+	return r.selectOverrides(r.db, namespace, name)
+}
+
+func (r *ConfigRepository) SetConfigLabels(labels *entity.ConfigLabels, audit *entity.AuditEvent) error {
+	if err := labels.Validate(); err != nil {
+		return err
+	}
+	return r.withTx(func(tx *sql.Tx) error {
+		if err := lockConfig(tx, labels.Namespace, labels.Name); err != nil {
+			return err
+		}
+		var exists bool
+		err := tx.QueryRow("SELECT EXISTS (SELECT 1 FROM configs WHERE namespace = $1 AND name = $2)",
+			labels.Namespace, labels.Name).Scan(&exists)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return usecase.ErrConfigNotFound
+		}
+		_, err = tx.Exec("DELETE FROM config_labels WHERE namespace = $1 AND name = $2", labels.Namespace, labels.Name)
+		if err != nil {
+			return err
+		}
+		keys := make([]string, 0, len(labels.Labels))
+		for key := range labels.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			_, err := tx.Exec("INSERT INTO config_labels (namespace, name, key, value) VALUES ($1, $2, $3, $4)",
+				labels.Namespace, labels.Name, key, labels.Labels[key])
+			if err != nil {
+				return err
+			}
+		}
+		if audit == nil {
+			return nil
+		}
+		audit.SetLabelsChange(labels)
+		return insertAuditEvent(tx, audit)
+	})
+}
+
+func (r *ConfigRepository) SetRelevantConfig(namespace, name string, version int64, audit *entity.AuditEvent) (*entity.Config, error) {
+	err := r.withTx(func(tx *sql.Tx) error {
+		if err := lockConfig(tx, namespace, name); err != nil {
+			return err
+		}
+		config := entity.Config{Namespace: namespace, Name: name, Version: version}
+		err := tx.QueryRow("SELECT id FROM configs WHERE namespace = $1 AND name = $2 AND version = $3",
+			namespace, name, version).Scan(&config.ID)
+		if err == sql.ErrNoRows {
+			return usecase.ErrConfigNotFound
+		} else if err != nil {
+			return err
+		}
+		if config.Data, config.Types, err = r.selectData(tx, config.ID); err != nil {
+			return err
+		}
+		if err := checkSchema(tx, &config); err != nil {
+			return err
+		}
+		from, err := r.relevantBefore(tx, namespace, name, audit)
+		if err != nil {
+			return err
+		}
+		if err := setRelevant(tx, namespace, name, version); err != nil {
+			return err
+		}
+		return recordChange(tx, audit, namespace, name, from, &config)
+	})
 	if err != nil {
 		return nil, err
 	}
-	return r.GetConfigByVersion(name, version)
+	return r.GetConfigByVersion(namespace, name, version)
 }
 
+// GetDataByConfigID returns the values of the config in their string form.
 func (r *ConfigRepository) GetDataByConfigID(id int) (map[string]string, error) {
-	rows, err := r.db.Query("SELECT key, value FROM pairs WHERE config_id = $1", id)
+	data, _, err := r.selectData(r.db, id)
+	return data, err
+}
+
+// selectData returns the values of the config and the types of the values that are not strings.
+func (r *ConfigRepository) selectData(q querier, id int) (map[string]string, map[string]entity.ValueType, error) {
+	rows, err := q.Query("SELECT key, value, type FROM pairs WHERE config_id = $1", id)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer func(rows *sql.Rows) {
 		err = rows.Close()
 	}(rows)
 	data := make(map[string]string)
+	var types map[string]entity.ValueType
+	for rows.Next() {
+		var key, value string
+		var valueType entity.ValueType
+		err = rows.Scan(&key, &value, &valueType)
+		if err != nil {
+			return nil, nil, err
+		}
+		if data[key], err = r.openPair(id, key, value, valueType); err != nil {
+			return nil, nil, err
+		}
+		if valueType != entity.TypeString {
+			if types == nil {
+				types = make(map[string]entity.ValueType)
+			}
+			types[key] = valueType
+		}
+	}
+	return data, types, nil
+}
+
+// openPair returns the value of a pair, decrypting it if it is secret.
+func (r *ConfigRepository) openPair(configID int, key, value string, valueType entity.ValueType) (string, error) {
+	if valueType != entity.TypeSecret {
+		return value, nil
+	}
+	return r.open(value, pairAdditionalData(configID, key))
+}
+
+func (r *ConfigRepository) selectOverrides(q querier, namespace, name string) (map[string]string, error) {
+	rows, err := q.Query("SELECT key, value, secret FROM config_overrides WHERE namespace = $1 AND name = $2",
+		namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	overrides := make(map[string]string)
 	for rows.Next() {
 		var key, value string
-		err = rows.Scan(&key, &value)
+		var secret bool
+		if err := rows.Scan(&key, &value, &secret); err != nil {
+			return nil, err
+		}
+		if secret {
+			if value, err = r.open(value, overrideAdditionalData(namespace, name, key)); err != nil {
+				return nil, err
+			}
+		}
+		overrides[key] = value
+	}
+	return overrides, rows.Err()
+}
+
+// replaceOverrides stores the overrides of the config in the namespace. Overrides of keys that are secret
+// in the source config are encrypted.
+func (r *ConfigRepository) replaceOverrides(tx *sql.Tx, source *entity.Config, namespace string,
+	overrides map[string]string) error {
+	_, err := tx.Exec("DELETE FROM config_overrides WHERE namespace = $1 AND name = $2", namespace, source.Name)
+	if err != nil {
+		return err
+	}
+	for key, value := range overrides {
+		secret := source.TypeOf(key) == entity.TypeSecret
+		if secret {
+			if value, err = r.seal(value, overrideAdditionalData(namespace, source.Name, key)); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec("INSERT INTO config_overrides (namespace, name, key, value, secret) VALUES ($1, $2, $3, $4, $5)",
+			namespace, source.Name, key, value, secret)
 		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// selectLabels returns the labels of the configs of the namespace by their names. Configs without labels
+// are missing from the result.
+func selectLabels(q querier, namespace string, names []string) (map[string]map[string]string, error) {
+	rows, err := q.Query("SELECT name, key, value FROM config_labels WHERE namespace = $1 AND name = ANY($2)",
+		namespace, pq.Array(names))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	labels := make(map[string]map[string]string)
+	for rows.Next() {
+		var name, key, value string
+		if err := rows.Scan(&name, &key, &value); err != nil {
 			return nil, err
 		}
-		data[key] = value
+		if labels[name] == nil {
+			labels[name] = make(map[string]string)
+		}
+		labels[name][key] = value
 	}
-	return data, nil
+	return labels, rows.Err()
 }
 
-func (r *ConfigRepository) GetRelevantLastUsed(name string) (time.Time, error) {
+func (r *ConfigRepository) GetRelevantLastUsed(namespace, name string) (time.Time, error) {
 	var lastUsed time.Time
-	err := r.db.QueryRow("SELECT last_used FROM configs WHERE name = $1 AND relevant = TRUE", name).Scan(&lastUsed)
+	err := r.db.QueryRow("SELECT last_used FROM configs WHERE namespace = $1 AND name = $2 AND relevant = TRUE",
+		namespace, name).Scan(&lastUsed)
 	if err == sql.ErrNoRows {
 		return time.Time{}, usecase.ErrConfigNotFound
 	}
 	return lastUsed, err
 }
 
-func (r *ConfigRepository) GetLastUsedByVersion(name string, version int64) (time.Time, error) {
+func (r *ConfigRepository) GetLastUsedByVersion(namespace, name string, version int64) (time.Time, error) {
 	var lastUsed time.Time
-	err := r.db.QueryRow("SELECT last_used FROM configs WHERE name = $1 AND version = $2", name, version).Scan(&lastUsed)
+	err := r.db.QueryRow("SELECT last_used FROM configs WHERE namespace = $1 AND name = $2 AND version = $3",
+		namespace, name, version).Scan(&lastUsed)
 	if err == sql.ErrNoRows {
 		return time.Time{}, usecase.ErrConfigNotFound
 	}
 	return lastUsed, err
 }
 
-func (r *ConfigRepository) insertData(configID int, data map[string]string) error {
-	for key, value := range data {
-		_, err := r.db.Exec("INSERT INTO pairs (config_id, key, value) VALUES ($1, $2, $3)", configID, key, value)
-		if err != nil {
-			return err
-		}
+// SetLastUsed updates the last use of every version in a single statement. Times are sent the way lib/pq sends
+// them, like those of the other updates of last_used.
+func (r *ConfigRepository) SetLastUsed(uses []*entity.LastUse) error {
+	if len(uses) == 0 {
+		return nil
 	}
-	return nil
+	namespaces := make([]string, 0, len(uses))
+	names := make([]string, 0, len(uses))
+	versions := make([]int64, 0, len(uses))
+	times := make([]string, 0, len(uses))
+	for _, use := range uses {
+		namespaces = append(namespaces, use.Namespace)
+		names = append(names, use.Name)
+		versions = append(versions, use.Version)
+		times = append(times, string(pq.FormatTimestamp(use.Time)))
+	}
+	_, err := r.db.Exec("UPDATE configs SET last_used = GREATEST(configs.last_used, uses.last_used) "+
+		"FROM unnest($1::text[], $2::text[], $3::bigint[], $4::timestamp[]) AS uses (namespace, name, version, last_used) "+
+		"WHERE configs.namespace = uses.namespace AND configs.name = uses.name AND configs.version = uses.version",
+		pq.Array(namespaces), pq.Array(names), pq.Array(versions), pq.Array(times))
+	return err
 }
 
-func (r *ConfigRepository) IsConfigExists(name string) (bool, error) {
+func (r *ConfigRepository) IsConfigExists(namespace, name string) (bool, error) {
 	var exists bool
-	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM configs WHERE name = $1)", name).Scan(&exists)
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM configs WHERE namespace = $1 AND name = $2)",
+		namespace, name).Scan(&exists)
 	return exists, err
 }
 
-func (r *ConfigRepository) IsConfigVersionExists(name string, version int64) (bool, error) {
+func (r *ConfigRepository) IsConfigVersionExists(namespace, name string, version int64) (bool, error) {
 	var exists bool
-	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM configs WHERE name = $1 AND version = $2)", name, version).Scan(&exists)
+	err := r.db.QueryRow("SELECT EXISTS (SELECT 1 FROM configs WHERE namespace = $1 AND name = $2 AND version = $3)",
+		namespace, name, version).Scan(&exists)
 	return exists, err
 }
 
-func (r *ConfigRepository) IsConfigRelevant(name string, version int64) (bool, error) {
+func (r *ConfigRepository) IsConfigRelevant(namespace, name string, version int64) (bool, error) {
 	var relevant bool
-	err := r.db.QueryRow("SELECT relevant FROM configs WHERE name = $1 AND version = $2", name, version).Scan(&relevant)
+	err := r.db.QueryRow("SELECT relevant FROM configs WHERE namespace = $1 AND name = $2 AND version = $3",
+		namespace, name, version).Scan(&relevant)
+	if err == sql.ErrNoRows {
+		return false, usecase.ErrConfigNotFound
+	}
 	return relevant, err
 }
 
-func (r *ConfigRepository) GetLastVersion(name string) (int64, error) {
-	var version int64
-	err := r.db.QueryRow("SELECT version FROM configs WHERE name = $1 ORDER BY version DESC LIMIT 1", name).Scan(&version)
+func (r *ConfigRepository) GetLastVersion(namespace, name string) (int64, error) {
+	version, err := lastVersion(r.db, namespace, name)
+	if err == sql.ErrNoRows {
+		return 0, usecase.ErrConfigNotFound
+	}
 	return version, err
 }
 
+func (r *ConfigRepository) GetNamespaces() ([]string, error) {
+	rows, err := r.db.Query("SELECT DISTINCT namespace FROM configs ORDER BY namespace")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var namespaces []string
+	for rows.Next() {
+		var namespace string
+		if err := rows.Scan(&namespace); err != nil {
+			return nil, err
+		}
+		namespaces = append(namespaces, namespace)
+	}
+	return namespaces, rows.Err()
+}
+
+func (r *ConfigRepository) GetConfigCounts() ([]*entity.ConfigCount, error) {
+	rows, err := r.db.Query("SELECT namespace, COUNT(DISTINCT name), COUNT(*) FROM configs GROUP BY namespace ORDER BY namespace")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make([]*entity.ConfigCount, 0)
+	for rows.Next() {
+		count := &entity.ConfigCount{}
+		if err := rows.Scan(&count.Namespace, &count.Configs, &count.Versions); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+func (r *ConfigRepository) CreateConfigSchema(schema *entity.ConfigSchema, audit *entity.AuditEvent) error {
+	if err := schema.Validate(); err != nil {
+		return err
+	}
+	keys, err := json.Marshal(schema.Keys)
+	if err != nil {
+		return err
+	}
+	return r.withTx(func(tx *sql.Tx) error {
+		if err := lockConfig(tx, schema.Namespace, schema.Name); err != nil {
+			return err
+		}
+		var version int64
+		err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM config_schemas WHERE namespace = $1 AND name = $2",
+			schema.Namespace, schema.Name).Scan(&version)
+		if err != nil {
+			return err
+		}
+		err = tx.QueryRow("INSERT INTO config_schemas (namespace, name, version, keys, allow_unknown_keys) "+
+			"VALUES ($1, $2, $3, $4, $5) RETURNING version, created_at",
+			schema.Namespace, schema.Name, version+1, string(keys), schema.AllowUnknownKeys).
+			Scan(&schema.Version, &schema.CreatedAt)
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+			return usecase.ErrConfigVersionConflict
+		} else if err != nil || audit == nil {
+			return err
+		}
+		audit.SetSchemaChange(schema.Namespace, schema.Name, version, schema.Version)
+		return insertAuditEvent(tx, audit)
+	})
+}
+
+func (r *ConfigRepository) GetConfigSchema(namespace, name string) (*entity.ConfigSchema, error) {
+	schema, err := latestSchema(r.db, namespace, name)
+	if err == sql.ErrNoRows {
+		return nil, usecase.ErrSchemaNotFound
+	}
+	return schema, err
+}
+
+func (r *ConfigRepository) GetConfigSchemaByVersion(namespace, name string, version int64) (*entity.ConfigSchema, error) {
+	schema := &entity.ConfigSchema{Namespace: namespace, Name: name}
+	err := scanSchema(r.db.QueryRow("SELECT version, created_at, keys, allow_unknown_keys FROM config_schemas "+
+		"WHERE namespace = $1 AND name = $2 AND version = $3", namespace, name, version), schema)
+	if err == sql.ErrNoRows {
+		return nil, usecase.ErrSchemaNotFound
+	} else if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+func (r *ConfigRepository) GetConfigSchemas(namespace, name string) ([]*entity.ConfigSchema, error) {
+	rows, err := r.db.Query("SELECT version, created_at, keys, allow_unknown_keys FROM config_schemas "+
+		"WHERE namespace = $1 AND name = $2 ORDER BY version DESC", namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	schemas := make([]*entity.ConfigSchema, 0)
+	for rows.Next() {
+		schema := &entity.ConfigSchema{Namespace: namespace, Name: name}
+		if err := scanSchema(rows, schema); err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, rows.Err()
+}
+
+func (r *ConfigRepository) DeleteConfigSchema(namespace, name string, audit *entity.AuditEvent) error {
+	return r.withTx(func(tx *sql.Tx) error {
+		if err := lockConfig(tx, namespace, name); err != nil {
+			return err
+		}
+		if audit != nil {
+			var version int64
+			err := tx.QueryRow("SELECT COALESCE(MAX(version), 0) FROM config_schemas WHERE namespace = $1 AND name = $2",
+				namespace, name).Scan(&version)
+			if err != nil {
+				return err
+			}
+			audit.SetSchemaChange(namespace, name, version, 0)
+			if err := insertAuditEvent(tx, audit); err != nil {
+				return err
+			}
+		}
+		_, err := tx.Exec("DELETE FROM config_schemas WHERE namespace = $1 AND name = $2", namespace, name)
+		return err
+	})
+}
+
+// checkSchema checks the config against the latest version of its schema, if it has one.
+func checkSchema(q querier, config *entity.Config) error {
+	schema, err := latestSchema(q, config.Namespace, config.Name)
+	if err == sql.ErrNoRows {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	return schema.Check(config)
+}
+
+func latestSchema(q querier, namespace, name string) (*entity.ConfigSchema, error) {
+	schema := &entity.ConfigSchema{Namespace: namespace, Name: name}
+	err := scanSchema(q.QueryRow("SELECT version, created_at, keys, allow_unknown_keys FROM config_schemas "+
+		"WHERE namespace = $1 AND name = $2 ORDER BY version DESC LIMIT 1", namespace, name), schema)
+	if err != nil {
+		return nil, err
+	}
+	return schema, nil
+}
+
+// scanSchema scans version, created_at, keys and allow_unknown_keys.
+func scanSchema(row scanner, schema *entity.ConfigSchema) error {
+	var keys []byte
+	if err := row.Scan(&schema.Version, &schema.CreatedAt, &keys, &schema.AllowUnknownKeys); err != nil {
+		return err
+	}
+	return json.Unmarshal(keys, &schema.Keys)
+}
+
 func (r *ConfigRepository) updateLastUsed(configID int) error {
 	_, err := r.db.Exec("UPDATE configs SET last_used = $1 WHERE id = $2", time.Now(), configID)
 	return err
 }
+
+// withTx runs fn in a single transaction, rolling it back if fn fails.
+func (r *ConfigRepository) withTx(fn func(tx *sql.Tx) error) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// lockConfig locks every version of the config until the end of the transaction,
+// so concurrent writers of the same config are serialized.
+func lockConfig(tx *sql.Tx, namespace, name string) error {
+	_, err := tx.Exec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE", namespace, name)
+	return err
+}
+
+// scanConfig scans id, name, version, created_at, promoted_from_namespace and promoted_from_version.
+func scanConfig(row scanner, config *entity.Config) error {
+	var promotedFromNamespace sql.NullString
+	var promotedFromVersion sql.NullInt64
+	err := row.Scan(&config.ID, &config.Name, &config.Version, &config.CreatedAt, &promotedFromNamespace, &promotedFromVersion)
+	if err != nil {
+		return err
+	}
+	if promotedFromNamespace.Valid {
+		config.PromotedFrom = &entity.ConfigSource{Namespace: promotedFromNamespace.String, Version: promotedFromVersion.Int64}
+	}
+	return nil
+}
+
+func insertConfig(tx *sql.Tx, config *entity.Config) error {
+	var promotedFromNamespace, promotedFromVersion interface{}
+	if config.PromotedFrom != nil {
+		promotedFromNamespace, promotedFromVersion = config.PromotedFrom.Namespace, config.PromotedFrom.Version
+	}
+	err := tx.QueryRow("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at",
+		config.Namespace, config.Name, config.Version, promotedFromNamespace, promotedFromVersion).
+		Scan(&config.ID, &config.Version, &config.CreatedAt)
+	if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolation {
+		return usecase.ErrConfigAlreadyExists
+	}
+	return err
+}
+
+// insertData inserts the pairs of the config in statements of up to pairBatchSize rows each, in the order of keys.
+func (r *ConfigRepository) insertData(tx *sql.Tx, config *entity.Config) error {
+	keys := make([]string, 0, len(config.Data))
+	for key := range config.Data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for start := 0; start < len(keys); start += r.pairBatchSize {
+		end := start + r.pairBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+		if err := r.insertPairs(tx, config, keys[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ConfigRepository) insertPairs(tx *sql.Tx, config *entity.Config, keys []string) error {
+	var query strings.Builder
+	query.WriteString("INSERT INTO pairs (config_id, key, value, type) VALUES ")
+	args := make([]interface{}, 0, len(keys)*pairColumns)
+	for i, key := range keys {
+		value := config.Data[key]
+		if config.TypeOf(key) == entity.TypeSecret {
+			var err error
+			if value, err = r.seal(value, pairAdditionalData(config.ID, key)); err != nil {
+				return err
+			}
+		}
+		if i > 0 {
+			query.WriteString(", ")
+		}
+		n := len(args)
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4)
+		args = append(args, config.ID, key, value, config.TypeOf(key))
+	}
+	_, err := tx.Exec(query.String(), args...)
+	return err
+}
+
+func setRelevant(tx *sql.Tx, namespace, name string, version int64) error {
+	_, err := tx.Exec("UPDATE configs SET relevant = FALSE WHERE namespace = $1 AND name = $2 AND relevant = TRUE",
+		namespace, name)
+	if err != nil {
+		return err
+	}
+	result, err := tx.Exec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE namespace = $2 AND name = $3 AND version = $4",
+		time.Now(), namespace, name, version)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return usecase.ErrConfigNotFound
+	}
+	return nil
+}
+
+// secretKeys returns the sorted keys of the secret values of the version of the config.
+func secretKeys(q querier, namespace, name string, version int64) ([]string, error) {
+	rows, err := q.Query("SELECT pairs.key FROM pairs JOIN configs ON configs.id = pairs.config_id "+
+		"WHERE configs.namespace = $1 AND configs.name = $2 AND configs.version = $3 AND pairs.type = $4 ORDER BY pairs.key",
+		namespace, name, version, entity.TypeSecret)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func lastVersion(q querier, namespace, name string) (int64, error) {
+	var version int64
+	err := q.QueryRow("SELECT version FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC LIMIT 1",
+		namespace, name).Scan(&version)
+	return version, err
+}
+
+// ReencryptSecrets encrypts the data keys of the secret values of every version and of the overrides with
+// the primary master key, so that the master keys they were encrypted with can be removed from the keyring.
+// It returns the number of values that were re-encrypted.
+func (r *ConfigRepository) ReencryptSecrets() (int, error) {
+	if r.keyring == nil {
+		return 0, usecase.ErrNoMasterKey
+	}
+	var count int
+	err := r.withTx(func(tx *sql.Tx) error {
+		pairs, err := r.reencryptPairs(tx)
+		if err != nil {
+			return err
+		}
+		overrides, err := r.reencryptOverrides(tx)
+		if err != nil {
+			return err
+		}
+		count = pairs + overrides
+		return nil
+	})
+	return count, err
+}
+
+func (r *ConfigRepository) reencryptPairs(tx *sql.Tx) (int, error) {
+	type pair struct {
+		configID   int
+		key, value string
+	}
+	rows, err := tx.Query("SELECT config_id, key, value FROM pairs WHERE type = $1 FOR UPDATE", entity.TypeSecret)
+	if err != nil {
+		return 0, err
+	}
+	var pairs []pair
+	for rows.Next() {
+		var p pair
+		if err := rows.Scan(&p.configID, &p.key, &p.value); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		var changed bool
+		if p.value, changed, err = r.keyring.Rewrap(p.value); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		if changed {
+			pairs = append(pairs, p)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return 0, err
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	for _, p := range pairs {
+		_, err := tx.Exec("UPDATE pairs SET value = $1 WHERE config_id = $2 AND key = $3", p.value, p.configID, p.key)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(pairs), nil
+}
+
+func (r *ConfigRepository) reencryptOverrides(tx *sql.Tx) (int, error) {
+	type override struct {
+		namespace, name, key, value string
+	}
+	rows, err := tx.Query("SELECT namespace, name, key, value FROM config_overrides WHERE secret = TRUE FOR UPDATE")
+	if err != nil {
+		return 0, err
+	}
+	var overrides []override
+	for rows.Next() {
+		var o override
+		if err := rows.Scan(&o.namespace, &o.name, &o.key, &o.value); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		var changed bool
+		if o.value, changed, err = r.keyring.Rewrap(o.value); err != nil {
+			_ = rows.Close()
+			return 0, err
+		}
+		if changed {
+			overrides = append(overrides, o)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return 0, err
+	}
+	if err := rows.Close(); err != nil {
+		return 0, err
+	}
+	for _, o := range overrides {
+		_, err := tx.Exec("UPDATE config_overrides SET value = $1 WHERE namespace = $2 AND name = $3 AND key = $4",
+			o.value, o.namespace, o.name, o.key)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(overrides), nil
+}
+
+// seal encrypts a secret value. The additional data binds the encrypted value to its row.
+func (r *ConfigRepository) seal(value, additionalData string) (string, error) {
+	if r.keyring == nil {
+		return "", usecase.ErrNoMasterKey
+	}
+	return r.keyring.Seal([]byte(value), []byte(additionalData))
+}
+
+func (r *ConfigRepository) open(sealed, additionalData string) (string, error) {
+	if r.keyring == nil {
+		return "", usecase.ErrNoMasterKey
+	}
+	value, err := r.keyring.Open(sealed, []byte(additionalData))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+func pairAdditionalData(configID int, key string) string {
+	return fmt.Sprintf("pairs/%d/%s", configID, key)
+}
+
+func overrideAdditionalData(namespace, name, key string) string {
+	return fmt.Sprintf("config_overrides/%s/%s/%s", namespace, name, key)
+}
@@ -1,10 +1,23 @@
 package pg_repository
 
 import (
+	"database/sql"
 	"database/sql/driver"
 	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository"
+	"distributedConfig/internal/repository/repositorytest"
+	"distributedConfig/internal/secrets"
+	"distributedConfig/internal/usecase"
+	"encoding/base64"
+	"errors"
 	"github.com/DATA-DOG/go-sqlmock"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/require"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -16,105 +29,890 @@ func (a AnyTime) Match(v driver.Value) bool {
 	return ok
 }
 
+// sealedValue matches a value sealed by the keyring with the master key that opens to the plaintext.
+type sealedValue struct {
+	keyring        *secrets.Keyring
+	masterKey      string
+	additionalData string
+	plaintext      string
+}
+
+func (a sealedValue) Match(v driver.Value) bool {
+	sealed, ok := v.(string)
+	if !ok || !strings.HasPrefix(sealed, a.masterKey+":") {
+		return false
+	}
+	plaintext, err := a.keyring.Open(sealed, []byte(a.additionalData))
+	return err == nil && string(plaintext) == a.plaintext
+}
+
+// testKeyring returns a keyring with the master keys k1 and k2 and the primary one.
+func testKeyring(t testing.TB, primary string) *secrets.Keyring {
+	t.Helper()
+	keyring, err := secrets.NewKeyring(secrets.KeyFile{Primary: primary, Keys: map[string]string{
+		"k1": base64.StdEncoding.EncodeToString([]byte(strings.Repeat("1", 32))),
+		"k2": base64.StdEncoding.EncodeToString([]byte(strings.Repeat("2", 32))),
+	}})
+	require.NoError(t, err)
+	return keyring
+}
+
 func TestConfigRepository_CreateConfig(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
 	defer db.Close()
-	mock.ExpectQuery("INSERT INTO configs (name, version) VALUES ($1, $2) RETURNING id, version, created_at").
-		WithArgs("test", 1).
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSchema(mock, entity.DefaultNamespace, "test", "")
+	mock.ExpectQuery("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at").
+		WithArgs(entity.DefaultNamespace, "test", 1, nil, nil).
 		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "created_at"}).
 			AddRow(1, 1, time.Now()))
-	mock.ExpectExec("INSERT INTO pairs (config_id, key, value) VALUES ($1, $2, $3)").
-		WithArgs(1, "key1", "value1").
-		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("UPDATE configs SET relevant = FALSE WHERE name = $1").
-		WithArgs("test").
+	mock.ExpectExec("INSERT INTO pairs (config_id, key, value, type) VALUES ($1, $2, $3, $4)").
+		WithArgs(1, "key1", "value1", "string").
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE name = $2 AND version = $3").
-		WithArgs(AnyTime{}, "test", 1).
+	mock.ExpectExec("UPDATE configs SET relevant = FALSE WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE namespace = $2 AND name = $3 AND version = $4").
+		WithArgs(AnyTime{}, entity.DefaultNamespace, "test", 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	rows := sqlmock.NewRows([]string{"id", "name", "version", "created_at"}).
-		AddRow(1, "test", 1, time.Now())
-	mock.ExpectQuery("SELECT id, name, version, created_at FROM configs WHERE name = $1 AND version = $2").
-		WithArgs("test", 1).
-		WillReturnRows(rows)
-	pairRows := sqlmock.NewRows([]string{"key", "value"}).
-		AddRow("key1", "value1").
-		AddRow("key2", "value2")
-	mock.ExpectQuery("SELECT key, value FROM pairs WHERE config_id = $1").
-		WithArgs(1).
-		WillReturnRows(pairRows)
-	mock.ExpectExec("UPDATE configs SET last_used = $1 WHERE id = $2").
-		WithArgs(AnyTime{}, 1).
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, nil)
+	err = repo.CreateConfig(&entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Version:   1,
+		Data:      map[string]string{"key1": "value1"},
+	}, nil)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_CreateConfigInBatches(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSchema(mock, entity.DefaultNamespace, "test", "")
+	mock.ExpectQuery("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at").
+		WithArgs(entity.DefaultNamespace, "test", 1, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "created_at"}).
+			AddRow(1, 1, time.Now()))
+	mock.ExpectExec("INSERT INTO pairs (config_id, key, value, type) VALUES ($1, $2, $3, $4), ($5, $6, $7, $8)").
+		WithArgs(1, "a", "1", "string", 1, "b", "2", "int").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO pairs (config_id, key, value, type) VALUES ($1, $2, $3, $4), ($5, $6, $7, $8)").
+		WithArgs(1, "c", "3", "string", 1, "d", "4", "string").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO pairs (config_id, key, value, type) VALUES ($1, $2, $3, $4)").
+		WithArgs(1, "e", "5", "string").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE configs SET relevant = FALSE WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE namespace = $2 AND name = $3 AND version = $4").
+		WithArgs(AnyTime{}, entity.DefaultNamespace, "test", 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	repo := NewConfigRepository(db)
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, nil)
+	repo.SetPairBatchSize(2)
 	err = repo.CreateConfig(&entity.Config{
-		Name:    "test",
-		Version: 1,
-		Data:    map[string]string{"key1": "value1"},
-	})
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Version:   1,
+		Data:      map[string]string{"e": "5", "d": "4", "c": "3", "b": "2", "a": "1"},
+		Types:     map[string]entity.ValueType{"b": entity.TypeInt},
+	}, nil)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_SetPairBatchSize(t *testing.T) {
+	repo := NewConfigRepository(nil, nil)
+	for _, tc := range []struct {
+		size     int
+		expected int
+	}{
+		{size: 100, expected: 100},
+		{size: 1, expected: 1},
+		{size: 0, expected: DefaultPairBatchSize},
+		{size: -1, expected: DefaultPairBatchSize},
+		{size: 100000, expected: 16383},
+	} {
+		repo.SetPairBatchSize(tc.size)
+		require.Equal(t, tc.expected, repo.pairBatchSize, "size %d", tc.size)
+	}
+}
+
+func TestConfigRepository_CreateConfigRollback(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSchema(mock, entity.DefaultNamespace, "test", "")
+	mock.ExpectQuery("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at").
+		WithArgs(entity.DefaultNamespace, "test", 1, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "created_at"}).
+			AddRow(1, 1, time.Now()))
+	mock.ExpectExec("INSERT INTO pairs (config_id, key, value, type) VALUES ($1, $2, $3, $4)").
+		WithArgs(1, "key1", "value1", "string").
+		WillReturnError(errors.New("connection reset"))
+	mock.ExpectRollback()
+	repo := NewConfigRepository(db, nil)
+	err = repo.CreateConfig(&entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Version:   1,
+		Data:      map[string]string{"key1": "value1"},
+	}, nil)
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestConfigRepository_CreateConfigAlreadyExists(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSchema(mock, entity.DefaultNamespace, "test", "")
+	mock.ExpectQuery("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at").
+		WithArgs(entity.DefaultNamespace, "test", 1, nil, nil).
+		WillReturnError(&pq.Error{Code: "23505"})
+	mock.ExpectRollback()
+	repo := NewConfigRepository(db, nil)
+	err = repo.CreateConfig(&entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Version:   1,
+		Data:      map[string]string{"key1": "value1"},
+	}, nil)
+	require.Equal(t, usecase.ErrConfigAlreadyExists, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_UpdateConfig(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT version FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC LIMIT 1").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	expectSecretKeys(mock, entity.DefaultNamespace, "test", 2)
+	expectSchema(mock, entity.DefaultNamespace, "test", "")
+	mock.ExpectQuery("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at").
+		WithArgs(entity.DefaultNamespace, "test", 3, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "created_at"}).
+			AddRow(3, 3, time.Now()))
+	mock.ExpectExec("INSERT INTO pairs (config_id, key, value, type) VALUES ($1, $2, $3, $4)").
+		WithArgs(3, "key1", "value1", "string").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE configs SET relevant = FALSE WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE namespace = $2 AND name = $3 AND version = $4").
+		WithArgs(AnyTime{}, entity.DefaultNamespace, "test", 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, nil)
+	config := &entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Data:      map[string]string{"key1": "value1"},
+	}
+	err = repo.UpdateConfig(config, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), config.Version)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_UpdateConfigAudited(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT version FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC LIMIT 1").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(2))
+	expectSecretKeys(mock, entity.DefaultNamespace, "test", 2)
+	expectSchema(mock, entity.DefaultNamespace, "test", "")
+	mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version "+
+		"FROM configs WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace",
+			"promoted_from_version"}).AddRow(2, "test", 2, time.Now(), nil, nil))
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value", "type"}).AddRow("key1", "value0", "string"))
+	mock.ExpectQuery("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at").
+		WithArgs(entity.DefaultNamespace, "test", 3, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "created_at"}).
+			AddRow(3, 3, time.Now()))
+	mock.ExpectExec("INSERT INTO pairs (config_id, key, value, type) VALUES ($1, $2, $3, $4)").
+		WithArgs(3, "key1", "value1", "string").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE configs SET relevant = FALSE WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE namespace = $2 AND name = $3 AND version = $4").
+		WithArgs(AnyTime{}, entity.DefaultNamespace, "test", 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("INSERT INTO audit_events "+
+		"(actor, method, namespace, config_name, from_version, to_version, diff_summary, client_ip) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, created_at").
+		WithArgs("alice", "UpdateConfig", entity.DefaultNamespace, "test", 2, 3, "changed: key1", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(7, time.Now()))
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, nil)
+	audit := &entity.AuditEvent{Actor: "alice", Method: "UpdateConfig"}
+	err = repo.UpdateConfig(&entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Data:      map[string]string{"key1": "value1"},
+	}, 0, audit)
+	require.NoError(t, err)
+	require.Equal(t, int64(7), audit.ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_UpdateConfigVersionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery("SELECT version FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC LIMIT 1").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+	mock.ExpectRollback()
+	repo := NewConfigRepository(db, nil)
+	err = repo.UpdateConfig(&entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Data:      map[string]string{"key1": "value1"},
+	}, 2, nil)
+	require.Equal(t, usecase.ErrConfigVersionConflict, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_PatchConfig(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery("SELECT id, name, version FROM configs WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version"}).AddRow(2, "test", 2))
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value", "type"}).AddRow("key1", "value1", "string").AddRow("key2", "value2", "string"))
+	expectSchema(mock, entity.DefaultNamespace, "test", "")
+	mock.ExpectQuery("SELECT version FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC LIMIT 1").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(3))
+	mock.ExpectQuery("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at").
+		WithArgs(entity.DefaultNamespace, "test", 4, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "created_at"}).
+			AddRow(4, 4, time.Now()))
+	mock.ExpectExec("INSERT INTO pairs (config_id, key, value, type) VALUES ($1, $2, $3, $4)").
+		WithArgs(4, "key1", "new", "string").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE configs SET relevant = FALSE WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE namespace = $2 AND name = $3 AND version = $4").
+		WithArgs(AnyTime{}, entity.DefaultNamespace, "test", 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, nil)
+	config, err := repo.PatchConfig(&entity.ConfigPatch{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Set:       map[string]string{"key1": "new"},
+		Unset:     []string{"key2"},
+	}, 2, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), config.Version)
+	require.Equal(t, map[string]string{"key1": "new"}, config.Data)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_PatchConfigVersionConflict(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery("SELECT id, name, version FROM configs WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version"}).AddRow(2, "test", 2))
+	mock.ExpectRollback()
+	repo := NewConfigRepository(db, nil)
+	_, err = repo.PatchConfig(&entity.ConfigPatch{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Set:       map[string]string{"key1": "new"},
+	}, 3, nil)
+	require.Equal(t, usecase.ErrConfigVersionConflict, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_PromoteConfig(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs("production", "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id, name, version FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs("staging", "test", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version"}).AddRow(7, "test", 3))
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value", "type"}).AddRow("key1", "value1", "string"))
+	mock.ExpectQuery("SELECT key, value, secret FROM config_overrides WHERE namespace = $1 AND name = $2").
+		WithArgs("production", "test").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value", "secret"}).AddRow("key2", "stored", false))
+	mock.ExpectExec("DELETE FROM config_overrides WHERE namespace = $1 AND name = $2").
+		WithArgs("production", "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO config_overrides (namespace, name, key, value, secret) VALUES ($1, $2, $3, $4, $5)").
+		WithArgs("production", "test", "key1", "production", false).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	expectSchema(mock, "production", "test", "")
+	mock.ExpectQuery("SELECT version FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC LIMIT 1").
+		WithArgs("production", "test").
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at").
+		WithArgs("production", "test", 2, "staging", 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "created_at"}).
+			AddRow(8, 2, time.Now()))
+	mock.ExpectExec("INSERT INTO pairs (config_id, key, value, type) VALUES ($1, $2, $3, $4)").
+		WithArgs(8, "key1", "production", "string").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE configs SET relevant = FALSE WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs("production", "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE namespace = $2 AND name = $3 AND version = $4").
+		WithArgs(AnyTime{}, "production", "test", 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, nil)
+	config, err := repo.PromoteConfig(&entity.ConfigPromotion{
+		SourceNamespace: "staging",
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         3,
+		SetOverrides:    map[string]string{"key1": "production"},
+		UnsetOverrides:  []string{"key2"},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), config.Version)
+	require.Equal(t, map[string]string{"key1": "production"}, config.Data)
+	require.Equal(t, &entity.ConfigSource{Namespace: "staging", Version: 3}, config.PromotedFrom)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_PromoteConfigNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs("production", "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT id, name, version FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs("staging", "test", 3).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	repo := NewConfigRepository(db, nil)
+	_, err = repo.PromoteConfig(&entity.ConfigPromotion{
+		SourceNamespace: "staging",
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         3,
+	}, nil)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_SetRelevantConfigNotFound(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT id FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 5).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+	repo := NewConfigRepository(db, nil)
+	_, err = repo.SetRelevantConfig(entity.DefaultNamespace, "test", 5, nil)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_SetRelevantConfigSchemaViolation(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT id FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value", "type"}).AddRow("port", "http", "string"))
+	expectSchema(mock, entity.DefaultNamespace, "test", `{"port": {"required": true, "type": "int"}}`)
+	mock.ExpectRollback()
+	repo := NewConfigRepository(db, nil)
+	_, err = repo.SetRelevantConfig(entity.DefaultNamespace, "test", 1, nil)
+	var validationErrors validation.Errors
+	require.ErrorAs(t, err, &validationErrors)
+	require.Contains(t, validationErrors["data"], "port")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_CreateConfigSchema(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT COALESCE(MAX(version), 0) FROM config_schemas WHERE namespace = $1 AND name = $2").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(1))
+	mock.ExpectQuery("INSERT INTO config_schemas (namespace, name, version, keys, allow_unknown_keys) "+
+		"VALUES ($1, $2, $3, $4, $5) RETURNING version, created_at").
+		WithArgs(entity.DefaultNamespace, "test", 2, `{"port":{"required":true,"type":"int"}}`, false).
+		WillReturnRows(sqlmock.NewRows([]string{"version", "created_at"}).AddRow(2, time.Now()))
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, nil)
+	schema := &entity.ConfigSchema{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Keys:      map[string]entity.KeyRule{"port": {Required: true, Type: entity.TypeInt}},
+	}
+	require.NoError(t, repo.CreateConfigSchema(schema, nil))
+	require.Equal(t, int64(2), schema.Version)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectSecretKeys expects the query of the secret keys of the version of the config.
+func expectSecretKeys(mock sqlmock.Sqlmock, namespace, name string, version int64, keys ...string) {
+	rows := sqlmock.NewRows([]string{"key"})
+	for _, key := range keys {
+		rows.AddRow(key)
+	}
+	mock.ExpectQuery("SELECT pairs.key FROM pairs JOIN configs ON configs.id = pairs.config_id "+
+		"WHERE configs.namespace = $1 AND configs.name = $2 AND configs.version = $3 AND pairs.type = $4 ORDER BY pairs.key").
+		WithArgs(namespace, name, version, entity.TypeSecret).
+		WillReturnRows(rows)
+}
+
+// expectSchema expects the query of the latest schema of the config, which has no schema if keys are empty.
+func expectSchema(mock sqlmock.Sqlmock, namespace, name, keys string) {
+	rows := sqlmock.NewRows([]string{"version", "created_at", "keys", "allow_unknown_keys"})
+	if keys != "" {
+		rows.AddRow(1, time.Now(), []byte(keys), false)
+	}
+	mock.ExpectQuery("SELECT version, created_at, keys, allow_unknown_keys FROM config_schemas "+
+		"WHERE namespace = $1 AND name = $2 ORDER BY version DESC LIMIT 1").
+		WithArgs(namespace, name).
+		WillReturnRows(rows)
+}
 func TestConfigRepository_GetConfig(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "name", "version", "created_at"}).
-		AddRow(1, "test", 1, time.Now())
-	mock.ExpectQuery("SELECT id, name, version, created_at FROM configs WHERE name = $1 AND relevant = TRUE").
-		WithArgs("test").
+	rows := sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace", "promoted_from_version"}).
+		AddRow(1, "test", 1, time.Now(), nil, nil)
+	mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version "+
+		"FROM configs WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
 		WillReturnRows(rows)
-	pairRows := sqlmock.NewRows([]string{"key", "value"}).
-		AddRow("key1", "value1").
-		AddRow("key2", "value2")
-	mock.ExpectQuery("SELECT key, value FROM pairs WHERE config_id = $1").
+	pairRows := sqlmock.NewRows([]string{"key", "value", "type"}).
+		AddRow("key1", "value1", "string").
+		AddRow("key2", "value2", "string")
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
 		WithArgs(1).
 		WillReturnRows(pairRows)
 	mock.ExpectExec("UPDATE configs SET last_used = $1 WHERE id = $2").
 		WithArgs(AnyTime{}, 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	repo := NewConfigRepository(db)
-	config, err := repo.GetConfig("test")
+	repo := NewConfigRepository(db, nil)
+	config, err := repo.GetConfig(entity.DefaultNamespace, "test")
 	require.NotNil(t, config)
 	require.Equal(t, "test", config.Name)
 	require.NoError(t, err)
 }
 
-func TestConfigRepository_GetConfigs(t *testing.T) {
+func TestConfigRepository_CreateConfigSecret(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	keyring := testKeyring(t, "k1")
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSchema(mock, entity.DefaultNamespace, "test", "")
+	mock.ExpectQuery("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at").
+		WithArgs(entity.DefaultNamespace, "test", 1, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "created_at"}).
+			AddRow(1, 1, time.Now()))
+	mock.ExpectExec("INSERT INTO pairs (config_id, key, value, type) VALUES ($1, $2, $3, $4)").
+		WithArgs(1, "password", sealedValue{keyring, "k1", "pairs/1/password", "p@ssw0rd"}, "secret").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE configs SET relevant = FALSE WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE namespace = $2 AND name = $3 AND version = $4").
+		WithArgs(AnyTime{}, entity.DefaultNamespace, "test", 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, keyring)
+	err = repo.CreateConfig(&entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Version:   1,
+		Data:      map[string]string{"password": "p@ssw0rd"},
+		Types:     map[string]entity.ValueType{"password": entity.TypeSecret},
+	}, nil)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_CreateConfigSecretWithoutKeyring(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
 	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectSchema(mock, entity.DefaultNamespace, "test", "")
+	mock.ExpectQuery("INSERT INTO configs (namespace, name, version, relevant, promoted_from_namespace, promoted_from_version) "+
+		"VALUES ($1, $2, $3, FALSE, $4, $5) RETURNING id, version, created_at").
+		WithArgs(entity.DefaultNamespace, "test", 1, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "version", "created_at"}).
+			AddRow(1, 1, time.Now()))
+	mock.ExpectRollback()
+	repo := NewConfigRepository(db, nil)
+	err = repo.CreateConfig(&entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Version:   1,
+		Data:      map[string]string{"password": "p@ssw0rd"},
+		Types:     map[string]entity.ValueType{"password": entity.TypeSecret},
+	}, nil)
+	require.Equal(t, usecase.ErrNoMasterKey, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
 
-	rows := sqlmock.NewRows([]string{"id", "name", "version", "created_at"}).
-		AddRow(1, "test", 1, time.Now()).
-		AddRow(2, "test", 2, time.Now())
-	mock.ExpectQuery("SELECT id, name, version, created_at FROM configs WHERE name = $1 ORDER BY version DESC").
-		WithArgs("test").
-		WillReturnRows(rows)
-	pairRows := sqlmock.NewRows([]string{"key", "value"}).
-		AddRow("key1", "value1").
-		AddRow("key2", "value2")
-	mock.ExpectQuery("SELECT key, value FROM pairs WHERE config_id = $1").
+func TestConfigRepository_GetConfigSecret(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	keyring := testKeyring(t, "k2")
+	sealed, err := keyring.Seal([]byte("p@ssw0rd"), []byte("pairs/1/password"))
+	require.NoError(t, err)
+
+	mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version "+
+		"FROM configs WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace",
+			"promoted_from_version"}).AddRow(1, "test", 1, time.Now(), nil, nil))
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
 		WithArgs(1).
-		WillReturnRows(pairRows)
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value", "type"}).
+			AddRow("user", "app", "string").
+			AddRow("password", sealed, "secret"))
 	mock.ExpectExec("UPDATE configs SET last_used = $1 WHERE id = $2").
 		WithArgs(AnyTime{}, 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectQuery("SELECT key, value FROM pairs WHERE config_id = $1").
-		WithArgs(2).
-		WillReturnRows(pairRows)
-	mock.ExpectExec("UPDATE configs SET last_used = $1 WHERE id = $2").
-		WithArgs(AnyTime{}, 2).
-		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	repo := NewConfigRepository(db)
-	configs, err := repo.GetConfigs("test")
-	require.NotNil(t, configs)
+	config, err := NewConfigRepository(db, keyring).GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"user": "app", "password": "p@ssw0rd"}, config.Data)
+	require.Equal(t, map[string]entity.ValueType{"password": entity.TypeSecret}, config.Types)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_ReencryptSecrets(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	old, rotated := testKeyring(t, "k1"), testKeyring(t, "k2")
+	sealedPair, err := old.Seal([]byte("p@ssw0rd"), []byte("pairs/1/password"))
+	require.NoError(t, err)
+	rotatedPair, err := rotated.Seal([]byte("p@ssw0rd"), []byte("pairs/2/password"))
+	require.NoError(t, err)
+	sealedOverride, err := old.Seal([]byte("production"), []byte("config_overrides/production/test/password"))
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT config_id, key, value FROM pairs WHERE type = $1 FOR UPDATE").
+		WithArgs(entity.TypeSecret).
+		WillReturnRows(sqlmock.NewRows([]string{"config_id", "key", "value"}).
+			AddRow(1, "password", sealedPair).
+			AddRow(2, "password", rotatedPair))
+	mock.ExpectExec("UPDATE pairs SET value = $1 WHERE config_id = $2 AND key = $3").
+		WithArgs(sealedValue{rotated, "k2", "pairs/1/password", "p@ssw0rd"}, 1, "password").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT namespace, name, key, value FROM config_overrides WHERE secret = TRUE FOR UPDATE").
+		WillReturnRows(sqlmock.NewRows([]string{"namespace", "name", "key", "value"}).
+			AddRow("production", "test", "password", sealedOverride))
+	mock.ExpectExec("UPDATE config_overrides SET value = $1 WHERE namespace = $2 AND name = $3 AND key = $4").
+		WithArgs(sealedValue{rotated, "k2", "config_overrides/production/test/password", "production"},
+			"production", "test", "password").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	count, err := NewConfigRepository(db, rotated).ReencryptSecrets()
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_GetConfigs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace", "promoted_from_version"}).
+		AddRow(1, "test", 1, time.Now(), nil, nil).
+		AddRow(2, "test", 2, time.Now(), "staging", 5)
+	mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version "+
+		"FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT config_id, key, value, type FROM pairs WHERE config_id = ANY($1)").
+		WithArgs("{1,2}").
+		WillReturnRows(sqlmock.NewRows([]string{"config_id", "key", "value", "type"}).
+			AddRow(1, "key1", "value1", "string").
+			AddRow(2, "key1", "value2", "string").
+			AddRow(2, "key2", "2", "int"))
+	mock.ExpectExec("UPDATE configs SET last_used = $1 WHERE id = ANY($2)").
+		WithArgs(AnyTime{}, "{1,2}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	repo := NewConfigRepository(db, nil)
+	configs, err := repo.GetConfigs(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
 	require.Equal(t, 2, len(configs))
+	require.Nil(t, configs[0].PromotedFrom)
+	require.Equal(t, &entity.ConfigSource{Namespace: "staging", Version: 5}, configs[1].PromotedFrom)
+	require.Equal(t, map[string]string{"key1": "value1"}, configs[0].Data)
+	require.Nil(t, configs[0].Types)
+	require.Equal(t, map[string]string{"key1": "value2", "key2": "2"}, configs[1].Data)
+	require.Equal(t, map[string]entity.ValueType{"key2": entity.TypeInt}, configs[1].Types)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_ListConfigVersions(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace", "promoted_from_version"}).
+		AddRow(4, "test", 4, time.Now(), nil, nil).
+		AddRow(3, "test", 3, time.Now(), nil, nil)
+	mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version "+
+		"FROM configs WHERE namespace = $1 AND name = $2 AND version < $3 ORDER BY version DESC LIMIT $4").
+		WithArgs(entity.DefaultNamespace, "test", 5, 2).
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT config_id, key, value, type FROM pairs WHERE config_id = ANY($1)").
+		WithArgs("{4,3}").
+		WillReturnRows(sqlmock.NewRows([]string{"config_id", "key", "value", "type"}).
+			AddRow(3, "key1", "value1", "string").
+			AddRow(4, "key1", "value2", "string"))
+	mock.ExpectExec("UPDATE configs SET last_used = $1 WHERE id = ANY($2)").
+		WithArgs(AnyTime{}, "{4,3}").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	repo := NewConfigRepository(db, nil)
+	configs, err := repo.ListConfigVersions(&entity.VersionFilter{Namespace: entity.DefaultNamespace, Name: "test",
+		BeforeVersion: 5, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	require.Equal(t, map[string]string{"key1": "value2"}, configs[0].Data)
+	require.Equal(t, map[string]string{"key1": "value1"}, configs[1].Data)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_ListConfigVersionsWithoutData(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace", "promoted_from_version"}).
+		AddRow(2, "test", 2, time.Now(), "staging", 5).
+		AddRow(1, "test", 1, time.Now(), nil, nil)
+	mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version "+
+		"FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(rows)
+
+	repo := NewConfigRepository(db, nil)
+	configs, err := repo.ListConfigVersions(&entity.VersionFilter{Namespace: entity.DefaultNamespace, Name: "test",
+		WithoutData: true})
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	require.Nil(t, configs[0].Data)
+	require.Equal(t, &entity.ConfigSource{Namespace: "staging", Version: 5}, configs[0].PromotedFrom)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_ListConfigNames(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	after := time.Now().Add(-time.Hour)
+	mock.ExpectQuery("SELECT name, version, created_at, last_used FROM configs c WHERE namespace = $1 AND relevant = TRUE "+
+		`AND name COLLATE "C" LIKE $2 AND name LIKE $3 `+
+		"AND EXISTS (SELECT 1 FROM config_labels l WHERE l.namespace = c.namespace AND l.name = c.name AND l.key = $4 AND l.value = $5) "+
+		"AND EXISTS (SELECT 1 FROM config_labels l WHERE l.namespace = c.namespace AND l.name = c.name AND l.key = $6 AND l.value = $7) "+
+		`AND (last_used, name COLLATE "C") < ($8, $9) ORDER BY last_used DESC, name COLLATE "C" DESC LIMIT $10`).
+		WithArgs(entity.DefaultNamespace, `pay\_%`, `%\%off%`, "team", "payments", "tier", "backend", after, "pay_b", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "version", "created_at", "last_used"}).
+			AddRow("pay_a%off", 3, time.Now(), time.Now()).
+			AddRow("pay_%off", 1, time.Now(), time.Now()))
+	mock.ExpectQuery("SELECT name, key, value FROM config_labels WHERE namespace = $1 AND name = ANY($2)").
+		WithArgs(entity.DefaultNamespace, `{"pay_a%off","pay_%off"}`).
+		WillReturnRows(sqlmock.NewRows([]string{"name", "key", "value"}).
+			AddRow("pay_a%off", "team", "payments").
+			AddRow("pay_a%off", "tier", "backend").
+			AddRow("pay_%off", "team", "payments").
+			AddRow("pay_%off", "tier", "backend"))
+
+	repo := NewConfigRepository(db, nil)
+	summaries, err := repo.ListConfigNames(&entity.NameFilter{
+		Namespace:  entity.DefaultNamespace,
+		Prefix:     "pay_",
+		Contains:   "%off",
+		Labels:     map[string]string{"tier": "backend", "team": "payments"},
+		OrderBy:    entity.OrderByLastUsed,
+		Descending: true,
+		After:      &entity.NameCursor{Time: after, Name: "pay_b"},
+		Limit:      2,
+	})
+	require.NoError(t, err)
+	require.Len(t, summaries, 2)
+	require.Equal(t, "pay_a%off", summaries[0].Name)
+	require.Equal(t, int64(3), summaries[0].Version)
+	require.Equal(t, map[string]string{"team": "payments", "tier": "backend"}, summaries[0].Labels)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_ListConfigNamesByName(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery("SELECT name, version, created_at, last_used FROM configs c WHERE namespace = $1 AND relevant = TRUE "+
+		`AND name COLLATE "C" > $2 ORDER BY name COLLATE "C"`).
+		WithArgs("staging", "api").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "version", "created_at", "last_used"}))
+
+	repo := NewConfigRepository(db, nil)
+	summaries, err := repo.ListConfigNames(&entity.NameFilter{Namespace: "staging", After: &entity.NameCursor{Name: "api"}})
+	require.NoError(t, err)
+	require.Empty(t, summaries)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_SetConfigLabels(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT EXISTS (SELECT 1 FROM configs WHERE namespace = $1 AND name = $2)").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectExec("DELETE FROM config_labels WHERE namespace = $1 AND name = $2").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO config_labels (namespace, name, key, value) VALUES ($1, $2, $3, $4)").
+		WithArgs(entity.DefaultNamespace, "test", "team", "payments").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO config_labels (namespace, name, key, value) VALUES ($1, $2, $3, $4)").
+		WithArgs(entity.DefaultNamespace, "test", "tier", "backend").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := NewConfigRepository(db, nil)
+	err = repo.SetConfigLabels(&entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "test",
+		Labels: map[string]string{"tier": "backend", "team": "payments"}}, nil)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_SetConfigLabelsUnknown(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT EXISTS (SELECT 1 FROM configs WHERE namespace = $1 AND name = $2)").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectRollback()
+
+	repo := NewConfigRepository(db, nil)
+	err = repo.SetConfigLabels(&entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "test"}, nil)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	require.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestConfigRepository_GetConfigByVersion(t *testing.T) {
@@ -122,56 +920,183 @@ func TestConfigRepository_GetConfigByVersion(t *testing.T) {
 	require.NoError(t, err)
 	defer db.Close()
 
-	rows := sqlmock.NewRows([]string{"id", "name", "version", "created_at"}).
-		AddRow(1, "test", 1, time.Now())
-	mock.ExpectQuery("SELECT id, name, version, created_at FROM configs WHERE name = $1 AND version = $2").
-		WithArgs("test", 1).
+	rows := sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace", "promoted_from_version"}).
+		AddRow(1, "test", 1, time.Now(), nil, nil)
+	mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version "+
+		"FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 1).
 		WillReturnRows(rows)
-	pairRows := sqlmock.NewRows([]string{"key", "value"}).
-		AddRow("key1", "value1").
-		AddRow("key2", "value2")
-	mock.ExpectQuery("SELECT key, value FROM pairs WHERE config_id = $1").
+	pairRows := sqlmock.NewRows([]string{"key", "value", "type"}).
+		AddRow("key1", "value1", "string").
+		AddRow("port", "8080", "int")
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
 		WithArgs(1).
 		WillReturnRows(pairRows)
 	mock.ExpectExec("UPDATE configs SET last_used = $1 WHERE id = $2").
 		WithArgs(AnyTime{}, 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	repo := NewConfigRepository(db)
-	config, err := repo.GetConfigByVersion("test", 1)
+	repo := NewConfigRepository(db, nil)
+	config, err := repo.GetConfigByVersion(entity.DefaultNamespace, "test", 1)
 	require.NotNil(t, config)
 	require.Equal(t, "test", config.Name)
 	require.Equal(t, int64(1), config.Version)
+	require.Equal(t, map[string]string{"key1": "value1", "port": "8080"}, config.Data)
+	require.Equal(t, map[string]entity.ValueType{"port": entity.TypeInt}, config.Types)
 	require.NoError(t, err)
 }
 
+func TestConfigRepository_PeekConfig(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace", "promoted_from_version"}).
+		AddRow(1, "test", 2, time.Now(), nil, nil)
+	mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version "+
+		"FROM configs WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value", "type"}).AddRow("key1", "value2", "string"))
+	rows = sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace", "promoted_from_version"}).
+		AddRow(2, "test", 1, time.Now(), nil, nil)
+	mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version "+
+		"FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 1).
+		WillReturnRows(rows)
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value", "type"}).AddRow("key1", "value1", "string"))
+
+	repo := NewConfigRepository(db, nil)
+	config, err := repo.PeekConfig(entity.DefaultNamespace, "test", 0)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key1": "value2"}, config.Data)
+	config, err = repo.PeekConfig(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key1": "value1"}, config.Data)
+	require.NoError(t, mock.ExpectationsWereMet(), "last use is not updated")
+}
+
 func TestConfigRepository_DeleteConfig(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
 	defer db.Close()
-	mock.ExpectExec("DELETE FROM pairs WHERE config_id IN (SELECT id FROM configs WHERE name = $1)").
-		WithArgs("test").
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM pairs WHERE config_id IN (SELECT id FROM configs WHERE namespace = $1 AND name = $2)").
+		WithArgs(entity.DefaultNamespace, "test").
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("DELETE FROM configs WHERE name = $1").
-		WithArgs("test").
+	mock.ExpectExec("DELETE FROM configs WHERE namespace = $1 AND name = $2").
+		WithArgs(entity.DefaultNamespace, "test").
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	repo := NewConfigRepository(db)
-	err = repo.DeleteConfig("test")
+	mock.ExpectExec("DELETE FROM config_overrides WHERE namespace = $1 AND name = $2").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM config_labels WHERE namespace = $1 AND name = $2").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, nil)
+	err = repo.DeleteConfig(entity.DefaultNamespace, "test", nil)
 	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
 }
+
 func TestConfigRepository_DeleteConfigByVersion(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
 	defer db.Close()
-	mock.ExpectExec("DELETE FROM pairs WHERE config_id IN (SELECT id FROM configs WHERE name = $1 AND version = $2)").
-		WithArgs("test", 1).
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT relevant FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"relevant"}).AddRow(false))
+	mock.ExpectExec("DELETE FROM pairs WHERE config_id IN "+
+		"(SELECT id FROM configs WHERE namespace = $1 AND name = $2 AND version = $3)").
+		WithArgs(entity.DefaultNamespace, "test", 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("DELETE FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, nil)
+	err = repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 1, nil)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_DeleteRelevantConfigVersion(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT relevant FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"relevant"}).AddRow(true))
+	expectNextRelevant(mock, 2, 1)
+	expectSchema(mock, entity.DefaultNamespace, "test", "")
+	mock.ExpectExec("DELETE FROM pairs WHERE config_id IN "+
+		"(SELECT id FROM configs WHERE namespace = $1 AND name = $2 AND version = $3)").
+		WithArgs(entity.DefaultNamespace, "test", 2).
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	mock.ExpectExec("DELETE FROM configs WHERE name = $1 AND version = $2").
-		WithArgs("test", 1).
+	mock.ExpectExec("DELETE FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 2).
 		WillReturnResult(sqlmock.NewResult(1, 1))
-	repo := NewConfigRepository(db)
-	err = repo.DeleteConfigVersion("test", 1)
+	mock.ExpectExec("UPDATE configs SET relevant = TRUE, last_used = $1 WHERE id = $2").
+		WithArgs(AnyTime{}, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	repo := NewConfigRepository(db, nil)
+	err = repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 2, nil)
 	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_DeleteRelevantConfigVersionSchemaViolation(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT id FROM configs WHERE namespace = $1 AND name = $2 FOR UPDATE").
+		WithArgs(entity.DefaultNamespace, "test").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectQuery("SELECT relevant FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"relevant"}).AddRow(true))
+	expectNextRelevant(mock, 2, 1)
+	expectSchema(mock, entity.DefaultNamespace, "test", `{"key2": {"required": true}}`)
+	mock.ExpectRollback()
+	repo := NewConfigRepository(db, nil)
+	err = repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 2, nil)
+	require.ErrorIs(t, err, usecase.ErrSchemaViolation)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// expectNextRelevant expects the queries of version 1 of the test config, which becomes relevant when
+// the relevant version is deleted.
+func expectNextRelevant(mock sqlmock.Sqlmock, deleted int64, id int) {
+	mock.ExpectQuery("SELECT version FROM configs WHERE namespace = $1 AND name = $2 AND version <> $3 "+
+		"ORDER BY version DESC LIMIT 1").
+		WithArgs(entity.DefaultNamespace, "test", deleted).
+		WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(1))
+	mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version "+
+		"FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace",
+			"promoted_from_version"}).AddRow(id, "test", 1, time.Now(), nil, nil))
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
+		WithArgs(id).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value", "type"}).AddRow("key1", "value1", "string"))
 }
 
 func TestConfigRepository_GetLastVersion(t *testing.T) {
@@ -180,11 +1105,11 @@ func TestConfigRepository_GetLastVersion(t *testing.T) {
 	defer db.Close()
 	rows := sqlmock.NewRows([]string{"version"}).
 		AddRow(1)
-	mock.ExpectQuery("SELECT version FROM configs WHERE name = $1 ORDER BY version DESC LIMIT 1").
-		WithArgs("test").
+	mock.ExpectQuery("SELECT version FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC LIMIT 1").
+		WithArgs(entity.DefaultNamespace, "test").
 		WillReturnRows(rows)
-	repo := NewConfigRepository(db)
-	version, err := repo.GetLastVersion("test")
+	repo := NewConfigRepository(db, nil)
+	version, err := repo.GetLastVersion(entity.DefaultNamespace, "test")
 	require.Equal(t, int64(1), version)
 	require.NoError(t, err)
 }
@@ -193,13 +1118,13 @@ func TestConfigRepository_GetDataByConfigID(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
 	defer db.Close()
-	rows := sqlmock.NewRows([]string{"key", "value"}).
-		AddRow("key1", "value1").
-		AddRow("key2", "value2")
-	mock.ExpectQuery("SELECT key, value FROM pairs WHERE config_id = $1").
+	rows := sqlmock.NewRows([]string{"key", "value", "type"}).
+		AddRow("key1", "value1", "string").
+		AddRow("key2", "value2", "string")
+	mock.ExpectQuery("SELECT key, value, type FROM pairs WHERE config_id = $1").
 		WithArgs(1).
 		WillReturnRows(rows)
-	repo := NewConfigRepository(db)
+	repo := NewConfigRepository(db, nil)
 	data, err := repo.GetDataByConfigID(1)
 	require.Equal(t, 2, len(data))
 	require.NoError(t, err)
@@ -211,11 +1136,11 @@ func TestConfigRepository_GetLastUsedByVersion(t *testing.T) {
 	defer db.Close()
 	rows := sqlmock.NewRows([]string{"last_used"}).
 		AddRow(time.Now())
-	mock.ExpectQuery("SELECT last_used FROM configs WHERE name = $1 AND version = $2").
-		WithArgs("test", 1).
+	mock.ExpectQuery("SELECT last_used FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 1).
 		WillReturnRows(rows)
-	repo := NewConfigRepository(db)
-	lastUsed, err := repo.GetLastUsedByVersion("test", 1)
+	repo := NewConfigRepository(db, nil)
+	lastUsed, err := repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
 	require.NotNil(t, lastUsed)
 	require.NoError(t, err)
 }
@@ -226,24 +1151,45 @@ func TestConfigRepository_GetRelevantLastUsed(t *testing.T) {
 	defer db.Close()
 	rows := sqlmock.NewRows([]string{"last_used"}).
 		AddRow(time.Now())
-	mock.ExpectQuery("SELECT last_used FROM configs WHERE name = $1 AND relevant = TRUE").
-		WithArgs("test").
+	mock.ExpectQuery("SELECT last_used FROM configs WHERE namespace = $1 AND name = $2 AND relevant = TRUE").
+		WithArgs(entity.DefaultNamespace, "test").
 		WillReturnRows(rows)
-	repo := NewConfigRepository(db)
-	lastUsed, err := repo.GetRelevantLastUsed("test")
+	repo := NewConfigRepository(db, nil)
+	lastUsed, err := repo.GetRelevantLastUsed(entity.DefaultNamespace, "test")
 	require.NotNil(t, lastUsed)
 	require.NoError(t, err)
 }
 
+func TestConfigRepository_SetLastUsed(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	used := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	mock.ExpectExec("UPDATE configs SET last_used = GREATEST(configs.last_used, uses.last_used) "+
+		"FROM unnest($1::text[], $2::text[], $3::bigint[], $4::timestamp[]) AS uses (namespace, name, version, last_used) "+
+		"WHERE configs.namespace = uses.namespace AND configs.name = uses.name AND configs.version = uses.version").
+		WithArgs(`{"default","staging"}`, `{"test","api"}`, "{2,1}",
+			`{"2024-03-01 12:30:00Z","2024-03-01 12:30:00Z"}`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	repo := NewConfigRepository(db, nil)
+	require.NoError(t, repo.SetLastUsed(nil))
+	err = repo.SetLastUsed([]*entity.LastUse{
+		{Namespace: entity.DefaultNamespace, Name: "test", Version: 2, Time: used},
+		{Namespace: "staging", Name: "api", Version: 1, Time: used},
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestConfigRepository_IsConfigExists(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
 	defer db.Close()
-	mock.ExpectQuery("SELECT EXISTS (SELECT 1 FROM configs WHERE name = $1)").
-		WithArgs("test").
+	mock.ExpectQuery("SELECT EXISTS (SELECT 1 FROM configs WHERE namespace = $1 AND name = $2)").
+		WithArgs(entity.DefaultNamespace, "test").
 		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
-	repo := NewConfigRepository(db)
-	exists, err := repo.IsConfigExists("test")
+	repo := NewConfigRepository(db, nil)
+	exists, err := repo.IsConfigExists(entity.DefaultNamespace, "test")
 	require.True(t, exists)
 	require.NoError(t, err)
 }
@@ -252,11 +1198,11 @@ func TestConfigRepository_IsConfigExistsByVersion(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
 	defer db.Close()
-	mock.ExpectQuery("SELECT EXISTS (SELECT 1 FROM configs WHERE name = $1 AND version = $2)").
-		WithArgs("test", 1).
+	mock.ExpectQuery("SELECT EXISTS (SELECT 1 FROM configs WHERE namespace = $1 AND name = $2 AND version = $3)").
+		WithArgs(entity.DefaultNamespace, "test", 1).
 		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
-	repo := NewConfigRepository(db)
-	exists, err := repo.IsConfigVersionExists("test", 1)
+	repo := NewConfigRepository(db, nil)
+	exists, err := repo.IsConfigVersionExists(entity.DefaultNamespace, "test", 1)
 	require.True(t, exists)
 	require.NoError(t, err)
 }
@@ -265,11 +1211,274 @@ func TestConfigRepository_IsConfigRelevant(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
 	require.NoError(t, err)
 	defer db.Close()
-	mock.ExpectQuery("SELECT relevant FROM configs WHERE name = $1 AND version = $2").
-		WithArgs("test", 1).
+	mock.ExpectQuery("SELECT relevant FROM configs WHERE namespace = $1 AND name = $2 AND version = $3").
+		WithArgs(entity.DefaultNamespace, "test", 1).
 		WillReturnRows(sqlmock.NewRows([]string{"relevant"}).AddRow(true))
-	repo := NewConfigRepository(db)
-	relevant, err := repo.IsConfigRelevant("test", 1)
+	repo := NewConfigRepository(db, nil)
+	relevant, err := repo.IsConfigRelevant(entity.DefaultNamespace, "test", 1)
 	require.True(t, relevant)
 	require.NoError(t, err)
 }
+
+func TestConfigRepository_GetNamespaces(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery("SELECT DISTINCT namespace FROM configs ORDER BY namespace").
+		WillReturnRows(sqlmock.NewRows([]string{"namespace"}).AddRow("default").AddRow("staging"))
+	repo := NewConfigRepository(db, nil)
+	namespaces, err := repo.GetNamespaces()
+	require.NoError(t, err)
+	require.Equal(t, []string{"default", "staging"}, namespaces)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigRepository_GetConfigCounts(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(t, err)
+	defer db.Close()
+	mock.ExpectQuery("SELECT namespace, COUNT(DISTINCT name), COUNT(*) FROM configs GROUP BY namespace ORDER BY namespace").
+		WillReturnRows(sqlmock.NewRows([]string{"namespace", "count", "count"}).AddRow("default", 2, 5).AddRow("staging", 1, 1))
+	repo := NewConfigRepository(db, nil)
+	counts, err := repo.GetConfigCounts()
+	require.NoError(t, err)
+	require.Equal(t, []*entity.ConfigCount{
+		{Namespace: "default", Configs: 2, Versions: 5},
+		{Namespace: "staging", Configs: 1, Versions: 1},
+	}, counts)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+// testDB connects to the Postgres database from TEST_DATABASE_DSN and recreates the schema.
+func testDB(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	require.NoError(t, err)
+	for _, migration := range []string{
+		"../../../migrations/01_create_initial_tables.up.sql",
+		"../../../migrations/02_add_config_constraints.up.sql",
+		"../../../migrations/03_create_audit_events.up.sql",
+		"../../../migrations/04_add_namespaces.up.sql",
+		"../../../migrations/05_add_config_promotions.up.sql",
+		"../../../migrations/06_add_typed_values.up.sql",
+		"../../../migrations/07_create_config_schemas.up.sql",
+		"../../../migrations/08_add_secret_values.up.sql",
+		"../../../migrations/09_add_config_labels.up.sql",
+	} {
+		query, err := os.ReadFile(migration)
+		require.NoError(t, err)
+		_, err = db.Exec(string(query))
+		require.NoError(t, err)
+	}
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+	return db
+}
+
+func TestConfigRepository_Conformance(t *testing.T) {
+	repositorytest.RunConfigRepositoryTests(t, func(t *testing.T) repository.ConfigRepository {
+		return NewConfigRepository(testDB(t), testKeyring(t, "k1"))
+	})
+}
+
+func TestConfigRepository_ConcurrentUpdateConfig(t *testing.T) {
+	db := testDB(t)
+	repo := NewConfigRepository(db, nil)
+	require.NoError(t, repo.CreateConfig(entity.TestConfig(t), nil))
+
+	const writers = 20
+	versions := make(chan int64, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			config := entity.TestConfig(t)
+			if err := repo.UpdateConfig(config, 0, nil); err != nil {
+				t.Error(err)
+				return
+			}
+			versions <- config.Version
+		}()
+	}
+	wg.Wait()
+	close(versions)
+
+	seen := make(map[int64]bool)
+	for version := range versions {
+		require.False(t, seen[version], "version %d was created twice", version)
+		seen[version] = true
+	}
+	require.Len(t, seen, writers)
+
+	var relevant int
+	err := db.QueryRow("SELECT COUNT(*) FROM configs WHERE name = $1 AND relevant = TRUE", "test").Scan(&relevant)
+	require.NoError(t, err)
+	require.Equal(t, 1, relevant)
+
+	var pairs int
+	err = db.QueryRow("SELECT COUNT(*) FROM pairs").Scan(&pairs)
+	require.NoError(t, err)
+	require.Equal(t, (writers+1)*len(entity.TestConfig(t).Data), pairs)
+}
+
+func TestConfigRepository_ConcurrentSetRelevantConfig(t *testing.T) {
+	db := testDB(t)
+	repo := NewConfigRepository(db, nil)
+	require.NoError(t, repo.CreateConfig(entity.TestConfig(t), nil))
+	for i := 0; i < 4; i++ {
+		require.NoError(t, repo.UpdateConfig(entity.TestConfig(t), 0, nil))
+	}
+
+	var wg sync.WaitGroup
+	for version := int64(1); version <= 5; version++ {
+		wg.Add(1)
+		go func(version int64) {
+			defer wg.Done()
+			if _, err := repo.SetRelevantConfig(entity.DefaultNamespace, "test", version, nil); err != nil {
+				t.Error(err)
+			}
+		}(version)
+	}
+	wg.Wait()
+
+	var relevant int
+	err := db.QueryRow("SELECT COUNT(*) FROM configs WHERE name = $1 AND relevant = TRUE", "test").Scan(&relevant)
+	require.NoError(t, err)
+	require.Equal(t, 1, relevant)
+}
+
+// benchmarkConfig returns a config with the number of keys.
+func benchmarkConfig(keys int) *entity.Config {
+	config := &entity.Config{Namespace: entity.DefaultNamespace, Name: "bench", Version: 1, Data: make(map[string]string, keys)}
+	for i := 0; i < keys; i++ {
+		config.Data["key"+strconv.Itoa(i)] = "value" + strconv.Itoa(i)
+	}
+	return config
+}
+
+// BenchmarkConfigRepository_GetConfigs loads the history of configs with many versions from the database
+// of TEST_DATABASE_DSN.
+func BenchmarkConfigRepository_GetConfigs(b *testing.B) {
+	for _, versions := range []int{10, 100, 500} {
+		b.Run(strconv.Itoa(versions)+" versions", func(b *testing.B) {
+			repo := NewConfigRepository(testDB(b), nil)
+			require.NoError(b, repo.CreateConfig(benchmarkConfig(20), nil))
+			for i := 1; i < versions; i++ {
+				require.NoError(b, repo.UpdateConfig(benchmarkConfig(20), 0, nil))
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				configs, err := repo.GetConfigs(entity.DefaultNamespace, "bench")
+				require.NoError(b, err)
+				require.Len(b, configs, versions)
+			}
+		})
+	}
+}
+
+// BenchmarkConfigRepository_GetConfigsRoundTrips loads the history of configs with many versions from a database
+// that answers every statement after a network round trip. The history is loaded in three statements, so the time
+// spent waiting for the database does not grow with the number of versions.
+func BenchmarkConfigRepository_GetConfigsRoundTrips(b *testing.B) {
+	const roundTrip = 200 * time.Microsecond
+	for _, versions := range []int{10, 100, 500} {
+		b.Run(strconv.Itoa(versions)+" versions", func(b *testing.B) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			require.NoError(b, err)
+			defer db.Close()
+			repo := NewConfigRepository(db, nil)
+			created := time.Now()
+			var statements int
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				configRows := sqlmock.NewRows([]string{"id", "name", "version", "created_at", "promoted_from_namespace", "promoted_from_version"})
+				pairRows := sqlmock.NewRows([]string{"config_id", "key", "value", "type"})
+				for version := versions; version >= 1; version-- {
+					configRows.AddRow(version, "bench", version, created, nil, nil)
+					for key := 0; key < 20; key++ {
+						pairRows.AddRow(version, "key"+strconv.Itoa(key), "value", "string")
+					}
+				}
+				mock.ExpectQuery("SELECT id, name, version, created_at, promoted_from_namespace, promoted_from_version " +
+					"FROM configs WHERE namespace = $1 AND name = $2 ORDER BY version DESC").
+					WillDelayFor(roundTrip).
+					WillReturnRows(configRows)
+				mock.ExpectQuery("SELECT config_id, key, value, type FROM pairs WHERE config_id = ANY($1)").
+					WillDelayFor(roundTrip).
+					WillReturnRows(pairRows)
+				mock.ExpectExec("UPDATE configs SET last_used = $1 WHERE id = ANY($2)").
+					WillDelayFor(roundTrip).
+					WillReturnResult(sqlmock.NewResult(0, int64(versions)))
+				statements += 3
+				b.StartTimer()
+
+				configs, err := repo.GetConfigs(entity.DefaultNamespace, "bench")
+				require.NoError(b, err)
+				require.Len(b, configs, versions)
+			}
+			b.StopTimer()
+			require.NoError(b, mock.ExpectationsWereMet())
+			b.ReportMetric(float64(statements)/float64(b.N), "statements/op")
+		})
+	}
+}
+
+// BenchmarkConfigRepository_UpdateConfig stores a version of a config with 2000 keys in the database
+// of TEST_DATABASE_DSN, inserting its pairs in batches of several sizes.
+func BenchmarkConfigRepository_UpdateConfig(b *testing.B) {
+	for _, batchSize := range []int{1, 100, DefaultPairBatchSize, 2000} {
+		b.Run("batch "+strconv.Itoa(batchSize), func(b *testing.B) {
+			repo := NewConfigRepository(testDB(b), nil)
+			repo.SetPairBatchSize(batchSize)
+			config := benchmarkConfig(2000)
+			require.NoError(b, repo.CreateConfig(config, nil))
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				require.NoError(b, repo.UpdateConfig(benchmarkConfig(2000), 0, nil))
+			}
+		})
+	}
+}
+
+// BenchmarkConfigRepository_InsertDataRoundTrips inserts the pairs of a config with 2000 keys into a database
+// that answers every statement after a network round trip, so that the time is dominated by the number of
+// statements.
+func BenchmarkConfigRepository_InsertDataRoundTrips(b *testing.B) {
+	const roundTrip = 200 * time.Microsecond
+	config := benchmarkConfig(2000)
+	config.ID = 1
+	for _, batchSize := range []int{1, 100, DefaultPairBatchSize, 2000} {
+		b.Run("batch "+strconv.Itoa(batchSize), func(b *testing.B) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherRegexp))
+			require.NoError(b, err)
+			defer db.Close()
+			repo := NewConfigRepository(db, nil)
+			repo.SetPairBatchSize(batchSize)
+			statements := (len(config.Data) + batchSize - 1) / batchSize
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				mock.ExpectBegin()
+				for j := 0; j < statements; j++ {
+					mock.ExpectExec("INSERT INTO pairs").
+						WillDelayFor(roundTrip).
+						WillReturnResult(sqlmock.NewResult(0, int64(batchSize)))
+				}
+				mock.ExpectCommit()
+				b.StartTimer()
+
+				require.NoError(b, repo.withTx(func(tx *sql.Tx) error {
+					return repo.insertData(tx, config)
+				}))
+			}
+			b.StopTimer()
+			require.NoError(b, mock.ExpectationsWereMet())
+			b.ReportMetric(float64(statements), "statements/op")
+		})
+	}
+}
@@ -1,23 +1,78 @@
 package repository
 
 import (
+	"context"
 	"distributedConfig/internal/entity"
+	"distributedConfig/internal/watcher"
 	"time"
 )
 
+// ConfigRepository stores configs. Changes take the audit event of the change, filled in with the caller,
+// which the repository completes with the config, the versions and the diff summary and records in the same
+// transaction as the change. A nil event is not recorded.
 type ConfigRepository interface {
-	CreateConfig(config *entity.Config) error
-	GetConfig(name string) (*entity.Config, error)
-	GetConfigs(name string) ([]*entity.Config, error)
-	GetConfigByVersion(name string, version int64) (*entity.Config, error)
-	DeleteConfig(name string) error
-	DeleteConfigVersion(name string, version int64) error
-	UpdateConfig(config *entity.Config) error
-	SetRelevantConfig(name string, version int64) (*entity.Config, error)
-	GetRelevantLastUsed(name string) (time.Time, error)
-	GetLastUsedByVersion(name string, version int64) (time.Time, error)
-	IsConfigExists(name string) (bool, error)
-	IsConfigVersionExists(name string, version int64) (bool, error)
-	IsConfigRelevant(name string, version int64) (bool, error)
-	GetLastVersion(name string) (int64, error)
+	CreateConfig(config *entity.Config, audit *entity.AuditEvent) error
+	GetConfig(namespace, name string) (*entity.Config, error)
+	GetConfigs(namespace, name string) ([]*entity.Config, error)
+	GetConfigByVersion(namespace, name string, version int64) (*entity.Config, error)
+	// PeekConfig returns the version of the config, or its relevant version if version is 0, without updating
+	// its last use. It is meant for reads made by the service itself rather than by the users of the config.
+	PeekConfig(namespace, name string, version int64) (*entity.Config, error)
+	// ListConfigVersions returns the versions of the config matching the filter, latest first. Like GetConfigs,
+	// it updates the last use of the versions, unless their data is not listed.
+	ListConfigVersions(filter *entity.VersionFilter) ([]*entity.Config, error)
+	DeleteConfig(namespace, name string, audit *entity.AuditEvent) error
+	// DeleteConfigVersion records the deleted version as the version the config is changed from.
+	DeleteConfigVersion(namespace, name string, version int64, audit *entity.AuditEvent) error
+	UpdateConfig(config *entity.Config, expectedVersion int64, audit *entity.AuditEvent) error
+	PatchConfig(patch *entity.ConfigPatch, expectedVersion int64, audit *entity.AuditEvent) (*entity.Config, error)
+	// PromoteConfig stores the overrides of the target changed by the promotion and creates the next version
+	// of the config in the target namespace, creating the config if necessary, from the source version
+	// with the overrides applied.
+	PromoteConfig(promotion *entity.ConfigPromotion, audit *entity.AuditEvent) (*entity.Config, error)
+	// GetConfigOverrides returns the overrides kept for promotions to the config. A config without
+	// overrides has an empty map.
+	GetConfigOverrides(namespace, name string) (map[string]string, error)
+	SetRelevantConfig(namespace, name string, version int64, audit *entity.AuditEvent) (*entity.Config, error)
+	GetRelevantLastUsed(namespace, name string) (time.Time, error)
+	// SetLastUsed records uses of versions that were read without updating their last use, e.g. from a cache.
+	// The last use of a version is only moved forward, and uses of versions that no longer exist are ignored.
+	SetLastUsed(uses []*entity.LastUse) error
+	GetLastUsedByVersion(namespace, name string, version int64) (time.Time, error)
+	IsConfigExists(namespace, name string) (bool, error)
+	IsConfigVersionExists(namespace, name string, version int64) (bool, error)
+	IsConfigRelevant(namespace, name string, version int64) (bool, error)
+	GetLastVersion(namespace, name string) (int64, error)
+	// ListConfigNames returns the configs matching the filter, without their data and without updating their last use.
+	ListConfigNames(filter *entity.NameFilter) ([]*entity.ConfigSummary, error)
+	// SetConfigLabels replaces the labels of the config. Labels are kept until the config is deleted.
+	SetConfigLabels(labels *entity.ConfigLabels, audit *entity.AuditEvent) error
+	// GetNamespaces returns the namespaces that have configs, in alphabetical order.
+	GetNamespaces() ([]string, error)
+	// GetConfigCounts returns the number of configs and versions of every namespace that has configs,
+	// in alphabetical order of the namespaces.
+	GetConfigCounts() ([]*entity.ConfigCount, error)
+	// CreateConfigSchema stores the schema as the next version of the schema of the config. From then on,
+	// the latest version is checked whenever a version of the config is created or set relevant.
+	CreateConfigSchema(schema *entity.ConfigSchema, audit *entity.AuditEvent) error
+	// GetConfigSchema returns the latest version of the schema of the config.
+	GetConfigSchema(namespace, name string) (*entity.ConfigSchema, error)
+	GetConfigSchemaByVersion(namespace, name string, version int64) (*entity.ConfigSchema, error)
+	// GetConfigSchemas returns every version of the schema of the config, latest first.
+	GetConfigSchemas(namespace, name string) ([]*entity.ConfigSchema, error)
+	// DeleteConfigSchema deletes every version of the schema of the config, which is no longer checked.
+	DeleteConfigSchema(namespace, name string, audit *entity.AuditEvent) error
+}
+
+// AuditRepository stores the audit log. Events are never changed or deleted. Changes of configs record their events
+// through ConfigRepository; CreateAuditEvent is meant for events that come with no change.
+type AuditRepository interface {
+	CreateAuditEvent(event *entity.AuditEvent) error
+	ListAuditEvents(filter *entity.AuditFilter) ([]*entity.AuditEvent, error)
+}
+
+// ConfigNotifier propagates changes of configs, identified by entity.QualifiedName, to watchers.
+type ConfigNotifier interface {
+	NotifyConfigChanged(name string) error
+	Listen(ctx context.Context, publisher watcher.Publisher) error
 }
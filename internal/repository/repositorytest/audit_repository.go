@@ -0,0 +1,124 @@
+package repositorytest
+
+import (
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// RunAuditRepositoryTests runs the conformance suite of repository.AuditRepository.
+// newRepository must return an empty repository.
+func RunAuditRepositoryTests(t *testing.T, newRepository func(t *testing.T) repository.AuditRepository) {
+	t.Helper()
+	testCases := []struct {
+		name string
+		test func(t *testing.T, repo repository.AuditRepository)
+	}{
+		{"create and list", testCreateAndListAuditEvents},
+		{"filter", testFilterAuditEvents},
+		{"paginate", testPaginateAuditEvents},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newRepository(t))
+		})
+	}
+}
+
+func createAuditEvents(t *testing.T, repo repository.AuditRepository, events ...entity.AuditEvent) []*entity.AuditEvent {
+	t.Helper()
+	created := make([]*entity.AuditEvent, 0, len(events))
+	for i := range events {
+		event := events[i]
+		require.NoError(t, repo.CreateAuditEvent(&event))
+		created = append(created, &event)
+	}
+	return created
+}
+
+func mustListAuditEvents(t *testing.T, repo repository.AuditRepository, filter entity.AuditFilter) []*entity.AuditEvent {
+	t.Helper()
+	events, err := repo.ListAuditEvents(&filter)
+	require.NoError(t, err)
+	return events
+}
+
+func auditEventIDs(events []*entity.AuditEvent) []int64 {
+	ids := make([]int64, 0, len(events))
+	for _, event := range events {
+		ids = append(ids, event.ID)
+	}
+	return ids
+}
+
+func testCreateAndListAuditEvents(t *testing.T, repo repository.AuditRepository) {
+	require.Empty(t, mustListAuditEvents(t, repo, entity.AuditFilter{}))
+	created := createAuditEvents(t, repo,
+		entity.AuditEvent{Actor: "alice", Method: "CreateConfig", ConfigName: "test", ToVersion: 1,
+			DiffSummary: "added: key1", ClientIP: "10.0.0.1"},
+		entity.AuditEvent{Actor: "bob", Method: "UpdateConfig", Namespace: "staging", ConfigName: "test", FromVersion: 1,
+			ToVersion: 2, DiffSummary: "changed: key1", ClientIP: "10.0.0.2"})
+	require.NotZero(t, created[0].ID)
+	require.Greater(t, created[1].ID, created[0].ID)
+	require.False(t, created[0].CreatedAt.IsZero())
+
+	events := mustListAuditEvents(t, repo, entity.AuditFilter{})
+	require.Equal(t, []int64{created[1].ID, created[0].ID}, auditEventIDs(events))
+	got := events[0]
+	require.Equal(t, "bob", got.Actor)
+	require.Equal(t, "UpdateConfig", got.Method)
+	require.Equal(t, "staging", got.Namespace)
+	require.Equal(t, "test", got.ConfigName)
+	require.Equal(t, int64(1), got.FromVersion)
+	require.Equal(t, int64(2), got.ToVersion)
+	require.Equal(t, "changed: key1", got.DiffSummary)
+	require.Equal(t, "10.0.0.2", got.ClientIP)
+	require.WithinDuration(t, created[1].CreatedAt, got.CreatedAt, time.Second)
+}
+
+func testFilterAuditEvents(t *testing.T, repo repository.AuditRepository) {
+	const staging = "staging"
+	created := createAuditEvents(t, repo,
+		entity.AuditEvent{Actor: "alice", Method: "CreateConfig", Namespace: entity.DefaultNamespace, ConfigName: "test",
+			ToVersion: 1},
+		entity.AuditEvent{Actor: "bob", Method: "CreateConfig", Namespace: entity.DefaultNamespace, ConfigName: "other",
+			ToVersion: 1},
+		entity.AuditEvent{Actor: "alice", Method: "DeleteConfig", Namespace: entity.DefaultNamespace, ConfigName: "other",
+			FromVersion: 1},
+		entity.AuditEvent{Actor: "bob", Method: "CreateConfig", Namespace: staging, ConfigName: "other", ToVersion: 1})
+
+	require.Equal(t, []int64{created[2].ID, created[0].ID},
+		auditEventIDs(mustListAuditEvents(t, repo, entity.AuditFilter{Actor: "alice"})))
+	require.Equal(t, []int64{created[3].ID, created[2].ID, created[1].ID},
+		auditEventIDs(mustListAuditEvents(t, repo, entity.AuditFilter{ConfigName: "other"})))
+	require.Equal(t, []int64{created[2].ID},
+		auditEventIDs(mustListAuditEvents(t, repo, entity.AuditFilter{ConfigName: "other", Actor: "alice"})))
+	require.Equal(t, []int64{created[3].ID},
+		auditEventIDs(mustListAuditEvents(t, repo, entity.AuditFilter{Namespace: staging})))
+	require.Equal(t, []int64{created[2].ID, created[1].ID},
+		auditEventIDs(mustListAuditEvents(t, repo,
+			entity.AuditFilter{Namespace: entity.DefaultNamespace, ConfigName: "other"})))
+
+	since := created[0].CreatedAt.Add(-time.Minute)
+	until := created[3].CreatedAt.Add(time.Minute)
+	require.Len(t, mustListAuditEvents(t, repo, entity.AuditFilter{Since: since, Until: until}), 4)
+	require.Empty(t, mustListAuditEvents(t, repo, entity.AuditFilter{Since: until}))
+	require.Empty(t, mustListAuditEvents(t, repo, entity.AuditFilter{Until: since}))
+}
+
+func testPaginateAuditEvents(t *testing.T, repo repository.AuditRepository) {
+	var events []entity.AuditEvent
+	for i := 0; i < 5; i++ {
+		events = append(events, entity.AuditEvent{Actor: "alice", Method: "UpdateConfig", ConfigName: "test"})
+	}
+	created := createAuditEvents(t, repo, events...)
+
+	page := mustListAuditEvents(t, repo, entity.AuditFilter{Limit: 2})
+	require.Equal(t, []int64{created[4].ID, created[3].ID}, auditEventIDs(page))
+	page = mustListAuditEvents(t, repo, entity.AuditFilter{Limit: 2, BeforeID: page[1].ID})
+	require.Equal(t, []int64{created[2].ID, created[1].ID}, auditEventIDs(page))
+	page = mustListAuditEvents(t, repo, entity.AuditFilter{Limit: 2, BeforeID: page[1].ID})
+	require.Equal(t, []int64{created[0].ID}, auditEventIDs(page))
+}
@@ -0,0 +1,970 @@
+// Package repositorytest contains the behaviour every repository.ConfigRepository implementation must have.
+package repositorytest
+
+import (
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository"
+	"distributedConfig/internal/usecase"
+	validation "github.com/go-ozzo/ozzo-validation"
+	"github.com/stretchr/testify/require"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// RunConfigRepositoryTests runs the conformance suite. newRepository must return an empty repository.
+func RunConfigRepositoryTests(t *testing.T, newRepository func(t *testing.T) repository.ConfigRepository) {
+	t.Helper()
+	testCases := []struct {
+		name string
+		test func(t *testing.T, repo repository.ConfigRepository)
+	}{
+		{"create and get", testCreateAndGet},
+		{"create existing", testCreateExisting},
+		{"get unknown", testGetUnknown},
+		{"update", testUpdate},
+		{"update expected version", testUpdateExpectedVersion},
+		{"patch", testPatch},
+		{"patch expected version", testPatchExpectedVersion},
+		{"patch invalid", testPatchInvalid},
+		{"list versions", testListVersions},
+		{"list versions by page", testListVersionsByPage},
+		{"list names", testListNames},
+		{"list names by page", testListNamesByPage},
+		{"labels", testLabels},
+		{"set relevant", testSetRelevant},
+		{"set relevant unknown version", testSetRelevantUnknownVersion},
+		{"delete config", testDeleteConfig},
+		{"delete relevant version", testDeleteRelevantVersion},
+		{"delete unknown version", testDeleteUnknownVersion},
+		{"last used", testLastUsed},
+		{"set last used", testSetLastUsed},
+		{"peek", testPeek},
+		{"audit", testAudit},
+		{"concurrent updates", testConcurrentUpdates},
+		{"namespaces", testNamespaces},
+		{"promote", testPromote},
+		{"promote unknown version", testPromoteUnknownVersion},
+		{"typed values", testTypedValues},
+		{"secrets", testSecrets},
+		{"secrets written without types", testSecretsWithoutTypes},
+		{"schemas", testSchemas},
+		{"schema before config", testSchemaBeforeConfig},
+		{"schema of version relevant after deletion", testSchemaAfterDeletion},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.test(t, newRepository(t))
+		})
+	}
+}
+
+func createVersions(t *testing.T, repo repository.ConfigRepository, versions int) {
+	t.Helper()
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, repo.CreateConfig(config, nil))
+	for i := 2; i <= versions; i++ {
+		config := entity.TestConfig(t)
+		config.Data["key1"] = "value" + strconv.Itoa(i)
+		require.NoError(t, repo.UpdateConfig(config, 0, nil))
+	}
+}
+
+func requireRelevant(t *testing.T, repo repository.ConfigRepository, version int64) {
+	t.Helper()
+	config, err := repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, version, config.Version)
+	for _, c := range mustGetConfigs(t, repo) {
+		relevant, err := repo.IsConfigRelevant(entity.DefaultNamespace, "test", c.Version)
+		require.NoError(t, err)
+		require.Equal(t, c.Version == version, relevant, "version %d", c.Version)
+	}
+}
+
+func mustGetConfigs(t *testing.T, repo repository.ConfigRepository) []*entity.Config {
+	t.Helper()
+	configs, err := repo.GetConfigs(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	return configs
+}
+
+func testCreateAndGet(t *testing.T, repo repository.ConfigRepository) {
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, repo.CreateConfig(config, nil))
+	require.NotZero(t, config.ID)
+	require.False(t, config.CreatedAt.IsZero())
+
+	got, err := repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, "test", got.Name)
+	require.Equal(t, int64(1), got.Version)
+	require.Equal(t, config.Data, got.Data)
+
+	exists, err := repo.IsConfigExists(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.True(t, exists)
+	exists, err = repo.IsConfigVersionExists(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.True(t, exists)
+	exists, err = repo.IsConfigVersionExists(entity.DefaultNamespace, "test", 2)
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func testCreateExisting(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 1)
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.Equal(t, usecase.ErrConfigAlreadyExists, repo.CreateConfig(config, nil))
+}
+
+func testGetUnknown(t *testing.T, repo repository.ConfigRepository) {
+	_, err := repo.GetConfig(entity.DefaultNamespace, "test")
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	_, err = repo.GetConfigByVersion(entity.DefaultNamespace, "test", 1)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	_, err = repo.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	_, err = repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	_, err = repo.GetLastVersion(entity.DefaultNamespace, "test")
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	configs, err := repo.GetConfigs(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Empty(t, configs)
+	exists, err := repo.IsConfigExists(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.False(t, exists)
+}
+
+func testUpdate(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 1)
+	config := &entity.Config{Namespace: entity.DefaultNamespace, Name: "test", Data: map[string]string{"key4": "value4"}}
+	require.NoError(t, repo.UpdateConfig(config, 0, nil))
+	require.Equal(t, int64(2), config.Version)
+
+	got, err := repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), got.Version)
+	require.Equal(t, map[string]string{"key4": "value4"}, got.Data)
+	requireRelevant(t, repo, 2)
+
+	last, err := repo.GetLastVersion(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), last)
+}
+
+func testUpdateExpectedVersion(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 2)
+	require.Equal(t, usecase.ErrConfigVersionConflict, repo.UpdateConfig(entity.TestConfig(t), 1, nil))
+	last, err := repo.GetLastVersion(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), last)
+
+	config := entity.TestConfig(t)
+	require.NoError(t, repo.UpdateConfig(config, 2, nil))
+	require.Equal(t, int64(3), config.Version)
+}
+
+func testPatch(t *testing.T, repo repository.ConfigRepository) {
+	_, err := repo.PatchConfig(&entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test", Unset: []string{"key1"}}, 0, nil)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+
+	createVersions(t, repo, 3)
+	_, err = repo.SetRelevantConfig(entity.DefaultNamespace, "test", 1, nil)
+	require.NoError(t, err)
+	config, err := repo.PatchConfig(&entity.ConfigPatch{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Set:       map[string]string{"key2": "new", "key4": "value4"},
+		Unset:     []string{"key3"},
+	}, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), config.Version)
+	require.NotZero(t, config.ID)
+
+	got, err := repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(4), got.Version)
+	require.Equal(t, map[string]string{"key1": "value1", "key2": "new", "key4": "value4"}, got.Data)
+	requireRelevant(t, repo, 4)
+}
+
+func testPatchExpectedVersion(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 2)
+	_, err := repo.SetRelevantConfig(entity.DefaultNamespace, "test", 1, nil)
+	require.NoError(t, err)
+	patch := &entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test", Set: map[string]string{"key1": "new"}}
+	_, err = repo.PatchConfig(patch, 2, nil)
+	require.Equal(t, usecase.ErrConfigVersionConflict, err)
+	requireRelevant(t, repo, 1)
+
+	config, err := repo.PatchConfig(patch, 1, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), config.Version)
+}
+
+func testPatchInvalid(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 1)
+	_, err := repo.PatchConfig(&entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test"}, 0, nil)
+	require.Error(t, err)
+	_, err = repo.PatchConfig(&entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test", Unset: []string{"key1", "key2", "key3"}}, 0, nil)
+	require.Error(t, err)
+	require.Len(t, mustGetConfigs(t, repo), 1)
+}
+
+func testListVersions(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 3)
+	configs := mustGetConfigs(t, repo)
+	require.Len(t, configs, 3)
+	for i, config := range configs {
+		require.Equal(t, int64(3-i), config.Version)
+		require.Equal(t, "test", config.Name)
+		require.Len(t, config.Data, len(entity.TestConfig(t).Data))
+	}
+}
+
+func testListVersionsByPage(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 5)
+	filter := &entity.VersionFilter{Namespace: entity.DefaultNamespace, Name: "test", Limit: 2}
+	configs, err := repo.ListConfigVersions(filter)
+	require.NoError(t, err)
+	require.Len(t, configs, 2)
+	require.Equal(t, []int64{5, 4}, []int64{configs[0].Version, configs[1].Version})
+	require.Equal(t, "value5", configs[0].Data["key1"])
+
+	filter.BeforeVersion = 4
+	configs, err = repo.ListConfigVersions(filter)
+	require.NoError(t, err)
+	require.Equal(t, []int64{3, 2}, []int64{configs[0].Version, configs[1].Version})
+
+	filter.BeforeVersion = 2
+	configs, err = repo.ListConfigVersions(filter)
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	require.Equal(t, int64(1), configs[0].Version)
+
+	before, err := repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	time.Sleep(10 * time.Millisecond)
+	filter.WithoutData = true
+	configs, err = repo.ListConfigVersions(filter)
+	require.NoError(t, err)
+	require.Len(t, configs, 1)
+	require.Equal(t, "test", configs[0].Name)
+	require.False(t, configs[0].CreatedAt.IsZero())
+	require.Empty(t, configs[0].Data)
+	after, err := repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+
+	configs, err = repo.ListConfigVersions(&entity.VersionFilter{Namespace: entity.DefaultNamespace, Name: "unknown"})
+	require.NoError(t, err)
+	require.Empty(t, configs)
+}
+
+func testSetRelevant(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 3)
+	config, err := repo.SetRelevantConfig(entity.DefaultNamespace, "test", 1, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), config.Version)
+	require.Equal(t, entity.TestConfig(t).Data, config.Data)
+	requireRelevant(t, repo, 1)
+}
+
+func testSetRelevantUnknownVersion(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 2)
+	_, err := repo.SetRelevantConfig(entity.DefaultNamespace, "test", 5, nil)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	requireRelevant(t, repo, 2)
+}
+
+func testDeleteConfig(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 2)
+	require.NoError(t, repo.DeleteConfig(entity.DefaultNamespace, "test", nil))
+	exists, err := repo.IsConfigExists(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.Empty(t, mustGetConfigs(t, repo))
+}
+
+func testDeleteRelevantVersion(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 3)
+	_, err := repo.SetRelevantConfig(entity.DefaultNamespace, "test", 2, nil)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 2, nil))
+	requireRelevant(t, repo, 3)
+	require.NoError(t, repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 3, nil))
+	requireRelevant(t, repo, 1)
+	require.NoError(t, repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 1, nil))
+	_, err = repo.GetConfig(entity.DefaultNamespace, "test")
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+}
+
+func testDeleteUnknownVersion(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 1)
+	require.Equal(t, usecase.ErrConfigNotFound, repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 2, nil))
+	requireRelevant(t, repo, 1)
+}
+
+func testLastUsed(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 2)
+	before, err := repo.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	oldVersion, err := repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	_, err = repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	after, err := repo.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.True(t, after.After(before))
+	unchanged, err := repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.Equal(t, oldVersion, unchanged)
+
+	_, err = repo.GetConfigByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	changed, err := repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.True(t, changed.After(oldVersion))
+}
+
+func testSetLastUsed(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 2)
+	relevant, err := repo.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	oldVersion, err := repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+
+	later := relevant.Add(time.Hour)
+	require.NoError(t, repo.SetLastUsed([]*entity.LastUse{
+		{Namespace: entity.DefaultNamespace, Name: "test", Version: 2, Time: later},
+		{Namespace: entity.DefaultNamespace, Name: "test", Version: 1, Time: oldVersion.Add(-time.Hour)},
+		{Namespace: entity.DefaultNamespace, Name: "test", Version: 3, Time: later},
+		{Namespace: entity.DefaultNamespace, Name: "unknown", Version: 1, Time: later},
+	}))
+	changed, err := repo.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.True(t, changed.Equal(later), "last use %s, expected %s", changed, later)
+	unchanged, err := repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.Equal(t, oldVersion, unchanged)
+	require.NoError(t, repo.SetLastUsed(nil))
+}
+
+func testPeek(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 2)
+	relevant, err := repo.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	oldVersion, err := repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+
+	time.Sleep(10 * time.Millisecond)
+	got, err := repo.PeekConfig(entity.DefaultNamespace, "test", 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), got.Version)
+	require.Equal(t, "value2", got.Data["key1"])
+	got, err = repo.PeekConfig(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), got.Version)
+	require.Equal(t, entity.TestConfig(t).Data, got.Data)
+	_, err = repo.PeekConfig(entity.DefaultNamespace, "test", 3)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	_, err = repo.PeekConfig(entity.DefaultNamespace, "unknown", 0)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+
+	unchanged, err := repo.GetRelevantLastUsed(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, relevant, unchanged)
+	unchanged, err = repo.GetLastUsedByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.Equal(t, oldVersion, unchanged)
+}
+
+func requireChange(t *testing.T, audit *entity.AuditEvent, namespace string, from, to int64, summary string) {
+	t.Helper()
+	require.Equal(t, "alice", audit.Actor)
+	require.Equal(t, namespace, audit.Namespace)
+	require.Equal(t, "test", audit.ConfigName)
+	require.Equal(t, from, audit.FromVersion)
+	require.Equal(t, to, audit.ToVersion)
+	require.Equal(t, summary, audit.DiffSummary)
+}
+
+func testAudit(t *testing.T, repo repository.ConfigRepository) {
+	newAudit := func(method string) *entity.AuditEvent {
+		return &entity.AuditEvent{Actor: "alice", Method: method}
+	}
+	config := entity.TestConfig(t)
+	config.Version = 1
+	audit := newAudit("CreateConfig")
+	require.NoError(t, repo.CreateConfig(config, audit))
+	requireChange(t, audit, entity.DefaultNamespace, 0, 1, "added: key1, key2, key3")
+
+	config = entity.TestConfig(t)
+	config.Data["key2"] = "new"
+	delete(config.Data, "key3")
+	audit = newAudit("UpdateConfig")
+	require.NoError(t, repo.UpdateConfig(config, 0, audit))
+	requireChange(t, audit, entity.DefaultNamespace, 1, 2, "removed: key3; changed: key2")
+
+	audit = newAudit("PatchConfig")
+	_, err := repo.PatchConfig(&entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test",
+		Set: map[string]string{"key4": "value4"}}, 0, audit)
+	require.NoError(t, err)
+	requireChange(t, audit, entity.DefaultNamespace, 2, 3, "added: key4")
+
+	audit = newAudit("SetRelevantConfig")
+	_, err = repo.SetRelevantConfig(entity.DefaultNamespace, "test", 1, audit)
+	require.NoError(t, err)
+	requireChange(t, audit, entity.DefaultNamespace, 3, 1, "added: key3; removed: key4; changed: key2")
+
+	audit = newAudit("DeleteConfigVersion")
+	require.NoError(t, repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 2, audit))
+	requireChange(t, audit, entity.DefaultNamespace, 2, 0, "removed: key1, key2")
+
+	audit = newAudit("PromoteConfig")
+	_, err = repo.PromoteConfig(&entity.ConfigPromotion{SourceNamespace: entity.DefaultNamespace,
+		TargetNamespace: "staging", Name: "test", Version: 1}, audit)
+	require.NoError(t, err)
+	requireChange(t, audit, "staging", 0, 1, "added: key1, key2, key3")
+
+	audit = newAudit("SetConfigLabels")
+	require.NoError(t, repo.SetConfigLabels(&entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "test",
+		Labels: map[string]string{"tier": "backend", "team": "payments"}}, audit))
+	requireChange(t, audit, entity.DefaultNamespace, 0, 0, "labels: team=payments, tier=backend")
+
+	audit = newAudit("SetConfigSchema")
+	require.NoError(t, repo.CreateConfigSchema(&entity.ConfigSchema{Namespace: entity.DefaultNamespace, Name: "test",
+		Keys: map[string]entity.KeyRule{"key1": {Required: true}}, AllowUnknownKeys: true}, audit))
+	requireChange(t, audit, entity.DefaultNamespace, 0, 1, "schema version 1")
+	audit = newAudit("DeleteConfigSchema")
+	require.NoError(t, repo.DeleteConfigSchema(entity.DefaultNamespace, "test", audit))
+	requireChange(t, audit, entity.DefaultNamespace, 1, 0, "schema deleted")
+
+	audit = newAudit("DeleteConfig")
+	require.NoError(t, repo.DeleteConfig(entity.DefaultNamespace, "test", audit))
+	requireChange(t, audit, entity.DefaultNamespace, 1, 0, "removed: key1, key2, key3")
+}
+
+func testConcurrentUpdates(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 1)
+	const writers = 10
+	versions := make(chan int64, writers)
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			config := entity.TestConfig(t)
+			if err := repo.UpdateConfig(config, 0, nil); err != nil {
+				t.Error(err)
+				return
+			}
+			versions <- config.Version
+		}()
+	}
+	wg.Wait()
+	close(versions)
+	seen := make(map[int64]bool)
+	for version := range versions {
+		require.False(t, seen[version], "version %d was created twice", version)
+		seen[version] = true
+	}
+	require.Len(t, seen, writers)
+	requireRelevant(t, repo, writers+1)
+}
+
+func createNamed(t *testing.T, repo repository.ConfigRepository, namespace string, names ...string) {
+	t.Helper()
+	for _, name := range names {
+		config := entity.TestConfig(t)
+		config.Namespace = namespace
+		config.Name = name
+		config.Version = 1
+		require.NoError(t, repo.CreateConfig(config, nil))
+	}
+}
+
+func listNames(t *testing.T, repo repository.ConfigRepository, filter *entity.NameFilter) []string {
+	t.Helper()
+	summaries, err := repo.ListConfigNames(filter)
+	require.NoError(t, err)
+	names := make([]string, 0, len(summaries))
+	for _, summary := range summaries {
+		names = append(names, summary.Name)
+	}
+	return names
+}
+
+func testListNames(t *testing.T, repo repository.ConfigRepository) {
+	require.Empty(t, listNames(t, repo, &entity.NameFilter{Namespace: entity.DefaultNamespace}))
+
+	createNamed(t, repo, entity.DefaultNamespace, "payments-api", "billing", "payments_worker", "Payments-legacy")
+	createNamed(t, repo, "staging", "payments-api")
+	config := entity.TestConfig(t)
+	config.Name = "billing"
+	require.NoError(t, repo.UpdateConfig(config, 0, nil))
+
+	summaries, err := repo.ListConfigNames(&entity.NameFilter{Namespace: entity.DefaultNamespace})
+	require.NoError(t, err)
+	require.Len(t, summaries, 4)
+	require.Equal(t, "Payments-legacy", summaries[0].Name)
+	require.Equal(t, "billing", summaries[1].Name)
+	require.Equal(t, entity.DefaultNamespace, summaries[1].Namespace)
+	require.Equal(t, int64(2), summaries[1].Version)
+	require.False(t, summaries[1].CreatedAt.IsZero())
+	require.False(t, summaries[1].LastUsed.IsZero())
+
+	testCases := []struct {
+		name   string
+		filter entity.NameFilter
+		want   []string
+	}{
+		{"prefix", entity.NameFilter{Prefix: "payments"}, []string{"payments-api", "payments_worker"}},
+		{"prefix is literal", entity.NameFilter{Prefix: "payments_"}, []string{"payments_worker"}},
+		{"contains", entity.NameFilter{Contains: "ment"}, []string{"Payments-legacy", "payments-api", "payments_worker"}},
+		{"contains is case sensitive", entity.NameFilter{Contains: "Pay"}, []string{"Payments-legacy"}},
+		{"prefix and contains", entity.NameFilter{Prefix: "pay", Contains: "work"}, []string{"payments_worker"}},
+		{"descending", entity.NameFilter{Descending: true}, []string{"payments_worker", "payments-api", "billing", "Payments-legacy"}},
+		{"limit", entity.NameFilter{Limit: 2}, []string{"Payments-legacy", "billing"}},
+		{"no match", entity.NameFilter{Prefix: "unknown"}, []string{}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter := tc.filter
+			filter.Namespace = entity.DefaultNamespace
+			require.Equal(t, tc.want, listNames(t, repo, &filter))
+		})
+	}
+	require.Equal(t, []string{"payments-api"}, listNames(t, repo, &entity.NameFilter{Namespace: "staging"}))
+}
+
+func testListNamesByPage(t *testing.T, repo repository.ConfigRepository) {
+	for _, name := range []string{"c", "a", "b", "d"} {
+		createNamed(t, repo, entity.DefaultNamespace, name)
+		time.Sleep(10 * time.Millisecond)
+	}
+	_, err := repo.GetConfig(entity.DefaultNamespace, "b")
+	require.NoError(t, err)
+
+	before, err := repo.GetRelevantLastUsed(entity.DefaultNamespace, "a")
+	require.NoError(t, err)
+	testCases := []struct {
+		name       string
+		orderBy    entity.ConfigOrder
+		descending bool
+		want       []string
+	}{
+		{"name", entity.OrderByName, false, []string{"a", "b", "c", "d"}},
+		{"name descending", entity.OrderByName, true, []string{"d", "c", "b", "a"}},
+		{"created", entity.OrderByCreated, false, []string{"c", "a", "b", "d"}},
+		{"created descending", entity.OrderByCreated, true, []string{"d", "b", "a", "c"}},
+		{"last used", entity.OrderByLastUsed, false, []string{"c", "a", "d", "b"}},
+		{"last used descending", entity.OrderByLastUsed, true, []string{"b", "d", "a", "c"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filter := &entity.NameFilter{Namespace: entity.DefaultNamespace, OrderBy: tc.orderBy, Descending: tc.descending, Limit: 3}
+			first, err := repo.ListConfigNames(filter)
+			require.NoError(t, err)
+			require.Len(t, first, 3)
+			filter.After = first[2].Cursor(tc.orderBy)
+			second, err := repo.ListConfigNames(filter)
+			require.NoError(t, err)
+			require.Len(t, second, 1)
+			filter.After = second[0].Cursor(tc.orderBy)
+			third, err := repo.ListConfigNames(filter)
+			require.NoError(t, err)
+			require.Empty(t, third)
+			require.Equal(t, tc.want, []string{first[0].Name, first[1].Name, first[2].Name, second[0].Name})
+		})
+	}
+	after, err := repo.GetRelevantLastUsed(entity.DefaultNamespace, "a")
+	require.NoError(t, err)
+	require.Equal(t, before, after)
+}
+
+func testLabels(t *testing.T, repo repository.ConfigRepository) {
+	err := repo.SetConfigLabels(&entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "api", Labels: map[string]string{"team": "payments"}}, nil)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+
+	createNamed(t, repo, entity.DefaultNamespace, "api", "worker", "web")
+	require.NoError(t, repo.SetConfigLabels(&entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "api",
+		Labels: map[string]string{"team": "payments", "tier": "backend"}}, nil))
+	require.NoError(t, repo.SetConfigLabels(&entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "worker",
+		Labels: map[string]string{"team": "payments", "tier": "batch"}}, nil))
+	require.NoError(t, repo.SetConfigLabels(&entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "web",
+		Labels: map[string]string{"team": "frontend"}}, nil))
+	err = repo.SetConfigLabels(&entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "web",
+		Labels: map[string]string{"bad key": "value"}}, nil)
+	require.IsType(t, validation.Errors{}, err)
+
+	summaries, err := repo.ListConfigNames(&entity.NameFilter{Namespace: entity.DefaultNamespace})
+	require.NoError(t, err)
+	require.Len(t, summaries, 3)
+	require.Equal(t, map[string]string{"team": "payments", "tier": "backend"}, summaries[0].Labels)
+	require.Equal(t, map[string]string{"team": "frontend"}, summaries[1].Labels)
+
+	require.Equal(t, []string{"api", "worker"}, listNames(t, repo, &entity.NameFilter{Namespace: entity.DefaultNamespace,
+		Labels: map[string]string{"team": "payments"}}))
+	require.Equal(t, []string{"worker"}, listNames(t, repo, &entity.NameFilter{Namespace: entity.DefaultNamespace,
+		Labels: map[string]string{"team": "payments", "tier": "batch"}}))
+	require.Empty(t, listNames(t, repo, &entity.NameFilter{Namespace: entity.DefaultNamespace,
+		Labels: map[string]string{"team": "frontend", "tier": "batch"}}))
+
+	require.NoError(t, repo.SetConfigLabels(&entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "web"}, nil))
+	require.Equal(t, []string{"api", "web", "worker"}, listNames(t, repo, &entity.NameFilter{Namespace: entity.DefaultNamespace}))
+	require.Empty(t, listNames(t, repo, &entity.NameFilter{Namespace: entity.DefaultNamespace,
+		Labels: map[string]string{"team": "frontend"}}))
+
+	require.NoError(t, repo.DeleteConfig(entity.DefaultNamespace, "api", nil))
+	createNamed(t, repo, entity.DefaultNamespace, "api")
+	summaries, err = repo.ListConfigNames(&entity.NameFilter{Namespace: entity.DefaultNamespace, Prefix: "api"})
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	require.Empty(t, summaries[0].Labels)
+}
+
+func testNamespaces(t *testing.T, repo repository.ConfigRepository) {
+	namespaces, err := repo.GetNamespaces()
+	require.NoError(t, err)
+	require.Empty(t, namespaces)
+	counts, err := repo.GetConfigCounts()
+	require.NoError(t, err)
+	require.Empty(t, counts)
+
+	createVersions(t, repo, 2)
+	config := entity.TestConfig(t)
+	config.Namespace = "staging"
+	config.Version = 1
+	config.Data = map[string]string{"key": "staging"}
+	require.NoError(t, repo.CreateConfig(config, nil))
+
+	got, err := repo.GetConfig("staging", "test")
+	require.NoError(t, err)
+	require.Equal(t, "staging", got.Namespace)
+	require.Equal(t, int64(1), got.Version)
+	require.Equal(t, map[string]string{"key": "staging"}, got.Data)
+	got, err = repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, entity.DefaultNamespace, got.Namespace)
+	require.Equal(t, int64(2), got.Version)
+
+	namespaces, err = repo.GetNamespaces()
+	require.NoError(t, err)
+	require.Equal(t, []string{entity.DefaultNamespace, "staging"}, namespaces)
+	counts, err = repo.GetConfigCounts()
+	require.NoError(t, err)
+	require.Equal(t, []*entity.ConfigCount{
+		{Namespace: entity.DefaultNamespace, Configs: 1, Versions: 2},
+		{Namespace: "staging", Configs: 1, Versions: 1},
+	}, counts)
+
+	require.NoError(t, repo.DeleteConfig("staging", "test", nil))
+	exists, err := repo.IsConfigExists("staging", "test")
+	require.NoError(t, err)
+	require.False(t, exists)
+	require.Len(t, mustGetConfigs(t, repo), 2)
+	_, err = repo.GetConfig("other", "test")
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+}
+
+func testPromote(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 2)
+	promotion := &entity.ConfigPromotion{
+		SourceNamespace: entity.DefaultNamespace,
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         1,
+		SetOverrides:    map[string]string{"key1": "production", "key4": "production"},
+	}
+	config, err := repo.PromoteConfig(promotion, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), config.Version)
+	require.Equal(t, &entity.ConfigSource{Namespace: entity.DefaultNamespace, Version: 1}, config.PromotedFrom)
+
+	// Stored overrides are applied to later promotions.
+	promotion = &entity.ConfigPromotion{
+		SourceNamespace: entity.DefaultNamespace,
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         2,
+		UnsetOverrides:  []string{"key4"},
+	}
+	config, err = repo.PromoteConfig(promotion, nil)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), config.Version)
+
+	got, err := repo.GetConfigByVersion("production", "test", 2)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key1": "production", "key2": "value2", "key3": "value3"}, got.Data)
+	require.Equal(t, &entity.ConfigSource{Namespace: entity.DefaultNamespace, Version: 2}, got.PromotedFrom)
+	got, err = repo.GetConfig("production", "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), got.Version)
+	overrides, err := repo.GetConfigOverrides("production", "test")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key1": "production"}, overrides)
+
+	// Versions that were not promoted have no lineage.
+	source, err := repo.GetConfigByVersion(entity.DefaultNamespace, "test", 2)
+	require.NoError(t, err)
+	require.Nil(t, source.PromotedFrom)
+
+	require.NoError(t, repo.DeleteConfig("production", "test", nil))
+	overrides, err = repo.GetConfigOverrides("production", "test")
+	require.NoError(t, err)
+	require.Empty(t, overrides)
+}
+
+func testPromoteUnknownVersion(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 1)
+	_, err := repo.PromoteConfig(&entity.ConfigPromotion{
+		SourceNamespace: entity.DefaultNamespace,
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         2,
+		SetOverrides:    map[string]string{"key1": "production"},
+	}, nil)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+	exists, err := repo.IsConfigExists("production", "test")
+	require.NoError(t, err)
+	require.False(t, exists)
+	overrides, err := repo.GetConfigOverrides("production", "test")
+	require.NoError(t, err)
+	require.Empty(t, overrides)
+}
+
+func testTypedValues(t *testing.T, repo repository.ConfigRepository) {
+	config := entity.TestConfig(t)
+	config.Version = 1
+	config.Data["port"] = "8080"
+	config.Data["hosts"] = `["` + strings.Repeat("a", 300) + `"]`
+	config.Types = map[string]entity.ValueType{"port": entity.TypeInt, "hosts": entity.TypeList}
+	require.NoError(t, repo.CreateConfig(config, nil))
+	got, err := repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, config.Data, got.Data)
+	require.Equal(t, config.Types, got.Types)
+
+	patched, err := repo.PatchConfig(&entity.ConfigPatch{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Set:       map[string]string{"port": "http", "timeout": "5s"},
+		Types:     map[string]entity.ValueType{"timeout": entity.TypeDuration},
+		Unset:     []string{"hosts"},
+	}, 0, nil)
+	require.NoError(t, err)
+	got, err = repo.GetConfigByVersion(entity.DefaultNamespace, "test", patched.Version)
+	require.NoError(t, err)
+	require.Equal(t, map[string]entity.ValueType{"timeout": entity.TypeDuration}, got.Types)
+
+	// Overrides keep the types of the source values.
+	promotion := &entity.ConfigPromotion{
+		SourceNamespace: entity.DefaultNamespace,
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         patched.Version,
+		SetOverrides:    map[string]string{"timeout": "1m"},
+	}
+	promoted, err := repo.PromoteConfig(promotion, nil)
+	require.NoError(t, err)
+	require.Equal(t, "1m", promoted.Data["timeout"])
+	require.Equal(t, entity.TypeDuration, promoted.TypeOf("timeout"))
+	promotion.SetOverrides = map[string]string{"timeout": "soon"}
+	_, err = repo.PromoteConfig(promotion, nil)
+	require.Error(t, err)
+	last, err := repo.GetLastVersion("production", "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), last)
+}
+
+// testSecretsWithoutTypes requires secrets written without a type, like configs read without the decrypt
+// permission and written back, to stay secret and not to be replaced by their mask.
+func testSecretsWithoutTypes(t *testing.T, repo repository.ConfigRepository) {
+	config := entity.TestConfig(t)
+	config.Version = 1
+	config.Data["password"] = "p@ssw0rd"
+	config.Types = map[string]entity.ValueType{"password": entity.TypeSecret}
+	require.NoError(t, repo.CreateConfig(config, nil))
+
+	masked := entity.TestConfig(t)
+	masked.Data["password"] = entity.SecretMask
+	require.Error(t, repo.UpdateConfig(masked, 0, nil))
+	_, err := repo.PatchConfig(&entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test",
+		Set: map[string]string{"password": entity.SecretMask}}, 0, nil)
+	require.Error(t, err)
+	got, err := repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), got.Version)
+	require.Equal(t, "p@ssw0rd", got.Data["password"])
+
+	updated := entity.TestConfig(t)
+	updated.Data["password"] = "updated"
+	require.NoError(t, repo.UpdateConfig(updated, 0, nil))
+	got, err = repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, "updated", got.Data["password"])
+	require.Equal(t, entity.TypeSecret, got.TypeOf("password"))
+
+	patched, err := repo.PatchConfig(&entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test",
+		Set: map[string]string{"password": "patched"}}, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, entity.TypeSecret, patched.TypeOf("password"))
+	got, err = repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, "patched", got.Data["password"])
+	require.Equal(t, entity.TypeSecret, got.TypeOf("password"))
+}
+
+// testSecrets requires repositories that encrypt secret values to be able to decrypt them.
+func testSecrets(t *testing.T, repo repository.ConfigRepository) {
+	config := entity.TestConfig(t)
+	config.Version = 1
+	config.Data["password"] = "p@ssw0rd"
+	config.Types = map[string]entity.ValueType{"password": entity.TypeSecret}
+	require.NoError(t, repo.CreateConfig(config, nil))
+	got, err := repo.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, config.Data, got.Data)
+	require.Equal(t, config.Types, got.Types)
+
+	// Overrides of secrets are secrets.
+	promoted, err := repo.PromoteConfig(&entity.ConfigPromotion{
+		SourceNamespace: entity.DefaultNamespace,
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         1,
+		SetOverrides:    map[string]string{"password": "production"},
+	}, nil)
+	require.NoError(t, err)
+	require.Equal(t, entity.TypeSecret, promoted.TypeOf("password"))
+	overrides, err := repo.GetConfigOverrides("production", "test")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"password": "production"}, overrides)
+	versions, err := repo.GetConfigs("production", "test")
+	require.NoError(t, err)
+	require.Len(t, versions, 1)
+	require.Equal(t, "production", versions[0].Data["password"])
+}
+
+// requireViolation requires err to report a schema violation of the key.
+func requireViolation(t *testing.T, err error, key string) {
+	t.Helper()
+	var errs validation.Errors
+	require.ErrorAs(t, err, &errs)
+	var data validation.Errors
+	require.ErrorAs(t, errs["data"], &data)
+	require.Contains(t, data, key)
+}
+
+// testSchemaAfterDeletion requires the version that becomes relevant when the relevant version is deleted
+// to satisfy the schema, like the versions made relevant by SetRelevantConfig.
+func testSchemaAfterDeletion(t *testing.T, repo repository.ConfigRepository) {
+	createVersions(t, repo, 3)
+	_, err := repo.SetRelevantConfig(entity.DefaultNamespace, "test", 1, nil)
+	require.NoError(t, err)
+	require.NoError(t, repo.CreateConfigSchema(&entity.ConfigSchema{
+		Namespace:        entity.DefaultNamespace,
+		Name:             "test",
+		Keys:             map[string]entity.KeyRule{"key1": {Required: true, Enum: []string{"value1", "value2"}}},
+		AllowUnknownKeys: true,
+	}, nil))
+
+	require.ErrorIs(t, repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 1, nil), usecase.ErrSchemaViolation)
+	requireRelevant(t, repo, 1)
+	require.Len(t, mustGetConfigs(t, repo), 3)
+
+	require.NoError(t, repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 3, nil))
+	require.NoError(t, repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 1, nil))
+	requireRelevant(t, repo, 2)
+	require.NoError(t, repo.DeleteConfigVersion(entity.DefaultNamespace, "test", 2, nil))
+}
+
+func testSchemas(t *testing.T, repo repository.ConfigRepository) {
+	_, err := repo.GetConfigSchema(entity.DefaultNamespace, "test")
+	require.Equal(t, usecase.ErrSchemaNotFound, err)
+	schemas, err := repo.GetConfigSchemas(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Empty(t, schemas)
+
+	createVersions(t, repo, 2)
+	schema := &entity.ConfigSchema{
+		Namespace:        entity.DefaultNamespace,
+		Name:             "test",
+		Keys:             map[string]entity.KeyRule{"key1": {Required: true, Enum: []string{"value1"}}},
+		AllowUnknownKeys: true,
+	}
+	require.NoError(t, repo.CreateConfigSchema(schema, nil))
+	require.Equal(t, int64(1), schema.Version)
+
+	config := entity.TestConfig(t)
+	config.Data["key1"] = "value3"
+	requireViolation(t, repo.UpdateConfig(config, 0, nil), "key1")
+	_, err = repo.PatchConfig(&entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test",
+		Unset: []string{"key1"}}, 0, nil)
+	requireViolation(t, err, "key1")
+	_, err = repo.SetRelevantConfig(entity.DefaultNamespace, "test", 1, nil)
+	require.NoError(t, err)
+	_, err = repo.SetRelevantConfig(entity.DefaultNamespace, "test", 2, nil)
+	requireViolation(t, err, "key1")
+	requireRelevant(t, repo, 1)
+
+	schema = &entity.ConfigSchema{
+		Namespace:        entity.DefaultNamespace,
+		Name:             "test",
+		Keys:             map[string]entity.KeyRule{"key1": {Required: true, Enum: []string{"value1", "value2"}}},
+		AllowUnknownKeys: true,
+	}
+	require.NoError(t, repo.CreateConfigSchema(schema, nil))
+	require.Equal(t, int64(2), schema.Version)
+	_, err = repo.SetRelevantConfig(entity.DefaultNamespace, "test", 2, nil)
+	require.NoError(t, err)
+
+	schemas, err = repo.GetConfigSchemas(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Len(t, schemas, 2)
+	require.Equal(t, int64(2), schemas[0].Version)
+	first, err := repo.GetConfigSchemaByVersion(entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.Equal(t, []string{"value1"}, first.Keys["key1"].Enum)
+	require.True(t, first.AllowUnknownKeys)
+	_, err = repo.GetConfigSchemaByVersion(entity.DefaultNamespace, "test", 3)
+	require.Equal(t, usecase.ErrSchemaNotFound, err)
+
+	require.NoError(t, repo.DeleteConfigSchema(entity.DefaultNamespace, "test", nil))
+	_, err = repo.GetConfigSchema(entity.DefaultNamespace, "test")
+	require.Equal(t, usecase.ErrSchemaNotFound, err)
+	require.NoError(t, repo.UpdateConfig(config, 0, nil))
+}
+
+func testSchemaBeforeConfig(t *testing.T, repo repository.ConfigRepository) {
+	min := 1.0
+	require.NoError(t, repo.CreateConfigSchema(&entity.ConfigSchema{
+		Namespace: "staging",
+		Name:      "test",
+		Keys:      map[string]entity.KeyRule{"port": {Required: true, Type: entity.TypeInt, Min: &min}},
+	}, nil))
+	config := &entity.Config{Namespace: "staging", Name: "test", Version: 1, Data: map[string]string{"port": "0"},
+		Types: map[string]entity.ValueType{"port": entity.TypeInt}}
+	requireViolation(t, repo.CreateConfig(config, nil), "port")
+	config.Data = map[string]string{"port": "8080", "host": "localhost"}
+	requireViolation(t, repo.CreateConfig(config, nil), "host")
+	delete(config.Data, "host")
+	require.NoError(t, repo.CreateConfig(config, nil))
+
+	_, err := repo.PromoteConfig(&entity.ConfigPromotion{SourceNamespace: "staging", TargetNamespace: "production",
+		Name: "test", Version: 1}, nil)
+	require.NoError(t, err)
+	_, err = repo.GetConfigSchema("production", "test")
+	require.Equal(t, usecase.ErrSchemaNotFound, err)
+}
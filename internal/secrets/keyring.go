@@ -0,0 +1,200 @@
+// Package secrets encrypts secret config values with envelope encryption: every value is encrypted with its own
+// random data key by AES-256-GCM, and the data key is encrypted with a master key of the keyring, which is only
+// configured locally and never stored with the values.
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gopkg.in/yaml.v3"
+	"io"
+	"os"
+	"strings"
+)
+
+const dataKeySize = 32
+
+var (
+	ErrUnknownKey      = errors.New("unknown master key")
+	ErrMalformedSecret = errors.New("malformed secret")
+)
+
+// KeyFile is the local file with the master keys, which are base64 encoded 32 byte AES keys generated,
+// for example, by "openssl rand -base64 32":
+//
+//	primary: 2024-01
+//	keys:
+//	  2023-06: "<base64 key>"
+//	  2024-01: "<base64 key>"
+//
+// New values are encrypted with the primary key. The other keys only decrypt values encrypted before
+// the primary key was rotated, until they are re-encrypted.
+type KeyFile struct {
+	Primary string            `yaml:"primary"`
+	Keys    map[string]string `yaml:"keys"`
+}
+
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// LoadKeyring reads the key file at path.
+func LoadKeyring(path string) (*Keyring, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var keyFile KeyFile
+	if err := yaml.Unmarshal(data, &keyFile); err != nil {
+		return nil, fmt.Errorf("invalid secrets key file %s: %w", path, err)
+	}
+	return NewKeyring(keyFile)
+}
+
+func NewKeyring(keyFile KeyFile) (*Keyring, error) {
+	k := &Keyring{primary: keyFile.Primary, keys: make(map[string]cipher.AEAD, len(keyFile.Keys))}
+	for id, encoded := range keyFile.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid master key ID %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != dataKeySize {
+			return nil, fmt.Errorf("master key %s must be %d base64 encoded bytes", id, dataKeySize)
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+	}
+	if _, ok := k.keys[k.primary]; !ok {
+		return nil, fmt.Errorf("primary master key %q is not in the keys", k.primary)
+	}
+	return k, nil
+}
+
+// Primary returns the ID of the master key that encrypts new values.
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// Seal encrypts the plaintext with a new data key and the data key with the primary master key. The result has
+// the form "<master key ID>:<encrypted data key>:<ciphertext>". The same additional data is required to open it,
+// which binds the ciphertext to where it is stored.
+func (k *Keyring) Seal(plaintext, additionalData []byte) (string, error) {
+	dataKey := make([]byte, dataKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(aead, plaintext, additionalData)
+	if err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{k.primary, encode(wrapped), encode(ciphertext)}, ":"), nil
+}
+
+// Open decrypts a value sealed by Seal with any of the master keys of the keyring.
+func (k *Keyring) Open(sealed string, additionalData []byte) ([]byte, error) {
+	_, dataKey, ciphertext, err := k.parse(sealed)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, ciphertext, additionalData)
+	if err != nil {
+		return nil, ErrMalformedSecret
+	}
+	return plaintext, nil
+}
+
+// Rewrap encrypts the data key of a sealed value with the primary master key, leaving the ciphertext as it is.
+// It reports whether the value changed, which it does not if the data key is already encrypted with the
+// primary key.
+func (k *Keyring) Rewrap(sealed string) (string, bool, error) {
+	keyID, dataKey, ciphertext, err := k.parse(sealed)
+	if err != nil {
+		return "", false, err
+	}
+	if keyID == k.primary {
+		return sealed, false, nil
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		return "", false, err
+	}
+	return strings.Join([]string{k.primary, encode(wrapped), encode(ciphertext)}, ":"), true, nil
+}
+
+// parse returns the master key ID, the decrypted data key and the ciphertext of a sealed value.
+func (k *Keyring) parse(sealed string) (string, []byte, []byte, error) {
+	parts := strings.Split(sealed, ":")
+	if len(parts) != 3 {
+		return "", nil, nil, ErrMalformedSecret
+	}
+	masterKey, ok := k.keys[parts[0]]
+	if !ok {
+		return "", nil, nil, fmt.Errorf("%w %s", ErrUnknownKey, parts[0])
+	}
+	wrapped, err := decode(parts[1])
+	if err != nil {
+		return "", nil, nil, ErrMalformedSecret
+	}
+	ciphertext, err := decode(parts[2])
+	if err != nil {
+		return "", nil, nil, ErrMalformedSecret
+	}
+	dataKey, err := open(masterKey, wrapped, []byte(parts[0]))
+	if err != nil {
+		return "", nil, nil, ErrMalformedSecret
+	}
+	return parts[0], dataKey, ciphertext, nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext with a random nonce, which precedes the ciphertext.
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrMalformedSecret
+	}
+	nonce, ciphertext := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	return aead.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func encode(data []byte) string {
+	return base64.RawStdEncoding.EncodeToString(data)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(s)
+}
@@ -0,0 +1,77 @@
+package secrets
+
+import (
+	"encoding/base64"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func testKey(b byte) string {
+	return base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(b), dataKeySize)))
+}
+
+func TestKeyring_SealOpen(t *testing.T) {
+	keyring, err := NewKeyring(KeyFile{Primary: "k1", Keys: map[string]string{"k1": testKey('a')}})
+	require.NoError(t, err)
+
+	sealed, err := keyring.Seal([]byte("p@ssw0rd"), []byte("pairs/1/password"))
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(sealed, "k1:"))
+	require.NotContains(t, sealed, "p@ssw0rd")
+	other, err := keyring.Seal([]byte("p@ssw0rd"), []byte("pairs/1/password"))
+	require.NoError(t, err)
+	require.NotEqual(t, sealed, other)
+
+	plaintext, err := keyring.Open(sealed, []byte("pairs/1/password"))
+	require.NoError(t, err)
+	require.Equal(t, "p@ssw0rd", string(plaintext))
+
+	_, err = keyring.Open(sealed, []byte("pairs/2/password"))
+	require.ErrorIs(t, err, ErrMalformedSecret)
+	_, err = keyring.Open("k1:not a secret", nil)
+	require.ErrorIs(t, err, ErrMalformedSecret)
+	_, err = keyring.Open("k2"+sealed[2:], []byte("pairs/1/password"))
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyring_Rewrap(t *testing.T) {
+	old, err := NewKeyring(KeyFile{Primary: "k1", Keys: map[string]string{"k1": testKey('a')}})
+	require.NoError(t, err)
+	sealed, err := old.Seal([]byte("p@ssw0rd"), nil)
+	require.NoError(t, err)
+
+	rotated, err := NewKeyring(KeyFile{Primary: "k2", Keys: map[string]string{"k1": testKey('a'), "k2": testKey('b')}})
+	require.NoError(t, err)
+	rewrapped, changed, err := rotated.Rewrap(sealed)
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.True(t, strings.HasPrefix(rewrapped, "k2:"))
+	require.Equal(t, sealed[strings.LastIndex(sealed, ":"):], rewrapped[strings.LastIndex(rewrapped, ":"):])
+	_, changed, err = rotated.Rewrap(rewrapped)
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	current, err := NewKeyring(KeyFile{Primary: "k2", Keys: map[string]string{"k2": testKey('b')}})
+	require.NoError(t, err)
+	plaintext, err := current.Open(rewrapped, nil)
+	require.NoError(t, err)
+	require.Equal(t, "p@ssw0rd", string(plaintext))
+	_, err = current.Open(sealed, nil)
+	require.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestNewKeyring_Invalid(t *testing.T) {
+	for name, keyFile := range map[string]KeyFile{
+		"no primary":      {Keys: map[string]string{"k1": testKey('a')}},
+		"unknown primary": {Primary: "k2", Keys: map[string]string{"k1": testKey('a')}},
+		"short key":       {Primary: "k1", Keys: map[string]string{"k1": base64.StdEncoding.EncodeToString([]byte("short"))}},
+		"not base64":      {Primary: "k1", Keys: map[string]string{"k1": "not base64"}},
+		"colon in ID":     {Primary: "k:1", Keys: map[string]string{"k:1": testKey('a')}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := NewKeyring(keyFile)
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"context"
+	"distributedConfig/internal/entity"
+)
+
+// anonymousActor is recorded as the actor of changes made by unauthenticated callers.
+const anonymousActor = "anonymous"
+
+// Caller identifies who requested a change, for the audit log.
+type Caller struct {
+	Actor    string
+	ClientIP string
+}
+
+type callerKey struct{}
+
+func WithCaller(ctx context.Context, caller Caller) context.Context {
+	return context.WithValue(ctx, callerKey{}, caller)
+}
+
+func callerFromContext(ctx context.Context) Caller {
+	caller, _ := ctx.Value(callerKey{}).(Caller)
+	if caller.Actor == "" {
+		caller.Actor = anonymousActor
+	}
+	return caller
+}
+
+// ListAuditEvents returns the audit events matching the filter, latest first.
+func (c *ConfigUseCase) ListAuditEvents(filter *entity.AuditFilter) ([]*entity.AuditEvent, error) {
+	events, err := c.audit.ListAuditEvents(filter)
+	if err != nil {
+		c.l.Error("Unable to list audit events: %s", err)
+		return nil, err
+	}
+	c.l.Info("Audit events listed: %d", len(events))
+	return events, nil
+}
+
+// auditEvent returns the audit event of a change requested by the caller, which the repository completes
+// and records together with the change, so that the audit log has every change and only the changes made.
+func auditEvent(ctx context.Context, method string) *entity.AuditEvent {
+	caller := callerFromContext(ctx)
+	return &entity.AuditEvent{Actor: caller.Actor, Method: method, ClientIP: caller.ClientIP}
+}
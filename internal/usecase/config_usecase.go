@@ -1,190 +1,378 @@
 package usecase
 
 import (
+	"context"
 	cfg "distributedConfig/config"
 	"distributedConfig/internal/entity"
 	"distributedConfig/internal/repository"
+	"distributedConfig/internal/watcher"
 	"distributedConfig/pkg/logger"
+	"sort"
 	"time"
 )
 
+// ReadCounter counts the versions of configs read by the users of the configs. The reads made by the service
+// itself, such as the loads of changed configs for watchers, are not counted.
+type ReadCounter interface {
+	CountReads(namespace, name string, versions int)
+	// ForgetReads drops the count of a deleted config.
+	ForgetReads(namespace, name string)
+}
+
 type ConfigUseCase struct {
 	l          logger.Logger
 	repository repository.ConfigRepository
+	audit      repository.AuditRepository
+	notifier   repository.ConfigNotifier
+	watchers   *watcher.Hub
+	reads      ReadCounter
 	cfg        *cfg.Config
 }
 
-func NewConfigUseCase(l logger.Logger, repository repository.ConfigRepository, cfg *cfg.Config) *ConfigUseCase {
-	return &ConfigUseCase{l: l, repository: repository, cfg: cfg}
+func NewConfigUseCase(l logger.Logger, repository repository.ConfigRepository, audit repository.AuditRepository,
+	notifier repository.ConfigNotifier, cfg *cfg.Config) *ConfigUseCase {
+	return &ConfigUseCase{
+		l:          l,
+		repository: repository,
+		audit:      audit,
+		notifier:   notifier,
+		watchers: watcher.NewHub(l, func(qualifiedName string) (*entity.Config, error) {
+			return repository.GetConfig(entity.SplitQualifiedName(qualifiedName))
+		}),
+		cfg: cfg,
+	}
+}
+
+// CountReads makes the use case count the versions read by GetConfig, GetConfigs, GetConfigByVersion and
+// ListConfigVersions with counter. It must be called before the use case is used.
+func (c *ConfigUseCase) CountReads(counter ReadCounter) {
+	c.reads = counter
 }
 
-func (c *ConfigUseCase) CreateConfig(config *entity.Config) error {
-	exists, err := c.repository.IsConfigExists(config.Name)
+// Watchers returns the hub that delivers relevant config changes to watchers. Configs are identified
+// in the hub by entity.QualifiedName.
+func (c *ConfigUseCase) Watchers() *watcher.Hub {
+	return c.watchers
+}
+
+func (c *ConfigUseCase) CreateConfig(ctx context.Context, config *entity.Config) error {
+	qualifiedName := entity.QualifiedName(config.Namespace, config.Name)
+	exists, err := c.repository.IsConfigExists(config.Namespace, config.Name)
 	if err != nil {
-		c.l.Error("Unable to check if config %s exists: %s", config.Name, err)
+		c.l.Error("Unable to check if config %s exists: %s", qualifiedName, err)
 		return err
 	}
 	if exists {
-		c.l.Error("Config %s already exists", config.Name)
+		c.l.Error("Config %s already exists", qualifiedName)
 		return ErrConfigAlreadyExists
 	}
-	err = c.repository.CreateConfig(config)
+	err = c.repository.CreateConfig(config, auditEvent(ctx, "CreateConfig"))
 	if err != nil {
 		c.l.Error("Unable to create config: %s", err)
 		return err
 	}
-	c.l.Info("Config created: %s %d", config.Name, config.Version)
+	c.l.Info("Config created: %s %d", qualifiedName, config.Version)
+	c.notifyConfigChanged(config.Namespace, config.Name)
 	return nil
 }
 
-func (c *ConfigUseCase) GetConfig(name string) (*entity.Config, error) {
-	config, err := c.repository.GetConfig(name)
+func (c *ConfigUseCase) GetConfig(namespace, name string) (*entity.Config, error) {
+	config, err := c.repository.GetConfig(namespace, name)
 	if err != nil {
 		c.l.Error("Unable to get config: %s", err)
 		return nil, err
 	}
-	c.l.Info("Config got: %s %d", config.Name, config.Version)
+	c.l.Info("Config got: %s %d", entity.QualifiedName(namespace, name), config.Version)
+	c.countReads(namespace, name, 1)
 	return config, nil
 }
 
-func (c *ConfigUseCase) GetConfigs(name string) ([]*entity.Config, error) {
-	configs, err := c.repository.GetConfigs(name)
+// PeekConfig returns the relevant version of the config for reads made by the service itself, which neither update
+// its last use nor are counted.
+func (c *ConfigUseCase) PeekConfig(namespace, name string) (*entity.Config, error) {
+	config, err := c.repository.PeekConfig(namespace, name, 0)
+	if err != nil {
+		c.l.Error("Unable to get config %s: %s", entity.QualifiedName(namespace, name), err)
+		return nil, err
+	}
+	return config, nil
+}
+
+func (c *ConfigUseCase) GetConfigs(namespace, name string) ([]*entity.Config, error) {
+	configs, err := c.repository.GetConfigs(namespace, name)
 	if err != nil {
 		c.l.Error("Unable to get configs: %s", err)
 		return nil, err
 	}
-	c.l.Info("Configs got: %s", name)
+	c.l.Info("Configs got: %s", entity.QualifiedName(namespace, name))
+	c.countReads(namespace, name, len(configs))
 	return configs, nil
 }
 
-func (c *ConfigUseCase) GetConfigByVersion(name string, version int64) (*entity.Config, error) {
-	config, err := c.repository.GetConfigByVersion(name, version)
+// ListConfigVersions returns a page of the versions of the config, latest first. The first page of a config
+// without versions is ErrConfigNotFound.
+func (c *ConfigUseCase) ListConfigVersions(filter *entity.VersionFilter) ([]*entity.Config, error) {
+	qualifiedName := entity.QualifiedName(filter.Namespace, filter.Name)
+	configs, err := c.repository.ListConfigVersions(filter)
 	if err != nil {
-		c.l.Error("Unable to get config: %s with version %d", name, version)
+		c.l.Error("Unable to list versions of config %s: %s", qualifiedName, err)
 		return nil, err
 	}
-	c.l.Info("Config got: %s %d", config.Name, config.Version)
+	if len(configs) == 0 && filter.BeforeVersion == 0 {
+		c.l.Error("Unable to list versions of config %s: %s", qualifiedName, ErrConfigNotFound)
+		return nil, ErrConfigNotFound
+	}
+	c.l.Info("Config versions listed: %s %d", qualifiedName, len(configs))
+	if !filter.WithoutData {
+		c.countReads(filter.Namespace, filter.Name, len(configs))
+	}
+	return configs, nil
+}
+
+func (c *ConfigUseCase) GetConfigByVersion(namespace, name string, version int64) (*entity.Config, error) {
+	config, err := c.repository.GetConfigByVersion(namespace, name, version)
+	if err != nil {
+		c.l.Error("Unable to get config: %s with version %d", entity.QualifiedName(namespace, name), version)
+		return nil, err
+	}
+	c.l.Info("Config got: %s %d", entity.QualifiedName(namespace, name), config.Version)
+	c.countReads(namespace, name, 1)
 	return config, nil
 }
 
-func (c *ConfigUseCase) DeleteConfig(name string) error {
-	lastUsed, err := c.repository.GetRelevantLastUsed(name)
+// ListNamespaces returns the namespaces that have configs. The default namespace is always listed.
+func (c *ConfigUseCase) ListNamespaces() ([]string, error) {
+	namespaces, err := c.repository.GetNamespaces()
+	if err != nil {
+		c.l.Error("Unable to get namespaces: %s", err)
+		return nil, err
+	}
+	i := sort.SearchStrings(namespaces, entity.DefaultNamespace)
+	if i == len(namespaces) || namespaces[i] != entity.DefaultNamespace {
+		namespaces = append(namespaces[:i], append([]string{entity.DefaultNamespace}, namespaces[i:]...)...)
+	}
+	c.l.Info("Namespaces listed: %d", len(namespaces))
+	return namespaces, nil
+}
+
+// ListConfigNames returns the configs of a namespace matching the filter, without their data.
+func (c *ConfigUseCase) ListConfigNames(filter *entity.NameFilter) ([]*entity.ConfigSummary, error) {
+	summaries, err := c.repository.ListConfigNames(filter)
+	if err != nil {
+		c.l.Error("Unable to list configs of namespace %s: %s", filter.Namespace, err)
+		return nil, err
+	}
+	c.l.Info("Configs listed: %s %d", filter.Namespace, len(summaries))
+	return summaries, nil
+}
+
+func (c *ConfigUseCase) DeleteConfig(ctx context.Context, namespace, name string) error {
+	qualifiedName := entity.QualifiedName(namespace, name)
+	lastUsed, err := c.repository.GetRelevantLastUsed(namespace, name)
 	if err != nil {
-		c.l.Error("Unable to get time of last use of %s config: %s", name, err)
+		c.l.Error("Unable to get time of last use of %s config: %s", qualifiedName, err)
 		return err
 	}
 	if !c.cfg.Server.DeleteConfigIfRecentlyUsed &&
 		time.Now().Sub(lastUsed) < time.Duration(c.cfg.Server.RecentUseDurationDays)*24*time.Hour {
-		c.l.Error("Unable to delete %s config: last use was less than 5 days ago", name)
+		c.l.Error("Unable to delete %s config: last use was less than 5 days ago", qualifiedName)
 		return ErrConfigWasRecentlyUsed
 	} else {
-		exists, err := c.repository.IsConfigExists(name)
+		exists, err := c.repository.IsConfigExists(namespace, name)
 		if err != nil {
-			c.l.Error("Unable to check if config %s exists: %s", name, err)
+			c.l.Error("Unable to check if config %s exists: %s", qualifiedName, err)
 			return err
 		}
 		if !exists {
-			c.l.Error("Config %s not found", name)
+			c.l.Error("Config %s not found", qualifiedName)
 			return ErrConfigNotFound
 		}
-		err = c.repository.DeleteConfig(name)
+		err = c.repository.DeleteConfig(namespace, name, auditEvent(ctx, "DeleteConfig"))
 		if err != nil {
-			c.l.Error("Unable to delete %s config: %s", name, err)
+			c.l.Error("Unable to delete %s config: %s", qualifiedName, err)
 			return err
 		}
-		c.l.Info("Config deleted: %s", name)
+		c.l.Info("Config deleted: %s", qualifiedName)
+		if c.reads != nil {
+			c.reads.ForgetReads(namespace, name)
+		}
+		c.notifyConfigChanged(namespace, name)
 		return nil
 	}
 }
 
-func (c *ConfigUseCase) DeleteConfigVersion(name string, version int64) error {
-	lastUsed, err := c.repository.GetLastUsedByVersion(name, version)
+func (c *ConfigUseCase) DeleteConfigVersion(ctx context.Context, namespace, name string, version int64) error {
+	qualifiedName := entity.QualifiedName(namespace, name)
+	lastUsed, err := c.repository.GetLastUsedByVersion(namespace, name, version)
 	if err != nil {
-		c.l.Error("Unable to get time of last use of %s config: %s", name, err)
+		c.l.Error("Unable to get time of last use of %s config: %s", qualifiedName, err)
 		return err
 	}
 	if !c.cfg.Server.DeleteConfigIfRecentlyUsed &&
 		time.Now().Sub(lastUsed) < time.Duration(c.cfg.Server.RecentUseDurationDays)*24*time.Hour {
-		c.l.Error("Unable to delete %s config: last use was less than 5 days ago", name)
+		c.l.Error("Unable to delete %s config: last use was less than 5 days ago", qualifiedName)
 		return ErrConfigWasRecentlyUsed
 	} else {
-		exists, err := c.repository.IsConfigVersionExists(name, version)
+		exists, err := c.repository.IsConfigVersionExists(namespace, name, version)
 		if err != nil {
-			c.l.Error("Unable to check if config %s with version %d exists: %s", name, version, err)
+			c.l.Error("Unable to check if config %s with version %d exists: %s", qualifiedName, version, err)
 			return err
 		}
 		if !exists {
-			c.l.Error("Config %s with version %d not found", name, version)
+			c.l.Error("Config %s with version %d not found", qualifiedName, version)
 			return ErrConfigNotFound
 		}
-		isRelevant, err := c.repository.IsConfigRelevant(name, version)
-		if err != nil {
-			c.l.Error("Unable to check if config %s with version %d is relevant: %s", name, version, err)
-			return err
-		}
-		if isRelevant {
-			err := c.setNewRelevantBeforeDeletion(name)
-			if err != nil {
-				return err
-			}
-		}
-		err = c.repository.DeleteConfigVersion(name, version)
+		err = c.repository.DeleteConfigVersion(namespace, name, version, auditEvent(ctx, "DeleteConfigVersion"))
 		if err != nil {
-			c.l.Error("Unable to delete %d version of %s config: %s", version, name, err)
+			c.l.Error("Unable to delete %d version of %s config: %s", version, qualifiedName, err)
 			return err
 		}
-		c.l.Info("Version %d of config %s deleted", version, name)
+		c.l.Info("Version %d of config %s deleted", version, qualifiedName)
+		c.notifyConfigChanged(namespace, name)
 		return nil
 	}
 }
 
-func (c *ConfigUseCase) UpdateConfig(config *entity.Config) error {
-	exists, err := c.repository.IsConfigExists(config.Name)
+func (c *ConfigUseCase) UpdateConfig(ctx context.Context, config *entity.Config, expectedVersion int64) error {
+	qualifiedName := entity.QualifiedName(config.Namespace, config.Name)
+	exists, err := c.repository.IsConfigExists(config.Namespace, config.Name)
 	if err != nil {
-		c.l.Error("Unable to check if config %s exists: %s", config.Name, err)
+		c.l.Error("Unable to check if config %s exists: %s", qualifiedName, err)
 		return err
 	} else if !exists {
-		c.l.Error("Config %s not found", config.Name)
+		c.l.Error("Config %s not found", qualifiedName)
 		return ErrConfigNotFound
 	}
-	err = c.repository.UpdateConfig(config)
-	if err != nil {
+	err = c.repository.UpdateConfig(config, expectedVersion, auditEvent(ctx, "UpdateConfig"))
+	if err != nil && err == ErrConfigVersionConflict {
+		c.l.Error("Unable to update config %s: latest version is not %d", qualifiedName, expectedVersion)
+		return err
+	} else if err != nil {
 		c.l.Error("Unable to update config: %s", err)
 		return err
 	}
-	c.l.Info("Config updated: %s %d", config.Name, config.Version)
+	c.l.Info("Config updated: %s %d", qualifiedName, config.Version)
+	c.notifyConfigChanged(config.Namespace, config.Name)
 	return nil
 }
 
-func (c *ConfigUseCase) setNewRelevantBeforeDeletion(name string) error {
-	version, err := c.repository.GetLastVersion(name)
+// PatchConfig creates a new version of the config from its relevant version with the patch applied.
+func (c *ConfigUseCase) PatchConfig(ctx context.Context, patch *entity.ConfigPatch, expectedVersion int64) (*entity.Config, error) {
+	qualifiedName := entity.QualifiedName(patch.Namespace, patch.Name)
+	config, err := c.repository.PatchConfig(patch, expectedVersion, auditEvent(ctx, "PatchConfig"))
+	if err != nil && err == ErrConfigVersionConflict {
+		c.l.Error("Unable to patch config %s: relevant version is not %d", qualifiedName, expectedVersion)
+		return nil, err
+	} else if err != nil {
+		c.l.Error("Unable to patch config %s: %s", qualifiedName, err)
+		return nil, err
+	}
+	c.l.Info("Config patched: %s %d", qualifiedName, config.Version)
+	c.notifyConfigChanged(config.Namespace, config.Name)
+	return config, nil
+}
+
+// PromoteConfig creates a new version of the config in the target namespace from a version of the config
+// in the source namespace, with the overrides of the target applied. The new version records the version
+// it was promoted from.
+func (c *ConfigUseCase) PromoteConfig(ctx context.Context, promotion *entity.ConfigPromotion) (*entity.Config, error) {
+	source := entity.QualifiedName(promotion.SourceNamespace, promotion.Name)
+	target := entity.QualifiedName(promotion.TargetNamespace, promotion.Name)
+	config, err := c.repository.PromoteConfig(promotion, auditEvent(ctx, "PromoteConfig"))
 	if err != nil {
-		c.l.Error("Unable to get last version of %s config: %s", name, err)
-		return err
+		c.l.Error("Unable to promote version %d of config %s to %s: %s", promotion.Version, source, target, err)
+		return nil, err
 	}
-	_, err = c.SetRelevantConfig(name, version)
+	c.l.Info("Config promoted: %s %d to %s %d", source, promotion.Version, target, config.Version)
+	c.notifyConfigChanged(config.Namespace, config.Name)
+	return config, nil
+}
+
+// GetConfigOverrides returns the overrides that promotions to the config apply.
+func (c *ConfigUseCase) GetConfigOverrides(namespace, name string) (map[string]string, error) {
+	overrides, err := c.repository.GetConfigOverrides(namespace, name)
 	if err != nil {
-		return err
+		c.l.Error("Unable to get overrides of config %s: %s", entity.QualifiedName(namespace, name), err)
+		return nil, err
 	}
-	return nil
+	c.l.Info("Config overrides got: %s", entity.QualifiedName(namespace, name))
+	return overrides, nil
 }
 
-func (c *ConfigUseCase) SetRelevantConfig(name string, version int64) (*entity.Config, error) {
-	exists, err := c.repository.IsConfigVersionExists(name, version)
+func (c *ConfigUseCase) SetRelevantConfig(ctx context.Context, namespace, name string, version int64) (*entity.Config, error) {
+	qualifiedName := entity.QualifiedName(namespace, name)
+	exists, err := c.repository.IsConfigVersionExists(namespace, name, version)
 	if err != nil {
-		c.l.Error("Unable to check if config %s with version %d exists: %s", name, version, err)
+		c.l.Error("Unable to check if config %s with version %d exists: %s", qualifiedName, version, err)
 		return nil, err
 	}
 	if !exists {
-		c.l.Error("Config %s with version %d not found", name, version)
+		c.l.Error("Config %s with version %d not found", qualifiedName, version)
 		return nil, ErrConfigNotFound
 	}
-	config, err := c.repository.SetRelevantConfig(name, version)
+	config, err := c.repository.SetRelevantConfig(namespace, name, version, auditEvent(ctx, "SetRelevantConfig"))
 	if err != nil {
-		c.l.Error("Unable to get config: %s with version %d", name, version)
+		c.l.Error("Unable to get config: %s with version %d", qualifiedName, version)
 		return nil, err
 	}
-	c.l.Info("Version %d of config %s set relevant", version, name)
-
+	c.l.Info("Version %d of config %s set relevant", version, qualifiedName)
+	c.notifyConfigChanged(namespace, name)
 	return config, nil
 }
+
+// DiffConfigVersions compares two versions of the config. A version of 0 stands for the relevant one.
+func (c *ConfigUseCase) DiffConfigVersions(namespace, name string, fromVersion, toVersion int64) (*entity.ConfigDiff, error) {
+	qualifiedName := entity.QualifiedName(namespace, name)
+	from, err := c.repository.PeekConfig(namespace, name, fromVersion)
+	if err != nil {
+		c.l.Error("Unable to get config: %s with version %d", qualifiedName, fromVersion)
+		return nil, err
+	}
+	to, err := c.repository.PeekConfig(namespace, name, toVersion)
+	if err != nil {
+		c.l.Error("Unable to get config: %s with version %d", qualifiedName, toVersion)
+		return nil, err
+	}
+	c.l.Info("Config diffed: %s %d %d", qualifiedName, from.Version, to.Version)
+	return entity.DiffConfigs(from, to), nil
+}
+
+// DiffProposedConfig compares the relevant version of the config with the data that UpdateConfig would store.
+func (c *ConfigUseCase) DiffProposedConfig(config *entity.Config) (*entity.ConfigDiff, error) {
+	qualifiedName := entity.QualifiedName(config.Namespace, config.Name)
+	if err := config.Validate(); err != nil {
+		c.l.Error("Unable to diff config %s: %s", qualifiedName, err)
+		return nil, err
+	}
+	relevant, err := c.repository.PeekConfig(config.Namespace, config.Name, 0)
+	if err != nil {
+		c.l.Error("Unable to get config: %s", err)
+		return nil, err
+	}
+	c.l.Info("Proposed config diffed: %s %d", qualifiedName, relevant.Version)
+	return entity.DiffConfigs(relevant, config), nil
+}
+
+func (c *ConfigUseCase) WatchConfig(namespace, name string) (*watcher.Subscription, error) {
+	qualifiedName := entity.QualifiedName(namespace, name)
+	subscription, err := c.watchers.Subscribe(qualifiedName)
+	if err != nil {
+		c.l.Error("Unable to watch config %s: %s", qualifiedName, err)
+		return nil, err
+	}
+	c.l.Info("Config watched: %s", qualifiedName)
+	return subscription, nil
+}
+
+func (c *ConfigUseCase) countReads(namespace, name string, versions int) {
+	if c.reads != nil && versions > 0 {
+		c.reads.CountReads(namespace, name, versions)
+	}
+}
+
+func (c *ConfigUseCase) notifyConfigChanged(namespace, name string) {
+	qualifiedName := entity.QualifiedName(namespace, name)
+	if err := c.notifier.NotifyConfigChanged(qualifiedName); err != nil {
+		c.l.Error("Unable to notify watchers of %s config: %s", qualifiedName, err)
+	}
+}
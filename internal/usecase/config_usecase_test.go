@@ -0,0 +1,400 @@
+package usecase_test
+
+import (
+	"context"
+	cfg "distributedConfig/config"
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository/memory_repository"
+	"distributedConfig/internal/usecase"
+	"distributedConfig/internal/watcher"
+	"distributedConfig/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+// hubNotifier delivers changes synchronously, so tests observe them without waiting.
+type hubNotifier struct {
+	publisher watcher.Publisher
+}
+
+func (n *hubNotifier) NotifyConfigChanged(name string) error {
+	n.publisher.Publish(name)
+	return nil
+}
+
+func (n *hubNotifier) Listen(ctx context.Context, publisher watcher.Publisher) error {
+	n.publisher = publisher
+	return nil
+}
+
+func newTestUseCase(t *testing.T, serverConfig cfg.ServerConfig) *usecase.ConfigUseCase {
+	t.Helper()
+	notifier := &hubNotifier{}
+	configRepository, auditRepository := memory_repository.NewAuditedConfigRepository()
+	configUseCase := usecase.NewConfigUseCase(*logger.New("error"), configRepository,
+		auditRepository, notifier, &cfg.Config{Server: serverConfig})
+	require.NoError(t, notifier.Listen(context.Background(), configUseCase.Watchers()))
+	return configUseCase
+}
+
+func nextUpdate(t *testing.T, updates <-chan watcher.Update) watcher.Update {
+	t.Helper()
+	select {
+	case update := <-updates:
+		return update
+	case <-time.After(time.Second):
+		t.Fatal("no update received")
+		return watcher.Update{}
+	}
+}
+
+func TestConfigUseCase_CreateConfig(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(context.Background(), config))
+	require.Equal(t, usecase.ErrConfigAlreadyExists, configUseCase.CreateConfig(context.Background(), entity.TestConfig(t)))
+}
+
+func TestConfigUseCase_UpdateConfig(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	require.Equal(t, usecase.ErrConfigNotFound, configUseCase.UpdateConfig(context.Background(), entity.TestConfig(t), 0))
+
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(context.Background(), config))
+	require.Equal(t, usecase.ErrConfigVersionConflict, configUseCase.UpdateConfig(context.Background(), entity.TestConfig(t), 2))
+	updated := entity.TestConfig(t)
+	require.NoError(t, configUseCase.UpdateConfig(context.Background(), updated, 1))
+	require.Equal(t, int64(2), updated.Version)
+}
+
+func TestConfigUseCase_DeleteRecentlyUsedConfig(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: false, RecentUseDurationDays: 5})
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(context.Background(), config))
+	require.Equal(t, usecase.ErrConfigWasRecentlyUsed,
+		configUseCase.DeleteConfig(context.Background(), entity.DefaultNamespace, "test"))
+	require.Equal(t, usecase.ErrConfigWasRecentlyUsed,
+		configUseCase.DeleteConfigVersion(context.Background(), entity.DefaultNamespace, "test", 1))
+}
+
+func TestConfigUseCase_DeleteRelevantConfigVersion(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(context.Background(), config))
+	require.NoError(t, configUseCase.UpdateConfig(context.Background(), entity.TestConfig(t), 0))
+
+	require.NoError(t, configUseCase.DeleteConfigVersion(context.Background(), entity.DefaultNamespace, "test", 2))
+	relevant, err := configUseCase.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), relevant.Version)
+	require.Equal(t, usecase.ErrConfigNotFound,
+		configUseCase.DeleteConfigVersion(context.Background(), entity.DefaultNamespace, "test", 2))
+}
+
+func TestConfigUseCase_WatchConfig(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	_, err := configUseCase.WatchConfig(entity.DefaultNamespace, "test")
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(context.Background(), config))
+	subscription, err := configUseCase.WatchConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	defer subscription.Close()
+	require.Equal(t, int64(1), nextUpdate(t, subscription.Updates()).Config.Version)
+
+	require.NoError(t, configUseCase.UpdateConfig(context.Background(), entity.TestConfig(t), 0))
+	require.Equal(t, int64(2), nextUpdate(t, subscription.Updates()).Config.Version)
+
+	_, err = configUseCase.SetRelevantConfig(context.Background(), entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), nextUpdate(t, subscription.Updates()).Config.Version)
+
+	require.NoError(t, configUseCase.DeleteConfig(context.Background(), entity.DefaultNamespace, "test"))
+	require.Equal(t, usecase.ErrConfigNotFound, nextUpdate(t, subscription.Updates()).Err)
+}
+
+func TestConfigUseCase_DiffConfigVersions(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(context.Background(), config))
+	require.NoError(t, configUseCase.UpdateConfig(context.Background(), &entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Data:      map[string]string{"key1": "value1", "key2": "new", "key4": "value4"},
+	}, 0))
+	_, err := configUseCase.SetRelevantConfig(context.Background(), entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+
+	diff, err := configUseCase.DiffConfigVersions(entity.DefaultNamespace, "test", 0, 2)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), diff.FromVersion)
+	require.Equal(t, int64(2), diff.ToVersion)
+	require.Equal(t, map[string]string{"key4": "value4"}, diff.Added)
+	require.Equal(t, map[string]string{"key3": "value3"}, diff.Removed)
+	require.Equal(t, map[string]entity.ValueChange{"key2": {Old: "value2", New: "new"}}, diff.Changed)
+
+	_, err = configUseCase.DiffConfigVersions(entity.DefaultNamespace, "test", 1, 3)
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+}
+
+func TestConfigUseCase_DiffProposedConfig(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	_, err := configUseCase.DiffProposedConfig(entity.TestConfig(t))
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(context.Background(), config))
+	diff, err := configUseCase.DiffProposedConfig(entity.TestConfig(t))
+	require.NoError(t, err)
+	require.Equal(t, int64(1), diff.FromVersion)
+	require.True(t, diff.IsEmpty())
+
+	_, err = configUseCase.DiffProposedConfig(&entity.Config{Namespace: entity.DefaultNamespace, Name: "test"})
+	require.Error(t, err)
+}
+
+func TestConfigUseCase_PatchConfig(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(context.Background(), config))
+	subscription, err := configUseCase.WatchConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	defer subscription.Close()
+	require.Equal(t, int64(1), nextUpdate(t, subscription.Updates()).Config.Version)
+
+	patched, err := configUseCase.PatchConfig(context.Background(),
+		&entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test", Unset: []string{"key1"}}, 1)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), patched.Version)
+	update := nextUpdate(t, subscription.Updates())
+	require.Equal(t, int64(2), update.Config.Version)
+	require.Equal(t, map[string]string{"key2": "value2", "key3": "value3"}, update.Config.Data)
+
+	_, err = configUseCase.PatchConfig(context.Background(),
+		&entity.ConfigPatch{Namespace: entity.DefaultNamespace, Name: "test", Unset: []string{"key2"}}, 1)
+	require.Equal(t, usecase.ErrConfigVersionConflict, err)
+}
+
+func TestConfigUseCase_AuditLog(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	ctx := usecase.WithCaller(context.Background(), usecase.Caller{Actor: "alice", ClientIP: "10.0.0.1"})
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(ctx, config))
+	require.NoError(t, configUseCase.UpdateConfig(context.Background(), &entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Data:      map[string]string{"key1": "value1", "key2": "new"},
+	}, 0))
+	_, err := configUseCase.PatchConfig(ctx, &entity.ConfigPatch{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Set:       map[string]string{"key4": "value4"},
+	}, 0)
+	require.NoError(t, err)
+	_, err = configUseCase.SetRelevantConfig(ctx, entity.DefaultNamespace, "test", 1)
+	require.NoError(t, err)
+	require.NoError(t, configUseCase.DeleteConfigVersion(ctx, entity.DefaultNamespace, "test", 2))
+	require.NoError(t, configUseCase.DeleteConfig(ctx, entity.DefaultNamespace, "test"))
+	require.Equal(t, usecase.ErrConfigNotFound, configUseCase.DeleteConfig(ctx, entity.DefaultNamespace, "test"))
+
+	events, err := configUseCase.ListAuditEvents(&entity.AuditFilter{Namespace: entity.DefaultNamespace, ConfigName: "test"})
+	require.NoError(t, err)
+	type change struct {
+		actor, method string
+		from, to      int64
+		summary       string
+	}
+	var changes []change
+	for _, event := range events {
+		changes = append(changes, change{event.Actor, event.Method, event.FromVersion, event.ToVersion, event.DiffSummary})
+	}
+	require.Equal(t, []change{
+		{"alice", "DeleteConfig", 1, 0, "removed: key1, key2, key3"},
+		{"alice", "DeleteConfigVersion", 2, 0, "removed: key1, key2"},
+		{"alice", "SetRelevantConfig", 3, 1, "added: key3; removed: key4; changed: key2"},
+		{"alice", "PatchConfig", 2, 3, "added: key4"},
+		{"anonymous", "UpdateConfig", 1, 2, "removed: key3; changed: key2"},
+		{"alice", "CreateConfig", 0, 1, "added: key1, key2, key3"},
+	}, changes)
+	require.Equal(t, "10.0.0.1", events[0].ClientIP)
+}
+
+func TestConfigUseCase_Namespaces(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	namespaces, err := configUseCase.ListNamespaces()
+	require.NoError(t, err)
+	require.Equal(t, []string{entity.DefaultNamespace}, namespaces)
+
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(context.Background(), config))
+	subscription, err := configUseCase.WatchConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	defer subscription.Close()
+	require.Equal(t, int64(1), nextUpdate(t, subscription.Updates()).Config.Version)
+
+	for _, namespace := range []string{"staging", "audit"} {
+		config := entity.TestConfig(t)
+		config.Namespace = namespace
+		config.Version = 1
+		config.Data = map[string]string{"key": namespace}
+		require.NoError(t, configUseCase.CreateConfig(context.Background(), config))
+	}
+	staging, err := configUseCase.GetConfig("staging", "test")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key": "staging"}, staging.Data)
+	require.NoError(t, configUseCase.DeleteConfig(context.Background(), "staging", "test"))
+	select {
+	case update := <-subscription.Updates():
+		t.Fatalf("unexpected update of the default namespace: %+v", update)
+	default:
+	}
+	relevant, err := configUseCase.GetConfig(entity.DefaultNamespace, "test")
+	require.NoError(t, err)
+	require.Equal(t, entity.TestConfig(t).Data, relevant.Data)
+
+	namespaces, err = configUseCase.ListNamespaces()
+	require.NoError(t, err)
+	require.Equal(t, []string{"audit", entity.DefaultNamespace}, namespaces)
+	events, err := configUseCase.ListAuditEvents(&entity.AuditFilter{Namespace: "staging"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+}
+
+func TestConfigUseCase_PromoteConfig(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	ctx := usecase.WithCaller(context.Background(), usecase.Caller{Actor: "alice"})
+	config := entity.TestConfig(t)
+	config.Namespace = "staging"
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(ctx, config))
+
+	promoted, err := configUseCase.PromoteConfig(ctx, &entity.ConfigPromotion{
+		SourceNamespace: "staging",
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         1,
+		SetOverrides:    map[string]string{"key1": "production"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(1), promoted.Version)
+	require.Equal(t, map[string]string{"key1": "production", "key2": "value2", "key3": "value3"}, promoted.Data)
+	subscription, err := configUseCase.WatchConfig("production", "test")
+	require.NoError(t, err)
+	defer subscription.Close()
+	require.Equal(t, int64(1), nextUpdate(t, subscription.Updates()).Config.Version)
+
+	_, err = configUseCase.PatchConfig(ctx, &entity.ConfigPatch{
+		Namespace: "staging",
+		Name:      "test",
+		Set:       map[string]string{"key1": "new", "key2": "new"},
+	}, 0)
+	require.NoError(t, err)
+	promoted, err = configUseCase.PromoteConfig(ctx, &entity.ConfigPromotion{
+		SourceNamespace: "staging",
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         2,
+	})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), nextUpdate(t, subscription.Updates()).Config.Version)
+	got, err := configUseCase.GetConfigByVersion("production", "test", promoted.Version)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key1": "production", "key2": "new", "key3": "value3"}, got.Data)
+	require.Equal(t, &entity.ConfigSource{Namespace: "staging", Version: 2}, got.PromotedFrom)
+	overrides, err := configUseCase.GetConfigOverrides("production", "test")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"key1": "production"}, overrides)
+
+	events, err := configUseCase.ListAuditEvents(&entity.AuditFilter{Namespace: "production"})
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, "PromoteConfig", events[0].Method)
+	require.Equal(t, "changed: key2", events[0].DiffSummary)
+
+	_, err = configUseCase.PromoteConfig(ctx, &entity.ConfigPromotion{
+		SourceNamespace: "staging",
+		TargetNamespace: "production",
+		Name:            "test",
+		Version:         3,
+	})
+	require.Equal(t, usecase.ErrConfigNotFound, err)
+}
+
+func TestConfigUseCase_ConfigSchema(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	ctx := usecase.WithCaller(context.Background(), usecase.Caller{Actor: "alice"})
+	schema := &entity.ConfigSchema{
+		Namespace:        entity.DefaultNamespace,
+		Name:             "test",
+		Keys:             map[string]entity.KeyRule{"key1": {Required: true, Pattern: "^value[0-9]$"}},
+		AllowUnknownKeys: true,
+	}
+	require.NoError(t, configUseCase.SetConfigSchema(ctx, schema))
+
+	config := entity.TestConfig(t)
+	config.Version = 1
+	config.Data["key1"] = "invalid"
+	require.Error(t, configUseCase.CreateConfig(ctx, config))
+	config.Data["key1"] = "value1"
+	require.NoError(t, configUseCase.CreateConfig(ctx, config))
+
+	got, err := configUseCase.GetConfigSchema(entity.DefaultNamespace, "test", 0)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), got.Version)
+	require.NoError(t, configUseCase.DeleteConfigSchema(ctx, entity.DefaultNamespace, "test"))
+	_, err = configUseCase.GetConfigSchema(entity.DefaultNamespace, "test", 1)
+	require.Equal(t, usecase.ErrSchemaNotFound, err)
+	require.Equal(t, usecase.ErrSchemaNotFound, configUseCase.DeleteConfigSchema(ctx, entity.DefaultNamespace, "test"))
+
+	events, err := configUseCase.ListAuditEvents(&entity.AuditFilter{ConfigName: "test"})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, "DeleteConfigSchema", events[0].Method)
+	require.Equal(t, "schema deleted", events[0].DiffSummary)
+	require.Equal(t, "SetConfigSchema", events[2].Method)
+	require.Equal(t, "schema version 1", events[2].DiffSummary)
+}
+
+func TestConfigUseCase_ConfigLabels(t *testing.T) {
+	configUseCase := newTestUseCase(t, cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true})
+	ctx := usecase.WithCaller(context.Background(), usecase.Caller{Actor: "alice"})
+	labels := &entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "test", Labels: map[string]string{"team": "payments"}}
+	require.Equal(t, usecase.ErrConfigNotFound, configUseCase.SetConfigLabels(ctx, labels))
+
+	config := entity.TestConfig(t)
+	config.Version = 1
+	require.NoError(t, configUseCase.CreateConfig(ctx, config))
+	labels.Labels["tier"] = "backend"
+	require.NoError(t, configUseCase.SetConfigLabels(ctx, labels))
+	summaries, err := configUseCase.ListConfigNames(&entity.NameFilter{Namespace: entity.DefaultNamespace,
+		Labels: map[string]string{"team": "payments"}})
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	require.Equal(t, "test", summaries[0].Name)
+	require.Equal(t, map[string]string{"team": "payments", "tier": "backend"}, summaries[0].Labels)
+
+	require.NoError(t, configUseCase.SetConfigLabels(ctx, &entity.ConfigLabels{Namespace: entity.DefaultNamespace, Name: "test"}))
+	summaries, err = configUseCase.ListConfigNames(&entity.NameFilter{Namespace: entity.DefaultNamespace,
+		Labels: map[string]string{"team": "payments"}})
+	require.NoError(t, err)
+	require.Empty(t, summaries)
+
+	events, err := configUseCase.ListAuditEvents(&entity.AuditFilter{ConfigName: "test"})
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, "SetConfigLabels", events[0].Method)
+	require.Equal(t, "labels removed", events[0].DiffSummary)
+	require.Equal(t, "labels: team=payments, tier=backend", events[1].DiffSummary)
+}
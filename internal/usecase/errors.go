@@ -6,4 +6,10 @@ var (
 	ErrConfigWasRecentlyUsed = errors.New("config was recently used")
 	ErrConfigNotFound        = errors.New("config not found")
 	ErrConfigAlreadyExists   = errors.New("config already exists")
+	ErrConfigVersionConflict = errors.New("config version conflict")
+	ErrSchemaNotFound        = errors.New("schema not found")
+	ErrNoMasterKey           = errors.New("secret values require a master key")
+	// ErrSchemaViolation is returned when a change would make relevant a version that violates the schema
+	// without the version being given by the caller.
+	ErrSchemaViolation = errors.New("version violates the schema")
 )
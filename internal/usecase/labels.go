@@ -0,0 +1,18 @@
+package usecase
+
+import (
+	"context"
+	"distributedConfig/internal/entity"
+)
+
+// SetConfigLabels replaces the labels of the config, by which it can be found with ListConfigNames.
+func (c *ConfigUseCase) SetConfigLabels(ctx context.Context, labels *entity.ConfigLabels) error {
+	qualifiedName := entity.QualifiedName(labels.Namespace, labels.Name)
+	err := c.repository.SetConfigLabels(labels, auditEvent(ctx, "SetConfigLabels"))
+	if err != nil {
+		c.l.Error("Unable to set labels of config %s: %s", qualifiedName, err)
+		return err
+	}
+	c.l.Info("Config labels set: %s %d", qualifiedName, len(labels.Labels))
+	return nil
+}
@@ -0,0 +1,65 @@
+package usecase
+
+import (
+	"context"
+	"distributedConfig/internal/entity"
+)
+
+// SetConfigSchema stores the schema as the next version of the schema of the config. Versions of the config
+// that are created or set relevant afterwards must follow it; versions stored before are not checked.
+func (c *ConfigUseCase) SetConfigSchema(ctx context.Context, schema *entity.ConfigSchema) error {
+	qualifiedName := entity.QualifiedName(schema.Namespace, schema.Name)
+	err := c.repository.CreateConfigSchema(schema, auditEvent(ctx, "SetConfigSchema"))
+	if err != nil {
+		c.l.Error("Unable to set schema of config %s: %s", qualifiedName, err)
+		return err
+	}
+	c.l.Info("Config schema set: %s %d", qualifiedName, schema.Version)
+	return nil
+}
+
+// GetConfigSchema returns a version of the schema of the config. A version of 0 stands for the latest one,
+// which is enforced.
+func (c *ConfigUseCase) GetConfigSchema(namespace, name string, version int64) (*entity.ConfigSchema, error) {
+	qualifiedName := entity.QualifiedName(namespace, name)
+	var schema *entity.ConfigSchema
+	var err error
+	if version == 0 {
+		schema, err = c.repository.GetConfigSchema(namespace, name)
+	} else {
+		schema, err = c.repository.GetConfigSchemaByVersion(namespace, name, version)
+	}
+	if err != nil {
+		c.l.Error("Unable to get schema of config %s with version %d: %s", qualifiedName, version, err)
+		return nil, err
+	}
+	c.l.Info("Config schema got: %s %d", qualifiedName, schema.Version)
+	return schema, nil
+}
+
+// ListConfigSchemas returns every version of the schema of the config, latest first.
+func (c *ConfigUseCase) ListConfigSchemas(namespace, name string) ([]*entity.ConfigSchema, error) {
+	schemas, err := c.repository.GetConfigSchemas(namespace, name)
+	if err != nil {
+		c.l.Error("Unable to list schemas of config %s: %s", entity.QualifiedName(namespace, name), err)
+		return nil, err
+	}
+	c.l.Info("Config schemas listed: %s %d", entity.QualifiedName(namespace, name), len(schemas))
+	return schemas, nil
+}
+
+// DeleteConfigSchema deletes every version of the schema of the config, so its data is no longer checked.
+func (c *ConfigUseCase) DeleteConfigSchema(ctx context.Context, namespace, name string) error {
+	qualifiedName := entity.QualifiedName(namespace, name)
+	if _, err := c.repository.GetConfigSchema(namespace, name); err != nil {
+		c.l.Error("Unable to get schema of config %s: %s", qualifiedName, err)
+		return err
+	}
+	err := c.repository.DeleteConfigSchema(namespace, name, auditEvent(ctx, "DeleteConfigSchema"))
+	if err != nil {
+		c.l.Error("Unable to delete schema of config %s: %s", qualifiedName, err)
+		return err
+	}
+	c.l.Info("Config schema deleted: %s", qualifiedName)
+	return nil
+}
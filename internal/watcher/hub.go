@@ -0,0 +1,266 @@
+package watcher
+
+import (
+	"distributedConfig/internal/entity"
+	"distributedConfig/pkg/logger"
+	"errors"
+	"sync"
+)
+
+// ErrClosed is delivered to every subscription when the hub is closed and returned by Subscribe afterwards.
+var ErrClosed = errors.New("watchers are closed")
+
+// LoadFunc returns the relevant version of the named config.
+type LoadFunc func(name string) (*entity.Config, error)
+
+// Update is delivered to a subscription when the relevant version of a config changes.
+// Err is set when the config could not be loaded, e.g. because it was deleted.
+type Update struct {
+	Config *entity.Config
+	Err    error
+}
+
+// Publisher is told about changes of configs. The Hub publishes them to its subscribers.
+type Publisher interface {
+	// Publish tells that the named config changed.
+	Publish(name string)
+	// PublishAll tells that any config could have changed, e.g. because notifications were missed.
+	PublishAll()
+}
+
+// Hub fans out changes of relevant configs to in-process subscribers.
+// A config is loaded once per change no matter how many subscribers watch it, and loads of different configs
+// do not wait for each other.
+type Hub struct {
+	l    logger.Logger
+	load LoadFunc
+
+	mu          sync.Mutex
+	locks       map[string]*nameLock
+	subscribers map[string]map[*Subscription]struct{}
+	current     map[string]*entity.Config
+	closed      bool
+}
+
+// nameLock serializes the loads of a config. It exists while someone holds or waits for it.
+type nameLock struct {
+	mu   sync.Mutex
+	refs int
+	// queued is set while a Publish waits for the lock, which makes later ones redundant: the waiting one
+	// loads the config after all of them.
+	queued bool
+}
+
+func NewHub(l logger.Logger, load LoadFunc) *Hub {
+	return &Hub{
+		l:           l,
+		load:        load,
+		locks:       make(map[string]*nameLock),
+		subscribers: make(map[string]map[*Subscription]struct{}),
+		current:     make(map[string]*entity.Config),
+	}
+}
+
+// Subscribe registers a watcher of the named config and immediately offers it the current relevant version.
+func (h *Hub) Subscribe(name string) (*Subscription, error) {
+	lock := h.lock(name, false)
+	defer h.unlock(name, lock)
+
+	h.mu.Lock()
+	config, ok := h.current[name]
+	closed := h.closed
+	h.mu.Unlock()
+	if closed {
+		return nil, ErrClosed
+	}
+	if !ok {
+		var err error
+		config, err = h.load(name)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	subscription := &Subscription{
+		name:    name,
+		hub:     h,
+		updates: make(chan Update, 1),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+	if h.subscribers[name] == nil {
+		h.subscribers[name] = make(map[*Subscription]struct{})
+	}
+	h.subscribers[name][subscription] = struct{}{}
+	h.current[name] = config
+	subscription.offer(Update{Config: config})
+	return subscription, nil
+}
+
+// Publish reloads the named config and delivers it to subscribers that have not seen its relevant version yet.
+// It returns at once if another Publish of the config is waiting to reload it.
+func (h *Hub) Publish(name string) {
+	lock := h.lock(name, true)
+	if lock == nil {
+		return
+	}
+	defer h.unlock(name, lock)
+
+	if h.Subscribers(name) == 0 {
+		return
+	}
+	config, err := h.load(name)
+	if err != nil {
+		h.l.Warn("Unable to load %s config for watchers: %s", name, err)
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return
+	}
+	if err == nil {
+		h.current[name] = config
+	} else {
+		delete(h.current, name)
+	}
+	for subscription := range h.subscribers[name] {
+		subscription.offer(Update{Config: config, Err: err})
+	}
+}
+
+// PublishAll reloads every watched config. It is used after missed notifications, e.g. on reconnect.
+func (h *Hub) PublishAll() {
+	h.mu.Lock()
+	names := make([]string, 0, len(h.subscribers))
+	for name := range h.subscribers {
+		names = append(names, name)
+	}
+	h.mu.Unlock()
+	for _, name := range names {
+		h.Publish(name)
+	}
+}
+
+// Close delivers ErrClosed to every subscription and rejects new ones, so that watch streams end
+// and their clients reconnect to another replica on shutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.closed = true
+	for _, subscribers := range h.subscribers {
+		for subscription := range subscribers {
+			subscription.offer(Update{Err: ErrClosed})
+		}
+	}
+}
+
+// Subscribers returns the number of watchers of the named config.
+func (h *Hub) Subscribers(name string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.subscribers[name])
+}
+
+// SubscriberCounts returns the number of watchers of every watched config.
+func (h *Hub) SubscriberCounts() map[string]int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	counts := make(map[string]int, len(h.subscribers))
+	for name, subscribers := range h.subscribers {
+		counts[name] = len(subscribers)
+	}
+	return counts
+}
+
+// lock locks the loads of the named config. If coalesce is set and a Publish of the config is already waiting
+// for the lock, it returns nil without locking.
+func (h *Hub) lock(name string, coalesce bool) *nameLock {
+	h.mu.Lock()
+	lock := h.locks[name]
+	if lock == nil {
+		lock = &nameLock{}
+		h.locks[name] = lock
+	} else if coalesce && lock.queued {
+		h.mu.Unlock()
+		return nil
+	}
+	lock.refs++
+	if coalesce {
+		lock.queued = true
+	}
+	h.mu.Unlock()
+
+	lock.mu.Lock()
+	if coalesce {
+		h.mu.Lock()
+		lock.queued = false
+		h.mu.Unlock()
+	}
+	return lock
+}
+
+func (h *Hub) unlock(name string, lock *nameLock) {
+	lock.mu.Unlock()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(h.locks, name)
+	}
+}
+
+func (h *Hub) unsubscribe(subscription *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	subscribers := h.subscribers[subscription.name]
+	delete(subscribers, subscription)
+	if len(subscribers) == 0 {
+		delete(h.subscribers, subscription.name)
+		delete(h.current, subscription.name)
+	}
+}
+
+// Subscription receives updates of a single config. Only the latest pending update is kept,
+// so a slow reader never blocks the hub and always observes the newest relevant version.
+type Subscription struct {
+	name    string
+	hub     *Hub
+	updates chan Update
+	version int64
+	once    sync.Once
+}
+
+func (s *Subscription) Updates() <-chan Update {
+	return s.updates
+}
+
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.hub.unsubscribe(s)
+	})
+}
+
+// offer must be called with hub.mu held.
+func (s *Subscription) offer(update Update) {
+	if update.Err == nil && update.Config != nil && update.Config.Version == s.version {
+		return
+	}
+	if update.Err == nil && update.Config != nil {
+		s.version = update.Config.Version
+	} else {
+		s.version = 0
+	}
+	select {
+	case s.updates <- update:
+	default:
+		select {
+		case <-s.updates:
+		default:
+		}
+		s.updates <- update
+	}
+}
@@ -0,0 +1,250 @@
+package watcher
+
+import (
+	"distributedConfig/internal/entity"
+	"distributedConfig/pkg/logger"
+	"errors"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+var errNotFound = errors.New("config not found")
+
+type testStore struct {
+	mu      sync.Mutex
+	configs map[string]*entity.Config
+	loads   int
+}
+
+func (s *testStore) load(name string) (*entity.Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loads++
+	config, ok := s.configs[name]
+	if !ok {
+		return nil, errNotFound
+	}
+	return config, nil
+}
+
+func (s *testStore) set(config *entity.Config) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configs[config.Name] = config
+}
+
+func newTestHub(t *testing.T) (*Hub, *testStore) {
+	t.Helper()
+	store := &testStore{configs: map[string]*entity.Config{}}
+	store.set(&entity.Config{Name: "test", Version: 1, Data: map[string]string{"key1": "value1"}})
+	return NewHub(*logger.New("error"), store.load), store
+}
+
+func TestHub_SubscribeSendsCurrentVersion(t *testing.T) {
+	hub, _ := newTestHub(t)
+	subscription, err := hub.Subscribe("test")
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	update := <-subscription.Updates()
+	require.NoError(t, update.Err)
+	require.Equal(t, int64(1), update.Config.Version)
+}
+
+func TestHub_SubscribeUnknownConfig(t *testing.T) {
+	hub, _ := newTestHub(t)
+	_, err := hub.Subscribe("unknown")
+	require.Equal(t, errNotFound, err)
+	require.Equal(t, 0, hub.Subscribers("unknown"))
+}
+
+func TestHub_PublishFansOutWithSingleLoad(t *testing.T) {
+	hub, store := newTestHub(t)
+	var subscriptions []*Subscription
+	for i := 0; i < 100; i++ {
+		subscription, err := hub.Subscribe("test")
+		require.NoError(t, err)
+		<-subscription.Updates()
+		subscriptions = append(subscriptions, subscription)
+	}
+	require.Equal(t, 1, store.loads)
+
+	store.set(&entity.Config{Name: "test", Version: 2, Data: map[string]string{"key1": "value2"}})
+	hub.Publish("test")
+	require.Equal(t, 2, store.loads)
+	for _, subscription := range subscriptions {
+		update := <-subscription.Updates()
+		require.Equal(t, int64(2), update.Config.Version)
+		subscription.Close()
+	}
+	require.Equal(t, 0, hub.Subscribers("test"))
+}
+
+func TestHub_PublishSkipsUnchangedVersion(t *testing.T) {
+	hub, _ := newTestHub(t)
+	subscription, err := hub.Subscribe("test")
+	require.NoError(t, err)
+	defer subscription.Close()
+	<-subscription.Updates()
+
+	hub.Publish("test")
+	select {
+	case update := <-subscription.Updates():
+		t.Fatalf("unexpected update: %v", update)
+	default:
+	}
+}
+
+func TestHub_SlowSubscriberGetsLatestVersion(t *testing.T) {
+	hub, store := newTestHub(t)
+	subscription, err := hub.Subscribe("test")
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	for version := int64(2); version <= 5; version++ {
+		store.set(&entity.Config{Name: "test", Version: version, Data: map[string]string{}})
+		hub.Publish("test")
+	}
+	update := <-subscription.Updates()
+	require.Equal(t, int64(5), update.Config.Version)
+}
+
+func TestHub_PublishDeletedConfig(t *testing.T) {
+	hub, store := newTestHub(t)
+	subscription, err := hub.Subscribe("test")
+	require.NoError(t, err)
+	defer subscription.Close()
+	<-subscription.Updates()
+
+	store.mu.Lock()
+	delete(store.configs, "test")
+	store.mu.Unlock()
+	hub.Publish("test")
+	update := <-subscription.Updates()
+	require.Equal(t, errNotFound, update.Err)
+}
+
+func TestHub_SubscriberCounts(t *testing.T) {
+	hub, _ := newTestHub(t)
+	require.Empty(t, hub.SubscriberCounts())
+	first, err := hub.Subscribe("test")
+	require.NoError(t, err)
+	second, err := hub.Subscribe("test")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"test": 2}, hub.SubscriberCounts())
+	first.Close()
+	second.Close()
+	require.Empty(t, hub.SubscriberCounts())
+}
+
+func TestHub_Close(t *testing.T) {
+	hub, _ := newTestHub(t)
+	subscription, err := hub.Subscribe("test")
+	require.NoError(t, err)
+	defer subscription.Close()
+	<-subscription.Updates()
+
+	hub.Close()
+	update := <-subscription.Updates()
+	require.Equal(t, ErrClosed, update.Err)
+	hub.Publish("test")
+	select {
+	case update := <-subscription.Updates():
+		t.Fatalf("unexpected update after close: %+v", update)
+	default:
+	}
+	_, err = hub.Subscribe("test")
+	require.Equal(t, ErrClosed, err)
+}
+
+// blockingStore holds the loads of the config named hold until release is closed.
+type blockingStore struct {
+	testStore
+	hold    string
+	loading chan struct{}
+	release chan struct{}
+}
+
+func newBlockingStore(hold string) *blockingStore {
+	store := &blockingStore{testStore: testStore{configs: map[string]*entity.Config{}}, hold: hold,
+		loading: make(chan struct{}, 1), release: make(chan struct{})}
+	store.set(&entity.Config{Name: "slow", Version: 1, Data: map[string]string{}})
+	store.set(&entity.Config{Name: "fast", Version: 1, Data: map[string]string{}})
+	return store
+}
+
+func (s *blockingStore) load(name string) (*entity.Config, error) {
+	s.mu.Lock()
+	hold := s.hold
+	s.mu.Unlock()
+	if name == hold {
+		s.loading <- struct{}{}
+		<-s.release
+	}
+	return s.testStore.load(name)
+}
+
+func (s *blockingStore) holdLoads(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.hold = name
+}
+
+func TestHub_SlowLoadDoesNotBlockOtherConfigs(t *testing.T) {
+	store := newBlockingStore("slow")
+	hub := NewHub(*logger.New("error"), store.load)
+	done := make(chan error)
+	go func() {
+		subscription, err := hub.Subscribe("slow")
+		if err == nil {
+			subscription.Close()
+		}
+		done <- err
+	}()
+	<-store.loading
+
+	subscription, err := hub.Subscribe("fast")
+	require.NoError(t, err)
+	defer subscription.Close()
+	<-subscription.Updates()
+	hub.Publish("fast")
+	close(store.release)
+	require.NoError(t, <-done)
+}
+
+func TestHub_PublishCoalescesWaitingReloads(t *testing.T) {
+	store := newBlockingStore("")
+	hub := NewHub(*logger.New("error"), store.load)
+	subscription, err := hub.Subscribe("slow")
+	require.NoError(t, err)
+	defer subscription.Close()
+
+	// The first reload is held and the second one waits for it, which makes the rest redundant.
+	store.holdLoads("slow")
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		hub.Publish("slow")
+	}()
+	<-store.loading
+	store.holdLoads("")
+	go func() {
+		defer wg.Done()
+		hub.Publish("slow")
+	}()
+	require.Eventually(t, func() bool {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		return hub.locks["slow"].queued
+	}, time.Second, time.Millisecond)
+	for i := 0; i < 10; i++ {
+		hub.Publish("slow")
+	}
+	close(store.release)
+	wg.Wait()
+	require.Equal(t, 3, store.loads)
+	require.Empty(t, hub.locks)
+}
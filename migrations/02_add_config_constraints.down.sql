@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS configs_name_relevant_idx;
+DROP INDEX IF EXISTS configs_name_version_idx;
+
+ALTER TABLE configs ALTER COLUMN relevant SET DEFAULT TRUE;
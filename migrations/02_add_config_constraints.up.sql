@@ -0,0 +1,9 @@
+UPDATE configs c
+SET relevant = FALSE
+WHERE relevant = TRUE
+  AND EXISTS (SELECT 1 FROM configs o WHERE o.name = c.name AND o.relevant = TRUE AND o.version > c.version);
+
+ALTER TABLE configs ALTER COLUMN relevant SET DEFAULT FALSE;
+
+CREATE UNIQUE INDEX configs_name_version_idx ON configs (name, version);
+CREATE UNIQUE INDEX configs_name_relevant_idx ON configs (name) WHERE relevant = TRUE;
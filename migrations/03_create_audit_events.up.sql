@@ -0,0 +1,22 @@
+DROP TABLE IF EXISTS audit_events CASCADE;
+
+CREATE TABLE audit_events
+(
+    id           BIGSERIAL PRIMARY KEY,
+    actor        VARCHAR(255) NOT NULL,
+    method       VARCHAR(255) NOT NULL,
+    config_name  VARCHAR(255) NOT NULL,
+    from_version BIGINT       NOT NULL,
+    to_version   BIGINT       NOT NULL,
+    diff_summary TEXT         NOT NULL,
+    client_ip    VARCHAR(64)  NOT NULL,
+    created_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX audit_events_config_name_idx ON audit_events (config_name, id);
+CREATE INDEX audit_events_actor_idx ON audit_events (actor, id);
+CREATE INDEX audit_events_created_at_idx ON audit_events (created_at);
+
+-- The audit log is append-only.
+CREATE RULE audit_events_no_update AS ON UPDATE TO audit_events DO INSTEAD NOTHING;
+CREATE RULE audit_events_no_delete AS ON DELETE TO audit_events DO INSTEAD NOTHING;
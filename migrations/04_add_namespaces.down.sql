@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS audit_events_namespace_config_name_idx;
+CREATE INDEX audit_events_config_name_idx ON audit_events (config_name, id);
+
+DROP INDEX IF EXISTS configs_namespace_name_relevant_idx;
+DROP INDEX IF EXISTS configs_namespace_name_version_idx;
+
+-- Configs outside the default namespace cannot be told apart from its configs without the column.
+DELETE FROM pairs WHERE config_id IN (SELECT id FROM configs WHERE namespace <> 'default');
+DELETE FROM configs WHERE namespace <> 'default';
+
+CREATE UNIQUE INDEX configs_name_version_idx ON configs (name, version);
+CREATE UNIQUE INDEX configs_name_relevant_idx ON configs (name) WHERE relevant = TRUE;
+
+ALTER TABLE audit_events DROP COLUMN namespace;
+ALTER TABLE configs DROP COLUMN namespace;
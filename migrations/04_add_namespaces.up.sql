@@ -0,0 +1,11 @@
+ALTER TABLE configs ADD COLUMN namespace VARCHAR(255) NOT NULL DEFAULT 'default';
+ALTER TABLE audit_events ADD COLUMN namespace VARCHAR(255) NOT NULL DEFAULT 'default';
+
+DROP INDEX IF EXISTS configs_name_relevant_idx;
+DROP INDEX IF EXISTS configs_name_version_idx;
+
+CREATE UNIQUE INDEX configs_namespace_name_version_idx ON configs (namespace, name, version);
+CREATE UNIQUE INDEX configs_namespace_name_relevant_idx ON configs (namespace, name) WHERE relevant = TRUE;
+
+DROP INDEX IF EXISTS audit_events_config_name_idx;
+CREATE INDEX audit_events_namespace_config_name_idx ON audit_events (namespace, config_name, id);
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS config_overrides;
+
+ALTER TABLE configs DROP COLUMN IF EXISTS promoted_from_version;
+ALTER TABLE configs DROP COLUMN IF EXISTS promoted_from_namespace;
@@ -0,0 +1,13 @@
+ALTER TABLE configs ADD COLUMN promoted_from_namespace VARCHAR(255);
+ALTER TABLE configs ADD COLUMN promoted_from_version BIGINT;
+
+DROP TABLE IF EXISTS config_overrides;
+
+CREATE TABLE config_overrides
+(
+    namespace VARCHAR(255) NOT NULL,
+    name      VARCHAR(255) NOT NULL,
+    key       VARCHAR(255) NOT NULL,
+    value     VARCHAR(255) NOT NULL,
+    PRIMARY KEY (namespace, name, key)
+);
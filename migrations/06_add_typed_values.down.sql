@@ -0,0 +1,5 @@
+-- Typed values keep their string form, which old versions of the service read as strings.
+-- Values longer than 255 characters do not fit the old column and are truncated.
+ALTER TABLE config_overrides ALTER COLUMN value TYPE VARCHAR(255) USING left(value, 255);
+ALTER TABLE pairs DROP COLUMN IF EXISTS type;
+ALTER TABLE pairs ALTER COLUMN value TYPE VARCHAR(255) USING left(value, 255);
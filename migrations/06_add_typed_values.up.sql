@@ -0,0 +1,3 @@
+ALTER TABLE pairs ALTER COLUMN value TYPE TEXT;
+ALTER TABLE pairs ADD COLUMN type VARCHAR(16) NOT NULL DEFAULT 'string';
+ALTER TABLE config_overrides ALTER COLUMN value TYPE TEXT;
@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS config_schemas;
+
+CREATE TABLE config_schemas
+(
+    id                 BIGSERIAL PRIMARY KEY,
+    namespace          VARCHAR(255) NOT NULL,
+    name               VARCHAR(255) NOT NULL,
+    version            BIGINT       NOT NULL,
+    keys               JSONB        NOT NULL,
+    allow_unknown_keys BOOLEAN      NOT NULL DEFAULT FALSE,
+    created_at         TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX config_schemas_namespace_name_version_idx ON config_schemas (namespace, name, version);
@@ -0,0 +1,2 @@
+-- Secret values stay encrypted and are read as strings by old versions of the service.
+ALTER TABLE config_overrides DROP COLUMN IF EXISTS secret;
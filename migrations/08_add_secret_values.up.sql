@@ -0,0 +1,2 @@
+-- Values of pairs of the secret type and secret overrides are stored encrypted with a master key.
+ALTER TABLE config_overrides ADD COLUMN secret BOOLEAN NOT NULL DEFAULT FALSE;
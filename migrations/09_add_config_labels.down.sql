@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS configs_name_trgm_idx;
+DROP INDEX IF EXISTS configs_namespace_last_used_idx;
+DROP INDEX IF EXISTS configs_namespace_created_at_idx;
+DROP INDEX IF EXISTS configs_namespace_name_c_idx;
+
+DROP TABLE IF EXISTS config_labels;
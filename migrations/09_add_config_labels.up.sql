@@ -0,0 +1,26 @@
+DROP TABLE IF EXISTS config_labels;
+
+CREATE TABLE config_labels
+(
+    namespace VARCHAR(255) NOT NULL,
+    name      VARCHAR(255) NOT NULL,
+    key       VARCHAR(63)  NOT NULL,
+    value     VARCHAR(255) NOT NULL,
+    PRIMARY KEY (namespace, name, key)
+);
+
+CREATE INDEX config_labels_namespace_key_value_idx ON config_labels (namespace, key, value);
+
+-- Configs are listed from their relevant versions. Names are compared bytewise, so that the order does not depend
+-- on the collation of the database and prefixes can be looked up in the same indexes.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+DROP INDEX IF EXISTS configs_namespace_name_c_idx;
+DROP INDEX IF EXISTS configs_namespace_created_at_idx;
+DROP INDEX IF EXISTS configs_namespace_last_used_idx;
+DROP INDEX IF EXISTS configs_name_trgm_idx;
+
+CREATE INDEX configs_namespace_name_c_idx ON configs (namespace, name COLLATE "C") WHERE relevant = TRUE;
+CREATE INDEX configs_namespace_created_at_idx ON configs (namespace, created_at, name COLLATE "C") WHERE relevant = TRUE;
+CREATE INDEX configs_namespace_last_used_idx ON configs (namespace, last_used, name COLLATE "C") WHERE relevant = TRUE;
+CREATE INDEX configs_name_trgm_idx ON configs USING gin (name gin_trgm_ops) WHERE relevant = TRUE;
@@ -0,0 +1,97 @@
+package client
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// diskCache keeps the last known good version of every config fetched by the client,
+// one JSON file per config. A config is written only when it differs from the one the client stored last.
+type diskCache struct {
+	dir string
+
+	mu     sync.Mutex
+	stored map[string]*Config
+}
+
+// namespaceDir returns the name of the cache subdirectory of the namespace. The namespace is escaped
+// like config names, and "." and ".." are escaped too, so that it always names a directory inside the cache.
+func namespaceDir(namespace string) string {
+	escaped := url.PathEscape(namespace)
+	if escaped == "." || escaped == ".." {
+		return strings.ReplaceAll(escaped, ".", "%2E")
+	}
+	return escaped
+}
+
+func (c *diskCache) path(name string) string {
+	return filepath.Join(c.dir, url.PathEscape(name)+".json")
+}
+
+func (c *diskCache) load(name string) (*Config, error) {
+	data, err := os.ReadFile(c.path(name))
+	if err != nil {
+		return nil, err
+	}
+	var config Config
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, err
+	}
+	config.FromCache = true
+	return &config, nil
+}
+
+func (c *diskCache) store(config *Config) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if config.equal(c.stored[config.Name]) {
+		return nil
+	}
+	if err := c.write(config); err != nil {
+		return err
+	}
+	if c.stored == nil {
+		c.stored = make(map[string]*Config)
+	}
+	c.stored[config.Name] = config
+	return nil
+}
+
+// remove deletes the config, so that it is not returned once it is deleted from the service.
+func (c *diskCache) remove(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.stored, name)
+	if err := os.Remove(c.path(name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (c *diskCache) write(config *Config) error {
+	if err := os.MkdirAll(c.dir, 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(c.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(config.Name))
+}
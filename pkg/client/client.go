@@ -0,0 +1,325 @@
+// Package client is the Go client of the distributed config service. It keeps watched configs
+// up to date in memory, survives restarts of the service with a local cache and retries failed calls.
+package client
+
+import (
+	"context"
+	"distributedConfig/internal/delivery/proto"
+	"distributedConfig/internal/entity"
+	"errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"math/rand"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+var ErrConfigNotFound = errors.New("config not found")
+
+type Options struct {
+	// Namespace of the configs read by the client, the default namespace if it is empty.
+	Namespace string
+	// CacheDir is the directory of the last known good configs. The cache is disabled if it is empty.
+	CacheDir string
+	// MaxRetries is the number of retries of a failed call, 3 by default.
+	MaxRetries int
+	// InitialBackoff and MaxBackoff bound the exponential delay between retries, 100ms and 10s by default.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RefreshInterval is the polling interval used when the service does not support watching, 30s by default.
+	RefreshInterval time.Duration
+}
+
+func (o *Options) setDefaults() {
+	if o.MaxRetries == 0 {
+		o.MaxRetries = 3
+	}
+	if o.InitialBackoff == 0 {
+		o.InitialBackoff = 100 * time.Millisecond
+	}
+	if o.MaxBackoff == 0 {
+		o.MaxBackoff = 10 * time.Second
+	}
+	if o.RefreshInterval == 0 {
+		o.RefreshInterval = 30 * time.Second
+	}
+}
+
+// Client reads configs from the service. It is safe for concurrent use.
+type Client struct {
+	api     proto.ConfigServiceClient
+	conn    *grpc.ClientConn
+	options Options
+	cache   *diskCache
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu        sync.RWMutex
+	watched   map[string]*Config
+	callbacks []func(config *Config)
+}
+
+// New creates a client on top of an existing connection, which stays owned by the caller.
+func New(conn grpc.ClientConnInterface, options Options) *Client {
+	options.setDefaults()
+	ctx, cancel := context.WithCancel(context.Background())
+	c := &Client{
+		api:     proto.NewConfigServiceClient(conn),
+		options: options,
+		ctx:     ctx,
+		cancel:  cancel,
+		watched: make(map[string]*Config),
+	}
+	if options.CacheDir != "" {
+		c.cache = &diskCache{dir: options.CacheDir}
+		// Configs of the default namespace stay in the cache directory itself, where they were kept
+		// before namespaces.
+		if options.Namespace != "" && options.Namespace != entity.DefaultNamespace {
+			c.cache.dir = filepath.Join(options.CacheDir, namespaceDir(options.Namespace))
+		}
+	}
+	return c
+}
+
+// Dial connects to the service at address. The connection is closed by Close.
+func Dial(address string, options Options, dialOptions ...grpc.DialOption) (*Client, error) {
+	conn, err := grpc.Dial(address, dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	c := New(conn, options)
+	c.conn = conn
+	return c, nil
+}
+
+// Close stops watching configs and closes the connection created by Dial.
+func (c *Client) Close() error {
+	c.cancel()
+	c.wg.Wait()
+	if c.conn != nil {
+		return c.conn.Close()
+	}
+	return nil
+}
+
+// OnChange registers a callback that is called with every new version of a watched config.
+// When a watched config is deleted, the callback is called with a config of version 0 without data.
+// Callbacks are called sequentially from a background goroutine.
+func (c *Client) OnChange(callback func(config *Config)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.callbacks = append(c.callbacks, callback)
+}
+
+// Get returns the relevant version of the config. Watched configs are served from memory.
+// If the service is unreachable, the last known good version from the cache is returned.
+// A config that is not found is removed from the cache.
+func (c *Client) Get(ctx context.Context, name string) (*Config, error) {
+	c.mu.RLock()
+	config, ok := c.watched[name]
+	c.mu.RUnlock()
+	if ok && config != nil {
+		return config, nil
+	}
+
+	config, err := c.fetch(ctx, name)
+	if err == nil {
+		c.store(config)
+		return config, nil
+	}
+	if err == ErrConfigNotFound && c.cache != nil {
+		_ = c.cache.remove(name)
+	}
+	if c.cache != nil && retryable(err) {
+		if cached, cacheErr := c.cache.load(name); cacheErr == nil {
+			return cached, nil
+		}
+	}
+	return nil, err
+}
+
+// Watch keeps the config up to date in the background until the client is closed.
+// Until the first version is received, Get falls back to the service and the cache.
+func (c *Client) Watch(name string) {
+	c.mu.Lock()
+	if _, ok := c.watched[name]; ok {
+		c.mu.Unlock()
+		return
+	}
+	c.watched[name] = nil
+	c.mu.Unlock()
+	if c.cache != nil {
+		if cached, err := c.cache.load(name); err == nil {
+			c.update(cached)
+		}
+	}
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.watch(name)
+	}()
+}
+
+func (c *Client) watch(name string) {
+	attempt := 0
+	for c.ctx.Err() == nil {
+		err := c.receive(name, func() {
+			attempt = 0
+		})
+		if status.Code(err) == codes.Unimplemented {
+			c.poll(name)
+			return
+		}
+		// The config is deleted. It is watched again with backoff, so that it is received if it is created again.
+		if status.Code(err) == codes.NotFound {
+			c.remove(name)
+		}
+		if !c.sleep(c.backoff(attempt)) {
+			return
+		}
+		attempt++
+	}
+}
+
+// receive streams versions of the config until the stream breaks.
+func (c *Client) receive(name string, onReceive func()) error {
+	stream, err := c.api.WatchConfig(c.ctx, &proto.ConfigName{Namespace: c.options.Namespace, ServiceName: name})
+	if err != nil {
+		return err
+	}
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		onReceive()
+		c.update(fromResponse(response))
+	}
+}
+
+func (c *Client) poll(name string) {
+	for c.sleep(c.options.RefreshInterval) {
+		if config, err := c.fetch(c.ctx, name); err == nil {
+			c.update(config)
+		} else if err == ErrConfigNotFound {
+			c.remove(name)
+		}
+	}
+}
+
+func (c *Client) fetch(ctx context.Context, name string) (*Config, error) {
+	var err error
+	for attempt := 0; ; attempt++ {
+		var response *proto.ConfigResponse
+		response, err = c.api.GetConfig(ctx, &proto.ConfigName{Namespace: c.options.Namespace, ServiceName: name})
+		if err == nil {
+			return fromResponse(response), nil
+		}
+		if status.Code(err) == codes.NotFound {
+			return nil, ErrConfigNotFound
+		}
+		if !retryable(err) || attempt >= c.options.MaxRetries {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(c.backoff(attempt)):
+		}
+	}
+}
+
+// update stores a new version of a watched config and calls the callbacks if it changed.
+func (c *Client) update(config *Config) {
+	c.mu.Lock()
+	current, watched := c.watched[config.Name]
+	if !watched || config.equal(current) {
+		c.mu.Unlock()
+		return
+	}
+	c.watched[config.Name] = config
+	callbacks := append([]func(config *Config){}, c.callbacks...)
+	c.mu.Unlock()
+
+	if !config.FromCache && c.cache != nil {
+		_ = c.cache.store(config)
+	}
+	for _, callback := range callbacks {
+		callback(config)
+	}
+}
+
+// remove forgets a deleted watched config, so that Get does not return it from memory or the cache,
+// and calls the callbacks if the config was known.
+func (c *Client) remove(name string) {
+	c.mu.Lock()
+	current, watched := c.watched[name]
+	if !watched {
+		c.mu.Unlock()
+		return
+	}
+	c.watched[name] = nil
+	callbacks := append([]func(config *Config){}, c.callbacks...)
+	c.mu.Unlock()
+
+	if c.cache != nil {
+		_ = c.cache.remove(name)
+	}
+	if current == nil {
+		return
+	}
+	for _, callback := range callbacks {
+		callback(&Config{Name: name})
+	}
+}
+
+func (c *Client) store(config *Config) {
+	if c.cache != nil {
+		_ = c.cache.store(config)
+	}
+}
+
+func (c *Client) sleep(d time.Duration) bool {
+	select {
+	case <-c.ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// backoff returns an exponential delay with jitter for the given attempt.
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.options.InitialBackoff
+	for i := 0; i < attempt && d < c.options.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > c.options.MaxBackoff {
+		d = c.options.MaxBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func retryable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+func fromResponse(response *proto.ConfigResponse) *Config {
+	return &Config{
+		Name:      response.GetConfig().GetServiceName(),
+		Version:   response.GetVersion(),
+		Data:      response.GetConfig().GetData(),
+		CreatedAt: response.GetCreatedAt().AsTime(),
+	}
+}
@@ -0,0 +1,306 @@
+package client
+
+import (
+	"context"
+	cfg "distributedConfig/config"
+	grpc_service "distributedConfig/internal/delivery/grpc"
+	"distributedConfig/internal/delivery/proto"
+	"distributedConfig/internal/entity"
+	"distributedConfig/internal/repository/memory_repository"
+	"distributedConfig/internal/usecase"
+	"distributedConfig/internal/watcher"
+	"distributedConfig/pkg/logger"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// hubNotifier delivers changes synchronously, so watchers observe them without waiting for a listener.
+type hubNotifier struct {
+	publisher watcher.Publisher
+}
+
+func (n *hubNotifier) NotifyConfigChanged(name string) error {
+	n.publisher.Publish(name)
+	return nil
+}
+
+func (n *hubNotifier) Listen(ctx context.Context, publisher watcher.Publisher) error {
+	n.publisher = publisher
+	return nil
+}
+
+type testServer struct {
+	server   *grpc.Server
+	listener *bufconn.Listener
+	useCase  *usecase.ConfigUseCase
+}
+
+func newTestServer(t *testing.T, serverOptions ...grpc.ServerOption) *testServer {
+	t.Helper()
+	notifier := &hubNotifier{}
+	configRepository, auditRepository := memory_repository.NewAuditedConfigRepository()
+	configUseCase := usecase.NewConfigUseCase(*logger.New("error"), configRepository,
+		auditRepository, notifier, &cfg.Config{Server: cfg.ServerConfig{DeleteConfigIfRecentlyUsed: true}})
+	require.NoError(t, notifier.Listen(context.Background(), configUseCase.Watchers()))
+
+	server := grpc.NewServer(serverOptions...)
+	proto.RegisterConfigServiceServer(server, grpc_service.NewConfigService(*configUseCase))
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+	return &testServer{server: server, listener: listener, useCase: configUseCase}
+}
+
+func (s *testServer) dial(t *testing.T, options Options) *Client {
+	t.Helper()
+	c, err := Dial("bufnet", options,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return s.listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+	return c
+}
+
+func (s *testServer) createConfig(t *testing.T, data map[string]string) {
+	t.Helper()
+	require.NoError(t, s.useCase.CreateConfig(context.Background(),
+		&entity.Config{Namespace: entity.DefaultNamespace, Name: "test", Data: data, Version: 1}))
+}
+
+func testOptions() Options {
+	return Options{MaxRetries: 2, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond}
+}
+
+func TestClient_Get(t *testing.T) {
+	s := newTestServer(t)
+	s.createConfig(t, map[string]string{"host": "localhost", "port": "8080", "debug": "true", "timeout": "1s"})
+	c := s.dial(t, testOptions())
+
+	config, err := c.Get(context.Background(), "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), config.Version)
+	require.False(t, config.FromCache)
+	host, err := config.GetString("host")
+	require.NoError(t, err)
+	require.Equal(t, "localhost", host)
+	port, err := config.GetInt("port")
+	require.NoError(t, err)
+	require.Equal(t, int64(8080), port)
+	debug, err := config.GetBool("debug")
+	require.NoError(t, err)
+	require.True(t, debug)
+	timeout, err := config.GetDuration("timeout")
+	require.NoError(t, err)
+	require.Equal(t, time.Second, timeout)
+	_, err = config.GetString("unknown")
+	require.ErrorIs(t, err, ErrKeyNotFound)
+
+	_, err = c.Get(context.Background(), "unknown")
+	require.Equal(t, ErrConfigNotFound, err)
+}
+
+func TestClient_Retries(t *testing.T) {
+	var calls int32
+	s := newTestServer(t, grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if atomic.AddInt32(&calls, 1) <= 2 {
+			return nil, status.Error(codes.Unavailable, "unavailable")
+		}
+		return handler(ctx, req)
+	}))
+	s.createConfig(t, map[string]string{"key": "value"})
+
+	config, err := s.dial(t, testOptions()).Get(context.Background(), "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), config.Version)
+	require.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, -10)
+	options := testOptions()
+	options.MaxRetries = 1
+	_, err = s.dial(t, options).Get(context.Background(), "test")
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestClient_CacheFallback(t *testing.T) {
+	s := newTestServer(t)
+	s.createConfig(t, map[string]string{"key": "value"})
+	options := testOptions()
+	options.CacheDir = t.TempDir()
+
+	_, err := s.dial(t, options).Get(context.Background(), "test")
+	require.NoError(t, err)
+	s.server.Stop()
+
+	config, err := s.dial(t, options).Get(context.Background(), "test")
+	require.NoError(t, err)
+	require.True(t, config.FromCache)
+	require.Equal(t, map[string]string{"key": "value"}, config.Data)
+
+	_, err = s.dial(t, options).Get(context.Background(), "unknown")
+	require.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestClient_CacheWrites(t *testing.T) {
+	s := newTestServer(t)
+	s.createConfig(t, map[string]string{"key": "value"})
+	options := testOptions()
+	options.CacheDir = t.TempDir()
+	c := s.dial(t, options)
+	path := filepath.Join(options.CacheDir, "test.json")
+
+	_, err := c.Get(context.Background(), "test")
+	require.NoError(t, err)
+	require.NoError(t, os.Remove(path))
+	_, err = c.Get(context.Background(), "test")
+	require.NoError(t, err)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "an unchanged version is not written again")
+
+	require.NoError(t, s.useCase.UpdateConfig(context.Background(), &entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Data:      map[string]string{"key": "new"},
+	}, 0))
+	_, err = c.Get(context.Background(), "test")
+	require.NoError(t, err)
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+
+	require.NoError(t, s.useCase.DeleteConfig(context.Background(), entity.DefaultNamespace, "test"))
+	_, err = c.Get(context.Background(), "test")
+	require.Equal(t, ErrConfigNotFound, err)
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err), "a deleted config is removed from the cache")
+}
+
+func TestClient_Namespace(t *testing.T) {
+	s := newTestServer(t)
+	s.createConfig(t, map[string]string{"key": "default"})
+	require.NoError(t, s.useCase.CreateConfig(context.Background(),
+		&entity.Config{Namespace: "staging", Name: "test", Data: map[string]string{"key": "staging"}, Version: 1}))
+	options := testOptions()
+	options.CacheDir = t.TempDir()
+	options.Namespace = "staging"
+
+	config, err := s.dial(t, options).Get(context.Background(), "test")
+	require.NoError(t, err)
+	require.Equal(t, "staging", config.Data["key"])
+	_, err = os.Stat(filepath.Join(options.CacheDir, "staging", "test.json"))
+	require.NoError(t, err)
+
+	config, err = s.dial(t, testOptions()).Get(context.Background(), "test")
+	require.NoError(t, err)
+	require.Equal(t, "default", config.Data["key"])
+}
+
+func TestClient_NamespaceStaysInCacheDir(t *testing.T) {
+	parent := t.TempDir()
+	cacheDir := filepath.Join(parent, "cache")
+	for namespace, dir := range map[string]string{
+		"../x": "..%2Fx",
+		"..":   "%2E%2E",
+		".":    "%2E",
+		"a/b":  "a%2Fb",
+	} {
+		c := New(nil, Options{CacheDir: cacheDir, Namespace: namespace})
+		require.Equal(t, filepath.Join(cacheDir, dir), c.cache.dir, namespace)
+		require.NoError(t, c.cache.store(&Config{Name: "test", Version: 1, Data: map[string]string{"key": "value"}}))
+		_, err := os.Stat(filepath.Join(cacheDir, dir, "test.json"))
+		require.NoError(t, err, namespace)
+		require.NoError(t, c.Close())
+	}
+	entries, err := os.ReadDir(parent)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+}
+
+func TestClient_Watch(t *testing.T) {
+	s := newTestServer(t)
+	s.createConfig(t, map[string]string{"key": "value"})
+	c := s.dial(t, testOptions())
+	changes := make(chan *Config, 10)
+	c.OnChange(func(config *Config) {
+		changes <- config
+	})
+
+	c.Watch("test")
+	nextChange := func() *Config {
+		t.Helper()
+		select {
+		case config := <-changes:
+			return config
+		case <-time.After(time.Second):
+			t.Fatal("no change received")
+			return nil
+		}
+	}
+	require.Equal(t, int64(1), nextChange().Version)
+
+	require.NoError(t, s.useCase.UpdateConfig(context.Background(), &entity.Config{
+		Namespace: entity.DefaultNamespace,
+		Name:      "test",
+		Data:      map[string]string{"key": "new"},
+	}, 0))
+	config := nextChange()
+	require.Equal(t, int64(2), config.Version)
+	require.Equal(t, "new", config.Data["key"])
+
+	watched, err := c.Get(context.Background(), "test")
+	require.NoError(t, err)
+	require.Equal(t, int64(2), watched.Version)
+}
+
+func TestClient_WatchDeleted(t *testing.T) {
+	s := newTestServer(t)
+	s.createConfig(t, map[string]string{"key": "value"})
+	options := testOptions()
+	options.CacheDir = t.TempDir()
+	c := s.dial(t, options)
+	changes := make(chan *Config, 10)
+	c.OnChange(func(config *Config) {
+		changes <- config
+	})
+
+	c.Watch("test")
+	nextChange := func() *Config {
+		t.Helper()
+		select {
+		case config := <-changes:
+			return config
+		case <-time.After(time.Second):
+			t.Fatal("no change received")
+			return nil
+		}
+	}
+	require.Equal(t, int64(1), nextChange().Version)
+
+	require.NoError(t, s.useCase.DeleteConfig(context.Background(), entity.DefaultNamespace, "test"))
+	deleted := nextChange()
+	require.Equal(t, "test", deleted.Name)
+	require.Zero(t, deleted.Version)
+	require.Nil(t, deleted.Data)
+	_, err := c.Get(context.Background(), "test")
+	require.Equal(t, ErrConfigNotFound, err)
+	_, err = os.Stat(filepath.Join(options.CacheDir, "test.json"))
+	require.True(t, os.IsNotExist(err))
+
+	s.createConfig(t, map[string]string{"key": "again"})
+	require.Equal(t, "again", nextChange().Data["key"])
+}
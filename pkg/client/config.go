@@ -0,0 +1,65 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var ErrKeyNotFound = errors.New("key not found")
+
+// Config is a version of a config received from the service.
+type Config struct {
+	Name      string            `json:"name"`
+	Version   int64             `json:"version"`
+	Data      map[string]string `json:"data"`
+	CreatedAt time.Time         `json:"created_at"`
+	// FromCache is set when the service was unreachable and the config was read from the on-disk cache.
+	FromCache bool `json:"-"`
+}
+
+func (c *Config) GetString(key string) (string, error) {
+	value, ok := c.Data[key]
+	if !ok {
+		return "", fmt.Errorf("%s: %w", key, ErrKeyNotFound)
+	}
+	return value, nil
+}
+
+func (c *Config) GetInt(key string) (int64, error) {
+	value, err := c.GetString(key)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func (c *Config) GetBool(key string) (bool, error) {
+	value, err := c.GetString(key)
+	if err != nil {
+		return false, err
+	}
+	return strconv.ParseBool(value)
+}
+
+// GetDuration parses values such as "300ms" or "1h30m".
+func (c *Config) GetDuration(key string) (time.Duration, error) {
+	value, err := c.GetString(key)
+	if err != nil {
+		return 0, err
+	}
+	return time.ParseDuration(value)
+}
+
+func (c *Config) equal(other *Config) bool {
+	if other == nil || c.Version != other.Version || len(c.Data) != len(other.Data) {
+		return false
+	}
+	for key, value := range c.Data {
+		if otherValue, ok := other.Data[key]; !ok || otherValue != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,28 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// SchemaVersion is the version of the latest migration in the migrations directory, which the service requires.
+const SchemaVersion = 9
+
+// CheckSchemaVersion returns an error unless the migrations applied by golang-migrate are clean and at least
+// as new as SchemaVersion. Newer versions are allowed, so that a replica keeps serving while a newer one is rolled out.
+func CheckSchemaVersion(ctx context.Context, db *sql.DB) error {
+	var version int64
+	var dirty bool
+	err := db.QueryRowContext(ctx, "SELECT version, dirty FROM schema_migrations").Scan(&version, &dirty)
+	if err != nil {
+		return fmt.Errorf("unable to read migration version: %w", err)
+	}
+	if dirty {
+		return fmt.Errorf("migration %d failed and left the schema dirty", version)
+	}
+	if version < SchemaVersion {
+		return fmt.Errorf("schema version %d is older than %d, migrations are not applied", version, SchemaVersion)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/require"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSchemaVersion_LatestMigration(t *testing.T) {
+	entries, err := os.ReadDir("../../migrations")
+	require.NoError(t, err)
+	var latest int64
+	for _, entry := range entries {
+		version, err := strconv.ParseInt(strings.SplitN(entry.Name(), "_", 2)[0], 10, 64)
+		require.NoError(t, err)
+		if version > latest {
+			latest = version
+		}
+	}
+	require.Equal(t, latest, int64(SchemaVersion), "SchemaVersion must be updated with new migrations")
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	testCases := []struct {
+		name    string
+		version int64
+		dirty   bool
+		err     error
+		wantErr bool
+	}{
+		{name: "current", version: SchemaVersion},
+		{name: "newer", version: SchemaVersion + 1},
+		{name: "older", version: SchemaVersion - 1, wantErr: true},
+		{name: "dirty", version: SchemaVersion, dirty: true, wantErr: true},
+		{name: "not migrated", err: sql.ErrNoRows, wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			require.NoError(t, err)
+			defer db.Close()
+			query := mock.ExpectQuery("SELECT version, dirty FROM schema_migrations")
+			if tc.err != nil {
+				query.WillReturnError(tc.err)
+			} else {
+				query.WillReturnRows(sqlmock.NewRows([]string{"version", "dirty"}).AddRow(tc.version, tc.dirty))
+			}
+			err = CheckSchemaVersion(context.Background(), db)
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
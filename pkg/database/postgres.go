@@ -8,15 +8,7 @@ import (
 )
 
 func NewDB(c *config.Config) (*sql.DB, error) {
-	dataSourceName := fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s",
-		c.Database.Host,
-		c.Database.Port,
-		c.Database.User,
-		c.Database.Dbname,
-		c.Database.Password,
-	)
-
-	db, err := sql.Open(c.Database.Driver, dataSourceName)
+	db, err := sql.Open(c.Database.Driver, DataSourceName(c))
 	if err != nil {
 		return nil, err
 	}
@@ -26,3 +18,13 @@ func NewDB(c *config.Config) (*sql.DB, error) {
 
 	return db, nil
 }
+
+func DataSourceName(c *config.Config) string {
+	return fmt.Sprintf("host=%s port=%s user=%s dbname=%s sslmode=disable password=%s",
+		c.Database.Host,
+		c.Database.Port,
+		c.Database.User,
+		c.Database.Dbname,
+		c.Database.Password,
+	)
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"github.com/rs/zerolog"
+	"io"
 	"os"
 	"strings"
 )
@@ -21,6 +22,11 @@ type Logger struct {
 var _ Interface = (*Logger)(nil)
 
 func New(level string) *Logger {
+	return NewWriter(level, os.Stdout)
+}
+
+// NewWriter creates a logger that writes to w instead of the standard output.
+func NewWriter(level string, w io.Writer) *Logger {
 	var l zerolog.Level
 
 	switch strings.ToLower(level) {
@@ -37,7 +43,7 @@ func New(level string) *Logger {
 	}
 
 	skipFrameCount := 3
-	logger := zerolog.New(os.Stdout).With().Timestamp().CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + skipFrameCount).Logger()
+	logger := zerolog.New(w).With().Timestamp().CallerWithSkipFrameCount(zerolog.CallerSkipFrameCount + skipFrameCount).Logger()
 	zerolog.SetGlobalLevel(l)
 	return &Logger{
 		logger: &logger,